@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// UserPermissions syncs the repository permissions of users from the authz providers to the
+// database (see db.UserPermissions), for the background permissions syncer of repo-updater.
+var UserPermissions = &userPermissions{}
+
+type userPermissions struct{}
+
+// syncReposPageSize is the number of repositories whose permissions are fetched from an authz
+// provider at once.
+const syncReposPageSize = 1000
+
+// Sync fetches the permissions of the user on the repositories of each authz provider and stores
+// them. The error of an authz provider is stored (see db.UserPermissions.SetSyncError) instead of
+// being returned, so that the permissions of the other authz providers are still synced. Either
+// way the sync time of each authz provider is updated, so that the user is not synced again before
+// the next sync interval (see db.UserPermissions.ListUsersToSync, which also ignores users when
+// there are no authz providers).
+func (s *userPermissions) Sync(ctx context.Context, userID int32) (err error) {
+	ctx, done := trace(ctx, "UserPermissions", "Sync", userID, &err)
+	defer done()
+
+	_, authzProviders := authz.GetProviders()
+	if len(authzProviders) == 0 {
+		return nil
+	}
+
+	// An error loading the user is stored for each authz provider, like their own errors.
+	user, userErr := db.Users.GetByID(ctx, userID)
+	var accts []*extsvc.ExternalAccount
+	if userErr == nil {
+		accts, userErr = db.ExternalAccounts.List(ctx, db.ExternalAccountsListOptions{UserID: userID})
+	}
+
+	for _, p := range authzProviders {
+		var repoIDs []api.RepoID
+		err := userErr
+		if err == nil {
+			repoIDs, err = s.fetchRepoIDs(ctx, user, accts, p)
+		}
+		if err != nil {
+			log15.Warn("Failed to sync repository permissions of user", "user", userID, "authzProvider", p.ServiceID(), "error", err)
+			if err := db.UserPermissions.SetSyncError(ctx, userID, authz.Read, p.ServiceType(), p.ServiceID(), err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		err = db.UserPermissions.Set(ctx, &db.UserPermissions{
+			UserID:      userID,
+			Perm:        authz.Read,
+			ServiceType: p.ServiceType(),
+			ServiceID:   p.ServiceID(),
+			RepoIDs:     repoIDs,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchRepoIDs returns the IDs of the repositories of the authz provider p which the user can read.
+func (*userPermissions) fetchRepoIDs(ctx context.Context, user *types.User, accts []*extsvc.ExternalAccount, p authz.Provider) ([]api.RepoID, error) {
+	// Determine the external account to use, as when checking permissions at request time (see
+	// db.authzFilter).
+	var acct *extsvc.ExternalAccount
+	for _, a := range accts {
+		if a.ServiceID == p.ServiceID() && a.ServiceType == p.ServiceType() {
+			acct = a
+			break
+		}
+	}
+	if acct == nil {
+		var err error
+		if acct, err = p.FetchAccount(ctx, user, accts); err != nil {
+			return nil, err
+		}
+		if acct != nil {
+			if err := db.ExternalAccounts.AssociateUserAndSave(ctx, user.ID, acct.ExternalAccountSpec, acct.ExternalAccountData); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 🚨 SECURITY: List all repositories of the authz provider (not only those which the actor
+	// of ctx can read), whose permissions are then checked for the user.
+	internalCtx := actor.WithActor(ctx, &actor.Actor{Internal: true})
+
+	var repoIDs []api.RepoID
+	for offset := 0; ; offset += syncReposPageSize {
+		repos, err := db.Repos.List(internalCtx, db.ReposListOptions{
+			Enabled:             true,
+			Disabled:            true,
+			ExternalServiceType: p.ServiceType(),
+			ExternalServiceID:   p.ServiceID(),
+			LimitOffset:         &db.LimitOffset{Limit: syncReposPageSize, Offset: offset},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(repos) == 0 {
+			break
+		}
+
+		perms, err := p.RepoPerms(ctx, acct, authz.ToRepos(repos))
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if perms[repo.Name][authz.Read] {
+				repoIDs = append(repoIDs, repo.ID)
+			}
+		}
+
+		if len(repos) < syncReposPageSize {
+			break
+		}
+	}
+	return repoIDs, nil
+}
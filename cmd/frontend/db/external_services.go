@@ -31,7 +31,9 @@ type externalServices struct{}
 // external services.
 var ExternalServiceKinds = map[string]ExternalServiceKind{
 	"AWSCODECOMMIT":   {CodeHost: true, Definition: "AWSCodeCommitConnection"},
+	"BITBUCKETCLOUD":  {CodeHost: true, Definition: "BitbucketCloudConnection"},
 	"BITBUCKETSERVER": {CodeHost: true, Definition: "BitbucketServerConnection"},
+	"GITEA":           {CodeHost: true, Definition: "GiteaConnection"},
 	"GITHUB":          {CodeHost: true, Definition: "GitHubConnection"},
 	"GITLAB":          {CodeHost: true, Definition: "GitLabConnection"},
 	"GITOLITE":        {CodeHost: true, Definition: "GitoliteConnection"},
@@ -355,6 +357,17 @@ func (c *externalServices) ListAWSCodeCommitConnections(ctx context.Context) ([]
 	return connections, nil
 }
 
+// ListBitbucketCloudConnections returns a list of BitbucketCloudConnection configs.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is a site admin.
+func (c *externalServices) ListBitbucketCloudConnections(ctx context.Context) ([]*schema.BitbucketCloudConnection, error) {
+	var connections []*schema.BitbucketCloudConnection
+	if err := c.listConfigs(ctx, "BITBUCKETCLOUD", &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
 // ListBitbucketServerConnections returns a list of BitbucketServer configs.
 //
 // 🚨 SECURITY: The caller must ensure that the actor is a site admin.
@@ -388,6 +401,17 @@ func (c *externalServices) ListGitLabConnections(ctx context.Context) ([]*schema
 	return connections, nil
 }
 
+// ListGiteaConnections returns a list of GiteaConnection configs.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is a site admin.
+func (c *externalServices) ListGiteaConnections(ctx context.Context) ([]*schema.GiteaConnection, error) {
+	var connections []*schema.GiteaConnection
+	if err := c.listConfigs(ctx, "GITEA", &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
 // ListGitoliteConnections returns a list of GitoliteConnection configs.
 //
 // 🚨 SECURITY: The caller must ensure that the actor is a site admin.
@@ -200,6 +200,48 @@ func TestExternalServices_ValidateConfig(t *testing.T) {
 			config: `{"url": "https://bitbucket.com/", "token": "secret-token"}`,
 			assert: equals("<nil>"),
 		},
+		{
+			kind:   "BITBUCKETCLOUD",
+			desc:   "without username nor appPassword",
+			config: `{}`,
+			assert: includes(
+				"username: username is required",
+				"appPassword: appPassword is required",
+			),
+		},
+		{
+			kind:   "BITBUCKETCLOUD",
+			desc:   "valid",
+			config: `{"username": "alice", "appPassword": "secret", "teams": ["myteam"]}`,
+			assert: equals("<nil>"),
+		},
+		{
+			kind:   "GITEA",
+			desc:   "without url",
+			config: `{}`,
+			assert: includes("url: url is required"),
+		},
+		{
+			kind:   "GITEA",
+			desc:   "with example.com url and badscheme",
+			config: `{"url": "badscheme://gitea.example.com"}`,
+			assert: includes(
+				"url: Must not validate the schema (not)",
+				"url: Does not match pattern '^https?://'",
+			),
+		},
+		{
+			kind:   "GITEA",
+			desc:   "invalid repos",
+			config: `{"repos": ["foo"]}`,
+			assert: includes(`repos.0: Does not match pattern '^[\w.-]+/[\w.-]+$'`),
+		},
+		{
+			kind:   "GITEA",
+			desc:   "valid",
+			config: `{"url": "https://gitea.io/", "token": "secret-token", "repositoryQuery": ["affiliated", "?q=foo"], "repos": ["org/repo"]}`,
+			assert: equals("<nil>"),
+		},
 		{
 			kind:   "GITHUB",
 			desc:   "without url nor token",
@@ -21,6 +21,9 @@ type MockStores struct {
 
 	ExternalAccounts MockExternalAccounts
 
+	UserPermissions    MockUserPermissions
+	RepoUpdateSchedule MockRepoUpdateSchedule
+
 	OrgInvitations MockOrgInvitations
 
 	ExternalServices MockExternalServices
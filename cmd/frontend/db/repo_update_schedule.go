@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+)
+
+// repoUpdateSchedule provides access to the `repo_update_schedule` table, which persists the state
+// of the repo-updater update scheduler.
+type repoUpdateSchedule struct{}
+
+// List lists the persisted update scheduler state of all repositories.
+func (*repoUpdateSchedule) List(ctx context.Context) ([]*api.RepoUpdateScheduleState, error) {
+	if Mocks.RepoUpdateSchedule.List != nil {
+		return Mocks.RepoUpdateSchedule.List()
+	}
+
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT repo.name, s.interval_seconds, s.due_at, s.last_error, s.last_error_at
+FROM repo_update_schedule s
+JOIN repo ON repo.id=s.repo_id
+ORDER BY s.due_at
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []*api.RepoUpdateScheduleState
+	for rows.Next() {
+		var (
+			s         api.RepoUpdateScheduleState
+			lastError sql.NullString
+		)
+		if err := rows.Scan(&s.Repo, &s.IntervalSeconds, &s.Due, &lastError, &s.LastErrorAt); err != nil {
+			return nil, err
+		}
+		s.LastError = lastError.String
+		states = append(states, &s)
+	}
+	return states, rows.Err()
+}
+
+// Upsert stores the update scheduler state of the repositories, replacing their previous state.
+// The states of repositories that don't exist are ignored.
+func (*repoUpdateSchedule) Upsert(ctx context.Context, states []*api.RepoUpdateScheduleState) error {
+	if Mocks.RepoUpdateSchedule.Upsert != nil {
+		return Mocks.RepoUpdateSchedule.Upsert(states)
+	}
+
+	if len(states) == 0 {
+		return nil
+	}
+	statesJSON, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	_, err = dbconn.Global.ExecContext(ctx, `
+INSERT INTO repo_update_schedule(repo_id, interval_seconds, due_at, last_error, last_error_at, updated_at)
+SELECT repo.id, s."intervalSeconds", s.due, NULLIF(s."lastError", ''), s."lastErrorAt", now()
+FROM json_to_recordset($1::json) AS s(repo text, "intervalSeconds" integer, due timestamp with time zone, "lastError" text, "lastErrorAt" timestamp with time zone)
+JOIN repo ON repo.name=s.repo
+ON CONFLICT (repo_id) DO UPDATE
+SET interval_seconds=excluded.interval_seconds, due_at=excluded.due_at, last_error=excluded.last_error, last_error_at=excluded.last_error_at, updated_at=excluded.updated_at
+`, string(statesJSON))
+	return err
+}
+
+type MockRepoUpdateSchedule struct {
+	List   func() ([]*api.RepoUpdateScheduleState, error)
+	Upsert func(states []*api.RepoUpdateScheduleState) error
+}
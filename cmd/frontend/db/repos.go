@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	regexpsyntax "regexp/syntax"
 	"strings"
@@ -99,18 +100,24 @@ func (s *repos) Count(ctx context.Context, opt ReposListOptions) (int, error) {
 	return count, nil
 }
 
+// getBySQL returns the repositories selected by querySuffix on which the current user has read
+// permission. The querySuffix may refer to the perm.authorized column (see syncedPermsJoin).
 func (s *repos) getBySQL(ctx context.Context, querySuffix *sqlf.Query) ([]*types.Repo, error) {
-	q := sqlf.Sprintf("SELECT id, name, description, language, enabled, created_at, updated_at, external_id, external_service_type, external_service_id FROM repo %s", querySuffix)
+	q := sqlf.Sprintf("SELECT id, name, description, language, enabled, created_at, updated_at, external_id, external_service_type, external_service_id, perm.authorized FROM repo %s %s", syncedPermsJoin(ctx, authz.Read), querySuffix)
 	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var repos []*types.Repo
+	var (
+		repos  []*types.Repo
+		synced = make(map[api.RepoName]bool)
+	)
 	for rows.Next() {
 		var repo types.Repo
 		var spec dbExternalRepoSpec
+		var authorized sql.NullBool
 
 		if err := rows.Scan(
 			&repo.ID,
@@ -121,11 +128,15 @@ func (s *repos) getBySQL(ctx context.Context, querySuffix *sqlf.Query) ([]*types
 			&repo.CreatedAt,
 			&repo.UpdatedAt,
 			&spec.id, &spec.serviceType, &spec.serviceID,
+			&authorized,
 		); err != nil {
 			return nil, err
 		}
 
 		repo.ExternalRepo = spec.toAPISpec()
+		if authorized.Valid {
+			synced[repo.Name] = authorized.Bool
+		}
 
 		repos = append(repos, &repo)
 	}
@@ -134,7 +145,7 @@ func (s *repos) getBySQL(ctx context.Context, querySuffix *sqlf.Query) ([]*types
 	}
 
 	// 🚨 SECURITY: This enforces repository permissions
-	return authzFilter(ctx, repos, authz.Read)
+	return authzFilter(ctx, repos, authz.Read, synced)
 }
 
 // ReposListOptions specifies the options for listing repositories.
@@ -175,6 +186,12 @@ type ReposListOptions struct {
 	// OnlyArchived excludes non-archived repositories from the list.
 	OnlyArchived bool
 
+	// ExternalServiceType and ExternalServiceID, if set, only include repositories from the
+	// external service (such as a code host) with this service type and ID (see
+	// api.ExternalRepoSpec).
+	ExternalServiceType string
+	ExternalServiceID   string
+
 	// Index when set will only include repositories which should be indexed
 	// if true. If false it will exclude repositories which should be
 	// indexed. An example use case of this is for indexed search only
@@ -242,6 +259,8 @@ func (s *repos) List(ctx context.Context, opt ReposListOptions) (results []*type
 	if err != nil {
 		return nil, err
 	}
+	// Exclude the repositories that the synced permissions deny before the limit is applied.
+	conds = append(conds, sqlf.Sprintf("perm.authorized IS NOT FALSE"))
 
 	// fetch matching repos
 	fetchSQL := sqlf.Sprintf("WHERE %s %s %s", sqlf.Join(conds, "AND"), opt.OrderBy.SQL(), opt.LimitOffset.SQL())
@@ -370,6 +389,12 @@ func (*repos) listSQL(opt ReposListOptions) (conds []*sqlf.Query, err error) {
 	if opt.OnlyArchived {
 		conds = append(conds, sqlf.Sprintf("archived"))
 	}
+	if opt.ExternalServiceType != "" {
+		conds = append(conds, sqlf.Sprintf("external_service_type=%s", opt.ExternalServiceType))
+	}
+	if opt.ExternalServiceID != "" {
+		conds = append(conds, sqlf.Sprintf("external_service_id=%s", opt.ExternalServiceID))
+	}
 
 	if opt.Index != nil {
 		// We don't currently have an index column, but when we want the
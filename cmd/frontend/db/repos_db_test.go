@@ -7,11 +7,16 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db/query"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/actor"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 /*
@@ -579,3 +584,86 @@ func TestRepos_Create_dupe(t *testing.T) {
 	// Add another repo with the same name.
 	createRepo(ctx, t, &types.Repo{Name: "a/b"})
 }
+
+func TestRepos_List_syncedPerms(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		ExperimentalFeatures:    &schema.ExperimentalFeatures{PermissionsBackgroundSync: "enabled"},
+		PermissionsSyncInterval: 60,
+	}})
+	defer conf.Mock(nil)
+
+	ctx := dbtesting.TestContext(t)
+
+	user, err := Users.Create(ctx, NewUser{Username: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Users.SetIsSiteAdmin(ctx, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var repos []*types.Repo
+	for _, name := range []api.RepoName{"gitlab.mine/r1", "gitlab.mine/r2"} {
+		err := Repos.Upsert(ctx, api.InsertRepoOp{
+			Name:         name,
+			Enabled:      true,
+			ExternalRepo: &api.ExternalRepoSpec{ID: string(name), ServiceType: "gitlab", ServiceID: "https://gitlab.mine/"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := Repos.GetByName(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repos = append(repos, repo)
+	}
+
+	// The authz provider allows reading r2, the synced permissions allow reading r1.
+	authz.SetProviders(false, []authz.Provider{
+		&MockAuthzProvider{
+			serviceID:   "https://gitlab.mine/",
+			serviceType: "gitlab",
+			repos:       map[api.RepoName]struct{}{"gitlab.mine/r1": {}, "gitlab.mine/r2": {}},
+			perms: map[extsvc.ExternalAccount]map[api.RepoName]map[authz.Perm]bool{
+				{}: {"gitlab.mine/r2": {authz.Read: true}},
+			},
+		},
+	})
+	defer authz.SetProviders(true, nil)
+	err = UserPermissions.Set(ctx, &UserPermissions{
+		UserID:      user.ID,
+		Perm:        authz.Read,
+		ServiceType: "gitlab",
+		ServiceID:   "https://gitlab.mine/",
+		RepoIDs:     []api.RepoID{repos[0].ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userCtx := actor.WithActor(ctx, &actor.Actor{UID: user.ID})
+	{
+		got, err := Repos.List(userCtx, ReposListOptions{Enabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertJSONEqual(t, repos[:1], got)
+	}
+
+	// Stale synced permissions are not used.
+	if _, err := dbconn.Global.ExecContext(ctx, "UPDATE user_permissions SET synced_at=now()-interval '3 hours'"); err != nil {
+		t.Fatal(err)
+	}
+	{
+		got, err := Repos.List(userCtx, ReposListOptions{Enabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertJSONEqual(t, repos[1:], got)
+	}
+}
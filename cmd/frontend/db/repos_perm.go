@@ -2,11 +2,14 @@ package db
 
 import (
 	"context"
+	"time"
 
+	"github.com/keegancsmith/sqlf"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/actor"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
 	log15 "gopkg.in/inconshreveable/log15.v2"
 )
@@ -15,8 +18,9 @@ var mockAuthzFilter func(ctx context.Context, repos []*types.Repo, p authz.Perm)
 
 // authzFilter is the enforcement mechanism for repository permissions. It accepts a list of repositories
 // and a permission type `p` and returns a subset of those repositories (no guarantee on order) for
-// which the currently authenticated user has the specified permission.
-func authzFilter(ctx context.Context, repos []*types.Repo, p authz.Perm) ([]*types.Repo, error) {
+// which the currently authenticated user has the specified permission. The permissions in synced
+// (see syncedPermsJoin) are used instead of asking the authz providers.
+func authzFilter(ctx context.Context, repos []*types.Repo, p authz.Perm, synced map[api.RepoName]bool) ([]*types.Repo, error) {
 	if mockAuthzFilter != nil {
 		return mockAuthzFilter(ctx, repos, p)
 	}
@@ -40,7 +44,7 @@ func authzFilter(ctx context.Context, repos []*types.Repo, p authz.Perm) ([]*typ
 		}
 	}
 
-	filteredRepoNames, err := getFilteredRepoNames(ctx, currentUser, authz.ToRepos(repos), p)
+	filteredRepoNames, err := getFilteredRepoNames(ctx, currentUser, authz.ToRepos(repos), p, synced)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +67,35 @@ func isInternalActor(ctx context.Context) bool {
 	return actor.FromContext(ctx).Internal
 }
 
-func getFilteredRepoNames(ctx context.Context, currentUser *types.User, repos map[authz.Repo]struct{}, p authz.Perm) (accepted map[api.RepoName]struct{}, err error) {
+// syncedPermsJoin returns the join of the repo table that adds the perm.authorized column, which
+// reports whether the current user has permission p on the repository as of the last sync of the
+// background permissions syncer (if it is enabled). The column is NULL if the permissions of the
+// repository have not been synced recently, in which case authzFilter asks the authz provider.
+func syncedPermsJoin(ctx context.Context, p authz.Perm) *sqlf.Query {
+	a := actor.FromContext(ctx)
+	if a.Internal || !a.IsAuthenticated() || !conf.PermissionsBackgroundSyncEnabled() {
+		return sqlf.Sprintf("LEFT JOIN (SELECT NULL::boolean AS authorized) perm ON TRUE")
+	}
+	if _, authzProviders := authz.GetProviders(); len(authzProviders) == 0 {
+		return sqlf.Sprintf("LEFT JOIN (SELECT NULL::boolean AS authorized) perm ON TRUE")
+	}
+
+	// 🚨 SECURITY: Ignore the permissions that were not synced in the last two sync intervals, so
+	// that revoked permissions don't last when the syncs of the user fail or are delayed. Site
+	// admins can access all repositories, so their synced permissions are ignored too.
+	syncedAfter := time.Now().Add(-2 * conf.PermissionsSyncInterval())
+	return sqlf.Sprintf(`LEFT JOIN LATERAL (
+SELECT repo.id = ANY(p.repo_ids) AS authorized
+FROM user_permissions p
+JOIN users ON users.id=p.user_id
+WHERE p.user_id=%s AND p.permission=%s AND p.service_type=repo.external_service_type AND p.service_id=repo.external_service_id AND p.synced_at >= %s AND NOT users.site_admin
+) perm ON TRUE`, a.UID, string(p), syncedAfter)
+}
+
+// getFilteredRepoNames returns the names of the repositories on which the current user has
+// permission p. The permissions in synced (see syncedPermsJoin) are used instead of asking the
+// authz providers, for the repositories of the providers that are still configured.
+func getFilteredRepoNames(ctx context.Context, currentUser *types.User, repos map[authz.Repo]struct{}, p authz.Perm, synced map[api.RepoName]bool) (accepted map[api.RepoName]struct{}, err error) {
 	var accts []*extsvc.ExternalAccount
 	authzAllowByDefault, authzProviders := authz.GetProviders()
 	if len(authzProviders) > 0 && currentUser != nil {
@@ -111,14 +143,26 @@ func getFilteredRepoNames(ctx context.Context, currentUser *types.User, repos ma
 		// determine which repos "belong" to this authz provider
 		myUnverified, nextUnverified := authzProvider.Repos(ctx, unverified)
 
-		// check the perms on those repos
-		perms, err := authzProvider.RepoPerms(ctx, providerAcct, myUnverified)
-		if err != nil {
-			return nil, err
+		// use the synced perms of those repos, if any
+		for repo := range myUnverified {
+			if ok, exists := synced[repo.RepoName]; exists {
+				if ok {
+					accepted[repo.RepoName] = struct{}{}
+				}
+				delete(myUnverified, repo)
+			}
 		}
-		for unverifiedRepo := range myUnverified {
-			if repoPerms, ok := perms[unverifiedRepo.RepoName]; ok && repoPerms[p] {
-				accepted[unverifiedRepo.RepoName] = struct{}{}
+
+		// check the perms on the remaining repos
+		if len(myUnverified) > 0 {
+			perms, err := authzProvider.RepoPerms(ctx, providerAcct, myUnverified)
+			if err != nil {
+				return nil, err
+			}
+			for unverifiedRepo := range myUnverified {
+				if repoPerms, ok := perms[unverifiedRepo.RepoName]; ok && repoPerms[p] {
+					accepted[unverifiedRepo.RepoName] = struct{}{}
+				}
 			}
 		}
 		// continue checking repos that didn't belong to this authz provider
@@ -59,7 +59,7 @@ func (r authzFilter_Test) run(t *testing.T) {
 		Mocks.ExternalAccounts.AssociateUserAndSave = func(userID int32, spec extsvc.ExternalAccountSpec, data extsvc.ExternalAccountData) error { return nil }
 		Mocks.ExternalAccounts.List = func(ExternalAccountsListOptions) ([]*extsvc.ExternalAccount, error) { return c.userAccounts, nil }
 
-		filteredRepos, err := authzFilter(ctx, c.repos, c.perm)
+		filteredRepos, err := authzFilter(ctx, c.repos, c.perm, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -651,6 +651,52 @@ func Test_authzFilter_createsNewUsers(t *testing.T) {
 	}
 }
 
+func Test_authzFilter_syncedPerms(t *testing.T) {
+	defer func() { Mocks = MockStores{} }()
+
+	Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: 1}, nil
+	}
+	Mocks.ExternalAccounts.AssociateUserAndSave = func(userID int32, spec extsvc.ExternalAccountSpec, data extsvc.ExternalAccountData) error { return nil }
+	Mocks.ExternalAccounts.List = func(ExternalAccountsListOptions) ([]*extsvc.ExternalAccount, error) {
+		return []*extsvc.ExternalAccount{acct(1, "gitlab", "https://gitlab.mine/", "u1")}, nil
+	}
+	authz.SetProviders(false, []authz.Provider{
+		&MockAuthzProvider{
+			serviceID:   "https://gitlab.mine/",
+			serviceType: "gitlab",
+			repos: map[api.RepoName]struct{}{
+				"gitlab.mine/u1/r0":     {},
+				"gitlab.mine/u2/r0":     {},
+				"gitlab.mine/public/r0": {},
+			},
+			perms: map[extsvc.ExternalAccount]map[api.RepoName]map[authz.Perm]bool{
+				// The synced permissions take precedence over these.
+				*acct(1, "gitlab", "https://gitlab.mine/", "u1"): {
+					"gitlab.mine/u2/r0":     {authz.Read: true},
+					"gitlab.mine/public/r0": {authz.Read: true},
+				},
+			},
+		},
+	})
+
+	ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+	repos := []*types.Repo{
+		{ID: 1, Name: "gitlab.mine/u1/r0"},
+		{ID: 2, Name: "gitlab.mine/u2/r0"},
+		{ID: 3, Name: "gitlab.mine/public/r0"},
+	}
+	// Repository 3 has not been synced.
+	synced := map[api.RepoName]bool{"gitlab.mine/u1/r0": true, "gitlab.mine/u2/r0": false}
+	filteredRepos, err := authzFilter(ctx, repos, authz.Read, synced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*types.Repo{repos[0], repos[2]}; !reflect.DeepEqual(filteredRepos, want) {
+		t.Errorf("got filtered repos %+v, want %+v", filteredRepos, want)
+	}
+}
+
 func acct(userID int32, serviceType, serviceID, accountID string) *extsvc.ExternalAccount {
 	return &extsvc.ExternalAccount{
 		UserID: userID,
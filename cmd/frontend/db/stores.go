@@ -19,5 +19,8 @@ var (
 
 	ExternalAccounts = &userExternalAccounts{}
 
+	UserPermissions    = &userPermissions{}
+	RepoUpdateSchedule = &repoUpdateSchedule{}
+
 	OrgInvitations = &orgInvitations{}
 )
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+)
+
+// UserPermissions are the permissions of a user on the repositories of an authz provider, as
+// materialized by the background permissions syncer.
+type UserPermissions struct {
+	UserID int32
+	Perm   authz.Perm
+
+	// ServiceType and ServiceID identify the authz provider (see authz.Provider).
+	ServiceType string
+	ServiceID   string
+
+	// RepoIDs are the IDs of the repositories of the authz provider on which the user has the
+	// permission, as of the last successful sync.
+	RepoIDs []api.RepoID
+
+	// SyncedAt is the time of the last successful sync, or nil if no sync succeeded.
+	SyncedAt *time.Time
+
+	// SyncError is the error of the last sync, or empty if it succeeded.
+	SyncError string
+
+	// UpdatedAt is the time of the last sync, successful or not.
+	UpdatedAt time.Time
+}
+
+// userPermissions provides access to the `user_permissions` table.
+type userPermissions struct{}
+
+// Set stores the result of a successful sync of the permissions of a user on the repositories of
+// an authz provider, replacing the previous ones and clearing the last sync error.
+func (*userPermissions) Set(ctx context.Context, p *UserPermissions) error {
+	if Mocks.UserPermissions.Set != nil {
+		return Mocks.UserPermissions.Set(p)
+	}
+
+	repoIDs := make([]int64, len(p.RepoIDs))
+	for i, id := range p.RepoIDs {
+		repoIDs[i] = int64(id)
+	}
+	_, err := dbconn.Global.ExecContext(ctx, `
+INSERT INTO user_permissions(user_id, permission, service_type, service_id, repo_ids, synced_at, sync_error, updated_at)
+VALUES($1, $2, $3, $4, $5, now(), NULL, now())
+ON CONFLICT (user_id, permission, service_type, service_id) DO UPDATE
+SET repo_ids=excluded.repo_ids, synced_at=excluded.synced_at, sync_error=NULL, updated_at=excluded.updated_at
+`, p.UserID, string(p.Perm), p.ServiceType, p.ServiceID, pq.Int64Array(repoIDs))
+	return err
+}
+
+// SetSyncError records that the last sync of the permissions of a user on the repositories of an
+// authz provider failed. The permissions of the last successful sync (if any) are kept, but they
+// are not used anymore once they are older than two sync intervals (see syncedPermsJoin).
+func (*userPermissions) SetSyncError(ctx context.Context, userID int32, perm authz.Perm, serviceType, serviceID, syncErr string) error {
+	if Mocks.UserPermissions.SetSyncError != nil {
+		return Mocks.UserPermissions.SetSyncError(userID, perm, serviceType, serviceID, syncErr)
+	}
+
+	_, err := dbconn.Global.ExecContext(ctx, `
+INSERT INTO user_permissions(user_id, permission, service_type, service_id, sync_error, updated_at)
+VALUES($1, $2, $3, $4, $5, now())
+ON CONFLICT (user_id, permission, service_type, service_id) DO UPDATE
+SET sync_error=excluded.sync_error, updated_at=excluded.updated_at
+`, userID, string(perm), serviceType, serviceID, syncErr)
+	return err
+}
+
+// ListByUser lists the permissions of the user on the repositories of each authz provider, without
+// their repository IDs.
+func (*userPermissions) ListByUser(ctx context.Context, userID int32) ([]*UserPermissions, error) {
+	if Mocks.UserPermissions.ListByUser != nil {
+		return Mocks.UserPermissions.ListByUser(userID)
+	}
+
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT user_id, permission, service_type, service_id, synced_at, sync_error, updated_at
+FROM user_permissions
+WHERE user_id=$1
+ORDER BY service_type, service_id, permission
+`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []*UserPermissions
+	for rows.Next() {
+		var (
+			p         UserPermissions
+			perm      string
+			syncError sql.NullString
+		)
+		if err := rows.Scan(&p.UserID, &perm, &p.ServiceType, &p.ServiceID, &p.SyncedAt, &syncError, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		p.Perm = authz.Perm(perm)
+		p.SyncError = syncError.String
+		perms = append(perms, &p)
+	}
+	return perms, rows.Err()
+}
+
+// ListUsersToSync returns the IDs of at most limit users whose permissions on the repositories of
+// one of the given authz providers were never synced or were last synced (successfully or not)
+// before the given time, least recently synced first. The permissions of other authz providers
+// (such as removed ones) are ignored, so no users are returned if there are no authz providers.
+func (*userPermissions) ListUsersToSync(ctx context.Context, providers []authz.Provider, syncedBefore time.Time, limit int) ([]int32, error) {
+	if Mocks.UserPermissions.ListUsersToSync != nil {
+		return Mocks.UserPermissions.ListUsersToSync(providers, syncedBefore, limit)
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	var (
+		serviceTypes, serviceIDs []string
+		seen                     = map[[2]string]bool{}
+	)
+	for _, p := range providers {
+		if key := [2]string{p.ServiceType(), p.ServiceID()}; !seen[key] {
+			seen[key] = true
+			serviceTypes = append(serviceTypes, key[0])
+			serviceIDs = append(serviceIDs, key[1])
+		}
+	}
+	// p.providers is the number of the given authz providers for which the user has permissions,
+	// and p.updated_at the time of the least recent sync of them. Users with permissions for
+	// fewer authz providers were never synced for some of them, so they are sorted first.
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT users.id
+FROM users
+LEFT JOIN (
+	SELECT user_id, COUNT(*) AS providers, MIN(updated_at) AS updated_at
+	FROM user_permissions
+	WHERE permission=$1 AND (service_type, service_id) IN (SELECT * FROM unnest($2::text[], $3::text[]))
+	GROUP BY user_id
+) p ON p.user_id=users.id
+WHERE users.deleted_at IS NULL AND (p.user_id IS NULL OR p.providers < $4 OR p.updated_at < $5)
+ORDER BY (CASE WHEN p.providers = $4 THEN p.updated_at END) ASC NULLS FIRST, users.id ASC
+LIMIT $6
+`, string(authz.Read), pq.Array(serviceTypes), pq.Array(serviceIDs), len(serviceTypes), syncedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+type MockUserPermissions struct {
+	Set             func(p *UserPermissions) error
+	SetSyncError    func(userID int32, perm authz.Perm, serviceType, serviceID, syncErr string) error
+	ListByUser      func(userID int32) ([]*UserPermissions, error)
+	ListUsersToSync func(providers []authz.Provider, syncedBefore time.Time, limit int) ([]int32, error)
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+)
+
+func TestUserPermissions_ListUsersToSync(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	var userIDs []int32
+	for _, username := range []string{"u1", "u2", "u3"} {
+		user, err := Users.Create(ctx, NewUser{Username: username})
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	a := &MockAuthzProvider{serviceType: "gitlab", serviceID: "https://a/"}
+	b := &MockAuthzProvider{serviceType: "gitlab", serviceID: "https://b/"}
+	removed := &MockAuthzProvider{serviceType: "gitlab", serviceID: "https://removed/"}
+
+	// u1 was synced for both authz providers, u2 only for a, and u3 never.
+	for _, p := range []struct {
+		userID   int32
+		provider authz.Provider
+	}{{userIDs[0], a}, {userIDs[0], b}, {userIDs[0], removed}, {userIDs[1], a}} {
+		err := UserPermissions.Set(ctx, &UserPermissions{
+			UserID:      p.userID,
+			Perm:        authz.Read,
+			ServiceType: p.provider.ServiceType(),
+			ServiceID:   p.provider.ServiceID(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The permissions of the removed authz provider are stale, but never synced again.
+	if _, err := dbconn.Global.ExecContext(ctx, "UPDATE user_permissions SET updated_at=now()-interval '3 hours' WHERE service_id='https://removed/'"); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(providers []authz.Provider, want []int32) {
+		t.Helper()
+		got, err := UserPermissions.ListUsersToSync(ctx, providers, time.Now().Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got users %v, want %v", got, want)
+		}
+	}
+	check([]authz.Provider{a, b}, []int32{userIDs[1], userIDs[2]})
+	check(nil, nil)
+
+	// Stale permissions of a configured authz provider are synced after the unsynced ones.
+	if _, err := dbconn.Global.ExecContext(ctx, "UPDATE user_permissions SET updated_at=now()-interval '2 hours' WHERE service_id='https://b/'"); err != nil {
+		t.Fatal(err)
+	}
+	check([]authz.Provider{a, b}, []int32{userIDs[1], userIDs[2], userIDs[0]})
+}
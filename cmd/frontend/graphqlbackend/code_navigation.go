@@ -0,0 +1,245 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/inventory/filelang"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Code navigation (go-to-definition and find-references) without language
+// servers. Definitions are the symbols found by the symbols service (ctags)
+// with the name of the identifier at a position, and references are the
+// occurrences of that identifier in files of the same kind. The blob's
+// repository is searched first, then the other repositories of the
+// repogroups that contain it.
+
+const (
+	maxDefinitionResults = 50
+	maxReferenceResults  = 500
+)
+
+type codeNavigationArgs struct {
+	Line      int32
+	Character int32
+}
+
+func (r *gitTreeEntryResolver) Definition(ctx context.Context, args *codeNavigationArgs) ([]*locationResolver, error) {
+	ident, err := r.identifierAt(ctx, args)
+	if err != nil || ident == "" {
+		return nil, err
+	}
+	// The symbol query is anchored so that only symbols named ident match.
+	pattern := fmt.Sprintf("type:symbol case:yes patterntype:regexp count:%d ^%s$", maxDefinitionResults, ident)
+	return r.navigate(ctx, pattern, true)
+}
+
+func (r *gitTreeEntryResolver) References(ctx context.Context, args *struct {
+	codeNavigationArgs
+	First *int32
+}) ([]*locationResolver, error) {
+	ident, err := r.identifierAt(ctx, &args.codeNavigationArgs)
+	if err != nil || ident == "" {
+		return nil, err
+	}
+	limit := maxReferenceResults
+	if args.First != nil && int(*args.First) < limit {
+		limit = int(*args.First)
+	}
+	pattern := fmt.Sprintf(`case:yes patterntype:regexp count:%d \b%s\b`, limit, ident)
+	if ext := path.Ext(r.path); ext != "" {
+		pattern += " file:" + regexp.QuoteMeta(ext) + "$"
+	}
+	locations, err := r.navigate(ctx, pattern, false)
+	if len(locations) > limit {
+		locations = locations[:limit]
+	}
+	return locations, err
+}
+
+// identifierAt returns the identifier at the given position of the blob, or
+// "" if there is none.
+func (r *gitTreeEntryResolver) identifierAt(ctx context.Context, args *codeNavigationArgs) (string, error) {
+	content, err := r.Content(ctx)
+	if err != nil {
+		return "", err
+	}
+	return identifierAt(content, int(args.Line), int(args.Character)), nil
+}
+
+// navigate runs the search query pattern in the blob's repository at its
+// commit and, if stopIfFound is false or nothing is found there, in the other
+// repositories of its repogroups. It returns the locations of the results,
+// ranked by rankLocations.
+func (r *gitTreeEntryResolver) navigate(ctx context.Context, pattern string, stopIfFound bool) ([]*locationResolver, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	repoName := r.commit.repo.repo.Name
+	repoPattern := "^" + regexp.QuoteMeta(string(repoName)) + "$"
+	queries := []string{fmt.Sprintf("repo:%s@%s %s", repoPattern, r.commit.oid, pattern)}
+
+	groups, err := resolveRepoGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var groupNames []string
+	for name, repos := range groups {
+		for _, repo := range repos {
+			if repo.Name == repoName {
+				groupNames = append(groupNames, name)
+				break
+			}
+		}
+	}
+	sort.Strings(groupNames)
+	for _, name := range groupNames {
+		queries = append(queries, fmt.Sprintf("repogroup:%s -repo:%s %s", name, repoPattern, pattern))
+	}
+
+	var locations []*locationResolver
+	for i, q := range queries {
+		if i > 0 && stopIfFound && len(locations) > 0 {
+			break
+		}
+		results, err := searchLocations(ctx, q)
+		if err != nil {
+			if len(locations) > 0 {
+				// Return the results of the previous queries.
+				log15.Warn("Code navigation search failed.", "query", q, "error", err)
+				break
+			}
+			return nil, err
+		}
+		locations = append(locations, results...)
+	}
+	rankLocations(locations, repoName, r.path)
+	return locations, nil
+}
+
+// searchLocations returns the locations of the symbols or line matches of
+// the results of the search query q.
+func searchLocations(ctx context.Context, q string) ([]*locationResolver, error) {
+	parsedQuery, err := query.ParseAndCheck(q)
+	if err != nil {
+		return nil, err
+	}
+	results, err := (&searchResolver{query: parsedQuery}).Results(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []*locationResolver
+	for _, result := range results.results {
+		fm, ok := result.ToFileMatch()
+		if !ok {
+			continue
+		}
+		for _, symbol := range fm.symbols {
+			locations = append(locations, symbol.location)
+		}
+		for _, lm := range fm.JLineMatches {
+			for _, rng := range lm.Ranges() {
+				rng := rng.lspRange
+				locations = append(locations, &locationResolver{resource: fm.File(), lspRange: &rng})
+			}
+		}
+	}
+	return locations, nil
+}
+
+// rankLocations sorts locations so that the most likely ones come first:
+// those in repoName, then those in files of the same language as filePath,
+// then those whose path shares the most leading components with filePath.
+func rankLocations(locations []*locationResolver, repoName api.RepoName, filePath string) {
+	lang := languageOfPath(filePath)
+	type rank struct {
+		sameRepo, sameLang bool
+		proximity          int
+	}
+	ranks := make(map[*locationResolver]rank, len(locations))
+	for _, l := range locations {
+		ranks[l] = rank{
+			sameRepo:  l.resource.commit.repo.repo.Name == repoName,
+			sameLang:  lang != "" && languageOfPath(l.resource.path) == lang,
+			proximity: pathProximity(l.resource.path, filePath),
+		}
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		a, b := ranks[locations[i]], ranks[locations[j]]
+		if a.sameRepo != b.sameRepo {
+			return a.sameRepo
+		}
+		if a.sameLang != b.sameLang {
+			return a.sameLang
+		}
+		if a.proximity != b.proximity {
+			return a.proximity > b.proximity
+		}
+		if ri, rj := locations[i].resource.commit.repo.repo.Name, locations[j].resource.commit.repo.repo.Name; ri != rj {
+			return ri < rj
+		}
+		return locations[i].resource.path < locations[j].resource.path
+	})
+}
+
+// languageOfPath returns the name of the most likely language of the file at
+// p, or "" if it is unknown.
+func languageOfPath(p string) string {
+	if langs := filelang.Langs.ByFilename(path.Base(p)); len(langs) > 0 {
+		return langs[0].Name
+	}
+	return ""
+}
+
+// pathProximity returns the number of leading path components that a and b
+// have in common.
+func pathProximity(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	n := 0
+	for n < len(as) && n < len(bs) && as[n] == bs[n] {
+		n++
+	}
+	return n
+}
+
+// identifierAt returns the identifier (a sequence of letters, digits and
+// underscores) at the zero-based line and character (in runes) of content, or
+// "" if there is none.
+func identifierAt(content string, line, character int) string {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) || character < 0 {
+		return ""
+	}
+	runes := []rune(lines[line])
+	if character > len(runes) {
+		return ""
+	}
+	isIdent := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	start, end := character, character
+	for start > 0 && isIdent(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && isIdent(runes[end]) {
+		end++
+	}
+	ident := string(runes[start:end])
+	if ident == "" {
+		return ""
+	}
+	if r, _ := utf8.DecodeRuneInString(ident); unicode.IsDigit(r) {
+		// A number, not an identifier.
+		return ""
+	}
+	return ident
+}
@@ -0,0 +1,60 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func TestIdentifierAt(t *testing.T) {
+	const content = "package main\n\nfunc main() {\n\tfmt.Println(héllo_1, 42)\n}"
+	tests := []struct {
+		line, character int
+		want            string
+	}{
+		{line: 2, character: 5, want: "main"},
+		{line: 2, character: 9, want: "main"}, // at the end of the identifier
+		{line: 3, character: 1, want: "fmt"},
+		{line: 3, character: 6, want: "Println"},
+		{line: 3, character: 14, want: "héllo_1"},
+		{line: 3, character: 20, want: "héllo_1"},
+		{line: 3, character: 23, want: ""}, // number
+		{line: 1, character: 0, want: ""},  // empty line
+		{line: 2, character: 11, want: ""}, // punctuation
+		{line: 5, character: 0, want: ""},  // out of range
+		{line: 0, character: 100, want: ""},
+	}
+	for _, test := range tests {
+		if got := identifierAt(content, test.line, test.character); got != test.want {
+			t.Errorf("identifierAt(%d, %d) = %q, want %q", test.line, test.character, got, test.want)
+		}
+	}
+}
+
+func TestRankLocations(t *testing.T) {
+	location := func(repo api.RepoName, path string) *locationResolver {
+		return &locationResolver{resource: &gitTreeEntryResolver{
+			commit: &gitCommitResolver{repo: &repositoryResolver{repo: &types.Repo{Name: repo}}},
+			path:   path,
+		}}
+	}
+	locations := []*locationResolver{
+		location("other", "a/b/c.go"),
+		location("repo", "x/y.js"),
+		location("repo", "z/w.go"),
+		location("repo", "a/b/d.go"),
+		location("repo", "a/e.go"),
+	}
+	rankLocations(locations, "repo", "a/b/c.go")
+
+	var got []string
+	for _, l := range locations {
+		got = append(got, string(l.resource.commit.repo.repo.Name)+"/"+l.resource.path)
+	}
+	want := []string{"repo/a/b/d.go", "repo/a/e.go", "repo/z/w.go", "repo/x/y.js", "other/a/b/c.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
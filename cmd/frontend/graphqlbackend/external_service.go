@@ -3,11 +3,13 @@ package graphqlbackend
 import (
 	"context"
 	"fmt"
+	"net/url"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"time"
 )
@@ -73,3 +75,13 @@ func (r *externalServiceResolver) CreatedAt() string {
 func (r *externalServiceResolver) UpdatedAt() string {
 	return r.externalService.UpdatedAt.Format(time.RFC3339)
 }
+
+func (r *externalServiceResolver) WebhookURL() *string {
+	switch r.externalService.Kind {
+	case "GITHUB", "GITLAB", "BITBUCKETSERVER":
+		u := globals.ExternalURL.ResolveReference(&url.URL{Path: fmt.Sprintf("/.api/webhooks/%d", r.externalService.ID)}).String()
+		return &u
+	default:
+		return nil
+	}
+}
@@ -2,7 +2,10 @@ package graphqlbackend
 
 import (
 	"context"
+	"errors"
+	"sync"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
@@ -10,13 +13,27 @@ import (
 
 func (r *gitTreeEntryResolver) Blame(ctx context.Context,
 	args *struct {
-		StartLine int32
-		EndLine   int32
+		StartLine         int32
+		EndLine           int32
+		IgnoreRevs        *[]string
+		UseIgnoreRevsFile bool
+		DetectMoves       bool
+		DetectCopies      bool
 	}) ([]*hunkResolver, error) {
+	var ignoreRevs []api.CommitID
+	if args.IgnoreRevs != nil {
+		for _, rev := range *args.IgnoreRevs {
+			ignoreRevs = append(ignoreRevs, api.CommitID(rev))
+		}
+	}
 	hunks, err := git.BlameFile(ctx, gitserver.Repo{Name: r.commit.repo.repo.Name}, r.path, &git.BlameOptions{
-		NewestCommit: api.CommitID(r.commit.oid),
-		StartLine:    int(args.StartLine),
-		EndLine:      int(args.EndLine),
+		NewestCommit:      api.CommitID(r.commit.oid),
+		StartLine:         int(args.StartLine),
+		EndLine:           int(args.EndLine),
+		IgnoreRevs:        ignoreRevs,
+		UseIgnoreRevsFile: args.UseIgnoreRevsFile,
+		DetectMoves:       args.DetectMoves,
+		DetectCopies:      args.DetectCopies,
 	})
 	if err != nil {
 		return nil, err
@@ -32,3 +49,114 @@ func (r *gitTreeEntryResolver) Blame(ctx context.Context,
 
 	return hunksResolver, nil
 }
+
+func (r *gitTreeEntryResolver) History(ctx context.Context, args *struct {
+	graphqlutil.ConnectionArgs
+	Follow    bool
+	StartLine *int32
+	EndLine   *int32
+}) (*fileHistoryConnectionResolver, error) {
+	opt := &git.FileHistoryOptions{
+		NewestCommit: api.CommitID(r.commit.oid),
+		Follow:       args.Follow,
+	}
+	if (args.StartLine == nil) != (args.EndLine == nil) {
+		return nil, errors.New("startLine and endLine must be given together")
+	}
+	if args.StartLine != nil {
+		if *args.StartLine < 1 || *args.EndLine < *args.StartLine {
+			return nil, errors.New("invalid line range")
+		}
+		opt.StartLine = int(*args.StartLine)
+		opt.EndLine = int(*args.EndLine)
+	}
+	return &fileHistoryConnectionResolver{
+		blob:  r,
+		opt:   opt,
+		first: args.ConnectionArgs.First,
+	}, nil
+}
+
+type fileHistoryConnectionResolver struct {
+	blob  *gitTreeEntryResolver
+	opt   *git.FileHistoryOptions
+	first *int32
+
+	// cache results because it is used by multiple fields
+	once    sync.Once
+	entries []*git.FileHistoryEntry
+	err     error
+}
+
+func (r *fileHistoryConnectionResolver) compute(ctx context.Context) ([]*git.FileHistoryEntry, error) {
+	r.once.Do(func() {
+		opt := *r.opt
+		if r.first != nil {
+			opt.N = uint(*r.first) + 1 // fetch +1 additional result so we can determine if a next page exists
+		}
+		r.entries, r.err = git.FileHistory(ctx, gitserver.Repo{Name: r.blob.commit.repo.repo.Name}, r.blob.path, &opt)
+	})
+	return r.entries, r.err
+}
+
+func (r *fileHistoryConnectionResolver) Nodes(ctx context.Context) ([]*fileHistoryEntryResolver, error) {
+	entries, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.first != nil && len(entries) > int(*r.first) {
+		// Don't return +1 results, which is used to determine if next page exists.
+		entries = entries[:*r.first]
+	}
+
+	resolvers := make([]*fileHistoryEntryResolver, len(entries))
+	for i, entry := range entries {
+		resolvers[i] = &fileHistoryEntryResolver{blob: r.blob, entry: entry}
+	}
+	return resolvers, nil
+}
+
+func (r *fileHistoryConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	entries, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return graphqlutil.HasNextPage(r.first != nil && len(entries) > int(*r.first)), nil
+}
+
+type fileHistoryEntryResolver struct {
+	blob  *gitTreeEntryResolver
+	entry *git.FileHistoryEntry
+}
+
+func (r *fileHistoryEntryResolver) Commit() *gitCommitResolver {
+	return toGitCommitResolver(r.blob.commit.repo, r.entry.Commit)
+}
+
+func (r *fileHistoryEntryResolver) Path() string {
+	if r.entry.Diff != nil {
+		if path := diffPathOrNull(r.entry.Diff.NewName); path != nil {
+			return *path
+		}
+	}
+	return r.blob.path
+}
+
+func (r *fileHistoryEntryResolver) OldPath() *string {
+	if r.entry.Diff == nil {
+		return nil
+	}
+	return diffPathOrNull(r.entry.Diff.OrigName)
+}
+
+func (r *fileHistoryEntryResolver) Hunks() []*diffHunk {
+	if r.entry.Diff == nil {
+		return []*diffHunk{}
+	}
+	hunks := make([]*diffHunk, len(r.entry.Diff.Hunks))
+	for i, hunk := range r.entry.Diff.Hunks {
+		hunks[i] = &diffHunk{hunk: hunk}
+	}
+	return hunks
+}
@@ -2,6 +2,7 @@ package graphqlutil
 
 // PageInfo implements the GraphQL type PageInfo.
 type PageInfo struct {
+	endCursor   *string
 	hasNextPage bool
 }
 
@@ -10,4 +11,11 @@ func HasNextPage(hasNextPage bool) *PageInfo {
 	return &PageInfo{hasNextPage: hasNextPage}
 }
 
-func (r *PageInfo) HasNextPage() bool { return r.hasNextPage }
+// NextPageCursor returns a new PageInfo indicating there is a next page with
+// the given end cursor.
+func NextPageCursor(endCursor string) *PageInfo {
+	return &PageInfo{endCursor: &endCursor, hasNextPage: true}
+}
+
+func (r *PageInfo) EndCursor() *string { return r.endCursor }
+func (r *PageInfo) HasNextPage() bool  { return r.hasNextPage }
@@ -46,6 +46,14 @@ func (r *hunkResolver) Message() string {
 	return r.hunk.Message
 }
 
+func (r *hunkResolver) OriginalPath() string {
+	return r.hunk.OrigPath
+}
+
+func (r *hunkResolver) OriginalStartLine() int32 {
+	return int32(r.hunk.OrigStartLine)
+}
+
 func (r *hunkResolver) Commit(ctx context.Context) (*gitCommitResolver, error) {
 	cachedRepo, err := backend.CachedGitRepo(ctx, r.repo.repo)
 	if err != nil {
@@ -0,0 +1,174 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+const (
+	defaultCommitGraphFirst = 100
+	maxCommitGraphFirst     = 1000
+)
+
+type commitGraphArgs struct {
+	Revisions *[]string
+	First     *int32
+	After     *string
+}
+
+func (a *commitGraphArgs) applyDefaultsAndConstraints() {
+	if a.First == nil || *a.First < 0 {
+		n := int32(defaultCommitGraphFirst)
+		a.First = &n
+	} else if *a.First > maxCommitGraphFirst {
+		n := int32(maxCommitGraphFirst)
+		a.First = &n
+	}
+}
+
+func (r *repositoryResolver) CommitGraph(args *commitGraphArgs) *commitGraphResolver {
+	args.applyDefaultsAndConstraints()
+	var revisions []string
+	if args.Revisions != nil {
+		revisions = *args.Revisions
+	}
+	return &commitGraphResolver{
+		repo:      r,
+		revisions: revisions,
+		first:     int(*args.First),
+		after:     args.After,
+	}
+}
+
+type commitGraphResolver struct {
+	repo      *repositoryResolver
+	revisions []string
+	first     int
+	after     *string
+
+	// cache results because it is used by multiple fields
+	once  sync.Once
+	nodes []*git.CommitGraphNode
+	err   error
+}
+
+func (r *commitGraphResolver) compute(ctx context.Context) ([]*git.CommitGraphNode, error) {
+	r.once.Do(func() {
+		opt := git.CommitGraphOptions{
+			Revisions: r.revisions,
+			N:         uint(r.first) + 1, // fetch +1 additional result so we can determine if a next page exists
+		}
+		if r.after != nil {
+			opt.After, r.err = parseCommitGraphCursor(*r.after)
+			if r.err != nil {
+				return
+			}
+		}
+		cachedRepo, err := backend.CachedGitRepo(ctx, r.repo.repo)
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.nodes, r.err = git.CommitGraph(ctx, *cachedRepo, opt)
+	})
+	return r.nodes, r.err
+}
+
+// commitGraphCursor returns the cursor to continue the commit graph after the given position. It
+// is "index:commit:lane,lane,...", with the commit expected next in each lane (or nothing if the
+// lane is free).
+func commitGraphCursor(pos git.CommitGraphPosition) string {
+	lanes := make([]string, len(pos.Lanes))
+	for i, lane := range pos.Lanes {
+		lanes[i] = string(lane)
+	}
+	return fmt.Sprintf("%d:%s:%s", pos.Index, pos.Commit, strings.Join(lanes, ","))
+}
+
+func parseCommitGraphCursor(cursor string) (*git.CommitGraphPosition, error) {
+	invalid := fmt.Errorf("invalid commit graph cursor: %q", cursor)
+	parts := strings.SplitN(cursor, ":", 3)
+	if len(parts) != 3 {
+		return nil, invalid
+	}
+	index, err := strconv.ParseUint(parts[0], 10, 0)
+	if err != nil || !git.IsAbsoluteRevision(parts[1]) {
+		return nil, invalid
+	}
+	pos := &git.CommitGraphPosition{Index: uint(index), Commit: api.CommitID(parts[1])}
+	if parts[2] != "" {
+		for _, lane := range strings.Split(parts[2], ",") {
+			if lane != "" && !git.IsAbsoluteRevision(lane) {
+				return nil, invalid
+			}
+			pos.Lanes = append(pos.Lanes, api.CommitID(lane))
+		}
+	}
+	return pos, nil
+}
+
+func (r *commitGraphResolver) Nodes(ctx context.Context) ([]*commitGraphNodeResolver, error) {
+	nodes, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) > r.first {
+		// Don't return +1 results, which is used to determine if next page exists.
+		nodes = nodes[:r.first]
+	}
+
+	resolvers := make([]*commitGraphNodeResolver, len(nodes))
+	for i, node := range nodes {
+		resolvers[i] = &commitGraphNodeResolver{repo: r.repo, node: node}
+	}
+	return resolvers, nil
+}
+
+func (r *commitGraphResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	nodes, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) <= r.first {
+		return graphqlutil.HasNextPage(false), nil
+	}
+	if r.first == 0 {
+		return graphqlutil.HasNextPage(true), nil // no node on this page to continue after
+	}
+	return graphqlutil.NextPageCursor(commitGraphCursor(nodes[r.first-1].Position)), nil
+}
+
+type commitGraphNodeResolver struct {
+	repo *repositoryResolver
+	node *git.CommitGraphNode
+}
+
+func (r *commitGraphNodeResolver) Commit() *gitCommitResolver {
+	return toGitCommitResolver(r.repo, r.node.Commit)
+}
+
+func (r *commitGraphNodeResolver) Lane() int32 { return int32(r.node.Lane) }
+
+func (r *commitGraphNodeResolver) Parents() []*commitGraphEdgeResolver {
+	resolvers := make([]*commitGraphEdgeResolver, len(r.node.Parents))
+	for i, edge := range r.node.Parents {
+		resolvers[i] = &commitGraphEdgeResolver{edge: edge}
+	}
+	return resolvers
+}
+
+type commitGraphEdgeResolver struct {
+	edge git.CommitGraphEdge
+}
+
+func (r *commitGraphEdgeResolver) OID() gitObjectID { return gitObjectID(r.edge.Parent) }
+func (r *commitGraphEdgeResolver) Lane() int32      { return int32(r.edge.Lane) }
@@ -0,0 +1,43 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+func TestCommitGraphCursor(t *testing.T) {
+	const (
+		a = api.CommitID("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+		b = api.CommitID("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	)
+	for _, pos := range []git.CommitGraphPosition{
+		{Index: 0, Commit: a},
+		{Index: 3, Commit: a, Lanes: []api.CommitID{b}},
+		{Index: 42, Commit: b, Lanes: []api.CommitID{a, "", b}},
+	} {
+		cursor := commitGraphCursor(pos)
+		got, err := parseCommitGraphCursor(cursor)
+		if err != nil {
+			t.Errorf("%q: %s", cursor, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, pos) {
+			t.Errorf("%q: got %+v, want %+v", cursor, *got, pos)
+		}
+	}
+
+	for _, cursor := range []string{
+		"",
+		"1",
+		"-1:" + string(a) + ":",
+		"1:HEAD:",
+		"1:" + string(a) + ":--output=x",
+	} {
+		if _, err := parseCommitGraphCursor(cursor); err == nil {
+			t.Errorf("%q: want error", cursor)
+		}
+	}
+}
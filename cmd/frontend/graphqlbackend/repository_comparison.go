@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -104,35 +105,45 @@ func (r *repositoryComparisonResolver) Commits(args *struct {
 	}
 }
 
-func (r *repositoryComparisonResolver) FileDiffs(args *struct {
-	First *int32
-}) *fileDiffConnectionResolver {
+type fileDiffsArgs struct {
+	First        *int32
+	After        *string
+	Paths        *[]string
+	MaxHunksSize *int32
+}
+
+func (r *repositoryComparisonResolver) FileDiffs(args *fileDiffsArgs) *fileDiffConnectionResolver {
 	return &fileDiffConnectionResolver{
-		cmp:   r,
-		first: args.First,
+		cmp:  r,
+		args: args,
 	}
 }
 
 type fileDiffConnectionResolver struct {
-	cmp   *repositoryComparisonResolver // {base,head}{,RevSpec} and repo
-	first *int32
+	cmp  *repositoryComparisonResolver // {base,head}{,RevSpec} and repo
+	args *fileDiffsArgs
 
 	// cache result because it is used by multiple fields
 	once        sync.Once
-	fileDiffs   []*diff.FileDiff
+	offset      int // the number of file diffs before this page (from the After cursor)
+	nextOffset  int // the number of file diffs before the next page
+	totalCount  int // the number of file diffs of the whole diff
+	fileDiffs   []*git.FileDiff
 	hasNextPage bool
 	err         error
 }
 
-func (r *fileDiffConnectionResolver) compute(ctx context.Context) ([]*diff.FileDiff, error) {
-	do := func() ([]*diff.FileDiff, error) {
-		var rangeSpec string
-		if r.cmp.base == nil {
-			// Rare case: the base is the empty tree, in which case we need ".." not "..." because the latter only works for commits.
-			rangeSpec = string(r.cmp.baseRevspec) + ".." + string(r.cmp.head.oid)
-		} else {
-			rangeSpec = string(r.cmp.base.oid) + "..." + string(r.cmp.head.oid)
+func (r *fileDiffConnectionResolver) compute(ctx context.Context) ([]*git.FileDiff, error) {
+	do := func() ([]*git.FileDiff, error) {
+		rangeSpec := r.rangeSpec()
+		if r.args.After != nil {
+			offset, err := parseFileDiffsCursor(*r.args.After, rangeSpec)
+			if err != nil {
+				return nil, err
+			}
+			r.offset = offset
 		}
+
 		if strings.HasPrefix(rangeSpec, "-") || strings.HasPrefix(rangeSpec, ".") {
 			// This should not be possible since r.head is a SHA returned by ResolveRevision, but be
 			// extra careful to avoid letting user input add additional `git diff` command-line
@@ -143,7 +154,17 @@ func (r *fileDiffConnectionResolver) compute(ctx context.Context) ([]*diff.FileD
 		if err != nil {
 			return nil, err
 		}
-		rdr, err := git.ExecReader(ctx, *cachedRepo, []string{
+
+		// The changed files are listed first, so that only the contents of the files of the page
+		// are diffed. Listing the changed files doesn't diff their contents, so even though the
+		// list is read from the start for each page, the file diffs before the page aren't
+		// computed again.
+		page, err := r.listChangedFiles(ctx, *cachedRepo, rangeSpec)
+		if err != nil || len(page) == 0 {
+			return nil, err
+		}
+
+		args := []string{
 			"diff",
 			"--find-renames",
 			"--find-copies",
@@ -152,17 +173,37 @@ func (r *fileDiffConnectionResolver) compute(ctx context.Context) ([]*diff.FileD
 			"--no-prefix",
 			rangeSpec,
 			"--",
-		})
+		}
+		// The sources of copies are diffed too (to detect the copies), but the file diffs of
+		// sources that aren't changed files of the page are omitted.
+		pagePaths := make(map[string]bool, len(page))
+		copySources := map[string]bool{}
+		for _, f := range page {
+			if f.OrigName == f.NewName {
+				pagePaths[f.OrigName] = true
+			} else if f.Status == 'C' {
+				copySources[f.OrigName] = true
+			}
+		}
+		for _, f := range page {
+			args = append(args, ":(literal)"+f.OrigName)
+			if f.NewName != f.OrigName {
+				args = append(args, ":(literal)"+f.NewName)
+			}
+		}
+		rdr, err := git.ExecReader(ctx, *cachedRepo, args)
 		if err != nil {
 			return nil, err
 		}
 		defer rdr.Close()
 
-		var fileDiffs []*diff.FileDiff
-		if r.first != nil {
-			fileDiffs = make([]*diff.FileDiff, 0, int(*r.first)) // preallocate
+		// The diff is streamed from gitserver and read one file diff at a time, so that the hunks
+		// of large file diffs are never buffered.
+		fileDiffs := make([]*git.FileDiff, 0, len(page))
+		dr := git.NewFileDiffReader(rdr)
+		if r.args.MaxHunksSize != nil {
+			dr.MaxHunksSize = int(*r.args.MaxHunksSize)
 		}
-		dr := diff.NewMultiFileDiffReader(rdr)
 		for {
 			fileDiff, err := dr.ReadFile()
 			if err == io.EOF {
@@ -171,16 +212,10 @@ func (r *fileDiffConnectionResolver) compute(ctx context.Context) ([]*diff.FileD
 			if err != nil {
 				return nil, err
 			}
-			fileDiffs = append(fileDiffs, fileDiff)
-			if r.first != nil && len(fileDiffs) == int(*r.first) {
-				// Check for hasNextPage.
-				_, err := dr.ReadFile()
-				if err != nil && err != io.EOF {
-					return nil, err
-				}
-				r.hasNextPage = err != io.EOF
-				break
+			if name := fileDiff.Diff.OrigName; name == fileDiff.Diff.NewName && copySources[name] && !pagePaths[name] {
+				continue
 			}
+			fileDiffs = append(fileDiffs, fileDiff)
 		}
 		return fileDiffs, nil
 	}
@@ -189,44 +224,117 @@ func (r *fileDiffConnectionResolver) compute(ctx context.Context) ([]*diff.FileD
 	return r.fileDiffs, r.err
 }
 
-func (r *fileDiffConnectionResolver) Nodes(ctx context.Context) ([]*fileDiffResolver, error) {
-	fileDiffs, err := r.compute(ctx)
+// listChangedFiles returns the changed files of the page, and sets the total count of file diffs
+// and the offset of the next page.
+func (r *fileDiffConnectionResolver) listChangedFiles(ctx context.Context, repo gitserver.Repo, rangeSpec string) ([]*git.ChangedFile, error) {
+	args := []string{
+		"diff",
+		"--raw",
+		"-z",
+		"--find-renames",
+		"--find-copies",
+		rangeSpec,
+		"--",
+	}
+	if r.args.Paths != nil {
+		for _, path := range *r.args.Paths {
+			// Paths are globs, not arbitrary pathspecs (which could use other pathspec magic).
+			args = append(args, ":(glob)"+path)
+		}
+	}
+	rdr, err := git.ExecReader(ctx, repo, args)
 	if err != nil {
 		return nil, err
 	}
+	defer rdr.Close()
+
+	var page []*git.ChangedFile
+	cr := git.NewChangedFileReader(rdr)
+	for n := 0; ; n++ {
+		f, err := cr.Read()
+		if err == io.EOF {
+			r.totalCount = n
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n >= r.offset && (r.args.First == nil || len(page) < int(*r.args.First)) {
+			page = append(page, f)
+		}
+	}
+	r.nextOffset = r.offset + len(page)
+	r.hasNextPage = r.totalCount > r.nextOffset
+	return page, nil
+}
+
+// rangeSpec returns the range of the diff, with the resolved base and head commits.
+func (r *fileDiffConnectionResolver) rangeSpec() string {
+	if r.cmp.base == nil {
+		// Rare case: the base is the empty tree, in which case we need ".." not "..." because the latter only works for commits.
+		return string(r.cmp.baseRevspec) + ".." + string(r.cmp.head.oid)
+	}
+	return string(r.cmp.base.oid) + "..." + string(r.cmp.head.oid)
+}
+
+// fileDiffsCursor returns the cursor of the page of file diffs after the first offset file diffs
+// of the diff of rangeSpec. The cursor includes the resolved range, so that the offset is not
+// applied to another diff if the base or head revision is updated between pages.
+func fileDiffsCursor(rangeSpec string, offset int) string {
+	return rangeSpec + ":" + strconv.Itoa(offset)
+}
+
+// parseFileDiffsCursor returns the offset of a cursor returned by fileDiffsCursor. It returns an
+// error if the cursor is not for the diff of rangeSpec.
+func parseFileDiffsCursor(cursor, rangeSpec string) (offset int, err error) {
+	i := strings.LastIndex(cursor, ":")
+	if i != -1 {
+		offset, err = strconv.Atoi(cursor[i+1:])
+	}
+	if i == -1 || err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid file diffs cursor: %q", cursor)
+	}
+	if cursor[:i] != rangeSpec {
+		return 0, fmt.Errorf("file diffs cursor %q is for another diff than %s (the base or head revision changed since the cursor was returned)", cursor, rangeSpec)
+	}
+	return offset, nil
+}
 
-	if r.first != nil && len(fileDiffs) > int(*r.first) {
-		// Don't return +1 results, which is used to determine if next page exists.
-		fileDiffs = fileDiffs[:*r.first]
+func (r *fileDiffConnectionResolver) Nodes(ctx context.Context) ([]*fileDiffResolver, error) {
+	fileDiffs, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	resolvers := make([]*fileDiffResolver, len(fileDiffs))
 	for i, fileDiff := range fileDiffs {
+		stat := fileDiff.Stat
 		resolvers[i] = &fileDiffResolver{
-			fileDiff: fileDiff,
-			cmp:      r.cmp,
+			fileDiff:     fileDiff.Diff,
+			hunksSkipped: fileDiff.HunksSkipped,
+			stat:         &stat,
+			cmp:          r.cmp,
 		}
 	}
 	return resolvers, nil
 }
 
 func (r *fileDiffConnectionResolver) TotalCount(ctx context.Context) (*int32, error) {
-	fileDiffs, err := r.compute(ctx)
-	if err != nil {
+	if _, err := r.compute(ctx); err != nil {
 		return nil, err
 	}
-	if r.first == nil || (len(fileDiffs) > int(*r.first)) {
-		n := int32(len(fileDiffs))
-		return &n, nil
-	}
-	return nil, nil // total count is not available
+	n := int32(r.totalCount)
+	return &n, nil
 }
 
 func (r *fileDiffConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
 	if _, err := r.compute(ctx); err != nil {
 		return nil, err
 	}
-	return graphqlutil.HasNextPage(r.hasNextPage), nil
+	if r.hasNextPage {
+		return graphqlutil.NextPageCursor(fileDiffsCursor(r.rangeSpec(), r.nextOffset)), nil
+	}
+	return graphqlutil.HasNextPage(false), nil
 }
 
 func (r *fileDiffConnectionResolver) DiffStat(ctx context.Context) (*diffStat, error) {
@@ -237,10 +345,9 @@ func (r *fileDiffConnectionResolver) DiffStat(ctx context.Context) (*diffStat, e
 
 	var stat diffStat
 	for _, fileDiff := range fileDiffs {
-		s := fileDiff.Stat()
-		stat.added += s.Added
-		stat.changed += s.Changed
-		stat.deleted += s.Deleted
+		stat.added += fileDiff.Stat.Added
+		stat.changed += fileDiff.Stat.Changed
+		stat.deleted += fileDiff.Stat.Deleted
 	}
 	return &stat, nil
 }
@@ -250,13 +357,19 @@ func (r *fileDiffConnectionResolver) RawDiff(ctx context.Context) (string, error
 	if err != nil {
 		return "", err
 	}
-	b, err := diff.PrintMultiFileDiff(fileDiffs)
+	diffs := make([]*diff.FileDiff, len(fileDiffs))
+	for i, fileDiff := range fileDiffs {
+		diffs[i] = fileDiff.Diff
+	}
+	b, err := diff.PrintMultiFileDiff(diffs)
 	return string(b), err
 }
 
 type fileDiffResolver struct {
-	fileDiff *diff.FileDiff
-	cmp      *repositoryComparisonResolver // {base,head}{,RevSpec} and repo
+	fileDiff     *diff.FileDiff
+	hunksSkipped bool                          // the hunks of fileDiff were omitted because it is too large
+	stat         *diff.Stat                    // the stat of the whole file diff, if not computed from its hunks
+	cmp          *repositoryComparisonResolver // {base,head}{,RevSpec} and repo
 }
 
 func (r *fileDiffResolver) OldPath() *string { return diffPathOrNull(r.fileDiff.OrigName) }
@@ -268,8 +381,12 @@ func (r *fileDiffResolver) Hunks() []*diffHunk {
 	}
 	return hunks
 }
+func (r *fileDiffResolver) HunksSkipped() bool { return r.hunksSkipped }
 func (r *fileDiffResolver) Stat() *diffStat {
 	stat := r.fileDiff.Stat()
+	if r.stat != nil {
+		stat = *r.stat
+	}
 	return &diffStat{
 		added:   stat.Added,
 		changed: stat.Changed,
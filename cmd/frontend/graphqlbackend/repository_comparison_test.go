@@ -0,0 +1,26 @@
+package graphqlbackend
+
+import "testing"
+
+func TestFileDiffsCursor(t *testing.T) {
+	const (
+		rangeSpec      = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa...bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+		otherRangeSpec = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa...cccccccccccccccccccccccccccccccccccccccc"
+	)
+
+	cursor := fileDiffsCursor(rangeSpec, 10)
+	if offset, err := parseFileDiffsCursor(cursor, rangeSpec); err != nil || offset != 10 {
+		t.Errorf("got offset %d (error %v), want 10", offset, err)
+	}
+
+	// The cursor is rejected if the head revision was updated since it was returned.
+	if _, err := parseFileDiffsCursor(cursor, otherRangeSpec); err == nil {
+		t.Error("got no error for cursor of another diff")
+	}
+
+	for _, cursor := range []string{"", "10", rangeSpec + ":-1", rangeSpec + ":x"} {
+		if _, err := parseFileDiffsCursor(cursor, rangeSpec); err == nil {
+			t.Errorf("%q: got no error for invalid cursor", cursor)
+		}
+	}
+}
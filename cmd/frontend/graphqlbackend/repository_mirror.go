@@ -147,6 +147,14 @@ func (r *updateScheduleResolver) Total() int32 {
 	return int32(r.schedule.Total)
 }
 
+func (r *updateScheduleResolver) LastError() *string {
+	return lastUpdateError(r.schedule.LastError)
+}
+
+func (r *updateScheduleResolver) LastErrorAt() *string {
+	return lastUpdateErrorAt(r.schedule.LastErrorAt)
+}
+
 func (r *repositoryMirrorInfoResolver) UpdateQueue(ctx context.Context) (*updateQueueResolver, error) {
 	info, err := r.repoUpdateSchedulerInfo(ctx)
 	if err != nil {
@@ -174,6 +182,29 @@ func (r *updateQueueResolver) Total() int32 {
 	return int32(r.queue.Total)
 }
 
+func (r *updateQueueResolver) LastError() *string {
+	return lastUpdateError(r.queue.LastError)
+}
+
+func (r *updateQueueResolver) LastErrorAt() *string {
+	return lastUpdateErrorAt(r.queue.LastErrorAt)
+}
+
+func lastUpdateError(err string) *string {
+	if err == "" {
+		return nil
+	}
+	return &err
+}
+
+func lastUpdateErrorAt(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
+
 func (r *schemaResolver) CheckMirrorRepositoryConnection(ctx context.Context, args *struct {
 	Repository *graphql.ID
 	Name       *string
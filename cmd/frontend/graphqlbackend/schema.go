@@ -815,7 +815,7 @@ type Search {
 }
 
 # A search result.
-union SearchResult = FileMatch | CommitSearchResult | Repository
+union SearchResult = FileMatch | CommitSearchResult | Repository | FileDiffSearchResult
 
 # An object representing a markdown string.
 type Markdown {
@@ -899,6 +899,17 @@ type SearchResults {
     elapsedMilliseconds: Int!
     # Dynamic filters generated by the search results
     dynamicFilters: [SearchFilter!]!
+    # The diffs that result from replacing the matches of the query's pattern with the value of its replace:
+    # field (e.g., "foo\((\w+)\) replace:bar($1)"), one for each file match that the replacement changes. It is
+    # empty if the query has no replace: field.
+    #
+    # The oldFile and newFile of each FileDiff are the file that was searched (the replaced contents only exist
+    # in the diff). The diffs can be in several repositories. The rawDiff of the diffs in a single repository
+    # can be applied to the searched commit with "git apply".
+    replacementDiffs(
+        # Return the first n file diffs from the list.
+        first: Int
+    ): FileDiffConnection!
 }
 
 # Statistics about search results.
@@ -1007,6 +1018,27 @@ type CommitSearchResult implements GenericSearchResultInterface {
     diffPreview: HighlightedString
 }
 
+# A search result that is a file with matching changes in the diff of a merge base range, for diff
+# searches scoped to a repository and range (such as repo:foo@main...release). The range is
+# searched as a single diff (from the merge base of its base and head to its head) instead of
+# commit by commit.
+type FileDiffSearchResult implements GenericSearchResultInterface {
+    # Base64 data uri to an icon.
+    icon: String!
+    # A markdown string that is rendered prominently.
+    label: Markdown!
+    # The URL of the result.
+    url: String!
+    # A markdown string that is rendered less prominently.
+    detail: Markdown!
+    # The result previews of the result.
+    matches: [SearchResultMatch!]!
+    # The comparison of the base and head of the range.
+    comparison: RepositoryComparison!
+    # The diff of the file, with only the hunks that match the search query.
+    fileDiff: FileDiff!
+}
+
 # A search result that is a diff between two diffable Git objects.
 type DiffSearchResult {
     # The diff that matched the search query.
@@ -1048,7 +1080,9 @@ type ExternalServiceConnection {
 # A specific kind of external service.
 enum ExternalServiceKind {
     AWSCODECOMMIT
+    BITBUCKETCLOUD
     BITBUCKETSERVER
+    GITEA
     GITHUB
     GITLAB
     GITOLITE
@@ -1070,6 +1104,10 @@ type ExternalService implements Node {
     createdAt: String!
     # When the external service was last updated.
     updatedAt: String!
+    # The URL of the push event webhooks of the external service, which trigger an immediate update
+    # of the pushed repositories. Webhook requests are verified with the webhookSecret of the
+    # external service's configuration. Null if the external service kind doesn't support webhooks.
+    webhookURL: String
 }
 
 # A list of repositories.
@@ -1180,6 +1218,20 @@ type Repository implements Node & GenericSearchResultInterface {
         # The head of the diff ("new" or "right-hand side"), or "HEAD" if not specified.
         head: String
     ): RepositoryComparison!
+    # The commits reachable from the given revisions, with the lanes and parent edges needed to draw
+    # the branch topology as a graph (similar to git log --graph).
+    commitGraph(
+        # The revisions (such as branch names, or ranges such as "main..feature") whose commits to
+        # include, or HEAD if not specified.
+        revisions: [String!]
+        # Return the first n commits of the graph (in topological order). Defaults to 100, and at most
+        # 1000 commits are returned.
+        first: Int
+        # Return the commits after this cursor (the endCursor of the previous page). The graph is laid
+        # out as if the commits of the previous pages were drawn above. An error is returned if the
+        # revisions no longer point to the same commits.
+        after: String
+    ): CommitGraph!
     # The repository's contributors.
     contributors(
         # The Git revision range to compute contributors in.
@@ -1249,6 +1301,10 @@ type UpdateSchedule {
     index: Int!
     # The total number of repos in the schedule.
     total: Int!
+    # The error of the last update of the repo, or null if it succeeded.
+    lastError: String
+    # The time of the last update error, or null if the last update succeeded.
+    lastErrorAt: String
 }
 
 # The state of a repository in the update queue.
@@ -1261,6 +1317,10 @@ type UpdateQueue {
     updating: Boolean!
     # The total number of repos in the update queue (including updating repos).
     total: Int!
+    # The error of the last update of the repo, or null if it succeeded.
+    lastError: String
+    # The time of the last update error, or null if the last update succeeded.
+    lastErrorAt: String
 }
 
 # A repository on an external service (such as GitHub, GitLab, Phabricator, etc.).
@@ -1343,6 +1403,17 @@ type RepositoryComparison {
     fileDiffs(
         # Return the first n file diffs from the list.
         first: Int
+        # Return the file diffs after this cursor (the endCursor of the previous page). An error is
+        # returned if the base or head revision points to another commit than when the cursor was
+        # returned.
+        after: String
+        # Only return the file diffs of files whose path matches one of these globs (such as *.go or
+        # cmd/**). A * does not match a /, and ** matches any number of directories.
+        paths: [String!]
+        # Omit the hunks of file diffs whose hunks are larger than this many bytes (not counting the
+        # file headers). Such file diffs are returned without hunks and with hunksSkipped set, so
+        # that comparisons with large changes (such as generated files) stay fast.
+        maxHunksSize: Int
     ): FileDiffConnection!
 }
 
@@ -1378,7 +1449,9 @@ type FileDiff {
     mostRelevantFile: File2!
     # Hunks that were changed from old to new.
     hunks: [FileDiffHunk!]!
-    # The diff stat for the whole file.
+    # Whether the hunks were omitted because they are larger than the requested maxHunksSize.
+    hunksSkipped: Boolean!
+    # The diff stat for the whole file (including omitted hunks).
     stat: DiffStat!
     # FOR INTERNAL USE ONLY.
     #
@@ -1461,6 +1534,10 @@ type Symbol {
     language: String!
     # The location where this symbol is defined.
     location: Location!
+    # The revisions in which this symbol is defined (in identical files), if it is the result
+    # of a search of several revisions of its repository (such as repo:foo@*refs/heads/*).
+    # Null otherwise.
+    revisions: [String!]
     # The URL to this symbol (using the input revision specifier, which may not be immutable).
     url: String!
     # The canonical URL to this symbol (using an immutable revision specifier).
@@ -1650,6 +1727,9 @@ type PhabricatorRepo {
 
 # Pagination information. See https://facebook.github.io/relay/graphql/connections.htm#sec-undefined.PageInfo.
 type PageInfo {
+    # When paginating forwards, the cursor to continue, or null if the connection does not support
+    # cursors or there is no next page.
+    endCursor: String
     # Whether there is a next page of nodes in the connection.
     hasNextPage: Boolean!
 }
@@ -1662,6 +1742,33 @@ type GitCommitConnection {
     pageInfo: PageInfo!
 }
 
+# A graph of Git commits, laid out in lanes (columns) for drawing the branch topology.
+type CommitGraph {
+    # The commits in topological order (no commit is shown before all of its children in the graph).
+    nodes: [CommitGraphNode!]!
+    # Pagination information.
+    pageInfo: PageInfo!
+}
+
+# A commit in a commit graph.
+type CommitGraphNode {
+    # The commit.
+    commit: GitCommit!
+    # The 0-indexed lane (column) of the commit. Lane 0 is the first-parent line of the first revision.
+    lane: Int!
+    # The edges to the commit's parents, in the order of GitCommit.parents.
+    parents: [CommitGraphEdge!]!
+}
+
+# An edge from a commit to one of its parents in a commit graph.
+type CommitGraphEdge {
+    # The parent's commit ID. The parent may not be in the graph if the graph was limited with first.
+    oid: GitObjectID!
+    # The lane that the edge continues in below the commit. The parent is in this lane, unless
+    # another edge leads to it in a lane further left (then the edges merge into that lane).
+    lane: Int!
+}
+
 # A Git commit.
 type GitCommit implements Node {
     # The globally addressable ID for this commit.
@@ -1948,7 +2055,57 @@ type GitBlob implements TreeEntry & File2 {
     # The URLs to this blob on its repository's external services.
     externalURLs: [ExternalLink!]!
     # Blame the blob.
-    blame(startLine: Int!, endLine: Int!): [Hunk!]!
+    blame(
+        startLine: Int!
+        endLine: Int!
+        # Commits whose changes are ignored, such as bulk formatting commits. The lines they
+        # changed are blamed on the previous commit that changed them. Commits that don't exist
+        # in the repository are skipped.
+        ignoreRevs: [String!]
+        # Also ignore the commits listed in the repository's .git-blame-ignore-revs file.
+        useIgnoreRevsFile: Boolean = true
+        # Blame lines that were moved or copied within the blob on the commit that originally
+        # added them (as git blame -M does).
+        detectMoves: Boolean = false
+        # Blame lines that were moved or copied from other files changed in the same commit on
+        # the commit that originally added them (as git blame -C does).
+        detectCopies: Boolean = false
+    ): [Hunk!]!
+    # The history of the blob: the commits that changed it (most recent first), with the diff of
+    # the blob in each commit. If startLine and endLine are given, only the commits that changed
+    # the lines in that range are returned, with the diff of that range (as git log -L does).
+    history(
+        # Returns the first n commits.
+        first: Int
+        # Continue the history of the blob past the commits that renamed it (as git log --follow
+        # does). The history of a line range always continues past renames.
+        follow: Boolean = false
+        # The first line (1-based) of the line range.
+        startLine: Int
+        # The last line (1-based, inclusive) of the line range.
+        endLine: Int
+    ): FileHistoryConnection!
+    # The definitions of the identifier at the given position, most likely first. They are
+    # the symbols with the identifier's name in this repository or, if there are none, in
+    # the other repositories of the repogroups that contain it. Symbols in files of the
+    # same language and with nearby paths are ranked first.
+    definition(
+        # The line (zero-based) of the position.
+        line: Int!
+        # The character offset (zero-based) in the line of the position.
+        character: Int!
+    ): [Location!]!
+    # The references to the identifier at the given position, most likely first. They are
+    # the occurrences of the identifier in files with the same extension in this repository
+    # and in the other repositories of the repogroups that contain it.
+    references(
+        # The line (zero-based) of the position.
+        line: Int!
+        # The character offset (zero-based) in the line of the position.
+        character: Int!
+        # Returns the first n references.
+        first: Int
+    ): [Location!]!
     # Highlight the blob contents.
     highlight(disableTimeout: Boolean!, isLightTheme: Boolean!): HighlightedFile!
     # Submodule metadata if this tree points to a submodule
@@ -1971,6 +2128,30 @@ type GitBlob implements TreeEntry & File2 {
     ): Boolean!
 }
 
+# A list of the commits in the history of a file.
+type FileHistoryConnection {
+    # The commits in the history of the file, with the diff of the file in each commit.
+    nodes: [FileHistoryEntry!]!
+    # Pagination information.
+    pageInfo: PageInfo!
+}
+
+# A commit in the history of a file, with the diff of the file in the commit.
+type FileHistoryEntry {
+    # The commit.
+    commit: GitCommit!
+    # The path of the file in the commit. It differs from the path of the blob whose history this
+    # is if the file was renamed in a later commit.
+    path: String!
+    # The path of the file before the commit, or null if the file was added in the commit (or if
+    # no diff is available, such as for merge commits). It differs from path if the file was
+    # renamed in the commit.
+    oldPath: String
+    # The diff hunks of the file in the commit. For the history of a line range, only the hunk
+    # of the line range is returned.
+    hunks: [FileDiffHunk!]!
+}
+
 # A highlighted file.
 type HighlightedFile {
     # Whether or not it was aborted.
@@ -2009,6 +2190,9 @@ type LineMatch {
     offsetAndLengths: [[Int!]!]!
     # Whether or not the limit was hit.
     limitHit: Boolean!
+    # The ranges of the matches. For a multiline search (multiline:yes), a match may span several lines,
+    # and the preview contains all lines from lineNumber to the last line of a match.
+    ranges: [Range!]!
 }
 
 # A hunk.
@@ -2029,6 +2213,11 @@ type Hunk {
     message: String!
     # The commit that contains the hunk.
     commit: GitCommit!
+    # The path of the file that contains the hunk in the commit. It differs from the blamed path if
+    # the lines were moved or copied from another file (or if the file was renamed) since.
+    originalPath: String!
+    # The start line (1-based) of the hunk in the file in the commit.
+    originalStartLine: Int!
 }
 
 # A list of users.
@@ -2121,6 +2310,17 @@ type User implements Node & SettingsSubject {
     #
     # Only the user and site admins can access this field.
     surveyResponses: [SurveyResponse!]!
+    # The last time that the user's repository permissions were synced from the authorization providers by the
+    # background permissions syncer (that is, the least recent of the last successful syncs from each
+    # authorization provider), or null if they have not been synced from all authorization providers.
+    #
+    # Only site admins can access this field.
+    permissionsSyncedAt: String
+    # The errors of the last sync of the user's repository permissions from each authorization provider by the
+    # background permissions syncer.
+    #
+    # Only site admins can access this field.
+    permissionsSyncErrors: [String!]!
     # The URL to view this user's customer information (for Sourcegraph.com site admins).
     #
     # Only Sourcegraph.com site admins may query this field.
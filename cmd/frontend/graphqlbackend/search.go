@@ -452,7 +452,21 @@ func resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevisions,
 				// searches like "repo:@foobar" (where foobar is an invalid revspec on most repos)
 				// taking a long time because they all ask gitserver to try to fetch from the remote
 				// repo.
-				if _, err := git.ResolveRevision(ctx, repoRev.GitserverRepo(), nil, rev.RevSpec, &git.ResolveRevisionOptions{NoEnsureRevision: true}); git.IsRevisionNotFound(err) || err == context.DeadlineExceeded {
+				//
+				// A merge base range ("base...head", used by diff searches) is validated by
+				// validating its base and head.
+				revSpecs := []string{rev.RevSpec}
+				if base, head, ok := rev.MergeBaseRange(); ok {
+					revSpecs = []string{base, head}
+				}
+				var missing bool
+				for _, revSpec := range revSpecs {
+					if _, err := git.ResolveRevision(ctx, repoRev.GitserverRepo(), nil, revSpec, &git.ResolveRevisionOptions{NoEnsureRevision: true}); git.IsRevisionNotFound(err) || err == context.DeadlineExceeded {
+						missing = true
+						break
+					}
+				}
+				if missing {
 					// The revspec does not exist, so don't include it, and report that it's missing.
 					if rev.RevSpec == "" {
 						// Report as HEAD not "" (empty string) to avoid user confusion.
@@ -513,6 +527,9 @@ func (r *searchResolver) suggestFilePaths(ctx context.Context, limit int) ([]*se
 	// Treat all default terms as though they had `file:` before them (to make it easy for users to
 	// jump to files by just typing their name).
 	for _, v := range r.query.Values(query.FieldDefault) {
+		if v.Not() {
+			continue
+		}
 		includePatterns = append(includePatterns, asString(v))
 	}
 
@@ -60,6 +60,9 @@ func (r *commitSearchResultResolver) Matches() []*searchResultMatchResolver {
 	return r.matches
 }
 
+// commitIcon is the icon of commit and diff search results.
+const commitIcon = "data:image/svg+xml;base64,PD94bWwgdmVyc2lvbj0iMS4wIiBlbmNvZGluZz0iVVRGLTgiPz48IURPQ1RZUEUgc3ZnIFBVQkxJQyAiLS8vVzNDLy9EVEQgU1ZHIDEuMS8vRU4iICJodHRwOi8vd3d3LnczLm9yZy9HcmFwaGljcy9TVkcvMS4xL0RURC9zdmcxMS5kdGQiPjxzdmcgeG1sbnM9Imh0dHA6Ly93d3cudzMub3JnLzIwMDAvc3ZnIiB4bWxuczp4bGluaz0iaHR0cDovL3d3dy53My5vcmcvMTk5OS94bGluayIgdmVyc2lvbj0iMS4xIiB3aWR0aD0iMjQiIGhlaWdodD0iMjQiIHZpZXdCb3g9IjAgMCAyNCAyNCI+PHBhdGggZD0iTTE3LDEyQzE3LDE0LjQyIDE1LjI4LDE2LjQ0IDEzLDE2LjlWMjFIMTFWMTYuOUM4LjcyLDE2LjQ0IDcsMTQuNDIgNywxMkM3LDkuNTggOC43Miw3LjU2IDExLDcuMVYzSDEzVjcuMUMxNS4yOCw3LjU2IDE3LDkuNTggMTcsMTJNMTIsOUEzLDMgMCAwLDAgOSwxMkEzLDMgMCAwLDAgMTIsMTVBMywzIDAgMCwwIDE1LDEyQTMsMyAwIDAsMCAxMiw5WiIgLz48L3N2Zz4="
+
 var mockSearchCommitDiffsInRepo func(ctx context.Context, repoRevs search.RepositoryRevisions, info *search.PatternInfo, query *query.Query) (results []*commitSearchResultResolver, limitHit, timedOut bool, err error)
 
 func searchCommitDiffsInRepo(ctx context.Context, repoRevs search.RepositoryRevisions, info *search.PatternInfo, query *query.Query) (results []*commitSearchResultResolver, limitHit, timedOut bool, err error) {
@@ -292,7 +295,6 @@ func searchCommitsInRepo(ctx context.Context, op commitSearchOp) (results []*com
 			matchBody, matchHighlights = cleanDiffPreview(fromVCSHighlights(rawResult.DiffHighlights), rawResult.Diff.Raw)
 		}
 
-		commitIcon := "data:image/svg+xml;base64,PD94bWwgdmVyc2lvbj0iMS4wIiBlbmNvZGluZz0iVVRGLTgiPz48IURPQ1RZUEUgc3ZnIFBVQkxJQyAiLS8vVzNDLy9EVEQgU1ZHIDEuMS8vRU4iICJodHRwOi8vd3d3LnczLm9yZy9HcmFwaGljcy9TVkcvMS4xL0RURC9zdmcxMS5kdGQiPjxzdmcgeG1sbnM9Imh0dHA6Ly93d3cudzMub3JnLzIwMDAvc3ZnIiB4bWxuczp4bGluaz0iaHR0cDovL3d3dy53My5vcmcvMTk5OS94bGluayIgdmVyc2lvbj0iMS4xIiB3aWR0aD0iMjQiIGhlaWdodD0iMjQiIHZpZXdCb3g9IjAgMCAyNCAyNCI+PHBhdGggZD0iTTE3LDEyQzE3LDE0LjQyIDE1LjI4LDE2LjQ0IDEzLDE2LjlWMjFIMTFWMTYuOUM4LjcyLDE2LjQ0IDcsMTQuNDIgNywxMkM3LDkuNTggOC43Miw3LjU2IDExLDcuMVYzSDEzVjcuMUMxNS4yOCw3LjU2IDE3LDkuNTggMTcsMTJNMTIsOUEzLDMgMCAwLDAgOSwxMkEzLDMgMCAwLDAgMTIsMTVBMywzIDAgMCwwIDE1LDEyQTMsMyAwIDAsMCAxMiw5WiIgLz48L3N2Zz4="
 		results[i].label = createLabel(rawResult, commitResolver)
 		commitHash := string(rawResult.Commit.ID)
 		if len(rawResult.Commit.ID) > 7 {
@@ -422,12 +424,46 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.Args) ([]*search
 	defer cancel()
 
 	var (
-		wg          sync.WaitGroup
-		mu          sync.Mutex
-		unflattened [][]*commitSearchResultResolver
-		common      = &searchResultsCommon{}
+		wg              sync.WaitGroup
+		mu              sync.Mutex
+		unflattened     [][]*commitSearchResultResolver
+		fileDiffResults [][]*fileDiffSearchResultResolver
+		common          = &searchResultsCommon{}
 	)
 	for _, repoRev := range args.Repos {
+		// Merge base ranges (repo:foo@base...head) are searched as a single diff, and the other
+		// revisions commit by commit.
+		ranges, commitRepoRev := splitMergeBaseRanges(*repoRev)
+		for _, rev := range ranges {
+			wg.Add(1)
+			go func(repoRev search.RepositoryRevisions) {
+				defer wg.Done()
+				base, head, _ := repoRev.Revs[0].MergeBaseRange()
+				results, repoLimitHit, searchErr := searchMergeBaseDiffsInRepo(ctx, repoRev, base, head, args.Pattern)
+				if ctx.Err() == context.Canceled {
+					// Our request has been canceled (either because another one of args.repos had a
+					// fatal error, or otherwise), so we can just ignore these results.
+					return
+				}
+				repoTimedOut := ctx.Err() == context.DeadlineExceeded
+				if searchErr != nil {
+					tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.String("searchErr", searchErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)))
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if fatalErr := handleRepoSearchResult(common, repoRev, repoLimitHit, repoTimedOut, searchErr); fatalErr != nil {
+					err = errors.Wrapf(searchErr, "failed to search diff %s", repoRev.String())
+					cancel()
+				}
+				if len(results) > 0 {
+					fileDiffResults = append(fileDiffResults, results)
+				}
+			}(search.RepositoryRevisions{Repo: repoRev.Repo, Revs: []search.RevisionSpecifier{rev}})
+		}
+		if commitRepoRev == nil {
+			continue
+		}
+
 		wg.Add(1)
 		go func(repoRev search.RepositoryRevisions) {
 			defer wg.Done()
@@ -450,7 +486,7 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.Args) ([]*search
 			if len(results) > 0 {
 				unflattened = append(unflattened, results)
 			}
-		}(*repoRev)
+		}(*commitRepoRev)
 	}
 	wg.Wait()
 	if err != nil {
@@ -461,7 +497,27 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.Args) ([]*search
 	for _, results := range unflattened {
 		flattened = append(flattened, results...)
 	}
-	return commitSearchResultsToSearchResults(flattened), common, nil
+	return append(fileDiffSearchResultsToSearchResults(fileDiffResults), commitSearchResultsToSearchResults(flattened)...), common, nil
+}
+
+// fileDiffSearchResultsToSearchResults returns the files with matching changes of each merge base
+// range before those of the next, ordered by repository and range.
+func fileDiffSearchResultsToSearchResults(unflattened [][]*fileDiffSearchResultResolver) []*searchResultResolver {
+	sort.Slice(unflattened, func(i, j int) bool {
+		a, b := unflattened[i][0].fileDiff.cmp, unflattened[j][0].fileDiff.cmp
+		if a.repo.Name() != b.repo.Name() {
+			return a.repo.Name() < b.repo.Name()
+		}
+		return a.Range().Expr() < b.Range().Expr()
+	})
+
+	var results []*searchResultResolver
+	for _, fileDiffs := range unflattened {
+		for _, fileDiff := range fileDiffs {
+			results = append(results, &searchResultResolver{fileDiff: fileDiff})
+		}
+	}
+	return results
 }
 
 var mockSearchCommitLogInRepos func(args *search.Args) ([]*searchResultResolver, *searchResultsCommon, error)
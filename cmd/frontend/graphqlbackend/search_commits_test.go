@@ -90,6 +90,72 @@ func TestSearchCommitsInRepo(t *testing.T) {
 	}
 }
 
+func TestSearchCommitDiffsInRepos_mergeBaseRange(t *testing.T) {
+	repo := &types.Repo{ID: 1, Name: "repo"}
+	cmp := &repositoryComparisonResolver{baseRevspec: "main", headRevspec: "release", repo: &repositoryResolver{repo: repo}}
+	fileDiff := &fileDiffSearchResultResolver{fileDiff: &fileDiffResolver{cmp: cmp}}
+
+	var gotRanges, gotRevs []string
+	mockSearchMergeBaseDiffsInRepo = func(ctx context.Context, repoRevs search.RepositoryRevisions, base, head string, info *search.PatternInfo) ([]*fileDiffSearchResultResolver, bool, error) {
+		gotRanges = append(gotRanges, base+" "+head)
+		return []*fileDiffSearchResultResolver{fileDiff}, false, nil
+	}
+	mockSearchCommitDiffsInRepo = func(ctx context.Context, repoRevs search.RepositoryRevisions, info *search.PatternInfo, query *query.Query) ([]*commitSearchResultResolver, bool, bool, error) {
+		gotRevs = append(gotRevs, repoRevs.RevSpecs()...)
+		return nil, false, false, nil
+	}
+	defer func() {
+		mockSearchMergeBaseDiffsInRepo = nil
+		mockSearchCommitDiffsInRepo = nil
+	}()
+
+	tests := map[string]struct {
+		revs       []search.RevisionSpecifier
+		wantRanges []string
+		wantRevs   []string
+	}{
+		"range": {
+			revs:       []search.RevisionSpecifier{{RevSpec: "main...release"}},
+			wantRanges: []string{"main release"},
+		},
+		"range and revs": {
+			revs:       []search.RevisionSpecifier{{RevSpec: "main...release"}, {RevSpec: "rev"}},
+			wantRanges: []string{"main release"},
+			wantRevs:   []string{"rev"},
+		},
+		"revs": {
+			revs:     []search.RevisionSpecifier{{RevSpec: "rev"}, {RevSpec: "a..b"}},
+			wantRevs: []string{"rev", "a..b"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotRanges, gotRevs = nil, nil
+			results, _, err := searchCommitDiffsInRepos(context.Background(), &search.Args{
+				Pattern: &search.PatternInfo{Pattern: "p", FileMatchLimit: int32(defaultMaxSearchResults)},
+				Repos:   []*search.RepositoryRevisions{{Repo: repo, Revs: test.revs}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotRanges, test.wantRanges) {
+				t.Errorf("got ranges %q, want %q", gotRanges, test.wantRanges)
+			}
+			if !reflect.DeepEqual(gotRevs, test.wantRevs) {
+				t.Errorf("got revs %q, want %q", gotRevs, test.wantRevs)
+			}
+			if len(results) != len(test.wantRanges) {
+				t.Fatalf("got %d results, want %d", len(results), len(test.wantRanges))
+			}
+			for _, result := range results {
+				if result.fileDiff != fileDiff {
+					t.Errorf("got result %+v, want the file diff result", result)
+				}
+			}
+		})
+	}
+}
+
 func (r *commitSearchResultResolver) String() string {
 	return fmt.Sprintf("{commit: %+v diffPreview: %+v messagePreview: %+v}", r.commit, r.diffPreview, r.messagePreview)
 }
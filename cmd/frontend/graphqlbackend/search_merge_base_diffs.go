@@ -0,0 +1,141 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/pkg/trace"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+)
+
+// fileDiffSearchResultResolver is a resolver for the GraphQL type `FileDiffSearchResult`, a file
+// with matching changes in the merge base diff of a diff search scoped to `repo:foo@base...head`.
+type fileDiffSearchResultResolver struct {
+	fileDiff   *fileDiffResolver // only has the matching hunks
+	highlights []*highlightedRange
+}
+
+func (r *fileDiffSearchResultResolver) Comparison() *repositoryComparisonResolver {
+	return r.fileDiff.cmp
+}
+
+func (r *fileDiffSearchResultResolver) FileDiff() *fileDiffResolver { return r.fileDiff }
+
+func (r *fileDiffSearchResultResolver) Icon() string {
+	return commitIcon
+}
+
+func (r *fileDiffSearchResultResolver) Label() *markdownResolver {
+	repo := r.fileDiff.cmp.repo
+	return &markdownResolver{text: fmt.Sprintf("[%s](%s) › [%s](%s)", displayRepoName(repo.Name()), repo.URL(), r.path(), r.URL())}
+}
+
+func (r *fileDiffSearchResultResolver) URL() string {
+	return r.comparisonURL() + "#diff-" + r.fileDiff.InternalID()
+}
+
+func (r *fileDiffSearchResultResolver) Detail() *markdownResolver {
+	return &markdownResolver{text: fmt.Sprintf("[`%s`](%s)", r.fileDiff.cmp.Range().Expr(), r.comparisonURL())}
+}
+
+func (r *fileDiffSearchResultResolver) Matches() ([]*searchResultMatchResolver, error) {
+	hunks, err := diff.PrintHunks(r.fileDiff.fileDiff.Hunks)
+	if err != nil {
+		return nil, err
+	}
+	return []*searchResultMatchResolver{{
+		body:       fmt.Sprintf("```diff\n%s```", hunks),
+		highlights: r.highlights,
+		url:        r.URL(),
+	}}, nil
+}
+
+// path returns the new path of the file, or the old path if it was deleted.
+func (r *fileDiffSearchResultResolver) path() string {
+	if path := r.fileDiff.NewPath(); path != nil {
+		return *path
+	}
+	return *r.fileDiff.OldPath()
+}
+
+func (r *fileDiffSearchResultResolver) comparisonURL() string {
+	return r.fileDiff.cmp.repo.URL() + "/-/compare/" + r.fileDiff.cmp.Range().Expr()
+}
+
+var mockSearchMergeBaseDiffsInRepo func(ctx context.Context, repoRevs search.RepositoryRevisions, base, head string, info *search.PatternInfo) (results []*fileDiffSearchResultResolver, limitHit bool, err error)
+
+// searchMergeBaseDiffsInRepo searches the changes made on head since it diverged from base (the
+// diff of `git diff base...head`, computed once instead of commit by commit), and returns the files
+// with matching changes.
+func searchMergeBaseDiffsInRepo(ctx context.Context, repoRevs search.RepositoryRevisions, base, head string, info *search.PatternInfo) (results []*fileDiffSearchResultResolver, limitHit bool, err error) {
+	if mockSearchMergeBaseDiffsInRepo != nil {
+		return mockSearchMergeBaseDiffsInRepo(ctx, repoRevs, base, head, info)
+	}
+
+	tr, ctx := trace.New(ctx, "searchMergeBaseDiffsInRepo", fmt.Sprintf("repoRevs: %v, pattern %+v", repoRevs, info))
+	defer func() {
+		tr.LazyPrintf("%d results, limitHit=%v", len(results), limitHit)
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	cmp, err := (&repositoryResolver{repo: repoRevs.Repo}).Comparison(ctx, &repositoryComparisonInput{Base: &base, Head: &head})
+	if err != nil {
+		return nil, false, err
+	}
+	if cmp.base == nil {
+		return nil, false, fmt.Errorf("invalid diff range base: %q", base)
+	}
+
+	rawResults, limitHit, err := git.MergeBaseDiffSearch(ctx, repoRevs.GitserverRepo(), git.MergeBaseDiffSearchOptions{
+		Base: cmp.base.oid,
+		Head: cmp.head.oid,
+		Query: git.TextSearchOptions{
+			Pattern:         info.Pattern,
+			IsRegExp:        info.IsRegExp,
+			IsCaseSensitive: info.IsCaseSensitive,
+		},
+		Paths: git.PathOptions{
+			IncludePatterns: info.IncludePatterns,
+			ExcludePattern:  info.ExcludePattern,
+			IsCaseSensitive: info.PathPatternsAreCaseSensitive,
+			IsRegExp:        info.PathPatternsAreRegExps,
+		},
+		Limit: int(info.FileMatchLimit),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	results = make([]*fileDiffSearchResultResolver, len(rawResults))
+	for i, rawResult := range rawResults {
+		results[i] = &fileDiffSearchResultResolver{
+			fileDiff:   &fileDiffResolver{fileDiff: rawResult.FileDiff, cmp: cmp},
+			highlights: fromVCSHighlights(rawResult.Highlights),
+		}
+	}
+	return results, limitHit, nil
+}
+
+// splitMergeBaseRanges splits the merge base ranges (such as "base...head") off repoRev, because
+// diff searches search them as a single diff instead of commit by commit. The returned rest is
+// nil if repoRev only has merge base ranges.
+func splitMergeBaseRanges(repoRev search.RepositoryRevisions) (ranges []search.RevisionSpecifier, rest *search.RepositoryRevisions) {
+	var revs []search.RevisionSpecifier
+	for _, rev := range repoRev.Revs {
+		if _, _, ok := rev.MergeBaseRange(); ok {
+			ranges = append(ranges, rev)
+		} else {
+			revs = append(revs, rev)
+		}
+	}
+	if len(ranges) == 0 {
+		return nil, &repoRev
+	}
+	if len(revs) == 0 {
+		return ranges, nil
+	}
+	return ranges, &search.RepositoryRevisions{Repo: repoRev.Repo, Revs: revs}
+}
@@ -0,0 +1,103 @@
+package graphqlbackend
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+)
+
+func (sr *searchResultsResolver) ReplacementDiffs(args *struct {
+	First *int32
+}) (*replacementDiffConnectionResolver, error) {
+	var diffs []*replacementDiffResolver
+	for _, result := range sr.results {
+		fm, ok := result.ToFileMatch()
+		if !ok || fm.JDiff == "" {
+			continue
+		}
+		fileDiff, err := diff.ParseFileDiff([]byte(fm.JDiff))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid replacement diff for %s", fm.uri)
+		}
+		// Searcher's diffs have the a/ and b/ prefixes of git diff, so
+		// that they can be applied with git apply. FileDiff paths do not.
+		fileDiff.OrigName = strings.TrimPrefix(fileDiff.OrigName, "a/")
+		fileDiff.NewName = strings.TrimPrefix(fileDiff.NewName, "b/")
+
+		commit := fm.File().commit
+		diffs = append(diffs, &replacementDiffResolver{
+			fileDiffResolver: &fileDiffResolver{
+				fileDiff: fileDiff,
+				cmp: &repositoryComparisonResolver{
+					baseRevspec: string(commit.oid),
+					headRevspec: string(commit.oid),
+					base:        commit,
+					head:        commit,
+					repo:        commit.repo,
+				},
+			},
+			fileMatch: fm,
+		})
+	}
+	return &replacementDiffConnectionResolver{diffs: diffs, first: args.First}, nil
+}
+
+// replacementDiffConnectionResolver is a resolver for the GraphQL type
+// `FileDiffConnection` of the diffs of replacing the matches of a search.
+type replacementDiffConnectionResolver struct {
+	diffs []*replacementDiffResolver
+	first *int32
+}
+
+func (r *replacementDiffConnectionResolver) Nodes() []*replacementDiffResolver {
+	if r.first != nil && len(r.diffs) > int(*r.first) {
+		return r.diffs[:*r.first]
+	}
+	return r.diffs
+}
+
+func (r *replacementDiffConnectionResolver) TotalCount() *int32 {
+	n := int32(len(r.diffs))
+	return &n
+}
+
+func (r *replacementDiffConnectionResolver) PageInfo() *graphqlutil.PageInfo {
+	return graphqlutil.HasNextPage(r.first != nil && len(r.diffs) > int(*r.first))
+}
+
+func (r *replacementDiffConnectionResolver) DiffStat() *diffStat {
+	var stat diffStat
+	for _, d := range r.Nodes() {
+		s := d.fileDiff.Stat()
+		stat.added += s.Added
+		stat.changed += s.Changed
+		stat.deleted += s.Deleted
+	}
+	return &stat
+}
+
+func (r *replacementDiffConnectionResolver) RawDiff() string {
+	var b strings.Builder
+	for _, d := range r.Nodes() {
+		b.WriteString(d.fileMatch.JDiff)
+	}
+	return b.String()
+}
+
+// replacementDiffResolver is a resolver for the GraphQL type `FileDiff` of
+// the diff of replacing the matches in a file match.
+type replacementDiffResolver struct {
+	*fileDiffResolver
+	fileMatch *fileMatchResolver
+}
+
+func (r *replacementDiffResolver) InternalID() string {
+	// The paths of replacement diffs are not unique because they can be in
+	// several repositories, but the file match URIs are.
+	b := sha256.Sum256([]byte(r.fileMatch.uri))
+	return hex.EncodeToString(b[:])[:32]
+}
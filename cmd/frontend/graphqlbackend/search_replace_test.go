@@ -0,0 +1,86 @@
+package graphqlbackend
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func TestSearchResults_ReplacementDiffs(t *testing.T) {
+	const rawDiff = `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ func main() {
+-	foo(x)
++	bar(x, nil)
+ }
+`
+	fileMatch := func(repo api.RepoName, diff string) *searchResultResolver {
+		return &searchResultResolver{fileMatch: &fileMatchResolver{
+			JPath:    "main.go",
+			JDiff:    diff,
+			uri:      fileMatchURI(repo, "", "main.go"),
+			repo:     &types.Repo{Name: repo},
+			commitID: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+		}}
+	}
+	sr := &searchResultsResolver{results: []*searchResultResolver{
+		fileMatch("a", rawDiff),
+		fileMatch("b", ""), // the replacement did not change the file
+		fileMatch("c", rawDiff),
+	}}
+
+	conn, err := sr.ReplacementDiffs(&struct{ First *int32 }{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := conn.Nodes()
+	if len(nodes) != 2 {
+		t.Fatalf("got %d replacement diffs, want 2", len(nodes))
+	}
+	for _, n := range nodes {
+		if got, want := *n.OldPath(), "main.go"; got != want {
+			t.Errorf("got old path %q, want %q", got, want)
+		}
+		if got, want := *n.NewPath(), "main.go"; got != want {
+			t.Errorf("got new path %q, want %q", got, want)
+		}
+		if got, want := len(n.Hunks()), 1; got != want {
+			t.Fatalf("got %d hunks, want %d", got, want)
+		}
+		if got, want := n.Hunks()[0].Body(), " func main() {\n-\tfoo(x)\n+\tbar(x, nil)\n }\n"; got != want {
+			t.Errorf("got hunk body %q, want %q", got, want)
+		}
+		if got, want := string(n.MostRelevantFile().commit.oid), "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"; got != want {
+			t.Errorf("got commit %q, want %q", got, want)
+		}
+	}
+	if nodes[0].InternalID() == nodes[1].InternalID() {
+		t.Errorf("replacement diffs in different repositories have the same internal ID %q", nodes[0].InternalID())
+	}
+	if got, want := *conn.TotalCount(), int32(2); got != want {
+		t.Errorf("got total count %d, want %d", got, want)
+	}
+	if got, want := conn.DiffStat().Changed(), int32(2); got != want {
+		t.Errorf("got %d changed lines, want %d", got, want)
+	}
+	if got, want := conn.RawDiff(), rawDiff+rawDiff; got != want {
+		t.Errorf("got raw diff %q, want %q", got, want)
+	}
+
+	first := int32(1)
+	conn, err = sr.ReplacementDiffs(&struct{ First *int32 }{First: &first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(conn.Nodes()), 1; got != want {
+		t.Errorf("got %d replacement diffs, want %d", got, want)
+	}
+	if !conn.PageInfo().HasNextPage() {
+		t.Error("got no next page, want a next page")
+	}
+	if got, want := conn.RawDiff(), rawDiff; got != want {
+		t.Errorf("got raw diff %q, want %q", got, want)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/syntax"
+	searchquerytypes "github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/types"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
@@ -406,6 +408,9 @@ loop:
 		case r.diff != nil:
 			// Diff searches are cheap, because we implicitly have author date info.
 			addPoint(r.diff.commit.author.date)
+		case r.fileDiff != nil:
+			// Merge base diffs are dated by their head commit.
+			addPoint(r.fileDiff.fileDiff.cmp.head.author.date)
 		case r.fileMatch != nil:
 			// File match searches are more expensive, because we must blame the
 			// (first) line in order to know its placement in our sparkline.
@@ -552,17 +557,12 @@ func (r *searchResolver) Stats(ctx context.Context) (stats *searchResultsStats,
 }
 
 func (r *searchResolver) getPatternInfo() (*search.PatternInfo, error) {
+	isStructural := r.query.PatternType() == query.PatternTypeStructural
+
 	var patternsToCombine []string
 	for _, v := range r.query.Values(query.FieldDefault) {
-		// Treat quoted strings as literal strings to match, not regexps.
-		var pattern string
-		switch {
-		case v.String != nil:
-			pattern = regexp.QuoteMeta(*v.String)
-		case v.Regexp != nil:
-			pattern = v.Regexp.String()
-		}
-		if pattern == "" {
+		pattern := termPattern(v, isStructural)
+		if pattern == "" || v.Not() {
 			continue
 		}
 		patternsToCombine = append(patternsToCombine, pattern)
@@ -582,18 +582,111 @@ func (r *searchResolver) getPatternInfo() (*search.PatternInfo, error) {
 	patternInfo := &search.PatternInfo{
 		IsRegExp:                     true,
 		IsCaseSensitive:              r.query.IsCaseSensitive(),
+		IsMultiline:                  r.query.BoolValue(query.FieldMultiline),
 		FileMatchLimit:               r.maxResults(),
 		Pattern:                      regexpPatternMatchingExprsInOrder(patternsToCombine),
 		IncludePatterns:              includePatterns,
 		PathPatternsAreRegExps:       true,
 		PathPatternsAreCaseSensitive: r.query.IsCaseSensitive(),
 	}
+	if r.query.HasBooleanPattern() {
+		if isStructural {
+			return nil, &badRequestError{fmt.Errorf("%s:%s does not support OR, NOT or negated terms", query.FieldPatternType, query.PatternTypeStructural)}
+		}
+		if len(patternsToCombine) == 0 || !r.query.Pattern.RequiresTerm() {
+			// Files are found (and matches highlighted) with the non-negated
+			// terms, so every match must contain one.
+			return nil, &badRequestError{errors.New("the query must require a match of a search term that is not negated (such as a -b, but not a OR -b)")}
+		}
+		patternInfo.PatternExpr = toPatternExpr(r.query.Pattern)
+		patternInfo.Pattern = unionRegExps(patternsToCombine)
+	}
+	if len(r.query.Values(query.FieldReplace)) > 0 {
+		if isStructural {
+			return nil, &badRequestError{fmt.Errorf("%s: is not supported with %s:%s", query.FieldReplace, query.FieldPatternType, query.PatternTypeStructural)}
+		}
+		if patternInfo.PatternExpr != nil {
+			return nil, &badRequestError{fmt.Errorf("%s: is not supported for search terms combined with OR or NOT (or negated)", query.FieldReplace)}
+		}
+		// Capture groups refer to the pattern, so it must be a single term
+		// (which regexpPatternMatchingExprsInOrder does not wrap in a group).
+		if len(patternsToCombine) != 1 {
+			return nil, &badRequestError{fmt.Errorf(`%s: requires exactly one search pattern (use quotes or \s to match spaces)`, query.FieldReplace)}
+		}
+		patternInfo.Replace = true
+		patternInfo.Replacement, _ = r.query.StringValue(query.FieldReplace)
+	}
+	if isStructural {
+		// The terms of a structural pattern are separated by whitespace,
+		// which matches any whitespace in a structural pattern.
+		patternInfo.IsRegExp = false
+		patternInfo.IsStructural = true
+		patternInfo.IsMultiline = false // structural patterns always match across lines
+		patternInfo.Pattern = strings.Join(patternsToCombine, " ")
+	}
 	if len(excludePatterns) > 0 {
 		patternInfo.ExcludePattern = unionRegExps(excludePatterns)
 	}
 	return patternInfo, nil
 }
 
+// termPattern returns the pattern to search for the search term v.
+func termPattern(v *searchquerytypes.Value, isStructural bool) string {
+	switch {
+	case isStructural:
+		// Structural patterns are passed through unchanged.
+		return *v.String
+	case v.String != nil:
+		// Treat quoted strings as literal strings to match, not regexps.
+		return regexp.QuoteMeta(*v.String)
+	case v.Regexp != nil:
+		return v.Regexp.String()
+	}
+	return ""
+}
+
+// fileContentsOnlyFeature returns a description of the feature used by p
+// which only applies to file contents (so it only supports type:file), or ""
+// if p uses none.
+func fileContentsOnlyFeature(p *search.PatternInfo) string {
+	switch {
+	case p.IsStructural:
+		// Structural patterns don't match paths, repositories or refs.
+		return query.FieldPatternType + ":" + query.PatternTypeStructural
+	case p.PatternExpr != nil:
+		// Boolean expressions are evaluated on file contents.
+		return "search terms combined with OR or NOT (or negated)"
+	case p.Replace:
+		// Only file contents can be replaced.
+		return query.FieldReplace + ":"
+	}
+	return ""
+}
+
+// toPatternExpr converts the boolean expression of a query's search terms
+// (eg "(a OR b) -c") to a search.PatternExpr.
+func toPatternExpr(e *searchquerytypes.BoolExpr) *search.PatternExpr {
+	var expr *search.PatternExpr
+	switch e.Op {
+	case syntax.OpAnd, syntax.OpOr:
+		operands := make([]*search.PatternExpr, len(e.Operands))
+		for i, operand := range e.Operands {
+			operands[i] = toPatternExpr(operand)
+		}
+		if e.Op == syntax.OpAnd {
+			expr = &search.PatternExpr{And: operands}
+		} else {
+			expr = &search.PatternExpr{Or: operands}
+		}
+	default:
+		expr = &search.PatternExpr{Pattern: termPattern(e.Value, false)}
+	}
+	if e.Not {
+		expr = &search.PatternExpr{Not: expr}
+	}
+	return expr
+}
+
 var (
 	// The default timeout to use for queries.
 	defaultTimeout = 10 * time.Second
@@ -684,6 +777,16 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		resultTypes, _ = r.query.StringValues(query.FieldType)
 		if len(resultTypes) == 0 {
 			resultTypes = []string{"file", "path", "repo", "ref"}
+			if fileContentsOnlyFeature(args.Pattern) != "" {
+				resultTypes = []string{"file"}
+			}
+		}
+	}
+	if feature := fileContentsOnlyFeature(args.Pattern); feature != "" {
+		for _, resultType := range resultTypes {
+			if resultType != "file" {
+				return nil, &badRequestError{fmt.Errorf("%s only supports type:file (got type:%s)", feature, resultType)}
+			}
 		}
 	}
 	seenResultTypes := make(map[string]struct{}, len(resultTypes))
@@ -929,9 +1032,10 @@ func isContextError(ctx context.Context, err error) bool {
 //
 // Note: Any new result types added here also need to be handled properly in search_results.go:301 (sparklines)
 type searchResultResolver struct {
-	repo      *repositoryResolver         // repo name match
-	fileMatch *fileMatchResolver          // text match
-	diff      *commitSearchResultResolver // diff or commit match
+	repo      *repositoryResolver           // repo name match
+	fileMatch *fileMatchResolver            // text match
+	diff      *commitSearchResultResolver   // diff or commit match
+	fileDiff  *fileDiffSearchResultResolver // merge base diff match
 }
 
 // getSearchResultURIs returns the repo name and file uri respectiveley
@@ -974,6 +1078,9 @@ func (g *searchResultResolver) ToFileMatch() (*fileMatchResolver, bool) {
 func (g *searchResultResolver) ToCommitSearchResult() (*commitSearchResultResolver, bool) {
 	return g.diff, g.diff != nil
 }
+func (g *searchResultResolver) ToFileDiffSearchResult() (*fileDiffSearchResultResolver, bool) {
+	return g.fileDiff, g.fileDiff != nil
+}
 
 func (g *searchResultResolver) resultCount() int32 {
 	switch {
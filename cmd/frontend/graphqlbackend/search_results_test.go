@@ -220,6 +220,57 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 			PathPatternsAreRegExps: true,
 			ExcludePattern:         `f|(\.graphql$|\.gql$)`,
 		},
+		"foo(:[args]) patterntype:structural": {
+			Pattern:                "foo(:[args])",
+			IsStructural:           true,
+			PathPatternsAreRegExps: true,
+		},
+		"foo(:[a], :[b]) patterntype:structural file:f": {
+			Pattern:                "foo(:[a], :[b])",
+			IsStructural:           true,
+			PathPatternsAreRegExps: true,
+			IncludePatterns:        []string{"f"},
+		},
+		"foo( patterntype:literal": {
+			Pattern:                `foo\(`,
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+		},
+		`p\n\s+q multiline:yes`: {
+			Pattern:                `p\n\s+q`,
+			IsRegExp:               true,
+			IsMultiline:            true,
+			PathPatternsAreRegExps: true,
+		},
+		`foo\((\w+)\) replace:bar($1)`: {
+			Pattern:                `foo\((\w+)\)`,
+			IsRegExp:               true,
+			Replace:                true,
+			Replacement:            "bar($1)",
+			PathPatternsAreRegExps: true,
+		},
+		`p1 OR "p.2"`: {
+			Pattern:                `p1|p\.2`,
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			PatternExpr:            &search.PatternExpr{Or: []*search.PatternExpr{{Pattern: "p1"}, {Pattern: `p\.2`}}},
+		},
+		"p1 -p2 file:f": {
+			Pattern:                "p1",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			IncludePatterns:        []string{"f"},
+			PatternExpr:            &search.PatternExpr{And: []*search.PatternExpr{{Pattern: "p1"}, {Not: &search.PatternExpr{Pattern: "p2"}}}},
+		},
+		"p1 NOT (p2 OR p3)": {
+			Pattern:                "p1",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			PatternExpr: &search.PatternExpr{And: []*search.PatternExpr{
+				{Pattern: "p1"},
+				{Not: &search.PatternExpr{Or: []*search.PatternExpr{{Pattern: "p2"}, {Pattern: "p3"}}}},
+			}},
+		},
 	}
 	for queryStr, want := range tests {
 		t.Run(queryStr, func(t *testing.T) {
@@ -241,6 +292,28 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 	}
 }
 
+func TestSearchResolver_getPatternInfo_error(t *testing.T) {
+	for _, queryStr := range []string{
+		"-p",
+		"NOT (p1 OR p2) file:f",
+		"foo(:[x]) OR bar(:[y]) patterntype:structural",
+		"foo(:[x]) replace:bar patterntype:structural",
+		"p1 p2 replace:q",
+		"p1 -p2 replace:q",
+	} {
+		t.Run(queryStr, func(t *testing.T) {
+			query, err := query.ParseAndCheck(queryStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sr := searchResolver{query: query}
+			if _, err := sr.getPatternInfo(); err == nil {
+				t.Error("got err == nil, want a bad request error")
+			}
+		})
+	}
+}
+
 func TestSearchResolver_DynamicFilters(t *testing.T) {
 	repo := &types.Repo{
 		Name: "testRepo",
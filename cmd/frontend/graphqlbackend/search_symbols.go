@@ -110,6 +110,10 @@ func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisio
 	}()
 	span.SetTag("repo", string(repoRevs.Repo.Name))
 
+	if isMultiRevision(repoRevs) {
+		return searchSymbolsInRepoRevisions(ctx, repoRevs, patternInfo, limit)
+	}
+
 	inputRev := repoRevs.RevSpecs()[0]
 	span.SetTag("rev", inputRev)
 	// Do not trigger a repo-updater lookup (e.g.,
@@ -166,6 +170,195 @@ func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisio
 	return fileMatches, err
 }
 
+// maxSymbolSearchRevisions is the maximum number of commits of a repository
+// that a symbol search of several revisions (eg repo:foo@*refs/heads/*)
+// searches.
+const maxSymbolSearchRevisions = 50
+
+// isMultiRevision reports whether repoRevs may refer to several revisions.
+func isMultiRevision(repoRevs *search.RepositoryRevisions) bool {
+	if len(repoRevs.Revs) > 1 {
+		return true
+	}
+	return len(repoRevs.Revs) == 1 && (repoRevs.Revs[0].RefGlob != "" || repoRevs.Revs[0].ExcludeRefGlob != "")
+}
+
+// revisionsCommit is a commit and the revisions (revspecs or ref names) which
+// resolve to it.
+type revisionsCommit struct {
+	commitID  api.CommitID
+	revisions []string
+}
+
+// resolveRevisionsCommits resolves the revspecs and expands the ref globs of
+// repoRevs. It returns the distinct commits, at most
+// maxSymbolSearchRevisions of them.
+func resolveRevisionsCommits(ctx context.Context, repoRevs *search.RepositoryRevisions) ([]*revisionsCommit, error) {
+	var (
+		commits  []*revisionsCommit
+		byCommit = map[api.CommitID]*revisionsCommit{}
+	)
+	add := func(revision string, commitID api.CommitID) {
+		if c, ok := byCommit[commitID]; ok {
+			c.revisions = append(c.revisions, revision)
+			return
+		}
+		c := &revisionsCommit{commitID: commitID, revisions: []string{revision}}
+		byCommit[commitID] = c
+		commits = append(commits, c)
+	}
+
+	hasRefGlobs := false
+	for _, rev := range repoRevs.Revs {
+		if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+			hasRefGlobs = true
+			continue
+		}
+		if strings.HasPrefix(rev.RevSpec, "^") {
+			// Excluding the commits reachable from a revision (as in
+			// type:diff searches) has no meaning for symbols.
+			continue
+		}
+		commitID, err := git.ResolveRevision(ctx, repoRevs.GitserverRepo(), nil, rev.RevSpec, nil)
+		if err != nil {
+			return nil, err
+		}
+		revision := rev.RevSpec
+		if revision == "" {
+			revision = "HEAD"
+		}
+		add(revision, commitID)
+	}
+	if hasRefGlobs {
+		refs, err := git.ListRefs(ctx, repoRevs.GitserverRepo())
+		if err != nil {
+			return nil, err
+		}
+		refNames := make([]string, len(refs))
+		refCommits := make(map[string]api.CommitID, len(refs))
+		for i, ref := range refs {
+			refNames[i] = ref.Name
+			refCommits[ref.Name] = ref.CommitID
+		}
+		names, err := search.ExpandRefGlobs(repoRevs.Revs, refNames)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			add(name, refCommits[name])
+		}
+	}
+
+	if len(commits) > maxSymbolSearchRevisions {
+		commits = commits[:maxSymbolSearchRevisions]
+	}
+	return commits, nil
+}
+
+// searchSymbolsInRepoRevisions searches the revisions of repoRevs for
+// symbols. The symbols of identical files (which have the same Git blob) in
+// several revisions are returned once, with all those revisions.
+func searchSymbolsInRepoRevisions(ctx context.Context, repoRevs *search.RepositoryRevisions, patternInfo *search.PatternInfo, limit int) (res []*fileMatchResolver, err error) {
+	commits, err := resolveRevisionsCommits(ctx, repoRevs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]protocol.Symbol, len(commits))
+	run := parallel.NewRun(10)
+	for i, c := range commits {
+		i, c := i, c
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			symbols, err := backend.Symbols.ListTags(ctx, protocol.SearchArgs{
+				Repo:            repoRevs.Repo.Name,
+				CommitID:        c.commitID,
+				Query:           patternInfo.Pattern,
+				IsCaseSensitive: patternInfo.IsCaseSensitive,
+				IsRegExp:        patternInfo.IsRegExp,
+				IncludePatterns: patternInfo.IncludePatterns,
+				ExcludePattern:  patternInfo.ExcludePattern,
+				First:           limit,
+			})
+			if err != nil {
+				run.Error(err)
+				return
+			}
+			results[i] = symbols
+		})
+	}
+	err = run.Wait()
+
+	merged := mergeRevisionsSymbols(commits, results, limit)
+	fileMatchesByURI := make(map[string]*fileMatchResolver)
+	for _, m := range merged {
+		inputRev := m.commit.revisions[0]
+		baseURI, err := gituri.Parse("git://" + string(repoRevs.Repo.Name) + "?" + url.QueryEscape(inputRev))
+		if err != nil {
+			return nil, err
+		}
+		commit := &gitCommitResolver{
+			repo:     &repositoryResolver{repo: repoRevs.Repo},
+			oid:      gitObjectID(m.commit.commitID),
+			inputRev: &inputRev,
+			// NOTE: Not all fields are set, for performance.
+		}
+		symbolRes := toSymbolResolver(symbolToLSPSymbolInformation(m.symbol, baseURI), strings.ToLower(m.symbol.Language), commit)
+		if symbolRes == nil {
+			continue
+		}
+		symbolRes.revisions = m.revisions
+		uri := makeFileMatchURIFromSymbol(symbolRes, inputRev)
+		if fileMatch, ok := fileMatchesByURI[uri]; ok {
+			fileMatch.symbols = append(fileMatch.symbols, symbolRes)
+		} else {
+			fileMatch := &fileMatchResolver{
+				symbols:  []*symbolResolver{symbolRes},
+				uri:      uri,
+				repo:     repoRevs.Repo,
+				commitID: m.commit.commitID,
+				inputRev: &inputRev,
+			}
+			fileMatchesByURI[uri] = fileMatch
+			res = append(res, fileMatch)
+		}
+	}
+	return res, err
+}
+
+// mergedSymbol is a symbol found in several revisions.
+type mergedSymbol struct {
+	symbol    protocol.Symbol
+	commit    *revisionsCommit // the first commit it was found in
+	revisions []string
+}
+
+// mergeRevisionsSymbols merges the symbols found in each commit (results[i]
+// are the symbols of commits[i]), in the order of the commits. A
+// protocol.Symbol includes the Git blob of its file, so identical symbols are
+// those of identical files. At most limit symbols are returned.
+func mergeRevisionsSymbols(commits []*revisionsCommit, results [][]protocol.Symbol, limit int) []*mergedSymbol {
+	var (
+		merged  []*mergedSymbol
+		indexes = map[protocol.Symbol]int{}
+	)
+	for i, c := range commits {
+		for _, symbol := range results[i] {
+			if j, ok := indexes[symbol]; ok {
+				merged[j].revisions = append(merged[j].revisions, c.revisions...)
+				continue
+			}
+			if len(merged) >= limit {
+				continue
+			}
+			indexes[symbol] = len(merged)
+			merged = append(merged, &mergedSymbol{symbol: symbol, commit: c, revisions: append([]string{}, c.revisions...)})
+		}
+	}
+	return merged
+}
+
 // makeFileMatchURIFromSymbol makes a git://repo?rev#path URI from a symbolResolver to use in a fileMatchResolver
 func makeFileMatchURIFromSymbol(symbolResolver *symbolResolver, inputRev string) string {
 	uri := "git:/" + string(symbolResolver.location.resource.commit.repo.URL())
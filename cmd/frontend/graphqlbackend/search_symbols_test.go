@@ -0,0 +1,48 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+)
+
+func TestMergeRevisionsSymbols(t *testing.T) {
+	commits := []*revisionsCommit{
+		{commitID: "c1", revisions: []string{"refs/heads/master", "refs/tags/v1"}},
+		{commitID: "c2", revisions: []string{"refs/heads/feature"}},
+	}
+	var (
+		a  = protocol.Symbol{Name: "a", Path: "a.go", Blob: "b1"}
+		b  = protocol.Symbol{Name: "b", Path: "a.go", Blob: "b1"}
+		a2 = protocol.Symbol{Name: "a", Path: "a.go", Blob: "b2"} // a.go changed in c2
+		c  = protocol.Symbol{Name: "c", Path: "c.go", Blob: "b3"}
+	)
+	results := [][]protocol.Symbol{{a, b, c}, {a2, c}}
+
+	type symbolRevisions struct {
+		Symbol    protocol.Symbol
+		Commit    string
+		Revisions []string
+	}
+	get := func(limit int) []symbolRevisions {
+		var got []symbolRevisions
+		for _, m := range mergeRevisionsSymbols(commits, results, limit) {
+			got = append(got, symbolRevisions{m.symbol, string(m.commit.commitID), m.revisions})
+		}
+		return got
+	}
+
+	want := []symbolRevisions{
+		{a, "c1", []string{"refs/heads/master", "refs/tags/v1"}},
+		{b, "c1", []string{"refs/heads/master", "refs/tags/v1"}},
+		{c, "c1", []string{"refs/heads/master", "refs/tags/v1", "refs/heads/feature"}},
+		{a2, "c2", []string{"refs/heads/feature"}},
+	}
+	if got := get(10); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := get(3); !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("with limit 3, got %+v, want %+v", got, want[:3])
+	}
+}
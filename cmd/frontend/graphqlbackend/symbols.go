@@ -121,6 +121,10 @@ type symbolResolver struct {
 	symbol   lsp.SymbolInformation
 	language string
 	location *locationResolver
+
+	// revisions is only set for the results of a search of several
+	// revisions of a repository.
+	revisions []string
 }
 
 func (r *symbolResolver) Name() string { return r.symbol.Name }
@@ -140,6 +144,13 @@ func (r *symbolResolver) Language() string { return r.language }
 
 func (r *symbolResolver) Location() *locationResolver { return r.location }
 
+func (r *symbolResolver) Revisions() *[]string {
+	if r.revisions == nil {
+		return nil
+	}
+	return &r.revisions
+}
+
 func (r *symbolResolver) URL(ctx context.Context) string { return r.location.URL(ctx) }
 
 func (r *symbolResolver) CanonicalURL() string { return r.location.CanonicalURL() }
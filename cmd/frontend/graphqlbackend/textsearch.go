@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -23,6 +25,7 @@ import (
 
 	"github.com/google/zoekt"
 	zoektquery "github.com/google/zoekt/query"
+	"github.com/sourcegraph/go-lsp"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -56,6 +59,7 @@ type fileMatchResolver struct {
 	JPath        string       `json:"Path"`
 	JLineMatches []*lineMatch `json:"LineMatches"`
 	JLimitHit    bool         `json:"LimitHit"`
+	JDiff        string       `json:"Diff"` // only set if the search has a replacement
 	symbols      []*symbolResolver
 	uri          string
 	repo         *types.Repo
@@ -108,6 +112,10 @@ type lineMatch struct {
 	JOffsetAndLengths [][2]int32 `json:"OffsetAndLengths"`
 	JLineNumber       int32      `json:"LineNumber"`
 	JLimitHit         bool       `json:"LimitHit"`
+
+	// JRanges is only set for multiline searches. Otherwise the ranges are
+	// derived from JOffsetAndLengths.
+	JRanges []lsp.Range `json:"Ranges,omitempty"`
 }
 
 func (lm *lineMatch) Preview() string {
@@ -130,9 +138,29 @@ func (lm *lineMatch) LimitHit() bool {
 	return lm.JLimitHit
 }
 
-// textSearch searches repo@commit with p.
-// Note: the returned matches do not set fileMatch.uri
-func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+func (lm *lineMatch) Ranges() []*rangeResolver {
+	if len(lm.JRanges) > 0 {
+		r := make([]*rangeResolver, len(lm.JRanges))
+		for i := range lm.JRanges {
+			r[i] = &rangeResolver{lm.JRanges[i]}
+		}
+		return r
+	}
+	r := make([]*rangeResolver, len(lm.JOffsetAndLengths))
+	for i, ol := range lm.JOffsetAndLengths {
+		line := int(lm.JLineNumber)
+		r[i] = &rangeResolver{lsp.Range{
+			Start: lsp.Position{Line: line, Character: int(ol[0])},
+			End:   lsp.Position{Line: line, Character: int(ol[0] + ol[1])},
+		}}
+	}
+	return r
+}
+
+// textSearch searches repo@commit with p, calling onMatch with each match as
+// soon as it is received from searcher.
+// Note: the matches do not set fileMatch.uri
+func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.PatternInfo, fetchTimeout time.Duration, onMatch func(*fileMatchResolver)) (limitHit bool, err error) {
 	tr, ctx := trace.New(ctx, "searcher.client", fmt.Sprintf("%s@%s", repo.Name, commit))
 	defer func() {
 		tr.SetError(err)
@@ -164,7 +192,7 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 	if deadline, ok := ctx.Deadline(); ok {
 		t, err := deadline.MarshalText()
 		if err != nil {
-			return nil, false, err
+			return false, err
 		}
 		q.Set("Deadline", string(t))
 	}
@@ -172,6 +200,23 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 	if p.IsRegExp {
 		q.Set("IsRegExp", "true")
 	}
+	if p.IsStructural {
+		q.Set("IsStructural", "true")
+	}
+	if p.IsMultiline {
+		q.Set("IsMultiline", "true")
+	}
+	if p.PatternExpr != nil {
+		expr, err := json.Marshal(p.PatternExpr)
+		if err != nil {
+			return false, err
+		}
+		q.Set("PatternExpr", string(expr))
+	}
+	if p.Replace {
+		q.Set("Replace", "true")
+		q.Set("Replacement", p.Replacement)
+	}
 	if p.IsWordMatch {
 		q.Set("IsWordMatch", "true")
 	}
@@ -188,6 +233,10 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 	// these fields from old frontends that do not (and provide a default in the latter case).
 	q.Set("PatternMatchesContent", strconv.FormatBool(p.PatternMatchesContent))
 	q.Set("PatternMatchesPath", strconv.FormatBool(p.PatternMatchesPath))
+	// Ask searcher to stream matches so that we can pass them on as soon as
+	// they are found, and keep the ones found so far if our deadline is hit
+	// before searcher is done.
+	q.Set("Stream", "true")
 	rawQuery := q.Encode()
 
 	// Searcher caches the file contents for repo@commit since it is
@@ -201,13 +250,18 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 		excludedSearchURLs = map[string]bool{}
 		attempt            = 0
 		maxAttempts        = 2
+		sent               = false // whether any match was passed to onMatch
 	)
+	send := func(fm *fileMatchResolver) {
+		sent = true
+		onMatch(fm)
+	}
 	for {
 		attempt++
 
 		searcherURL, err := Search().SearcherURLs.Get(consistentHashKey, excludedSearchURLs)
 		if err != nil {
-			return nil, false, err
+			return false, err
 		}
 
 		// Fallback to a bad host if nothing is left
@@ -215,28 +269,30 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 			tr.LazyPrintf("failed to find endpoint, trying again without excludes")
 			searcherURL, err = Search().SearcherURLs.Get(consistentHashKey, nil)
 			if err != nil {
-				return nil, false, err
+				return false, err
 			}
 		}
 
 		url := searcherURL + "?" + rawQuery
 		tr.LazyPrintf("attempt %d: %s", attempt, url)
-		matches, limitHit, err = textSearchURL(ctx, url)
+		limitHit, err = textSearchURL(ctx, url, send)
 		// Useful trace for debugging:
 		//
-		// tr.LazyPrintf("%d matches, limitHit=%v, err=%v, ctx.Err()=%v", len(matches), limitHit, err, ctx.Err())
+		// tr.LazyPrintf("sent=%v, limitHit=%v, err=%v, ctx.Err()=%v", sent, limitHit, err, ctx.Err())
 		if err == nil || errcode.IsTimeout(err) {
-			return matches, limitHit, err
+			return limitHit, err
 		}
 
 		// If we are canceled, return that error.
 		if err := ctx.Err(); err != nil {
-			return nil, false, err
+			return false, err
 		}
 
-		// If not temporary or our last attempt then don't try again.
-		if !errcode.IsTemporary(err) || attempt == maxAttempts {
-			return nil, false, err
+		// If not temporary or our last attempt then don't try again. Matches
+		// that were already passed on can't be taken back, so we don't try
+		// again either if the search failed after some were sent.
+		if !errcode.IsTemporary(err) || attempt == maxAttempts || sent {
+			return false, err
 		}
 
 		tr.LazyPrintf("transient error %s", err.Error())
@@ -245,10 +301,10 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 	}
 }
 
-func textSearchURL(ctx context.Context, url string) ([]*fileMatchResolver, bool, error) {
+func textSearchURL(ctx context.Context, url string, onMatch func(*fileMatchResolver)) (bool, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, false, err
+		return false, err
 	}
 	req = req.WithContext(ctx)
 
@@ -259,7 +315,7 @@ func textSearchURL(ctx context.Context, url string) ([]*fileMatchResolver, bool,
 
 	// Limit number of outstanding searcher requests
 	if err := textSearchLimiter.Acquire(ctx); err != nil {
-		return nil, false, err
+		return false, err
 	}
 	defer textSearchLimiter.Release()
 
@@ -270,30 +326,78 @@ func textSearchURL(ctx context.Context, url string) ([]*fileMatchResolver, bool,
 		if ctx.Err() != nil {
 			err = ctx.Err()
 		}
-		return nil, false, errors.Wrap(err, "searcher request failed")
+		return false, errors.Wrap(err, "searcher request failed")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, false, err
+			return false, err
+		}
+		return false, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
+	}
+
+	// BACKCOMPAT: searchers which do not support streaming ignore the
+	// Stream parameter and respond with a single JSON object.
+	if resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		r := struct {
+			Matches     []*fileMatchResolver
+			LimitHit    bool
+			DeadlineHit bool
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&r)
+		if err != nil {
+			return false, errors.Wrap(err, "searcher response invalid")
 		}
-		return nil, false, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
+		for _, fm := range r.Matches {
+			onMatch(fm)
+		}
+		if r.DeadlineHit {
+			err = context.DeadlineExceeded
+		}
+		return r.LimitHit, err
 	}
 
-	r := struct {
-		Matches     []*fileMatchResolver
-		LimitHit    bool
-		DeadlineHit bool
-	}{}
-	err = json.NewDecoder(resp.Body).Decode(&r)
-	if err != nil {
-		return nil, false, errors.Wrap(err, "searcher response invalid")
-	}
-	if r.DeadlineHit {
-		err = context.DeadlineExceeded
+	return decodeSearcherStream(ctx, resp.Body, onMatch)
+}
+
+// decodeSearcherStream reads the newline-delimited JSON events streamed by
+// searcher, calling onMatch with each match as soon as it is decoded. If the
+// stream is interrupted by our context being done, the context's error is
+// returned (the matches received so far were already passed to onMatch).
+func decodeSearcherStream(ctx context.Context, r io.Reader, onMatch func(*fileMatchResolver)) (limitHit bool, err error) {
+	dec := json.NewDecoder(r)
+	for {
+		var ev struct {
+			Match       *fileMatchResolver
+			Done        bool
+			LimitHit    bool
+			DeadlineHit bool
+			Error       string
+		}
+		if err := dec.Decode(&ev); err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return false, errors.Wrap(err, "searcher response invalid")
+		}
+		if ev.Match != nil {
+			onMatch(ev.Match)
+		}
+		if !ev.Done {
+			continue
+		}
+		if ev.Error != "" {
+			return false, errors.New(ev.Error)
+		}
+		if ev.DeadlineHit {
+			err = context.DeadlineExceeded
+		}
+		return ev.LimitHit, err
 	}
-	return r.Matches, r.LimitHit, err
 }
 
 type searcherError struct {
@@ -315,9 +419,15 @@ func (e *searcherError) Error() string {
 
 var mockSearchFilesInRepo func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error)
 
-func searchFilesInRepo(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+// searchFilesInRepo searches repo@rev with info, calling onMatch with each
+// match as soon as it is found.
+func searchFilesInRepo(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.PatternInfo, fetchTimeout time.Duration, onMatch func(*fileMatchResolver)) (limitHit bool, err error) {
 	if mockSearchFilesInRepo != nil {
-		return mockSearchFilesInRepo(ctx, repo, gitserverRepo, rev, info, fetchTimeout)
+		matches, limitHit, err := mockSearchFilesInRepo(ctx, repo, gitserverRepo, rev, info, fetchTimeout)
+		for _, fm := range matches {
+			onMatch(fm)
+		}
+		return limitHit, err
 	}
 
 	// Do not trigger a repo-updater lookup (e.g.,
@@ -326,20 +436,17 @@ func searchFilesInRepo(ctx context.Context, repo *types.Repo, gitserverRepo gits
 	// repo is not on gitserver.
 	commit, err := git.ResolveRevision(ctx, gitserverRepo, nil, rev, &git.ResolveRevisionOptions{NoEnsureRevision: true})
 	if err != nil {
-		return nil, false, err
+		return false, err
 	}
 
-	matches, limitHit, err = textSearch(ctx, gitserverRepo, commit, info, fetchTimeout)
-
 	workspace := fileMatchURI(repo.Name, rev, "")
-	for _, fm := range matches {
+	return textSearch(ctx, gitserverRepo, commit, info, fetchTimeout, func(fm *fileMatchResolver) {
 		fm.uri = workspace + fm.JPath
 		fm.repo = repo
 		fm.commitID = commit
 		fm.inputRev = &rev
-	}
-
-	return matches, limitHit, err
+		onMatch(fm)
+	})
 }
 
 func fileMatchURI(name api.RepoName, ref, path string) string {
@@ -579,13 +686,39 @@ func queryToZoektQuery(query *search.PatternInfo) (zoektquery.Q, error) {
 		return parseRe(pattern, true)
 	}
 
-	if query.IsRegExp {
-		q, err := parseRe(query.Pattern, false)
+	if query.PatternExpr != nil {
+		// Files must match the boolean expression of content patterns.
+		var exprToQ func(e *search.PatternExpr) (zoektquery.Q, error)
+		exprToQ = func(e *search.PatternExpr) (zoektquery.Q, error) {
+			switch {
+			case e.Not != nil:
+				child, err := exprToQ(e.Not)
+				if err != nil {
+					return nil, err
+				}
+				return &zoektquery.Not{Child: child}, nil
+			case len(e.And) > 0 || len(e.Or) > 0:
+				var children []zoektquery.Q
+				for _, operand := range e.Operands() {
+					child, err := exprToQ(operand)
+					if err != nil {
+						return nil, err
+					}
+					children = append(children, child)
+				}
+				if len(e.And) > 0 {
+					return zoektquery.NewAnd(children...), nil
+				}
+				return zoektquery.NewOr(children...), nil
+			}
+			return parseRe(e.Pattern, false)
+		}
+		q, err := exprToQ(query.PatternExpr)
 		if err != nil {
 			return nil, err
 		}
 		and = append(and, q)
-	} else {
+	} else if query.IsRegExp {
 		and = append(and, &zoektquery.Substring{
 			Pattern:       query.Pattern,
 			CaseSensitive: query.IsCaseSensitive,
@@ -666,6 +799,22 @@ func zoektIndexedRepos(ctx context.Context, repos []*search.RepositoryRevisions)
 	return indexed, unindexed, nil
 }
 
+// searcherOnlyFeature returns a description of the feature used by p which
+// indexed search does not support, or "" if p uses none.
+func searcherOnlyFeature(p *search.PatternInfo) string {
+	switch {
+	case p.IsStructural:
+		return query.FieldPatternType + ":" + query.PatternTypeStructural
+	case p.IsMultiline:
+		// Indexed search reports matches line by line.
+		return query.FieldMultiline + ":yes"
+	case p.Replace:
+		// Replacement diffs are computed by searcher.
+		return query.FieldReplace + ":"
+	}
+	return ""
+}
+
 var mockSearchFilesInRepos func(args *search.Args) ([]*fileMatchResolver, *searchResultsCommon, error)
 
 // searchFilesInRepos searches a set of repos for a pattern.
@@ -705,7 +854,15 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 
 	// Support index:yes (default), index:only, and index:no in search query.
 	index, _ := args.Query.StringValues(query.FieldIndex)
-	if len(index) > 0 {
+	if feature := searcherOnlyFeature(args.Pattern); feature != "" {
+		// Indexed search does not support the pattern, so all repositories
+		// are searched by searcher.
+		if len(index) > 0 && parseYesNoOnly(index[len(index)-1]) == Only {
+			return nil, common, fmt.Errorf("invalid index:%q (%s is not supported by indexed search)", index[len(index)-1], feature)
+		}
+		searcherRepos = append(searcherRepos, zoektRepos...)
+		zoektRepos = nil
+	} else if len(index) > 0 {
 		index := index[len(index)-1]
 		switch parseYesNoOnly(index) {
 		case Yes, True:
@@ -737,7 +894,8 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 		mu                sync.Mutex
 		unflattened       [][]*fileMatchResolver
 		flattenedSize     int
-		overLimitCanceled bool // canceled because we were over the limit
+		overLimitCanceled bool  // canceled because we were over the limit
+		streamedSize      int32 // matches received from searcher so far, accessed atomically
 	)
 
 	// addMatches assumes the caller holds mu.
@@ -791,12 +949,34 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 		go func(repoRev search.RepositoryRevisions) {
 			defer wg.Done()
 			rev := repoRev.RevSpecs()[0] // TODO(sqs): search multiple revs
-			matches, repoLimitHit, searchErr := searchFilesInRepo(ctx, repoRev.Repo, repoRev.GitserverRepo(), rev, args.Pattern, fetchTimeout)
+			var matches []*fileMatchResolver
+			repoLimitHit, searchErr := searchFilesInRepo(ctx, repoRev.Repo, repoRev.GitserverRepo(), rev, args.Pattern, fetchTimeout, func(fm *fileMatchResolver) {
+				matches = append(matches, fm)
+				// Stop searching as soon as enough matches were received from
+				// all repositories, instead of waiting for the searches of
+				// the repositories that have many matches to finish.
+				if atomic.AddInt32(&streamedSize, 1) > args.Pattern.FileMatchLimit {
+					mu.Lock()
+					if !overLimitCanceled {
+						tr.LazyPrintf("cancel due to streamed result size: %d > %d", atomic.LoadInt32(&streamedSize), args.Pattern.FileMatchLimit)
+						overLimitCanceled = true
+						common.limitHit = true
+						cancel()
+					}
+					mu.Unlock()
+				}
+			})
 			if searchErr != nil {
 				tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.String("searchErr", searchErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)))
 			}
 			mu.Lock()
 			defer mu.Unlock()
+			if searchErr != nil && overLimitCanceled && ctx.Err() == context.Canceled {
+				// We canceled the search because enough matches were found in
+				// total, so keep the matches received so far.
+				repoLimitHit = true
+				searchErr = nil
+			}
 			if ctx.Err() == nil {
 				common.searched = append(common.searched, repoRev.Repo)
 			}
@@ -2,8 +2,11 @@ package graphqlbackend
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -90,6 +93,20 @@ func TestQueryToZoektQuery(t *testing.T) {
 			},
 			Query: `foo case:yes f:\.go$ f:\.yaml$ -f:\bvendor\b`,
 		},
+		{
+			Name: "expr",
+			Pattern: &search.PatternInfo{
+				IsRegExp: true,
+				Pattern:  "foo|bar",
+				PatternExpr: &search.PatternExpr{And: []*search.PatternExpr{
+					{Or: []*search.PatternExpr{{Pattern: "foo"}, {Pattern: "bar"}}},
+					{Not: &search.PatternExpr{Pattern: "ba.z"}},
+				}},
+				IncludePatterns:        []string{`\.go$`},
+				PathPatternsAreRegExps: true,
+			},
+			Query: `(foo or bar) -ba.z case:no f:\.go$`,
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
@@ -205,6 +222,147 @@ func TestSearchFilesInRepos(t *testing.T) {
 	}
 }
 
+func TestSearchFilesInRepos_overLimit(t *testing.T) {
+	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+		repoName := repo.Name
+		fm := func(path string) *fileMatchResolver {
+			return &fileMatchResolver{uri: "git://" + string(repoName) + "?" + rev + "#" + path}
+		}
+		switch repoName {
+		case "foo/many":
+			return []*fileMatchResolver{fm("a.go"), fm("b.go"), fm("c.go")}, false, nil
+		case "foo/slow":
+			// Still searching when the matches of foo/many exceed the limit.
+			<-ctx.Done()
+			return []*fileMatchResolver{fm("a.go")}, false, ctx.Err()
+		default:
+			return nil, false, errors.New("Unexpected repo")
+		}
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	q, err := query.ParseAndCheck("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &search.Args{
+		Pattern: &search.PatternInfo{
+			FileMatchLimit: 2,
+			Pattern:        "foo",
+		},
+		Repos: makeRepositoryRevisions("foo/many", "foo/slow"),
+		Query: q,
+	}
+	results, common, err := searchFilesInRepos(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected two results, got %d", len(results))
+	}
+	if !common.limitHit {
+		t.Error("expected limitHit")
+	}
+	if _, ok := common.partial["foo/slow"]; !ok {
+		t.Errorf("expected foo/slow to be partially searched, got partial %v", common.partial)
+	}
+}
+
+func TestDecodeSearcherStream(t *testing.T) {
+	cases := []struct {
+		Name         string
+		Body         string
+		WantPaths    []string
+		WantLimitHit bool
+		WantErr      string
+	}{
+		{
+			Name:      "empty",
+			Body:      `{"Done":true}`,
+			WantPaths: nil,
+		},
+		{
+			Name: "matches",
+			Body: `{"Match":{"Path":"a.go"}}
+{"Match":{"Path":"b.go"}}
+{"Done":true,"LimitHit":true}
+`,
+			WantPaths:    []string{"a.go", "b.go"},
+			WantLimitHit: true,
+		},
+		{
+			Name: "deadline",
+			Body: `{"Match":{"Path":"a.go"}}
+{"Done":true,"DeadlineHit":true}
+`,
+			WantPaths: []string{"a.go"},
+			WantErr:   context.DeadlineExceeded.Error(),
+		},
+		{
+			Name: "error",
+			Body: `{"Match":{"Path":"a.go"}}
+{"Done":true,"Error":"boom"}
+`,
+			WantPaths: []string{"a.go"},
+			WantErr:   "boom",
+		},
+		{
+			Name:      "truncated",
+			Body:      `{"Match":{"Path":"a.go"}}`,
+			WantPaths: []string{"a.go"},
+			WantErr:   "searcher response invalid: unexpected EOF",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var paths []string
+			limitHit, err := decodeSearcherStream(context.Background(), strings.NewReader(tc.Body), func(fm *fileMatchResolver) {
+				paths = append(paths, fm.JPath)
+			})
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tc.WantErr {
+				t.Errorf("got err %q, want %q", gotErr, tc.WantErr)
+			}
+			if !reflect.DeepEqual(paths, tc.WantPaths) {
+				t.Errorf("got paths %v, want %v", paths, tc.WantPaths)
+			}
+			if limitHit != tc.WantLimitHit {
+				t.Errorf("got limitHit %v, want %v", limitHit, tc.WantLimitHit)
+			}
+		})
+	}
+}
+
+func TestLineMatch_Ranges(t *testing.T) {
+	format := func(lm *lineMatch) string {
+		var ranges []string
+		for _, r := range lm.Ranges() {
+			ranges = append(ranges, fmt.Sprintf("%d:%d-%d:%d", r.Start().Line(), r.Start().Character(), r.End().Line(), r.End().Character()))
+		}
+		return strings.Join(ranges, " ")
+	}
+
+	var lm lineMatch
+	if err := json.Unmarshal([]byte(`{"Preview":"a b","LineNumber":3,"OffsetAndLengths":[[0,1],[2,1]]}`), &lm); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := format(&lm), "3:0-3:1 3:2-3:3"; got != want {
+		t.Errorf("got ranges %q, want %q", got, want)
+	}
+
+	// Multiline matches from searcher.
+	lm = lineMatch{}
+	if err := json.Unmarshal([]byte(`{"Preview":"a(\nb)","LineNumber":3,"OffsetAndLengths":[[1,1]],"Ranges":[{"Start":{"Line":3,"Character":1},"End":{"Line":4,"Character":2}}]}`), &lm); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := format(&lm), "3:1-4:2"; got != want {
+		t.Errorf("got ranges %q, want %q", got, want)
+	}
+}
+
 func makeRepositoryRevisions(repos ...string) []*search.RepositoryRevisions {
 	r := make([]*search.RepositoryRevisions, len(repos))
 	for i, repospec := range repos {
@@ -0,0 +1,71 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+)
+
+// userPermissionsByProvider returns the synced repository permissions of the user for each
+// configured authz provider (nil for the providers which have not synced them yet).
+func (r *UserResolver) userPermissionsByProvider(ctx context.Context) ([]authz.Provider, []*db.UserPermissions, error) {
+	// 🚨 SECURITY: Only site admins can view the status of the user's permissions syncs.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	perms, err := db.UserPermissions.ListByUser(ctx, r.user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, authzProviders := authz.GetProviders()
+	byProvider := make([]*db.UserPermissions, len(authzProviders))
+	for i, p := range authzProviders {
+		for _, perm := range perms {
+			if perm.Perm == authz.Read && perm.ServiceType == p.ServiceType() && perm.ServiceID == p.ServiceID() {
+				byProvider[i] = perm
+				break
+			}
+		}
+	}
+	return authzProviders, byProvider, nil
+}
+
+func (r *UserResolver) PermissionsSyncedAt(ctx context.Context) (*string, error) {
+	authzProviders, perms, err := r.userPermissionsByProvider(ctx)
+	if err != nil || len(authzProviders) == 0 {
+		return nil, err
+	}
+
+	// The permissions are as old as the least recently synced ones.
+	var syncedAt time.Time
+	for _, perm := range perms {
+		if perm == nil || perm.SyncedAt == nil {
+			return nil, nil
+		}
+		if syncedAt.IsZero() || perm.SyncedAt.Before(syncedAt) {
+			syncedAt = *perm.SyncedAt
+		}
+	}
+	t := syncedAt.Format(time.RFC3339)
+	return &t, nil
+}
+
+func (r *UserResolver) PermissionsSyncErrors(ctx context.Context) ([]string, error) {
+	authzProviders, perms, err := r.userPermissionsByProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	syncErrors := []string{}
+	for i, perm := range perms {
+		if perm != nil && perm.SyncError != "" {
+			syncErrors = append(syncErrors, fmt.Sprintf("%s: %s", authzProviders[i].ServiceID(), perm.SyncError))
+		}
+	}
+	return syncErrors, nil
+}
@@ -39,6 +39,8 @@ func NewHandler(m *mux.Router) http.Handler {
 
 	m.Get(apirouter.Telemetry).Handler(trace.TraceRoute(telemetryHandler))
 
+	m.Get(apirouter.Webhooks).Handler(trace.TraceRoute(handler(serveWebhook)))
+
 	if envvar.SourcegraphDotComMode() {
 		m.Path("/updates").Methods("GET").Name("updatecheck").Handler(trace.TraceRoute(http.HandlerFunc(updatecheck.Handler)))
 	}
@@ -70,8 +72,12 @@ func NewInternalHandler(m *mux.Router) http.Handler {
 	m.Get(apirouter.ExternalServiceConfigs).Handler(trace.TraceRoute(handler(serveExternalServiceConfigs)))
 	m.Get(apirouter.ExternalServicesList).Handler(trace.TraceRoute(handler(serveExternalServicesList)))
 	m.Get(apirouter.PhabricatorRepoCreate).Handler(trace.TraceRoute(handler(servePhabricatorRepoCreate)))
+	m.Get(apirouter.PermissionsSyncUser).Handler(trace.TraceRoute(handler(servePermissionsSyncUser)))
+	m.Get(apirouter.PermissionsUsersToSync).Handler(trace.TraceRoute(handler(servePermissionsUsersToSync)))
 	m.Get(apirouter.ReposCreateIfNotExists).Handler(trace.TraceRoute(handler(serveReposCreateIfNotExists)))
 	m.Get(apirouter.ReposUpdateMetadata).Handler(trace.TraceRoute(handler(serveReposUpdateMetadata)))
+	m.Get(apirouter.ReposScheduleList).Handler(trace.TraceRoute(handler(serveReposUpdateScheduleList)))
+	m.Get(apirouter.ReposScheduleSave).Handler(trace.TraceRoute(handler(serveReposUpdateScheduleSave)))
 	m.Get(apirouter.ReposInventory).Handler(trace.TraceRoute(handler(serveReposInventory)))
 	m.Get(apirouter.ReposInventoryUncached).Handler(trace.TraceRoute(handler(serveReposInventoryUncached)))
 	m.Get(apirouter.ReposList).Handler(trace.TraceRoute(handler(serveReposList)))
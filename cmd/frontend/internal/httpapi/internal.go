@@ -12,6 +12,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
@@ -100,6 +101,54 @@ func servePhabricatorRepoCreate(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func servePermissionsSyncUser(w http.ResponseWriter, r *http.Request) error {
+	var userID int32
+	if err := json.NewDecoder(r.Body).Decode(&userID); err != nil {
+		return err
+	}
+	return backend.UserPermissions.Sync(r.Context(), userID)
+}
+
+// servePermissionsUsersToSync serves the IDs of the users whose repository permissions should be
+// synced next by the background permissions syncer.
+func servePermissionsUsersToSync(w http.ResponseWriter, r *http.Request) error {
+	var req api.PermissionsUsersToSyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+	_, authzProviders := authz.GetProviders()
+	userIDs, err := db.UserPermissions.ListUsersToSync(r.Context(), authzProviders, req.SyncedBefore, req.Limit)
+	if err != nil {
+		return err
+	}
+	if userIDs == nil {
+		userIDs = []int32{}
+	}
+	return json.NewEncoder(w).Encode(userIDs)
+}
+
+// serveReposUpdateScheduleList serves the persisted state of the repo-updater update scheduler.
+func serveReposUpdateScheduleList(w http.ResponseWriter, r *http.Request) error {
+	states, err := db.RepoUpdateSchedule.List(r.Context())
+	if err != nil {
+		return err
+	}
+	if states == nil {
+		states = []*api.RepoUpdateScheduleState{}
+	}
+	return json.NewEncoder(w).Encode(states)
+}
+
+// serveReposUpdateScheduleSave persists the state of the repo-updater update scheduler for the
+// repositories in the request.
+func serveReposUpdateScheduleSave(w http.ResponseWriter, r *http.Request) error {
+	var states []*api.RepoUpdateScheduleState
+	if err := json.NewDecoder(r.Body).Decode(&states); err != nil {
+		return err
+	}
+	return db.RepoUpdateSchedule.Upsert(r.Context(), states)
+}
+
 // serveExternalServiceConfigs serves a JSON response that is an array of all
 // external service configs that match the requested kind.
 func serveExternalServiceConfigs(w http.ResponseWriter, r *http.Request) error {
@@ -0,0 +1,215 @@
+package httpapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/jsonc"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+	"github.com/sourcegraph/sourcegraph/schema"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// maxWebhookPayloadSize is the maximum size of the webhook request bodies that are read. Push
+// event payloads list the pushed commits, but code hosts truncate the list long before this size.
+const maxWebhookPayloadSize = 25 << 20
+
+// serveWebhook handles the webhook requests that code hosts send for the external service with the
+// ID in the URL. Verified push events enqueue an update of the pushed repository, so that it is
+// updated without waiting for its next scheduled update.
+//
+// 🚨 SECURITY: Webhook requests are anonymous. They must only be acted on after being verified with
+// the webhook secret of the external service.
+func serveWebhook(w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(mux.Vars(r)["ExternalServiceID"], 10, 64)
+	if err != nil {
+		return &errcode.HTTPErr{Status: http.StatusNotFound, Err: err}
+	}
+
+	// The webhook secret and the repository are read as an internal actor, because the request
+	// has no authenticated actor.
+	ctx := actor.WithActor(r.Context(), &actor.Actor{Internal: true})
+
+	svc, err := db.ExternalServices.GetByID(ctx, id)
+	if err != nil {
+		return &errcode.HTTPErr{Status: http.StatusNotFound, Err: err}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		return err
+	}
+
+	var repoName api.RepoName
+	switch svc.Kind {
+	case "GITHUB":
+		var c schema.GitHubConnection
+		if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+			return err
+		}
+		repoName, err = gitHubWebhookRepoName(&c, r.Header, body)
+	case "GITLAB":
+		var c schema.GitLabConnection
+		if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+			return err
+		}
+		repoName, err = gitLabWebhookRepoName(&c, r.Header, body)
+	case "BITBUCKETSERVER":
+		var c schema.BitbucketServerConnection
+		if err := jsonc.Unmarshal(svc.Config, &c); err != nil {
+			return err
+		}
+		repoName, err = bitbucketServerWebhookRepoName(&c, r.Header, body)
+	default:
+		return &errcode.HTTPErr{Status: http.StatusNotFound, Err: fmt.Errorf("webhooks are not supported for external services of kind %s", svc.Kind)}
+	}
+	if err != nil {
+		return err
+	}
+	if repoName == "" {
+		return nil // not a push event (such as a ping)
+	}
+
+	repo, err := db.Repos.GetByName(ctx, repoName)
+	if errcode.IsNotFound(err) {
+		log15.Debug("Ignoring webhook push event of unknown repository.", "externalService", id, "repo", repoName)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return repoupdater.DefaultClient.EnqueueRepoUpdate(ctx, gitserver.Repo{Name: repo.Name})
+}
+
+var errWebhookSecretNotConfigured = &errcode.HTTPErr{
+	Status: http.StatusUnauthorized,
+	Err:    errors.New("webhookSecret is not configured for this external service"),
+}
+
+var errInvalidWebhookSignature = &errcode.HTTPErr{
+	Status: http.StatusUnauthorized,
+	Err:    errors.New("invalid webhook signature"),
+}
+
+// verifyHubSignature verifies the X-Hub-Signature header of a webhook request (such as
+// "sha1=<hex HMAC of the body>"), which GitHub and Bitbucket Server send to sign their requests.
+func verifyHubSignature(header http.Header, body []byte, secret, algorithm string, newHash func() hash.Hash) error {
+	if secret == "" {
+		return errWebhookSecretNotConfigured
+	}
+	got := header.Get("X-Hub-Signature")
+	if !strings.HasPrefix(got, algorithm+"=") {
+		return errInvalidWebhookSignature
+	}
+	gotMAC, err := hex.DecodeString(strings.TrimPrefix(got, algorithm+"="))
+	if err != nil {
+		return errInvalidWebhookSignature
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(gotMAC, mac.Sum(nil)) {
+		return errInvalidWebhookSignature
+	}
+	return nil
+}
+
+// gitHubWebhookRepoName verifies a GitHub webhook request and returns the name of the repository
+// of its push event (or "" if it is another event).
+func gitHubWebhookRepoName(c *schema.GitHubConnection, header http.Header, body []byte) (api.RepoName, error) {
+	if err := verifyHubSignature(header, body, c.WebhookSecret, "sha1", sha1.New); err != nil {
+		return "", err
+	}
+	if header.Get("X-GitHub-Event") != "push" {
+		return "", nil
+	}
+
+	var payload struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+	}
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return "", err
+	}
+	return reposource.GitHubRepoName(c.RepositoryPathPattern, baseURL.Hostname(), payload.Repository.FullName), nil
+}
+
+// gitLabWebhookRepoName verifies a GitLab webhook request and returns the name of the repository
+// of its push event (or "" if it is another event).
+func gitLabWebhookRepoName(c *schema.GitLabConnection, header http.Header, body []byte) (api.RepoName, error) {
+	if c.WebhookSecret == "" {
+		return "", errWebhookSecretNotConfigured
+	}
+	// GitLab sends the secret itself, not a signature.
+	if subtle.ConstantTimeCompare([]byte(header.Get("X-Gitlab-Token")), []byte(c.WebhookSecret)) != 1 {
+		return "", errInvalidWebhookSignature
+	}
+	if event := header.Get("X-Gitlab-Event"); event != "Push Hook" && event != "Tag Push Hook" {
+		return "", nil
+	}
+
+	var payload struct {
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+	}
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return "", err
+	}
+	return reposource.GitLabRepoName(c.RepositoryPathPattern, baseURL.Hostname(), payload.Project.PathWithNamespace), nil
+}
+
+// bitbucketServerWebhookRepoName verifies a Bitbucket Server webhook request and returns the name
+// of the repository of its push event (or "" if it is another event).
+func bitbucketServerWebhookRepoName(c *schema.BitbucketServerConnection, header http.Header, body []byte) (api.RepoName, error) {
+	if err := verifyHubSignature(header, body, c.WebhookSecret, "sha256", sha256.New); err != nil {
+		return "", err
+	}
+	if header.Get("X-Event-Key") != "repo:refs_changed" {
+		return "", nil
+	}
+
+	var payload struct {
+		Repository struct {
+			Slug    string `json:"slug"`
+			Project struct {
+				Key string `json:"key"`
+			} `json:"project"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+	}
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return "", err
+	}
+	return reposource.BitbucketServerRepoName(c.RepositoryPathPattern, baseURL.Hostname(), payload.Repository.Project.Key, payload.Repository.Slug), nil
+}
@@ -0,0 +1,191 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+)
+
+func TestWebhook(t *testing.T) {
+	c := newTest()
+
+	externalServices := map[int64]*types.ExternalService{
+		1: {ID: 1, Kind: "GITHUB", Config: `{"url": "https://github.com", "token": "t", "webhookSecret": "s3cr3t"}`},
+		2: {ID: 2, Kind: "GITLAB", Config: `{"url": "https://gitlab.example.com", "token": "t", "webhookSecret": "s3cr3t"}`},
+		3: {ID: 3, Kind: "BITBUCKETSERVER", Config: `{"url": "https://bitbucket.example.com", "token": "t", "webhookSecret": "s3cr3t"}`},
+		4: {ID: 4, Kind: "GITHUB", Config: `{"url": "https://github.com", "token": "t"}`},
+		5: {ID: 5, Kind: "GITOLITE", Config: `{}`},
+	}
+	db.Mocks.ExternalServices.GetByID = func(id int64) (*types.ExternalService, error) {
+		if svc, ok := externalServices[id]; ok {
+			return svc, nil
+		}
+		return nil, fmt.Errorf("external service not found: id=%d", id)
+	}
+	db.Mocks.Repos.GetByName = func(ctx context.Context, name api.RepoName) (*types.Repo, error) {
+		switch name {
+		case "github.com/foo/bar", "gitlab.example.com/foo/bar", "bitbucket.example.com/FOO/bar":
+			return &types.Repo{ID: 1, Name: name}, nil
+		default:
+			return nil, &errcode.Mock{IsNotFound: true}
+		}
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	var enqueued []api.RepoName
+	repoupdater.MockEnqueueRepoUpdate = func(ctx context.Context, repo gitserver.Repo) error {
+		enqueued = append(enqueued, repo.Name)
+		return nil
+	}
+	defer func() { repoupdater.MockEnqueueRepoUpdate = nil }()
+
+	hubSignature := func(algorithm string, newHash func() hash.Hash, body string) string {
+		mac := hmac.New(newHash, []byte("s3cr3t"))
+		mac.Write([]byte(body))
+		return algorithm + "=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	const (
+		gitHubPush          = `{"ref": "refs/heads/master", "repository": {"full_name": "foo/bar"}}`
+		gitHubPushUnknown   = `{"ref": "refs/heads/master", "repository": {"full_name": "foo/baz"}}`
+		gitLabPush          = `{"ref": "refs/heads/master", "project": {"path_with_namespace": "foo/bar"}}`
+		bitbucketServerPush = `{"repository": {"slug": "bar", "project": {"key": "FOO"}}}`
+	)
+
+	tests := []struct {
+		name         string
+		externalSvc  int
+		header       map[string]string
+		body         string
+		wantStatus   int
+		wantEnqueued api.RepoName
+	}{
+		{
+			name:         "GitHub push",
+			externalSvc:  1,
+			header:       map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature": hubSignature("sha1", sha1.New, gitHubPush)},
+			body:         gitHubPush,
+			wantStatus:   http.StatusOK,
+			wantEnqueued: "github.com/foo/bar",
+		},
+		{
+			name:        "GitHub ping",
+			externalSvc: 1,
+			header:      map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature": hubSignature("sha1", sha1.New, `{}`)},
+			body:        `{}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "GitHub push of unknown repository",
+			externalSvc: 1,
+			header:      map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature": hubSignature("sha1", sha1.New, gitHubPushUnknown)},
+			body:        gitHubPushUnknown,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "GitHub invalid signature",
+			externalSvc: 1,
+			header:      map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature": hubSignature("sha1", sha1.New, "other")},
+			body:        gitHubPush,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "GitHub missing signature",
+			externalSvc: 1,
+			header:      map[string]string{"X-GitHub-Event": "push"},
+			body:        gitHubPush,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "GitHub without webhookSecret",
+			externalSvc: 4,
+			header:      map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature": hubSignature("sha1", sha1.New, gitHubPush)},
+			body:        gitHubPush,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:         "GitLab push",
+			externalSvc:  2,
+			header:       map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "s3cr3t"},
+			body:         gitLabPush,
+			wantStatus:   http.StatusOK,
+			wantEnqueued: "gitlab.example.com/foo/bar",
+		},
+		{
+			name:        "GitLab invalid token",
+			externalSvc: 2,
+			header:      map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "wrong"},
+			body:        gitLabPush,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:         "Bitbucket Server push",
+			externalSvc:  3,
+			header:       map[string]string{"X-Event-Key": "repo:refs_changed", "X-Hub-Signature": hubSignature("sha256", sha256.New, bitbucketServerPush)},
+			body:         bitbucketServerPush,
+			wantStatus:   http.StatusOK,
+			wantEnqueued: "bitbucket.example.com/FOO/bar",
+		},
+		{
+			name:        "Bitbucket Server SHA-1 signature",
+			externalSvc: 3,
+			header:      map[string]string{"X-Event-Key": "repo:refs_changed", "X-Hub-Signature": hubSignature("sha1", sha1.New, bitbucketServerPush)},
+			body:        bitbucketServerPush,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "unsupported kind",
+			externalSvc: 5,
+			body:        `{}`,
+			wantStatus:  http.StatusNotFound,
+		},
+		{
+			name:        "unknown external service",
+			externalSvc: 6,
+			body:        `{}`,
+			wantStatus:  http.StatusNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enqueued = nil
+
+			req, err := http.NewRequest("POST", fmt.Sprintf("/webhooks/%d", test.externalSvc), strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range test.header {
+				req.Header.Set(k, v)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+
+			var wantEnqueued []api.RepoName
+			if test.wantEnqueued != "" {
+				wantEnqueued = []api.RepoName{test.wantEnqueued}
+			}
+			if fmt.Sprint(enqueued) != fmt.Sprint(wantEnqueued) {
+				t.Errorf("got enqueued repo updates %v, want %v", enqueued, wantEnqueued)
+			}
+		})
+	}
+}
@@ -3,21 +3,27 @@
 package query
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/syntax"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/types"
 )
 
 // All field names.
 const (
-	FieldDefault   = ""
-	FieldCase      = "case"
-	FieldRepo      = "repo"
-	FieldRepoGroup = "repogroup"
-	FieldFile      = "file"
-	FieldFork      = "fork"
-	FieldArchived  = "archived"
-	FieldLang      = "lang"
-	FieldType      = "type"
+	FieldDefault     = ""
+	FieldCase        = "case"
+	FieldRepo        = "repo"
+	FieldRepoGroup   = "repogroup"
+	FieldFile        = "file"
+	FieldFork        = "fork"
+	FieldArchived    = "archived"
+	FieldLang        = "lang"
+	FieldType        = "type"
+	FieldPatternType = "patterntype"
+	FieldMultiline   = "multiline"
+	FieldReplace     = "replace"
 
 	// For diff and commit search only:
 	FieldBefore    = "before"
@@ -33,21 +39,32 @@ const (
 	FieldTimeout = "timeout"
 )
 
+// All values of the patterntype: field. They determine how the default field
+// (the search pattern) is interpreted.
+const (
+	PatternTypeRegexp     = "regexp"
+	PatternTypeLiteral    = "literal"
+	PatternTypeStructural = "structural"
+)
+
 var (
 	regexpNegatableFieldType = types.FieldType{Literal: types.RegexpType, Quoted: types.RegexpType, Negatable: true}
 	stringFieldType          = types.FieldType{Literal: types.StringType, Quoted: types.StringType}
 
 	conf = types.Config{
 		FieldTypes: map[string]types.FieldType{
-			FieldDefault:   {Literal: types.RegexpType, Quoted: types.StringType},
-			FieldCase:      {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
-			FieldRepo:      regexpNegatableFieldType,
-			FieldRepoGroup: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldFile:      regexpNegatableFieldType,
-			FieldFork:      {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldArchived:  {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldLang:      {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
-			FieldType:      stringFieldType,
+			FieldDefault:     {Literal: types.RegexpType, Quoted: types.StringType, Negatable: true},
+			FieldCase:        {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldRepo:        regexpNegatableFieldType,
+			FieldRepoGroup:   {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldFile:        regexpNegatableFieldType,
+			FieldFork:        {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldArchived:    {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldLang:        {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
+			FieldType:        stringFieldType,
+			FieldPatternType: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldMultiline:   {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldReplace:     {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 
 			FieldBefore:    stringFieldType,
 			FieldAfter:     stringFieldType,
@@ -93,6 +110,16 @@ func parseAndCheck(conf *types.Config, input string) (*Query, error) {
 	if err != nil {
 		return nil, err
 	}
+	patternType, err := parsePatternType(syntaxQuery)
+	if err != nil {
+		return nil, err
+	}
+	if patternType != PatternTypeRegexp {
+		// The search pattern is not a regexp, so don't typecheck it as one.
+		typ := conf.FieldTypes[FieldDefault]
+		typ.Literal, typ.Quoted = types.StringType, types.StringType
+		conf = withFieldType(conf, FieldDefault, typ)
+	}
 	checkedQuery, err := conf.Check(syntaxQuery)
 	if err != nil {
 		return nil, err
@@ -100,6 +127,39 @@ func parseAndCheck(conf *types.Config, input string) (*Query, error) {
 	return &Query{conf: conf, Query: checkedQuery}, nil
 }
 
+// parsePatternType returns the value of the patterntype: field in q, or
+// PatternTypeRegexp if it is not set. It is needed before typechecking
+// because the pattern type determines the type of the default field.
+func parsePatternType(q *syntax.Query) (string, error) {
+	for _, expr := range q.Expr {
+		if expr.Field != FieldPatternType {
+			continue
+		}
+		value := expr.Value
+		if expr.ValueType == syntax.TokenQuoted {
+			if v, err := strconv.Unquote(value); err == nil {
+				value = v
+			}
+		}
+		switch value {
+		case PatternTypeRegexp, PatternTypeLiteral, PatternTypeStructural:
+			return value, nil
+		}
+		return "", &types.TypeError{Pos: expr.Pos, Err: fmt.Errorf("invalid %s:%q (valid values are: %s, %s, %s)", FieldPatternType, value, PatternTypeRegexp, PatternTypeLiteral, PatternTypeStructural)}
+	}
+	return PatternTypeRegexp, nil
+}
+
+// withFieldType returns a copy of conf in which field has type typ.
+func withFieldType(conf *types.Config, field string, typ types.FieldType) *types.Config {
+	fieldTypes := make(map[string]types.FieldType, len(conf.FieldTypes))
+	for f, t := range conf.FieldTypes {
+		fieldTypes[f] = t
+	}
+	fieldTypes[field] = typ
+	return &types.Config{FieldTypes: fieldTypes, FieldAliases: conf.FieldAliases}
+}
+
 // BoolValue returns the last boolean value (yes/no) for the field. For example, if the query is
 // "foo:yes foo:no foo:yes", then the last boolean value for the "foo" field is true ("yes"). The
 // default boolean value is false.
@@ -118,6 +178,22 @@ func (q *Query) IsCaseSensitive() bool {
 	return q.BoolValue(FieldCase)
 }
 
+// PatternType returns how the query's search pattern is interpreted. It is
+// one of the PatternType* values.
+func (q *Query) PatternType() string {
+	if v, _ := q.StringValue(FieldPatternType); v != "" {
+		return v
+	}
+	return PatternTypeRegexp
+}
+
+// HasBooleanPattern reports whether the query's search terms are combined
+// with OR or NOT, or are negated (e.g., "a OR b" or "a -b"), so that the query
+// can't be searched for as a single pattern. Use q.Pattern to evaluate it.
+func (q *Query) HasBooleanPattern() bool {
+	return q.Pattern != nil && !q.Pattern.IsSimple()
+}
+
 // Values returns the values for the given field.
 func (q *Query) Values(field string) []*types.Value {
 	if _, ok := q.conf.FieldTypes[field]; !ok {
@@ -45,6 +45,32 @@ func TestQuery_IsCaseSensitive(t *testing.T) {
 	})
 }
 
+func TestQuery_HasBooleanPattern(t *testing.T) {
+	tests := map[string]bool{
+		"":                             false,
+		"repo:a":                       false,
+		"a b":                          false,
+		"a AND b repo:c":               false,
+		"a OR b":                       true,
+		"a -b":                         true,
+		"NOT a":                        true,
+		"-repo:a b":                    false,
+		"(a OR b) c":                   true,
+		`"a" patterntype:literal -"b"`: true,
+	}
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := ParseAndCheck(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := query.HasBooleanPattern(); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func TestQuery_RegexpPatterns(t *testing.T) {
 	conf := types.Config{
 		FieldTypes: map[string]types.FieldType{
@@ -131,6 +157,47 @@ func TestQuery_StringValues(t *testing.T) {
 	})
 }
 
+func TestQuery_PatternType(t *testing.T) {
+	tests := map[string]string{
+		"foo":                          PatternTypeRegexp,
+		"foo patterntype:regexp":       PatternTypeRegexp,
+		"foo patterntype:literal":      PatternTypeLiteral,
+		`foo patterntype:"structural"`: PatternTypeStructural,
+	}
+	for input, want := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := ParseAndCheck(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := query.PatternType(); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	t.Run("default field is not a regexp for non-regexp pattern types", func(t *testing.T) {
+		for _, input := range []string{"foo(:[args]) patterntype:structural", "foo( patterntype:literal"} {
+			query, err := ParseAndCheck(input)
+			if err != nil {
+				t.Fatalf("%s: %s", input, err)
+			}
+			if v := query.Values(FieldDefault); len(v) != 1 || v[0].String == nil {
+				t.Errorf("%s: got %+v, want a single string value", input, v)
+			}
+		}
+		if _, err := ParseAndCheck("foo("); err == nil {
+			t.Error("expected invalid regexp to fail to typecheck")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := ParseAndCheck("foo patterntype:bar"); err == nil {
+			t.Error("expected invalid patterntype to fail")
+		}
+	})
+}
+
 func checkPanic(t *testing.T, msg string, f func()) {
 	t.Helper()
 	defer func() {
@@ -1,6 +1,9 @@
 package syntax
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // ParseError describes an error in query parsing.
 type ParseError struct {
@@ -15,6 +18,8 @@ func (e *ParseError) Error() string {
 type parser struct {
 	tokens []Token
 	pos    int
+	depth  int     // the number of enclosing parenthesized groups
+	fields []*Expr // the top-level field expressions, which are not operands of groups
 }
 
 // context holds settings active within a given scope during parsing.
@@ -27,19 +32,44 @@ type context struct {
 //
 // BNF-ish query syntax:
 //
-//   exprList  := {exprSign} | exprSign (sep exprSign)*
+//   query     := {orExpr}
+//   orExpr    := andExpr (sep "OR" sep andExpr)*
+//   andExpr   := notExpr ((sep | sep "AND" sep) notExpr)*
+//   notExpr   := "NOT" sep notExpr | {"-"} "(" orExpr ")" | exprSign
 //   exprSign  := {"-"} expr
 //   expr      := fieldExpr | lit | quoted | pattern
 //   fieldExpr := lit ":" value
 //   value     := lit | quoted
+//
+// NOT binds tighter than AND, which binds tighter than OR. Field expressions
+// outside of parentheses apply to the whole query, so they are not operands
+// of AND and OR (in "repo:x a OR b", OR combines only a and b). The top-level
+// expressions of the query (which are implicitly ANDed together) are
+// returned in Query.Expr, in the order they appear in the input.
 func Parse(input string) (*Query, error) {
 	tokens := Scan(input)
 	p := parser{tokens: tokens}
 	ctx := context{field: ""}
-	exprs, err := p.parseExprList(ctx)
+	p.skipSep()
+	if p.peek().Type == TokenEOF {
+		return &Query{Input: input}, nil
+	}
+	expr, err := p.parseOrExpr(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if tok := p.next(); tok.Type != TokenEOF {
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want EOF", tok.Type)}
+	}
+	var exprs []*Expr
+	if expr != nil {
+		exprs = []*Expr{expr}
+		if expr.Op == OpAnd && !expr.Not {
+			exprs = expr.Operands
+		}
+	}
+	exprs = append(exprs, p.fields...)
+	sort.SliceStable(exprs, func(i, j int) bool { return exprs[i].Pos < exprs[j].Pos })
 	return &Query{Expr: exprs, Input: input}, nil
 }
 
@@ -68,30 +98,151 @@ func (p *parser) next() Token {
 	return Token{Type: TokenEOF}
 }
 
-// exprList := {exprSign} | exprSign (sep exprSign)*
-func (p *parser) parseExprList(ctx context) (exprList []*Expr, err error) {
-	if p.peek().Type == TokenEOF {
+// skipSep consumes any separators at the current position.
+func (p *parser) skipSep() {
+	for p.peek().Type == TokenSep {
+		p.next()
+	}
+}
+
+// keyword reports whether the next token is the operator keyword kw (e.g.,
+// "OR"). Keywords must be uppercase and followed by a separator.
+func (p *parser) keyword(kw string) bool {
+	tok := p.peek()
+	if tok.Type != TokenLiteral || tok.Value != kw {
+		return false
+	}
+	return p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Type == TokenSep
+}
+
+// orExpr := andExpr (sep "OR" sep andExpr)*
+//
+// It returns nil if the expression only consists of top-level fields.
+func (p *parser) parseOrExpr(ctx context) (*Expr, error) {
+	var (
+		operands   []*Expr
+		fieldsOnly = -1 // the position of an operand that only consists of top-level fields
+	)
+	for {
+		pos := p.peek().Pos
+		expr, err := p.parseAndExpr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if expr == nil {
+			fieldsOnly = pos
+		} else {
+			operands = appendOperand(operands, OpOr, expr)
+		}
+
+		p.skipSep()
+		if !p.keyword("OR") {
+			break
+		}
+		p.next()
+		p.skipSep()
+	}
+	if fieldsOnly != -1 {
+		if len(operands) > 0 {
+			return nil, &ParseError{Pos: fieldsOnly, Msg: "only search terms can be combined with OR (fields apply to the whole query)"}
+		}
 		return nil, nil
 	}
+	return newGroup(OpOr, operands), nil
+}
 
+// andExpr := notExpr ((sep | sep "AND" sep) notExpr)*
+//
+// It returns nil if the expression only consists of top-level fields.
+func (p *parser) parseAndExpr(ctx context) (*Expr, error) {
+	var operands []*Expr
 	for {
-		tok := p.peek()
-		if tok.Type == TokenEOF {
+		expr, err := p.parseNotExpr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if p.depth == 0 && expr.Op == OpNone && expr.Field != "" {
+			p.fields = append(p.fields, expr)
+		} else {
+			operands = appendOperand(operands, OpAnd, expr)
+		}
+
+		p.skipSep()
+		if tok := p.peek(); tok.Type == TokenEOF || tok.Type == TokenRParen || p.keyword("OR") {
 			break
 		}
-		if tok.Type == TokenSep {
+		if p.keyword("AND") {
 			p.next()
-			continue
+			p.skipSep()
 		}
+	}
+	if len(operands) == 0 {
+		return nil, nil
+	}
+	return newGroup(OpAnd, operands), nil
+}
 
-		expr, err := p.parseExprSign(ctx)
+// notExpr := "NOT" sep notExpr | {"-"} "(" orExpr ")" | exprSign
+func (p *parser) parseNotExpr(ctx context) (*Expr, error) {
+	if p.keyword("NOT") {
+		p.next()
+		p.skipSep()
+		expr, err := p.parseNotExpr(ctx)
 		if err != nil {
 			return nil, err
 		}
-		exprList = append(exprList, expr)
+		expr.Not = !expr.Not
+		return expr, nil
 	}
 
-	return exprList, nil
+	not := false
+	if p.peek().Type == TokenMinus && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Type == TokenLParen {
+		p.next()
+		not = true
+	}
+	if p.peek().Type != TokenLParen {
+		return p.parseExprSign(ctx)
+	}
+
+	lparen := p.next()
+	p.skipSep()
+	p.depth++
+	expr, err := p.parseOrExpr(ctx)
+	p.depth--
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok.Type != TokenRParen {
+		return nil, &ParseError{Pos: lparen.Pos, Msg: "unclosed group"}
+	}
+	if tok := p.next(); tok.Type != TokenSep && tok.Type != TokenEOF && tok.Type != TokenRParen {
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok.Type)}
+	} else if tok.Type != TokenSep {
+		p.backup()
+	}
+	if not {
+		expr.Not = !expr.Not
+	}
+	return expr, nil
+}
+
+// appendOperand appends expr to the operands of a group with operator op.
+// Operands which are themselves (non-negated) groups with the same operator
+// are flattened, so that "(a OR b) OR c" is the same as "a OR b OR c".
+func appendOperand(operands []*Expr, op Operator, expr *Expr) []*Expr {
+	if expr.Op == op && !expr.Not {
+		return append(operands, expr.Operands...)
+	}
+	return append(operands, expr)
+}
+
+// newGroup returns a group expression with the given operator and operands,
+// or the sole operand if there is only one.
+func newGroup(op Operator, operands []*Expr) *Expr {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return &Expr{Pos: operands[0].Pos, Op: op, Operands: operands}
 }
 
 // exprSign := {"-"} expr
@@ -129,16 +280,24 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 			valueTok := p.next()
 			switch valueTok.Type {
 			case TokenLiteral, TokenQuoted:
-				if tok3 := p.next(); tok3.Type != TokenSep && tok3.Type != TokenEOF {
+				if tok3 := p.next(); tok3.Type == TokenRParen {
+					p.backup()
+				} else if tok3.Type != TokenSep && tok3.Type != TokenEOF {
 					return nil, &ParseError{Pos: tok3.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok3.Type)}
 				}
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: valueTok.Value, ValueType: valueTok.Type}, nil
-			case TokenSep, TokenEOF:
+			case TokenSep, TokenEOF, TokenRParen:
+				if valueTok.Type == TokenRParen {
+					p.backup()
+				}
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: "", ValueType: TokenLiteral}, nil
 			default:
 				return nil, &ParseError{Pos: valueTok.Pos, Msg: fmt.Sprintf("got %s, want value", valueTok.Type)}
 			}
-		case TokenSep, TokenEOF:
+		case TokenSep, TokenEOF, TokenRParen:
+			if tok2.Type == TokenRParen {
+				p.backup()
+			}
 			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
 		default:
 			panic("unreachable")
@@ -146,7 +305,10 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 	case TokenQuoted, TokenPattern:
 		tok2 := p.next()
 		switch tok2.Type {
-		case TokenSep, TokenEOF:
+		case TokenSep, TokenEOF, TokenRParen:
+			if tok2.Type == TokenRParen {
+				p.backup()
+			}
 			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
 		default:
 			return nil, &ParseError{Pos: tok2.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok2.Type)}
@@ -79,6 +79,122 @@ func TestParser(t *testing.T) {
 		`"a":b`: {
 			wantErr: &ParseError{Pos: 3, Msg: "got TokenColon, want separator or EOF"},
 		},
+		"a AND b": {
+			wantExpr: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			},
+			wantString: "a b",
+		},
+		"a OR b": {
+			wantExpr: []*Expr{{Op: OpOr, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			}}},
+			wantString: "(a OR b)",
+		},
+		"a b OR c": {
+			wantExpr: []*Expr{{Op: OpOr, Operands: []*Expr{
+				{Op: OpAnd, Operands: []*Expr{
+					{Value: "a", ValueType: TokenLiteral},
+					{Value: "b", ValueType: TokenLiteral},
+				}},
+				{Value: "c", ValueType: TokenLiteral},
+			}}},
+			wantString: "((a AND b) OR c)",
+		},
+		"x:y (a OR b) c": {
+			wantExpr: []*Expr{
+				{Field: "x", Value: "y", ValueType: TokenLiteral},
+				{Op: OpOr, Operands: []*Expr{
+					{Value: "a", ValueType: TokenLiteral},
+					{Value: "b", ValueType: TokenLiteral},
+				}},
+				{Value: "c", ValueType: TokenLiteral},
+			},
+			wantString: "x:y (a OR b) c",
+		},
+		"(a OR b) OR c": {
+			wantExpr: []*Expr{{Op: OpOr, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+				{Value: "c", ValueType: TokenLiteral},
+			}}},
+			wantString: "(a OR b OR c)",
+		},
+		"NOT a": {
+			wantExpr:   []*Expr{{Not: true, Value: "a", ValueType: TokenLiteral}},
+			wantString: "-a",
+		},
+		"NOT (a OR /b/)": {
+			wantExpr: []*Expr{{Not: true, Op: OpOr, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenPattern},
+			}}},
+		},
+		"-(a b)": {
+			wantExpr: []*Expr{{Not: true, Op: OpAnd, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			}}},
+			wantString: "NOT (a AND b)",
+		},
+		"(a)": {
+			wantExpr: []*Expr{{Value: "(a)", ValueType: TokenLiteral}},
+		},
+		"( a )": {
+			wantExpr:   []*Expr{{Value: "a", ValueType: TokenLiteral}},
+			wantString: "a",
+		},
+		"or OR": {
+			wantExpr: []*Expr{
+				{Value: "or", ValueType: TokenLiteral},
+				{Value: "OR", ValueType: TokenLiteral},
+			},
+		},
+		"(a OR b": {
+			wantExpr: []*Expr{{Op: OpOr, Operands: []*Expr{
+				{Value: "(a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			}}},
+			wantString: "((a OR b)",
+		},
+		"repo:x a OR b": {
+			wantExpr: []*Expr{
+				{Field: "repo", Value: "x", ValueType: TokenLiteral},
+				{Op: OpOr, Operands: []*Expr{
+					{Value: "a", ValueType: TokenLiteral},
+					{Value: "b", ValueType: TokenLiteral},
+				}},
+			},
+			wantString: "repo:x (a OR b)",
+		},
+		"a OR b file:y c": {
+			wantExpr: []*Expr{
+				{Op: OpOr, Operands: []*Expr{
+					{Value: "a", ValueType: TokenLiteral},
+					{Op: OpAnd, Operands: []*Expr{
+						{Value: "b", ValueType: TokenLiteral},
+						{Value: "c", ValueType: TokenLiteral},
+					}},
+				}},
+				{Field: "file", Value: "y", ValueType: TokenLiteral},
+			},
+			wantString: "(a OR (b AND c)) file:y",
+		},
+		"repo:x OR b": {
+			wantErr: &ParseError{Pos: 0, Msg: "only search terms can be combined with OR (fields apply to the whole query)"},
+		},
+		"(repo:x OR b)": {
+			wantExpr: []*Expr{{Op: OpOr, Operands: []*Expr{
+				{Field: "repo", Value: "x", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			}}},
+			wantString: "(repo:x OR b)",
+		},
+		"a OR ( )": {
+			wantErr: &ParseError{Pos: 7, Msg: "got TokenRParen, want expr"},
+		},
 	}
 	for input, test := range tests {
 		t.Run(input, func(t *testing.T) {
@@ -97,7 +213,7 @@ func TestParser(t *testing.T) {
 				query.Expr = []*Expr{}
 			}
 			for _, expr := range query.Expr {
-				expr.Pos = 0
+				clearPos(expr)
 			}
 			if !reflect.DeepEqual(query.Expr, test.wantExpr) {
 				t.Errorf("expr: %s\ngot  %v\nwant %v", input, query.Expr, test.wantExpr)
@@ -111,3 +227,10 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func clearPos(expr *Expr) {
+	expr.Pos = 0
+	for _, operand := range expr.Operands {
+		clearPos(operand)
+	}
+}
@@ -11,17 +11,57 @@ type Query struct {
 	Expr  []*Expr // expressions in this query
 }
 
-// An Expr describes an expression in a query.
+// Operator is the boolean operator of a group expression.
+type Operator int
+
+// All Operator values.
+const (
+	OpNone Operator = iota // not a group (a field or term expression)
+	OpAnd                  // all operands must match (e.g., "a AND b" or "a b")
+	OpOr                   // any operand must match (e.g., "a OR b")
+)
+
+func (op Operator) String() string {
+	switch op {
+	case OpAnd:
+		return "AND"
+	case OpOr:
+		return "OR"
+	}
+	return ""
+}
+
+// An Expr describes an expression in a query. It is either a field or term
+// expression, or (if Op != OpNone) a group of operand expressions.
 type Expr struct {
 	Pos       int       // the starting character position of the query expression
-	Not       bool      // the expression is negated (e.g., -term or -field:term)
+	Not       bool      // the expression is negated (e.g., -term, -field:term or NOT (a OR b))
 	Field     string    // the field that this expression applies to
 	Value     string    // the raw field value
 	ValueType TokenType // the type of the value
+
+	Op       Operator // the operator of a group expression
+	Operands []*Expr  // the operands of a group expression
 }
 
 func (e Expr) String() string {
 	var buf bytes.Buffer
+	if e.Op != OpNone {
+		if e.Not {
+			buf.WriteString("NOT ")
+		}
+		buf.WriteByte('(')
+		for i, operand := range e.Operands {
+			if i > 0 {
+				buf.WriteByte(' ')
+				buf.WriteString(e.Op.String())
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(operand.String())
+		}
+		buf.WriteByte(')')
+		return buf.String()
+	}
 	if e.Not {
 		buf.WriteByte('-')
 	}
@@ -39,6 +79,18 @@ func (e Expr) String() string {
 	return buf.String()
 }
 
+// Walk calls fn for each field or term expression in e (including e itself
+// if it is not a group), in order.
+func (e *Expr) Walk(fn func(*Expr)) {
+	if e.Op == OpNone {
+		fn(e)
+		return
+	}
+	for _, operand := range e.Operands {
+		operand.Walk(fn)
+	}
+}
+
 // ExprString returns the query string that parses to expr.
 func ExprString(expr []*Expr) string {
 	s := make([]string, len(expr))
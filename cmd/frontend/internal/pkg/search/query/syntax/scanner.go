@@ -21,7 +21,9 @@ const (
 	TokenPattern
 	TokenColon
 	TokenMinus
-	TokenSep // separator (like a semicolon)
+	TokenSep    // separator (like a semicolon)
+	TokenLParen // "(" opening a group
+	TokenRParen // ")" closing a group
 )
 
 var singleCharTokens = map[rune]TokenType{
@@ -54,6 +56,7 @@ type scanner struct {
 	pos     int
 	prevPos int
 	start   int
+	parens  int // number of unclosed TokenLParen tokens
 }
 
 func (s *scanner) next() rune {
@@ -106,6 +109,26 @@ func scanDefault(s *scanner) stateFn {
 	if !unicode.IsSpace(r) {
 		s.backup()
 		s.ignore()
+		if r == ':' && strings.HasPrefix(s.input[s.pos:], ":[") {
+			// A structural search hole (e.g., ":[args]"), not a field
+			// separator.
+			return scanLiteral
+		}
+		if r == '(' && !closesWithinTerm(s.input[s.pos:]) && isClosed(s.input[s.pos:]) {
+			// A group, not part of a regexp like "(open|close)file". An
+			// unclosed "(" is part of a term, as it was before groups
+			// were supported.
+			s.next()
+			s.emit(TokenLParen)
+			s.parens++
+			return scanDefault
+		}
+		if r == ')' && s.parens > 0 {
+			s.next()
+			s.emit(TokenRParen)
+			s.parens--
+			return scanDefault
+		}
 		if typ, ok := singleCharTokens[r]; ok {
 			s.next()
 			s.emit(typ)
@@ -180,10 +203,93 @@ func scanLiteral(s *scanner) stateFn {
 		}
 	}
 
-	s.emit(TokenLiteral)
+	// Unbalanced trailing parentheses close open groups, as in "(a OR b)".
+	n := unbalancedTrailingParens(s.input[s.start:s.pos])
+	if n > s.parens {
+		n = s.parens
+	}
+	s.pos -= n
+	if s.pos > s.start {
+		s.emit(TokenLiteral)
+	}
+	for i := 0; i < n; i++ {
+		s.pos++
+		s.emit(TokenRParen)
+		s.parens--
+	}
 	return scanDefault
 }
 
+// closesWithinTerm reports whether the "(" at the start of s is closed by a
+// matching ")" before the next whitespace.
+func closesWithinTerm(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return true
+			}
+		case unicode.IsSpace(rune(c)):
+			return false
+		}
+	}
+	return false
+}
+
+// isClosed reports whether the "(" at the start of s is closed by a matching
+// ")" anywhere in s.
+func isClosed(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unbalancedTrailingParens returns the number of ")" at the end of the
+// literal s that do not close a "(" in s.
+func unbalancedTrailingParens(s string) int {
+	depth, unbalanced := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			} else {
+				unbalanced++
+			}
+		}
+	}
+	trailing := 0
+	for trailing < len(s) && s[len(s)-1-trailing] == ')' {
+		trailing++
+	}
+	if trailing > unbalanced {
+		return unbalanced
+	}
+	return trailing
+}
+
 func scanQuoted(s *scanner) stateFn {
 	q := s.next()
 	escaped := false
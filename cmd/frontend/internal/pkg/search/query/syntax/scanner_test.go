@@ -20,6 +20,8 @@ func TestScanner(t *testing.T) {
 		"a : b":    {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenColon, TokenSep, TokenLiteral}, wantValues: []string{"a", " ", ":", " ", "b"}},
 		"a: b":     {wantTypes: []TokenType{TokenLiteral, TokenColon, TokenSep, TokenLiteral}, wantValues: []string{"a", ":", " ", "b"}},
 		`a:" b"`:   {wantTypes: []TokenType{TokenLiteral, TokenColon, TokenQuoted}, wantValues: []string{"a", ":", `" b"`}},
+		":[a]":     {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{":[a]"}},
+		"a :[b])":  {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenLiteral}, wantValues: []string{"a", " ", ":[b])"}},
 		"a :b":     {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenColon, TokenLiteral}, wantValues: []string{"a", " ", ":", "b"}},
 		"-a":       {wantTypes: []TokenType{TokenMinus, TokenLiteral}, wantValues: []string{"-", "a"}},
 		"-a:b":     {wantTypes: []TokenType{TokenMinus, TokenLiteral, TokenColon, TokenLiteral}, wantValues: []string{"-", "a", ":", "b"}},
@@ -67,6 +69,19 @@ func TestScanner(t *testing.T) {
 		"a /b/ c":  {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenPattern, TokenSep, TokenLiteral}, wantValues: []string{"a", " ", "b", " ", "c"}},
 		"a /b c":   {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenPattern}, wantValues: []string{"a", " ", "b c"}},
 		"a /b c/":  {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenPattern}, wantValues: []string{"a", " ", "b c"}},
+		"(a)":      {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"(a)"}},
+		"(a)b":     {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"(a)b"}},
+		"a)":       {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"a)"}},
+		"(a b)":    {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", " ", "b", ")"}},
+		"((a b))":  {wantTypes: []TokenType{TokenLParen, TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen, TokenRParen}},
+		"(a f(b))": {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", " ", "f(b)", ")"}},
+		`(a b\))`:  {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", " ", `b\)`, ")"}},
+		"(a b) )":  {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen, TokenSep, TokenLiteral}, wantValues: []string{"(", "a", " ", "b", ")", " ", ")"}},
+		"(a:b c)":  {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenColon, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}},
+		`(a "b")`:  {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenQuoted, TokenRParen}},
+		"-(a b)":   {wantTypes: []TokenType{TokenMinus, TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}},
+		"(a b":     {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenLiteral}, wantValues: []string{"(a", " ", "b"}},
+		"(a (b c)": {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"(a", " ", "(", "b", " ", "c", ")"}},
 	}
 	for input, test := range tests {
 		t.Run(input, func(t *testing.T) {
@@ -44,8 +44,18 @@ func (c *Config) Check(query *syntax.Query) (*Query, error) {
 		Syntax: query,
 		Fields: map[string][]*Value{},
 	}
+	var terms []*BoolExpr
 	for _, expr := range query.Expr {
-		field, fieldType, value, err := c.checkExpr(expr)
+		if expr.Op != syntax.OpNone {
+			term, err := c.checkGroup(expr, false, checkedQuery.Fields)
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, term)
+			continue
+		}
+
+		field, fieldType, value, err := c.checkExpr(expr, false)
 		if err != nil {
 			return nil, err
 		}
@@ -53,10 +63,53 @@ func (c *Config) Check(query *syntax.Query) (*Query, error) {
 			return nil, &TypeError{Pos: expr.Pos, Err: fmt.Errorf("field %q may not be used more than once", field)}
 		}
 		checkedQuery.Fields[field] = append(checkedQuery.Fields[field], value)
+		if field == "" {
+			terms = append(terms, &BoolExpr{Not: value.Not(), Value: value})
+		}
+	}
+	switch len(terms) {
+	case 0:
+	case 1:
+		checkedQuery.Pattern = terms[0]
+	default:
+		checkedQuery.Pattern = &BoolExpr{Op: syntax.OpAnd, Operands: terms}
 	}
 	return &checkedQuery, nil
 }
 
+// checkGroup typechecks a group expression. Groups may only contain terms
+// (values of the "" field), which are added to fields. not is whether the
+// group is inside a negated group.
+func (c *Config) checkGroup(expr *syntax.Expr, not bool, fields map[string][]*Value) (*BoolExpr, error) {
+	if expr.Not {
+		if _, _, err := c.resolveField("", true); err != nil {
+			return nil, &TypeError{Pos: expr.Pos, Err: err}
+		}
+	}
+	group := &BoolExpr{Op: expr.Op, Not: expr.Not}
+	for _, operand := range expr.Operands {
+		if operand.Op != syntax.OpNone {
+			term, err := c.checkGroup(operand, not != expr.Not, fields)
+			if err != nil {
+				return nil, err
+			}
+			group.Operands = append(group.Operands, term)
+			continue
+		}
+
+		field, _, value, err := c.checkExpr(operand, not != expr.Not)
+		if err != nil {
+			return nil, err
+		}
+		if field != "" {
+			return nil, &TypeError{Pos: operand.Pos, Err: fmt.Errorf("field %q may not be used in a group (only search terms can be combined with AND, OR and NOT)", field)}
+		}
+		fields[field] = append(fields[field], value)
+		group.Operands = append(group.Operands, &BoolExpr{Not: operand.Not, Value: value})
+	}
+	return group, nil
+}
+
 func (c *Config) resolveField(field string, not bool) (resolvedField string, typ FieldType, err error) {
 	// Resolve field alias, if any.
 	if resolvedField, ok := c.FieldAliases[field]; ok {
@@ -85,7 +138,9 @@ func (c *Config) resolveField(field string, not bool) (resolvedField string, typ
 	return field, typ, nil
 }
 
-func (c *Config) checkExpr(expr *syntax.Expr) (field string, fieldType FieldType, value *Value, err error) {
+// checkExpr typechecks a field or term expression. inNegatedGroup is whether
+// the expression is inside a negated group.
+func (c *Config) checkExpr(expr *syntax.Expr, inNegatedGroup bool) (field string, fieldType FieldType, value *Value, err error) {
 	// Resolve field name.
 	resolvedField, fieldType, err := c.resolveField(expr.Field, expr.Not)
 	if err != nil {
@@ -93,7 +148,7 @@ func (c *Config) checkExpr(expr *syntax.Expr) (field string, fieldType FieldType
 	}
 
 	// Resolve value.
-	value = &Value{syntax: expr}
+	value = &Value{syntax: expr, not: expr.Not != inNegatedGroup}
 	switch expr.ValueType {
 	case syntax.TokenLiteral:
 		if err := setValue(value, expr.Value, fieldType.Literal); err != nil {
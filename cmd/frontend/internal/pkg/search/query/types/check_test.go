@@ -6,6 +6,7 @@ import (
 	"errors"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/syntax"
@@ -100,6 +101,14 @@ func TestCheck(t *testing.T) {
 		"b:z":        {wantErr: &TypeError{Pos: 0, Err: errors.New(`invalid boolean "z"`)}},
 		`b:"z"`:      {wantErr: &TypeError{Pos: 0, Err: errors.New(`invalid boolean "z"`)}},
 		"z:a":        {wantErr: &TypeError{Pos: 0, Err: errors.New(`unrecognized field "z"`)}},
+		"(a OR f:b) r:c": {
+			want: map[string][]value{
+				"":  {{Value: regexp.MustCompile("a")}, {Value: regexp.MustCompile("b")}},
+				"r": {{Value: regexp.MustCompile("c")}},
+			},
+		},
+		"(a OR r:b)":   {wantErr: &TypeError{Pos: 6, Err: errors.New(`field "r" may not be used in a group (only search terms can be combined with AND, OR and NOT)`)}},
+		"NOT (a OR b)": {wantErr: &TypeError{Pos: 5, Err: errors.New(`negated terms (-term) are not yet supported`)}},
 	}
 	for input, test := range tests {
 		t.Run(input, func(t *testing.T) {
@@ -125,6 +134,113 @@ func TestCheck(t *testing.T) {
 	}
 }
 
+func TestCheck_pattern(t *testing.T) {
+	conf := Config{
+		FieldTypes: map[string]FieldType{
+			"":  {Literal: StringType, Quoted: StringType, Negatable: true},
+			"r": {Literal: StringType, Quoted: StringType},
+		},
+	}
+	var format func(e *BoolExpr) string
+	format = func(e *BoolExpr) string {
+		var s string
+		if e.Op == syntax.OpNone {
+			s = *e.Value.String
+		} else {
+			operands := make([]string, len(e.Operands))
+			for i, operand := range e.Operands {
+				operands[i] = format(operand)
+			}
+			s = "(" + strings.Join(operands, " "+e.Op.String()+" ") + ")"
+		}
+		if e.Not {
+			s = "NOT " + s
+		}
+		return s
+	}
+	tests := map[string]struct {
+		want       string
+		wantSimple bool
+		wantNot    []string // the negated values
+	}{
+		"r:x":                 {want: ""},
+		"a":                   {want: "a", wantSimple: true},
+		"a r:x b":             {want: "(a AND b)", wantSimple: true},
+		"a -b":                {want: "(a AND NOT b)", wantNot: []string{"b"}},
+		"a OR b c":            {want: "(a OR (b AND c))"},
+		"r:x (a OR b) c":      {want: "((a OR b) AND c)"},
+		"a NOT (b OR -c)":     {want: "(a AND NOT (b OR NOT c))", wantNot: []string{"b"}},
+		"NOT (a AND NOT b) c": {want: "(NOT (a AND NOT b) AND c)", wantNot: []string{"a"}},
+		"r:x a OR b":          {want: "(a OR b)"},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			syntaxQuery, err := syntax.Parse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, err := conf.Check(syntaxQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.Pattern == nil {
+				if test.want != "" {
+					t.Fatalf("got nil pattern, want %q", test.want)
+				}
+				return
+			}
+			if got := format(query.Pattern); got != test.want {
+				t.Errorf("got pattern %q, want %q", got, test.want)
+			}
+			if got := query.Pattern.IsSimple(); got != test.wantSimple {
+				t.Errorf("got IsSimple %v, want %v", got, test.wantSimple)
+			}
+			var not []string
+			for _, v := range query.Fields[""] {
+				if v.Not() {
+					not = append(not, *v.String)
+				}
+			}
+			if !reflect.DeepEqual(not, test.wantNot) {
+				t.Errorf("got negated values %q, want %q", not, test.wantNot)
+			}
+		})
+	}
+}
+
+func TestBoolExpr_RequiresTerm(t *testing.T) {
+	conf := Config{
+		FieldTypes: map[string]FieldType{
+			"": {Literal: StringType, Quoted: StringType, Negatable: true},
+		},
+	}
+	tests := map[string]bool{
+		"a":                   true,
+		"-a":                  false,
+		"a -b":                true,
+		"a OR b":              true,
+		"a OR -b":             false,
+		"(a -b) OR c":         true,
+		"NOT (a OR b)":        false,
+		"NOT (-a OR -b)":      true,
+		"NOT (-a AND b)":      false,
+		"NOT (a AND NOT b) c": true,
+	}
+	for input, want := range tests {
+		syntaxQuery, err := syntax.Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query, err := conf.Check(syntaxQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := query.Pattern.RequiresTerm(); got != want {
+			t.Errorf("%s: got RequiresTerm %v, want %v", input, got, want)
+		}
+	}
+}
+
 func TestUnquoteString(t *testing.T) {
 	tests := map[string]string{
 		`"ab"`:    "ab",
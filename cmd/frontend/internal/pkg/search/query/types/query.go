@@ -9,7 +9,65 @@ import (
 // A Query is the typechecked representation of a search query.
 type Query struct {
 	Syntax *syntax.Query       // the query syntax
-	Fields map[string][]*Value // map of field name -> values
+	Fields map[string][]*Value // map of field name -> values (including the terms in groups)
+
+	// Pattern is the boolean expression of the query's terms (the values of
+	// the "" field), eg "(a OR b) -c". It is nil if the query has no terms.
+	Pattern *BoolExpr
+}
+
+// A BoolExpr is a boolean combination of term values.
+type BoolExpr struct {
+	Op       syntax.Operator // OpAnd or OpOr, or OpNone if this is a single Value
+	Not      bool            // the expression is negated
+	Operands []*BoolExpr     // the operands (if Op != OpNone)
+	Value    *Value          // the term value (if Op == OpNone)
+}
+
+// IsSimple reports whether e is a conjunction of non-negated terms (eg "a b"),
+// which is the only kind of expression that queries without AND, OR, NOT,
+// negated terms or groups can express.
+func (e *BoolExpr) IsSimple() bool {
+	if e.Not {
+		return false
+	}
+	switch e.Op {
+	case syntax.OpNone:
+		return true
+	case syntax.OpAnd:
+		for _, operand := range e.Operands {
+			if operand.Op != syntax.OpNone || operand.Not {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// RequiresTerm reports whether e only matches content that matches one of its
+// non-negated terms. Expressions like "a OR NOT b" can match content without
+// any match of a term (to highlight or to find candidate files with).
+func (e *BoolExpr) RequiresTerm() bool {
+	return e.requiresTerm(false)
+}
+
+// requiresTerm is like RequiresTerm, for e inside a negated group if not is
+// true.
+func (e *BoolExpr) requiresTerm(not bool) bool {
+	not = not != e.Not
+	if e.Op == syntax.OpNone {
+		return !not
+	}
+	// A conjunction requires a term if any operand does, a disjunction only
+	// if all operands do. Negation swaps them (De Morgan).
+	conjunction := (e.Op == syntax.OpAnd) != not
+	for _, operand := range e.Operands {
+		if operand.requiresTerm(not) == conjunction {
+			return conjunction
+		}
+	}
+	return !conjunction
 }
 
 // ValueType is the set of types of values in queries.
@@ -25,15 +83,17 @@ const (
 // A Value is a field value in a query.
 type Value struct {
 	syntax *syntax.Expr // the underlying query expression
+	not    bool         // whether the value is negated, taking enclosing groups into account
 
 	String *string        // if a string value, the string value (with escape sequences interpreted)
 	Regexp *regexp.Regexp // if a regexp pattern, the compiled regular expression (call its String method to get source pattern string)
 	Bool   *bool          // if a bool value, the bool value
 }
 
-// Not returns whether the value is negated in the query (e.g., -value or -field:value).
+// Not returns whether the value is negated in the query (e.g., -value,
+// -field:value or NOT (a OR value)).
 func (v *Value) Not() bool {
-	return v.syntax.Not
+	return v.not
 }
 
 // Value returns the value as an interface{}.
@@ -38,6 +38,24 @@ func (r1 RevisionSpecifier) String() string {
 	return r1.RevSpec
 }
 
+// MergeBaseRange returns the base and head of the revspec if it is a merge
+// base range ("base...head"), which refers to the changes made on head since
+// it diverged from base. An omitted base or head defaults to HEAD, as in git.
+func (r1 RevisionSpecifier) MergeBaseRange() (base, head string, ok bool) {
+	i := strings.Index(r1.RevSpec, "...")
+	if i == -1 {
+		return "", "", false
+	}
+	base, head = r1.RevSpec[:i], r1.RevSpec[i+len("..."):]
+	if base == "" {
+		base = "HEAD"
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+	return base, head, true
+}
+
 // Less compares two revspecOrRefGlob entities, suitable for use
 // with sort.Slice()
 //
@@ -140,6 +158,89 @@ func (r *RepositoryRevisions) RevSpecs() []string {
 	return revspecs
 }
 
+// ExpandRefGlobs returns the names (of refNames) of the refs which match at
+// least one of the RefGlobs of revs and none of its ExcludeRefGlobs, like the
+// refs `git log --glob=... --exclude=...` would use.
+func ExpandRefGlobs(revs []RevisionSpecifier, refNames []string) ([]string, error) {
+	var include, exclude []*regexp.Regexp
+	for _, rev := range revs {
+		switch {
+		case rev.RefGlob != "":
+			// Like git, a glob that doesn't start with refs/ is relative to
+			// refs/, and a glob without wildcards matches the refs under it.
+			glob := rev.RefGlob
+			if !strings.HasPrefix(glob, "refs/") {
+				glob = "refs/" + glob
+			}
+			if !strings.ContainsAny(glob, "*?[") {
+				glob = strings.TrimSuffix(glob, "/") + "/*"
+			}
+			re, err := compileRefGlob(glob)
+			if err != nil {
+				return nil, err
+			}
+			include = append(include, re)
+		case rev.ExcludeRefGlob != "":
+			re, err := compileRefGlob(rev.ExcludeRefGlob)
+			if err != nil {
+				return nil, err
+			}
+			exclude = append(exclude, re)
+		}
+	}
+
+	matchAny := func(res []*regexp.Regexp, name string) bool {
+		for _, re := range res {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+	var names []string
+	for _, name := range refNames {
+		if matchAny(include, name) && !matchAny(exclude, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// compileRefGlob compiles a ref glob to a regexp. As in git, wildcards match
+// slashes.
+func compileRefGlob(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, errors.Errorf("invalid ref glob %q: unterminated character class", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
 // RepoRevisionsQuery evaulates ref specifiers in q to find out which
 // revisions need to be searched for each repository.
 func RepoRevisionsQuery(q query.Q, repos []*types.Repo) ([]RepositoryRevisions, error) {
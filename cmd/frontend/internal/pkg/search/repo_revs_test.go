@@ -50,6 +50,64 @@ func TestParseRepositoryRevisions(t *testing.T) {
 	}
 }
 
+func TestRevisionSpecifier_MergeBaseRange(t *testing.T) {
+	tests := map[string]struct {
+		base, head string
+		ok         bool
+	}{
+		"":             {},
+		"rev":          {},
+		"rev1..rev2":   {},
+		"rev1...rev2":  {base: "rev1", head: "rev2", ok: true},
+		"rev1...":      {base: "rev1", head: "HEAD", ok: true},
+		"...rev2":      {base: "HEAD", head: "rev2", ok: true},
+		"a/b...v1.2.3": {base: "a/b", head: "v1.2.3", ok: true},
+	}
+	for revSpec, want := range tests {
+		base, head, ok := RevisionSpecifier{RevSpec: revSpec}.MergeBaseRange()
+		if base != want.base || head != want.head || ok != want.ok {
+			t.Errorf("%q: got (%q, %q, %v), want (%q, %q, %v)", revSpec, base, head, ok, want.base, want.head, want.ok)
+		}
+	}
+}
+
+func TestExpandRefGlobs(t *testing.T) {
+	refNames := []string{
+		"refs/heads/master",
+		"refs/heads/feature/a",
+		"refs/heads/feature/b",
+		"refs/heads/old-1",
+		"refs/remotes/origin/master",
+		"refs/tags/v1.0",
+		"refs/tags/v2.0",
+	}
+	tests := []struct {
+		revs string // as in ParseRepositoryRevisions
+		want []string
+	}{
+		{revs: "*refs/heads/*", want: []string{"refs/heads/master", "refs/heads/feature/a", "refs/heads/feature/b", "refs/heads/old-1"}},
+		{revs: "*refs/heads/*:*!refs/heads/old-*", want: []string{"refs/heads/master", "refs/heads/feature/a", "refs/heads/feature/b"}},
+		{revs: "*heads/feature", want: []string{"refs/heads/feature/a", "refs/heads/feature/b"}},
+		{revs: "*refs/tags/v[!1].?", want: []string{"refs/tags/v2.0"}},
+		{revs: "*refs/heads/m*:*refs/remotes/*/master", want: []string{"refs/heads/master", "refs/remotes/origin/master"}},
+		{revs: "master", want: nil},
+	}
+	for _, test := range tests {
+		_, revs := ParseRepositoryRevisions("repo@" + test.revs)
+		got, err := ExpandRefGlobs(revs, refNames)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.revs, got, test.want)
+		}
+	}
+
+	if _, err := ExpandRefGlobs([]RevisionSpecifier{{RefGlob: "refs/heads/[a"}}, refNames); err == nil {
+		t.Error("got no error for an invalid glob, want an error")
+	}
+}
+
 func TestRepoRevisionsQuery(t *testing.T) {
 	repos := []*types.Repo{{Name: "foo"}, {Name: "bar"}, {Name: "baz"}}
 	cases := map[string]string{
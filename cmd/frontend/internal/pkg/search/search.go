@@ -12,12 +12,12 @@ import (
 type PatternInfo struct {
 	Pattern         string
 	IsRegExp        bool
+	IsStructural    bool
 	IsWordMatch     bool
 	IsCaseSensitive bool
+	IsMultiline     bool
 	FileMatchLimit  int32
 
-	// We do not support IsMultiline
-	//IsMultiline     bool
 	IncludePattern  string
 	IncludePatterns []string
 	ExcludePattern  string
@@ -27,6 +27,50 @@ type PatternInfo struct {
 
 	PatternMatchesContent bool
 	PatternMatchesPath    bool
+
+	// PatternExpr, if set, is the boolean expression of regexps (eg
+	// "(a OR b) AND NOT c") that a file's content must match. Pattern is then
+	// the union of its non-negated regexps, which are highlighted.
+	PatternExpr *PatternExpr
+
+	// Replace, if true, requests the diff of replacing the matches of
+	// Pattern with Replacement (which may refer to capture groups, eg "$1")
+	// in each matching file.
+	Replace     bool
+	Replacement string
+}
+
+// PatternExpr is a boolean combination of regular expressions. Exactly one of
+// its fields is set, except that a PatternExpr with an empty Pattern matches
+// everything. Keep it in sync with cmd/searcher/protocol.PatternExpr.
+type PatternExpr struct {
+	And     []*PatternExpr `json:",omitempty"`
+	Or      []*PatternExpr `json:",omitempty"`
+	Not     *PatternExpr   `json:",omitempty"`
+	Pattern string         `json:",omitempty"`
+}
+
+// Operands returns the operands of e if it is an And or Or expression.
+func (e *PatternExpr) Operands() []*PatternExpr {
+	if len(e.And) > 0 {
+		return e.And
+	}
+	return e.Or
+}
+
+// Patterns returns the regular expressions in e.
+func (e *PatternExpr) Patterns() []string {
+	switch {
+	case e.Not != nil:
+		return e.Not.Patterns()
+	case len(e.And) > 0 || len(e.Or) > 0:
+		var patterns []string
+		for _, operand := range e.Operands() {
+			patterns = append(patterns, operand.Patterns()...)
+		}
+		return patterns
+	}
+	return []string{e.Pattern}
 }
 
 func (p *PatternInfo) IsEmpty() bool {
@@ -40,6 +84,13 @@ func (p *PatternInfo) Validate() error {
 			return err
 		}
 	}
+	if p.PatternExpr != nil {
+		for _, pattern := range p.PatternExpr.Patterns() {
+			if _, err := syntax.Parse(pattern, syntax.Perl); err != nil {
+				return err
+			}
+		}
+	}
 
 	if p.PathPatternsAreRegExps {
 		if p.IncludePattern != "" {
@@ -2,6 +2,7 @@
 package main // import "github.com/sourcegraph/sourcegraph/cmd/gitserver"
 
 import (
+	"context"
 	"log"
 	"net"
 	"net/http"
@@ -18,6 +19,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/gitserver/server"
 	"github.com/sourcegraph/sourcegraph/pkg/debugserver"
 	"github.com/sourcegraph/sourcegraph/pkg/env"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/tracer"
 )
 
@@ -26,6 +28,7 @@ const janitorInterval = 24 * time.Hour
 var (
 	reposDir          = env.Get("SRC_REPOS_DIR", "/data/repos", "Root dir containing repos.")
 	runRepoCleanup, _ = strconv.ParseBool(env.Get("SRC_RUN_REPO_CLEANUP", "", "Periodically remove inactive repositories."))
+	hostname          = env.Get("HOSTNAME", "", "Name of this gitserver, used to find its address in SRC_GIT_SERVERS. Repositories assigned to other gitservers are removed once they have cloned them.")
 )
 
 func main() {
@@ -43,6 +46,11 @@ func main() {
 	gitserver := server.Server{
 		ReposDir:                reposDir,
 		DeleteStaleRepositories: runRepoCleanup,
+		Hostname:                hostname,
+		ReplicationFactor:       gitserver.DefaultClient.ReplicationFactor,
+		GitServerAddrs: func() []string {
+			return gitserver.DefaultClient.Addrs(context.Background())
+		},
 	}
 	gitserver.RegisterMetrics()
 
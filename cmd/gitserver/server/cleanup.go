@@ -15,6 +15,7 @@ import (
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -48,13 +49,29 @@ var reposRecloned = prometheus.NewCounter(prometheus.CounterOpts{
 // cleanupRepos walks the repos directory and performs maintenance tasks:
 //
 // 1. Remove corrupt repos.
-// 2. Remove stale lock files.
-// 3. Remove inactive repos on sourcegraph.com
-// 4. Reclone repos after a while. (simulate git gc)
+// 2. Remove repos replicated to other gitservers which have cloned them.
+// 3. Remove stale lock files.
+// 4. Remove inactive repos on sourcegraph.com
+// 5. Reclone repos after a while. (simulate git gc)
 func (s *Server) cleanupRepos() {
 	bCtx, bCancel := s.serverContext()
 	defer bCancel()
 
+	var addrs []string
+	if s.GitServerAddrs != nil {
+		addrs = s.GitServerAddrs()
+	}
+	var selfAddr string
+	for _, addr := range addrs {
+		if hostnameMatch(s.Hostname, addr) {
+			selfAddr = addr
+			break
+		}
+	}
+	if len(addrs) > 0 && selfAddr == "" {
+		log15.Warn("not removing repos assigned to other gitservers: hostname not found in gitserver addresses", "hostname", s.Hostname, "addrs", addrs)
+	}
+
 	maybeRemoveCorrupt := func(gitDir string) (done bool, err error) {
 		// We treat repositories missing HEAD to be corrupt. Both our cloning
 		// and fetching ensure there is a HEAD file.
@@ -71,6 +88,34 @@ func (s *Server) cleanupRepos() {
 		return true, nil
 	}
 
+	maybeRemoveNonOwned := func(gitDir string) (done bool, err error) {
+		repo := protocol.NormalizeRepo(api.RepoName(strings.TrimPrefix(filepath.Dir(gitDir), s.ReposDir+"/")))
+		replicas := gitserver.AddrsForRepo(repo, addrs, s.ReplicationFactor)
+		for _, addr := range replicas {
+			if addr == selfAddr {
+				return false, nil
+			}
+		}
+		owner := replicas[0]
+
+		// We only remove our copy once the gitserver the repo is assigned to
+		// has cloned it, since requests are served from here until then
+		// during a shard migration.
+		ctx, cancel := context.WithTimeout(bCtx, time.Minute)
+		defer cancel()
+		cloned, err := gitserver.DefaultClient.IsRepoClonedAt(ctx, owner, repo)
+		if err != nil || !cloned {
+			return false, err
+		}
+
+		log15.Info("removing repo assigned to another gitserver", "repo", repo, "gitserver", owner)
+		if err := s.removeRepoDirectory(gitDir); err != nil {
+			return true, err
+		}
+		reposRemoved.Inc()
+		return true, nil
+	}
+
 	maybeRemoveInactive := func(gitDir string) (done bool, err error) {
 		// We rewrite the HEAD file whenever we update a repo, and repos are
 		// updated in response to user traffic. Check to see the last time
@@ -123,7 +168,9 @@ func (s *Server) cleanupRepos() {
 			return false, errors.Wrap(err, "failed to get remote URL")
 		}
 
-		if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true, Overwrite: true}); err != nil {
+		// Reclone the same way the repository was cloned.
+		opts := &cloneOptions{Block: true, Overwrite: true, CloneOptions: repoCloneOptions(gitDir)}
+		if _, err := s.cloneRepo(ctx, repo, remoteURL, opts); err != nil {
 			return true, err
 		}
 		reposRecloned.Inc()
@@ -173,13 +220,19 @@ func (s *Server) cleanupRepos() {
 	cleanups := []cleanupFn{
 		// Do some sanity checks on the repository.
 		{"maybe remove corrupt", maybeRemoveCorrupt},
+	}
+	if selfAddr != "" {
+		// Repos are moved when gitservers are added or removed.
+		cleanups = append(cleanups, cleanupFn{"maybe remove non-owned", maybeRemoveNonOwned})
+	}
+	cleanups = append(cleanups, []cleanupFn{
 		// If git is interrupted it can leave lock files lying around. It does
 		// not clean these up, and instead fails commands.
 		{"remove stale locks", removeStaleLocks},
 		// We always want to have the same git attributes file at
 		// info/attributes.
 		{"ensure git attributes", ensureGitAttributes},
-	}
+	}...)
 	if s.DeleteStaleRepositories {
 		// Sourcegraph.com can potentially clone all of github.com, so we
 		// delete repos which have not been used for a period of
@@ -451,3 +504,16 @@ func removeFileOlderThan(path string, maxAge time.Duration) error {
 	}
 	return nil
 }
+
+// hostnameMatch reports whether addr is the address of the host named
+// hostname, i.e. addr is hostname optionally followed by a domain or port.
+func hostnameMatch(hostname, addr string) bool {
+	if hostname == "" || !strings.HasPrefix(addr, hostname) {
+		return false
+	}
+	if addr == hostname {
+		return true
+	}
+	next := addr[len(hostname)]
+	return next == '.' || next == ':'
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"io/ioutil"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path"
@@ -11,6 +12,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 )
 
 const (
@@ -191,6 +195,71 @@ func TestCleanupOldLocks(t *testing.T) {
 	)
 }
 
+func TestCleanupNonOwned(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	otherRoot, otherCleanup := tmpDir(t)
+	defer otherCleanup()
+
+	other := &Server{ReposDir: otherRoot}
+	ts := httptest.NewServer(other.Handler())
+	defer ts.Close()
+	otherAddr := strings.TrimPrefix(ts.URL, "http://")
+	addrs := []string{"gitserver-0.gitserver:3178", otherAddr}
+
+	// Find repos assigned to each gitserver.
+	var owned, moved, pending string
+	for i := 0; owned == "" || moved == "" || pending == ""; i++ {
+		repo := "github.com/foo/repo" + strconv.Itoa(i)
+		switch {
+		case gitserver.AddrForRepo(api.RepoName(repo), addrs) == addrs[0]:
+			if owned == "" {
+				owned = repo
+			}
+		case moved == "":
+			moved = repo
+		case pending == "":
+			pending = repo
+		}
+	}
+	mkFiles(t, root, owned+"/.git/HEAD", moved+"/.git/HEAD", pending+"/.git/HEAD")
+	mkFiles(t, otherRoot, moved+"/.git/HEAD")
+
+	s := &Server{
+		ReposDir:       root,
+		Hostname:       "gitserver-0",
+		GitServerAddrs: func() []string { return addrs },
+	}
+	s.Handler() // Handler as a side-effect sets up Server
+	s.cleanupRepos()
+
+	for repo, wantRemoved := range map[string]bool{owned: false, moved: true, pending: false} {
+		_, err := os.Stat(filepath.Join(root, repo, ".git", "HEAD"))
+		if removed := os.IsNotExist(err); removed != wantRemoved {
+			t.Errorf("%s: got removed %v, want %v", repo, removed, wantRemoved)
+		}
+	}
+}
+
+func TestHostnameMatch(t *testing.T) {
+	cases := []struct {
+		hostname, addr string
+		want           bool
+	}{
+		{"gitserver-1", "gitserver-1", true},
+		{"gitserver-1", "gitserver-1:3178", true},
+		{"gitserver-1", "gitserver-1.gitserver:3178", true},
+		{"gitserver-1", "gitserver-10:3178", false},
+		{"gitserver-1", "gitserver-2:3178", false},
+		{"", "gitserver-1:3178", false},
+	}
+	for _, c := range cases {
+		if got := hostnameMatch(c.hostname, c.addr); got != c.want {
+			t.Errorf("hostnameMatch(%q, %q) = %v, want %v", c.hostname, c.addr, got, c.want)
+		}
+	}
+}
+
 func TestSetupAndClearTmp(t *testing.T) {
 	root, cleanup := tmpDir(t)
 	defer cleanup()
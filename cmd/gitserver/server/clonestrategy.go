@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Shallow and partial clones
+//
+// Repositories can be configured (per repository, in the external service
+// configuration) to be cloned shallowly (git clone --depth) or as a partial
+// clone (git clone --filter) instead of as a full mirror. Large repositories
+// clone much faster and use much less disk that way.
+//
+// The options a repository was cloned with are recorded in its Git config, so
+// that fetches and reclones keep using them without needing the caller to pass
+// them again. Git records the filter of a partial clone itself
+// (remote.origin.partialclonefilter), and fetches missing objects from the
+// origin remote on demand. Git has no such fallback for missing history, so we
+// record the depth (sourcegraph.clonedepth) and fetch the full history of a
+// shallow clone the first time a command fails because it needs older commits
+// (see missingHistory and unshallow). Commands that succeed within the shallow
+// history (such as git log -n 1 for the commit of a page view) don't unshallow
+// the repository.
+
+// cloneDepthConfigKey is the Git config key that records the depth a
+// repository was cloned with.
+const cloneDepthConfigKey = "sourcegraph.clonedepth"
+
+// fetchRefSpecs are the refs that are fetched when updating a repository.
+var fetchRefSpecs = []string{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*"}
+
+// cloneArgs returns the additional git clone arguments for opts.
+func cloneArgs(opts *protocol.CloneOptions) []string {
+	args := fetchArgs(opts)
+	if opts != nil && opts.Depth > 0 {
+		// --depth implies --single-branch, but we mirror all branches.
+		args = append(args, "--no-single-branch")
+	}
+	return args
+}
+
+// fetchArgs returns the additional git fetch arguments for a repository
+// cloned with opts.
+func fetchArgs(opts *protocol.CloneOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	var args []string
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+	return args
+}
+
+// setCloneOptions records the options the repository in dir was cloned with.
+func setCloneOptions(dir string, opts *protocol.CloneOptions) error {
+	if opts == nil || opts.Depth == 0 {
+		return nil
+	}
+	cmd := exec.Command("git", "config", cloneDepthConfigKey, strconv.Itoa(opts.Depth))
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to record clone depth: %s", out)
+	}
+	return nil
+}
+
+// repoCloneOptions returns the options that the repository in dir was cloned
+// with and that still apply to it, or nil if it is a full clone.
+func repoCloneOptions(dir string) *protocol.CloneOptions {
+	var opts protocol.CloneOptions
+	if repoShallow(dir) {
+		opts.Depth, _ = strconv.Atoi(gitConfigGet(dir, cloneDepthConfigKey))
+	}
+	opts.Filter = gitConfigGet(dir, "remote.origin.partialclonefilter")
+	if opts.Depth == 0 && opts.Filter == "" {
+		return nil
+	}
+	return &opts
+}
+
+// repoShallow reports whether the repository in dir (its working directory or
+// its .git directory) is a shallow clone. Git records the boundary commits of
+// a shallow clone in the shallow file of the .git directory, so checking for
+// it is cheap enough to do on every exec.
+func repoShallow(dir string) bool {
+	for _, name := range []string{filepath.Join(dir, "shallow"), filepath.Join(dir, ".git", "shallow")} {
+		if _, err := os.Stat(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// missingHistoryErrors are parts of the error messages of git commands that
+// fail because they refer to commits that are not in the repository.
+var missingHistoryErrors = []string{
+	"bad object",
+	"bad revision",
+	"Not a valid object name",
+	"unknown revision",
+	"Invalid revision range",
+	"no such commit",
+}
+
+// missingHistory reports whether a git command with args that exited with
+// exitStatus and stderr in a shallow clone may have failed because it needs
+// commits that are older than the shallow history.
+func missingHistory(args []string, exitStatus int, stderr string) bool {
+	if exitStatus == 0 {
+		return false
+	}
+	if len(args) > 0 && args[0] == "merge-base" && exitStatus == 1 && stderr == "" {
+		// No merge base was found, which may be older than the shallow
+		// history.
+		return true
+	}
+	for _, msg := range missingHistoryErrors {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitConfigGet returns the value of the Git config key in the repository in
+// dir, or "" if it is not set.
+func gitConfigGet(dir, key string) string {
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// unshallow fetches the full history of the repository if it is a shallow
+// clone, so that commands that need older commits work. Subsequent fetches of
+// the repository fetch its full history. It is a no-op for other
+// repositories.
+func (s *Server) unshallow(ctx context.Context, repo api.RepoName, dir string) error {
+	if !repoShallow(dir) {
+		return nil
+	}
+
+	s.repoUpdateLocksMu.Lock()
+	mu := s.repoUpdateLocksFor(repo).mu
+	s.repoUpdateLocksMu.Unlock()
+
+	// Unshallowing can take longer than the request that needs it, so it is
+	// not canceled when ctx is done.
+	done := make(chan struct{})
+	var err error
+	go func() {
+		defer close(done)
+		mu.Lock() // Prevent running in parallel with updates of the repository.
+		defer mu.Unlock()
+
+		if !repoShallow(dir) {
+			return // someone else unshallowed it while we were waiting
+		}
+
+		ctx, cancel := s.serverContext()
+		defer cancel()
+		ctx, cancel2 := context.WithTimeout(ctx, longGitCommandTimeout)
+		defer cancel2()
+
+		log15.Info("fetching full history of shallow clone", "repo", repo)
+		cmd := exec.CommandContext(ctx, "git", append([]string{"fetch", "--unshallow", "origin"}, fetchRefSpecs...)...)
+		cmd.Dir = dir
+		if output, err2 := s.runWithRemoteOpts(ctx, cmd, nil); err2 != nil {
+			err = errors.Wrapf(err2, "failed to fetch full history. Output: %s", string(output))
+			return
+		}
+		cmd = exec.Command("git", "config", "--unset", cloneDepthConfigKey)
+		cmd.Dir = dir
+		if output, err2 := cmd.CombinedOutput(); err2 != nil {
+			log15.Warn("Failed to unset clone depth", "repo", repo, "error", err2, "output", string(output))
+		}
+		repoUnshallowedCounter.Inc()
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// repoUpdateLocksFor returns the locks that serialize the updates of repo.
+// The caller must hold s.repoUpdateLocksMu.
+func (s *Server) repoUpdateLocksFor(repo api.RepoName) *locks {
+	l, ok := s.repoUpdateLocks[repo]
+	if !ok {
+		l = &locks{
+			once: new(sync.Once),
+			mu:   new(sync.Mutex),
+		}
+		s.repoUpdateLocks[repo] = l
+	}
+	return l
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Git LFS
+//
+// Files stored with Git LFS are committed as small pointer files that contain
+// the OID (SHA-256 hash) and size of the actual contents, which are stored on
+// an LFS server. When enabled in the site configuration, gitserver fetches
+// LFS objects on demand (with the LFS batch API) so that clients can
+// substitute them for the pointer files. Fetched objects are stored in the
+// repository's GIT_DIR (at the same location git-lfs uses) and are removed
+// with the clone.
+
+// lfsNotAvailableError is returned when a Git LFS object can't be fetched
+// because the LFS server doesn't have it or because the repository's LFS
+// endpoint is not supported. Clients use the pointer file instead.
+type lfsNotAvailableError struct {
+	reason string
+}
+
+func (e *lfsNotAvailableError) Error() string {
+	return "Git LFS object not available: " + e.reason
+}
+
+func (s *Server) handleLFSObject(w http.ResponseWriter, r *http.Request) {
+	var req protocol.LFSObjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !gitserver.IsLFSOID(req.OID) {
+		http.Error(w, "invalid Git LFS object ID", http.StatusBadRequest)
+		return
+	}
+	if !conf.GitLFSEnabled() || req.Size > conf.GitLFSMaxFileSize() {
+		http.Error(w, "Git LFS object not available", http.StatusNotFound)
+		return
+	}
+
+	dir := path.Join(s.ReposDir, string(protocol.NormalizeRepo(req.Repo)))
+	if !repoCloned(dir) {
+		http.Error(w, "repository not cloned", http.StatusNotFound)
+		return
+	}
+
+	f, err := s.openLFSObject(r.Context(), dir, req.OID, req.Size)
+	if err != nil {
+		if _, ok := err.(*lfsNotAvailableError); ok {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log15.Warn("Failed to fetch Git LFS object", "repo", req.Repo, "oid", req.OID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if fi, err := f.Stat(); err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	}
+	_, _ = io.Copy(w, f)
+}
+
+// openLFSObject opens the Git LFS object of the repository in dir, fetching
+// it from the repository's LFS endpoint first if it isn't stored yet.
+func (s *Server) openLFSObject(ctx context.Context, dir, oid string, size int64) (*os.File, error) {
+	gitDir := filepath.Join(dir, ".git")
+	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
+		gitDir = dir
+	}
+	objectPath := filepath.Join(gitDir, "lfs", "objects", oid[:2], oid[2:4], oid)
+	if f, err := os.Open(objectPath); !os.IsNotExist(err) {
+		return f, err
+	}
+
+	endpoint, err := lfsEndpoint(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	href, header, err := lfsDownloadAction(ctx, endpoint, oid, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Download to a temporary file first, so that partial or corrupt
+	// downloads are never stored.
+	tmpDir, err := s.tempDir("lfs-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpPath := filepath.Join(tmpDir, oid)
+	if err := lfsDownload(ctx, href, header, endpoint, tmpPath, oid, size); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, objectPath); err != nil {
+		return nil, err
+	}
+	return os.Open(objectPath)
+}
+
+// lfsEndpoint returns the URL of the Git LFS server of the repository in dir,
+// the way git-lfs determines it: the URL in the site configuration, or else
+// the lfs.url of the repository's .lfsconfig file, or else the repository's
+// remote URL followed by /info/lfs. The credentials of the remote URL are used
+// for endpoints on the same host.
+//
+// 🚨 SECURITY: The .lfsconfig file is controlled by anyone who can push to the
+// repository, so its lfs.url is only used if it is on the host of the remote
+// URL. Otherwise gitserver could be made to send requests to any host that it
+// can reach (such as internal services).
+func lfsEndpoint(ctx context.Context, dir string) (*url.URL, error) {
+	remote, err := repoRemoteURL(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	remoteURL, _ := url.Parse(remote) // nil for scp-like SSH remotes, such as git@example.com:foo/bar
+
+	var rawurl string
+	if lfs := conf.Get().GitLfs; lfs != nil && lfs.Url != "" {
+		rawurl = lfs.Url
+	} else {
+		cmd := exec.Command("git", "config", "--blob", "HEAD:.lfsconfig", "--get", "lfs.url")
+		cmd.Dir = dir
+		if out, err := cmd.Output(); err == nil {
+			rawurl = strings.TrimSpace(string(out))
+			if u, err := url.Parse(rawurl); err != nil || remoteURL == nil || !strings.EqualFold(u.Host, remoteURL.Host) {
+				log15.Warn("Ignoring Git LFS endpoint of .lfsconfig that is not on the host of the remote URL.", "dir", dir, "url", rawurl)
+				rawurl = ""
+			}
+		}
+	}
+
+	var endpoint *url.URL
+	if rawurl != "" {
+		endpoint, err = url.Parse(rawurl)
+		if err != nil {
+			return nil, &lfsNotAvailableError{reason: "invalid LFS endpoint URL"}
+		}
+	} else {
+		if remoteURL == nil {
+			return nil, &lfsNotAvailableError{reason: "unsupported Git remote URL"}
+		}
+		u := *remoteURL
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		if !strings.HasSuffix(u.Path, ".git") {
+			u.Path += ".git"
+		}
+		u.Path += "/info/lfs"
+		endpoint = &u
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, &lfsNotAvailableError{reason: fmt.Sprintf("unsupported LFS endpoint URL scheme %q", endpoint.Scheme)}
+	}
+
+	if endpoint.User == nil && remoteURL != nil && strings.EqualFold(endpoint.Host, remoteURL.Host) {
+		endpoint.User = remoteURL.User
+	}
+	return endpoint, nil
+}
+
+// lfsDownloadAction requests the download action of a Git LFS object from the
+// batch API of the LFS server at endpoint. See
+// https://github.com/git-lfs/git-lfs/blob/master/docs/api/batch.md.
+func lfsDownloadAction(ctx context.Context, endpoint *url.URL, oid string, size int64) (href string, header map[string]string, err error) {
+	type object struct {
+		OID  string `json:"oid"`
+		Size int64  `json:"size"`
+	}
+	body, err := json.Marshal(struct {
+		Operation string   `json:"operation"`
+		Transfers []string `json:"transfers"`
+		Objects   []object `json:"objects"`
+	}{
+		Operation: "download",
+		Transfers: []string{"basic"},
+		Objects:   []object{{OID: oid, Size: size}},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	req, err := http.NewRequest("POST", lfsURL(endpoint)+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	setBasicAuth(req, endpoint)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", nil, errors.Wrap(err, "LFS batch request failed")
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", nil, &lfsNotAvailableError{reason: "LFS endpoint not found"}
+	default:
+		return "", nil, fmt.Errorf("LFS batch request failed: http status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Objects []struct {
+			OID     string `json:"oid"`
+			Actions struct {
+				Download *struct {
+					Href   string            `json:"href"`
+					Header map[string]string `json:"header"`
+				} `json:"download"`
+			} `json:"actions"`
+			Error *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"objects"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", nil, errors.Wrap(err, "invalid LFS batch response")
+	}
+	for _, o := range result.Objects {
+		if o.OID != oid {
+			continue
+		}
+		if o.Error != nil {
+			if o.Error.Code == http.StatusNotFound || o.Error.Code == http.StatusGone {
+				return "", nil, &lfsNotAvailableError{reason: o.Error.Message}
+			}
+			return "", nil, fmt.Errorf("LFS batch request failed: %d %s", o.Error.Code, o.Error.Message)
+		}
+		if o.Actions.Download == nil {
+			break
+		}
+		return o.Actions.Download.Href, o.Actions.Download.Header, nil
+	}
+	return "", nil, &lfsNotAvailableError{reason: "no download action for object"}
+}
+
+// lfsDownload downloads the Git LFS object at href to dst, and verifies its
+// size and hash.
+func lfsDownload(ctx context.Context, href string, header map[string]string, endpoint *url.URL, dst, oid string, size int64) error {
+	req, err := http.NewRequest("GET", href, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if req.Header.Get("Authorization") == "" && strings.EqualFold(req.URL.Host, endpoint.Host) {
+		setBasicAuth(req, endpoint)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "LFS download failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("LFS download failed: http status %d", resp.StatusCode)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(resp.Body, size+1))
+	if err != nil {
+		return errors.Wrap(err, "LFS download failed")
+	}
+	if n != size || hex.EncodeToString(h.Sum(nil)) != oid {
+		return fmt.Errorf("LFS download failed: downloaded object doesn't match OID %s and size %d", oid, size)
+	}
+	return f.Close()
+}
+
+// lfsURL returns endpoint without its credentials.
+func lfsURL(endpoint *url.URL) string {
+	u := *endpoint
+	u.User = nil
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+// setBasicAuth sets the credentials of endpoint (if any) on req.
+func setBasicAuth(req *http.Request, endpoint *url.URL) {
+	if endpoint.User == nil {
+		return
+	}
+	password, _ := endpoint.User.Password()
+	req.SetBasicAuth(endpoint.User.Username(), password)
+}
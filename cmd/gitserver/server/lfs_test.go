@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestServer_handleLFSObject(t *testing.T) {
+	content := []byte("the real content")
+	sum := sha256.Sum256(content)
+	oid := hex.EncodeToString(sum[:])
+	corruptOID := strings.Repeat("a", 64)
+	missingOID := strings.Repeat("b", 64)
+
+	var downloads int32
+	lfs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "u" || pass != "p" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/foo/bar.git/info/lfs/objects/batch":
+			var req struct {
+				Objects []struct {
+					OID  string `json:"oid"`
+					Size int64  `json:"size"`
+				} `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			o := req.Objects[0]
+			if o.OID == missingOID {
+				fmt.Fprintf(w, `{"objects":[{"oid":%q,"size":%d,"error":{"code":404,"message":"Object does not exist"}}]}`, o.OID, o.Size)
+				return
+			}
+			fmt.Fprintf(w, `{"objects":[{"oid":%q,"size":%d,"actions":{"download":{"href":"http://%s/objects/%s"}}}]}`, o.OID, o.Size, r.Host, o.OID)
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/objects/"):
+			atomic.AddInt32(&downloads, 1)
+			w.Write(content)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer lfs.Close()
+
+	reposDir, cleanup := tmpDir(t)
+	defer cleanup()
+	repoDir := filepath.Join(reposDir, "example.com/foo/bar")
+	if err := os.MkdirAll(repoDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	remote := strings.Replace(lfs.URL, "http://", "http://u:p@", 1) + "/foo/bar"
+	for _, args := range [][]string{{"init"}, {"remote", "add", "origin", remote}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %s (%s)", args, err, out)
+		}
+	}
+
+	s := &Server{ReposDir: reposDir}
+	h := s.Handler()
+
+	getLFSObject := func(t *testing.T, oid string, size int64) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(protocol.LFSObjectRequest{Repo: "example.com/foo/bar", OID: oid, Size: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("POST", "/lfs-object", bytes.NewReader(body)))
+		return rr
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		conf.Mock(&conf.Unified{})
+		defer conf.Mock(nil)
+		if rr := getLFSObject(t, oid, int64(len(content))); rr.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{GitLfs: &schema.GitLFS{Enabled: true, MaxFileSize: 100}}})
+	defer conf.Mock(nil)
+
+	t.Run("fetch", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			rr := getLFSObject(t, oid, int64(len(content)))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body)
+			}
+			if !bytes.Equal(rr.Body.Bytes(), content) {
+				t.Errorf("got content %q, want %q", rr.Body, content)
+			}
+		}
+		if downloads != 1 {
+			t.Errorf("got %d downloads, want the object to be stored after the first download", downloads)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		if rr := getLFSObject(t, oid, 101); rr.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if rr := getLFSObject(t, missingOID, 1); rr.Code != http.StatusNotFound {
+			t.Errorf("got status %d, want %d", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		if rr := getLFSObject(t, corruptOID, int64(len(content))); rr.Code != http.StatusBadGateway {
+			t.Errorf("got status %d, want %d", rr.Code, http.StatusBadGateway)
+		}
+		if _, err := os.Stat(filepath.Join(repoDir, ".git/lfs/objects/aa/aa", corruptOID)); !os.IsNotExist(err) {
+			t.Errorf("corrupt object was stored: %v", err)
+		}
+	})
+
+	t.Run("invalid oid", func(t *testing.T) {
+		if rr := getLFSObject(t, "../../HEAD", 1); rr.Code != http.StatusBadRequest {
+			t.Errorf("got status %d, want %d", rr.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestLFSEndpoint_lfsconfig(t *testing.T) {
+	conf.Mock(&conf.Unified{})
+	defer conf.Mock(nil)
+
+	tests := map[string]struct {
+		lfsURL string
+		want   string
+	}{
+		"same host": {
+			lfsURL: "https://example.com/custom/lfs",
+			want:   "https://u:p@example.com/custom/lfs",
+		},
+		"other host": {
+			lfsURL: "http://169.254.169.254/latest",
+			want:   "https://u:p@example.com/foo/bar.git/info/lfs",
+		},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			dir, cleanup := tmpDir(t)
+			defer cleanup()
+			if err := ioutil.WriteFile(filepath.Join(dir, ".lfsconfig"), []byte("[lfs]\n\turl = "+test.lfsURL+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			for _, args := range [][]string{
+				{"init"},
+				{"remote", "add", "origin", "https://u:p@example.com/foo/bar"},
+				{"add", ".lfsconfig"},
+				{"-c", "user.name=a", "-c", "user.email=a@a.com", "commit", "-m", "lfsconfig"},
+			} {
+				cmd := exec.Command("git", args...)
+				cmd.Dir = dir
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %v failed: %s (%s)", args, err, out)
+				}
+			}
+
+			endpoint, err := lfsEndpoint(context.Background(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if endpoint.String() != test.want {
+				t.Errorf("got endpoint %q, want %q", endpoint, test.want)
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/env"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/pkg/honey"
 	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
@@ -112,6 +113,24 @@ type Server struct {
 	// Janitor job runs.
 	DeleteStaleRepositories bool
 
+	// Hostname is the name of this gitserver, which is used to find its own
+	// address among the addresses of all gitservers. It is usually the
+	// HOSTNAME of its container, e.g. gitserver-0 for the address
+	// gitserver-0.gitserver:3178.
+	Hostname string
+
+	// GitServerAddrs returns the addresses of all gitservers. If it is set,
+	// the Janitor removes the repositories which are assigned to another
+	// gitserver (see gitserver.AddrForRepo) once that gitserver has cloned
+	// them.
+	GitServerAddrs func() []string
+
+	// ReplicationFactor is the number of gitservers each repository is cloned
+	// to (see gitserver.AddrsForRepo). When this gitserver is the primary of
+	// a repository, it forwards its updates to the other replicas, which
+	// clone it if needed. Values less than 2 disable replication.
+	ReplicationFactor int
+
 	// skipCloneForTests is set by tests to avoid clones.
 	skipCloneForTests bool
 
@@ -229,6 +248,7 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/upload-pack", s.handleUploadPack)
 	mux.HandleFunc("/getGitolitePhabricatorMetadata", s.handleGetGitolitePhabricatorMetadata)
 	mux.HandleFunc("/create-commit-from-patch", s.handleCreateCommitFromPatch)
+	mux.HandleFunc("/lfs-object", s.handleLFSObject)
 	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -401,7 +421,7 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 		// optimistically, we assume that our cloning attempt might
 		// succeed.
 		resp.CloneInProgress = true
-		_, err := s.cloneRepo(ctx, req.Repo, req.URL, nil)
+		_, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{CloneOptions: req.CloneOptions})
 		if err != nil {
 			log15.Warn("error cloning repo", "repo", req.Repo, "err", err)
 			resp.Error = err.Error()
@@ -569,7 +589,7 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 			_ = json.NewEncoder(w).Encode(&protocol.NotFoundPayload{CloneInProgress: false})
 			return
 		}
-		cloneProgress, err := s.cloneRepo(ctx, req.Repo, req.URL, nil)
+		cloneProgress, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{CloneOptions: req.CloneOptions})
 		if err != nil {
 			log15.Debug("error cloning repo", "repo", req.Repo, "err", err)
 			status = "repo-not-found"
@@ -616,13 +636,27 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 	stderrW := &writeCounter{w: &stderrBuf}
 
 	cmdStart = time.Now()
-	cmd := exec.CommandContext(ctx, "git", req.Args...)
-	cmd.Dir = dir
-	cmd.Stdout = stdoutW
-	cmd.Stderr = stderrW
-
 	var err error
-	exitStatus, err = runCommand(ctx, cmd)
+	run := func() {
+		cmd := exec.CommandContext(ctx, "git", req.Args...)
+		cmd.Dir = dir
+		cmd.Stdout = stdoutW
+		cmd.Stderr = stderrW
+		exitStatus, err = runCommand(ctx, cmd)
+	}
+	run()
+	if stdoutW.n == 0 && missingHistory(req.Args, exitStatus, stderrBuf.String()) && repoShallow(dir) {
+		// The command may need commits that are older than the history of
+		// the shallow clone. Fetch the full history and run it again (it
+		// hasn't written any output yet).
+		if err := s.unshallow(ctx, req.Repo, dir); err != nil {
+			log15.Warn("Failed to fetch full history of shallow clone", "repo", req.Repo, "error", err)
+		} else {
+			stderrBuf.Reset()
+			stderrW.n = 0
+			run()
+		}
+	}
 	if err != nil {
 		errStr = err.Error()
 	}
@@ -674,6 +708,10 @@ type cloneOptions struct {
 
 	// Overwrite will overwrite the existing clone.
 	Overwrite bool
+
+	// CloneOptions configures a shallow or partial clone. If nil, the
+	// repository is fully cloned.
+	CloneOptions *protocol.CloneOptions
 }
 
 // cloneRepo issues a git clone command for the given repo. It is
@@ -751,7 +789,12 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		defer os.RemoveAll(tmpPath)
 		tmpPath = filepath.Join(tmpPath, ".git")
 
-		cmd := exec.CommandContext(ctx, "git", "clone", "--mirror", "--progress", url, tmpPath)
+		var gitOpts *protocol.CloneOptions
+		if opts != nil {
+			gitOpts = opts.CloneOptions
+		}
+		args := append([]string{"clone", "--mirror", "--progress"}, cloneArgs(gitOpts)...)
+		cmd := exec.CommandContext(ctx, "git", append(args, url, tmpPath)...)
 		log15.Info("cloning repo", "repo", repo, "tmp", tmpPath, "dst", dstPath)
 
 		pr, pw := io.Pipe()
@@ -772,6 +815,10 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 			return err
 		}
 
+		if err := setCloneOptions(tmpPath, gitOpts); err != nil {
+			return err
+		}
+
 		if overwrite {
 			// remove the current repo by putting it into our temporary directory
 			err := os.Rename(dstPath, filepath.Join(filepath.Dir(tmpPath), "old"))
@@ -960,6 +1007,12 @@ var (
 		Name:      "repo_cloned",
 		Help:      "number of successful git clones run",
 	})
+	repoUnshallowedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repo_unshallowed",
+		Help:      "number of shallow clones whose full history was fetched on demand",
+	})
 )
 
 func init() {
@@ -968,6 +1021,7 @@ func init() {
 	prometheus.MustRegister(cloneQueue)
 	prometheus.MustRegister(lsRemoteQueue)
 	prometheus.MustRegister(repoClonedCounter)
+	prometheus.MustRegister(repoUnshallowedCounter)
 }
 
 var headBranchPattern = regexp.MustCompile(`HEAD branch: (.+?)\n`)
@@ -979,14 +1033,7 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	defer span.Finish()
 
 	s.repoUpdateLocksMu.Lock()
-	l, ok := s.repoUpdateLocks[repo]
-	if !ok {
-		l = &locks{
-			once: new(sync.Once),
-			mu:   new(sync.Mutex),
-		}
-		s.repoUpdateLocks[repo] = l
-	}
+	l := s.repoUpdateLocksFor(repo)
 	once := l.once
 	mu := l.mu
 	s.repoUpdateLocksMu.Unlock()
@@ -1007,6 +1054,9 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 			s.repoUpdateLocksMu.Unlock()
 
 			err = s.doRepoUpdate2(repo, url)
+			if err == nil {
+				s.updateReplicas(repo, url)
+			}
 		})
 	}()
 
@@ -1019,6 +1069,38 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	}
 }
 
+// updateReplicas asks the other replicas of repo to update it (or clone it
+// if they haven't yet) if this gitserver is its primary. It doesn't wait for
+// the updates.
+func (s *Server) updateReplicas(repo api.RepoName, url string) {
+	if s.ReplicationFactor < 2 || s.GitServerAddrs == nil {
+		return
+	}
+	replicas := gitserver.AddrsForRepo(protocol.NormalizeRepo(repo), s.GitServerAddrs(), s.ReplicationFactor)
+	if len(replicas) < 2 || !hostnameMatch(s.Hostname, replicas[0]) {
+		// Only the primary forwards updates, so that replicas don't forward
+		// them back.
+		return
+	}
+	// Replicas that haven't cloned repo yet clone it the same way.
+	cloneOpts := repoCloneOptions(path.Join(s.ReposDir, string(protocol.NormalizeRepo(repo))))
+	for _, addr := range replicas[1:] {
+		ctx, cancel := s.serverContext()
+		go func(addr string) {
+			defer cancel()
+			ctx, cancel := context.WithTimeout(ctx, longGitCommandTimeout)
+			defer cancel()
+			resp, err := gitserver.DefaultClient.RequestRepoUpdateAt(ctx, addr, gitserver.Repo{Name: repo, URL: url, CloneOptions: cloneOpts}, 0)
+			if err == nil && resp.Error != "" {
+				err = errors.New(resp.Error)
+			}
+			if err != nil {
+				log15.Warn("failed to update replica", "repo", repo, "replica", addr, "error", err)
+			}
+		}(addr)
+	}
+}
+
 // setLastChanged discerns an approximate last-changed timestamp for a
 // repository. This can be approximate; it's used to determine how often we
 // should run `git fetch`, but is not relied on strongly. The basic plan
@@ -1197,7 +1279,16 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 		}
 	}
 
-	cmd := exec.CommandContext(ctx, "git", "fetch", "--prune", url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*")
+	// Keep shallow and partial clones shallow and partial. Only the origin
+	// remote (whose URL we just set) can be used to fetch into a partial
+	// clone.
+	remote := url
+	cloneOpts := repoCloneOptions(dir)
+	if cloneOpts != nil && cloneOpts.Filter != "" {
+		remote = "origin"
+	}
+	args := append(append([]string{"fetch", "--prune"}, fetchArgs(cloneOpts)...), remote)
+	cmd := exec.CommandContext(ctx, "git", append(args, fetchRefSpecs...)...)
 	cmd.Dir = dir
 
 	// drop temporary pack files after a fetch. this function won't
@@ -1279,6 +1370,17 @@ func (s *Server) ensureRevision(ctx context.Context, repo api.RepoName, url, rev
 	}
 	// Revision not found, update before returning.
 	s.doRepoUpdate(ctx, repo, url)
+
+	// The revision may be older than the history of a shallow clone.
+	if repoShallow(repoDir) {
+		cmd = exec.Command("git", "rev-parse", rev, "--")
+		cmd.Dir = repoDir
+		if err := cmd.Run(); err != nil {
+			if err := s.unshallow(ctx, repo, repoDir); err != nil {
+				log15.Warn("Failed to fetch full history of shallow clone", "repo", repo, "error", err)
+			}
+		}
+	}
 	return true
 }
 
@@ -16,6 +16,8 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
 )
 
@@ -388,3 +390,96 @@ func TestCloneRepo(t *testing.T) {
 		t.Fatal("failed to clone")
 	}
 }
+
+func TestCloneRepo_shallow(t *testing.T) {
+	remote, cleanup1 := tmpDir(t)
+	defer cleanup1()
+
+	repo := remote
+	cmd := func(name string, arg ...string) string {
+		t.Helper()
+		c := exec.Command(name, arg...)
+		c.Dir = repo
+		c.Env = []string{
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		}
+		b, err := c.Output()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s", name, strings.Join(arg, " "), err)
+		}
+		return strings.TrimSpace(string(b))
+	}
+
+	// Setup a repo with a few commits so we can see how much history is cloned.
+	cmd("git", "init", ".")
+	for _, msg := range []string{"a", "b", "c"} {
+		cmd("sh", "-c", "echo "+msg+" > hello.txt")
+		cmd("git", "add", "hello.txt")
+		cmd("git", "commit", "-m", msg)
+	}
+
+	reposDir, cleanup2 := tmpDir(t)
+	defer cleanup2()
+
+	s := &Server{
+		ReposDir:         reposDir,
+		ctx:              context.Background(),
+		locker:           &RepositoryLocker{},
+		cloneLimiter:     mutablelimiter.New(1),
+		cloneableLimiter: mutablelimiter.New(1),
+		repoUpdateLocks:  make(map[api.RepoName]*locks),
+	}
+	// Shallow clones of local repositories require a file:// URL.
+	opts := &cloneOptions{Block: true, CloneOptions: &protocol.CloneOptions{Depth: 1}}
+	if _, err := s.cloneRepo(context.Background(), "example.com/foo/bar", "file://"+remote, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(s.ReposDir, "example.com/foo/bar")
+	repo = dst
+	if got := cmd("git", "rev-list", "--count", "HEAD"); got != "1" {
+		t.Fatalf("got %s commits in shallow clone, want 1", got)
+	}
+	if !repoShallow(dst) {
+		t.Fatal("got repoShallow false, want true")
+	}
+	if got, want := repoCloneOptions(dst), (&protocol.CloneOptions{Depth: 1}); got == nil || *got != *want {
+		t.Fatalf("got clone options %+v, want %+v", got, want)
+	}
+
+	if err := s.unshallow(context.Background(), "example.com/foo/bar", dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := cmd("git", "rev-list", "--count", "HEAD"); got != "3" {
+		t.Fatalf("got %s commits after unshallowing, want 3", got)
+	}
+	if got := repoCloneOptions(dst); got != nil {
+		t.Fatalf("got clone options %+v after unshallowing, want nil", got)
+	}
+	if repoShallow(dst) {
+		t.Fatal("got repoShallow true after unshallowing, want false")
+	}
+}
+
+func TestMissingHistory(t *testing.T) {
+	tests := []struct {
+		args       []string
+		exitStatus int
+		stderr     string
+		want       bool
+	}{
+		{args: []string{"log", "-n", "1", "HEAD"}, exitStatus: 0, want: false},
+		{args: []string{"log", "deadbeef"}, exitStatus: 128, stderr: "fatal: bad object deadbeef", want: true},
+		{args: []string{"rev-parse", "v1.0"}, exitStatus: 128, stderr: "fatal: ambiguous argument 'v1.0': unknown revision or path not in the working tree.", want: true},
+		{args: []string{"merge-base", "a", "b"}, exitStatus: 1, want: true},
+		{args: []string{"show", "HEAD:missing.txt"}, exitStatus: 128, stderr: "fatal: path 'missing.txt' does not exist in 'HEAD'", want: false},
+	}
+	for _, test := range tests {
+		if got := missingHistory(test.args, test.exitStatus, test.stderr); got != test.want {
+			t.Errorf("%v (exit status %d, stderr %q): got %v, want %v", test.args, test.exitStatus, test.stderr, got, test.want)
+		}
+	}
+}
@@ -85,6 +85,9 @@ func main() {
 	// Repos purging thread
 	go repos.RunRepositoryPurgeWorker(ctx)
 
+	// Repository permissions syncing thread
+	go repos.RunPermissionsSyncWorker(ctx)
+
 	// GitHub connections and repos syncing threads
 	go repos.SyncGitHubConnections(ctx)
 	go repos.RunGitHubRepositorySyncWorker(ctx)
@@ -107,6 +110,14 @@ func main() {
 	go repos.SyncBitbucketServerConnections(ctx)
 	go repos.RunBitbucketServerRepositorySyncWorker(ctx)
 
+	// Bitbucket Cloud connections and repos syncing threads
+	go repos.SyncBitbucketCloudConnections(ctx)
+	go repos.RunBitbucketCloudRepositorySyncWorker(ctx)
+
+	// Gitea connections and repos syncing threads
+	go repos.SyncGiteaConnections(ctx)
+	go repos.RunGiteaRepositorySyncWorker(ctx)
+
 	// Start other repos syncer syncing thread
 	go func() { log.Fatal(syncer.Run(ctx, repos.GetUpdateInterval())) }()
 
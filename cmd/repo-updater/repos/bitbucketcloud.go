@@ -0,0 +1,342 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+var bitbucketCloudConnections = func() *atomicvalue.Value {
+	c := atomicvalue.New()
+	c.Set(func() interface{} {
+		return []*bitbucketCloudConnection{}
+	})
+	return c
+}()
+
+// SyncBitbucketCloudConnections periodically syncs connections from
+// the Frontend API.
+func SyncBitbucketCloudConnections(ctx context.Context) {
+	t := time.NewTicker(configWatchInterval)
+	var lastConfig []*schema.BitbucketCloudConnection
+	for range t.C {
+		config, err := conf.BitbucketCloudConfigs(ctx)
+		if err != nil {
+			log15.Error("unable to fetch Bitbucket Cloud configs", "err", err)
+			continue
+		}
+
+		if reflect.DeepEqual(config, lastConfig) {
+			continue
+		}
+		lastConfig = config
+
+		var conns []*bitbucketCloudConnection
+		for _, c := range config {
+			conn, err := newBitbucketCloudConnection(c)
+			if err != nil {
+				log15.Error("Error processing configured Bitbucket Cloud connection. Skipping it.", "url", c.Url, "error", err)
+				continue
+			}
+			conns = append(conns, conn)
+		}
+
+		bitbucketCloudConnections.Set(func() interface{} {
+			return conns
+		})
+
+		bitbucketCloudRepositorySyncWorker.restart()
+	}
+}
+
+// getBitbucketCloudConnection returns the Bitbucket Cloud connection (config + API client) that is
+// responsible for the repository specified by the args.
+func getBitbucketCloudConnection(args protocol.RepoLookupArgs) (*bitbucketCloudConnection, error) {
+	conns := bitbucketCloudConnections.Get().([]*bitbucketCloudConnection)
+
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == bitbucketcloud.ServiceType {
+		// Look up by external repository spec.
+		for _, conn := range conns {
+			if args.ExternalRepo.ServiceID == conn.baseURL.String() {
+				return conn, nil
+			}
+		}
+		return nil, errors.Errorf("no configured Bitbucket Cloud connection with URL: %q", args.ExternalRepo.ServiceID)
+	}
+
+	if args.Repo != "" {
+		// Look up by repository name.
+		repo := strings.ToLower(string(args.Repo))
+		for _, conn := range conns {
+			if strings.HasPrefix(repo, conn.baseURL.Hostname()+"/") {
+				return conn, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// GetBitbucketCloudRepository queries a configured Bitbucket Cloud connection for information
+// about the specified repository.
+//
+// If args.Repo refers to a repository that is not known to be on a configured Bitbucket Cloud
+// connection's host, it returns authoritative == false.
+func GetBitbucketCloudRepository(ctx context.Context, args protocol.RepoLookupArgs) (repo *protocol.RepoInfo, authoritative bool, err error) {
+	conn, err := getBitbucketCloudConnection(args)
+	if err != nil {
+		return nil, true, err // refers to a Bitbucket Cloud repo but the host is not configured
+	}
+	if conn == nil {
+		return nil, false, nil // refers to a non-Bitbucket Cloud repo
+	}
+
+	// The Bitbucket Cloud API can only look up repositories by name (not by UUID), so repositories
+	// specified only by their external repository spec can't be looked up.
+	if args.Repo != "" {
+		// Look up by repository name. Expect {host}/{workspace}/{slug}.
+		nameWithOwner := strings.TrimPrefix(strings.ToLower(string(args.Repo)), conn.baseURL.Hostname()+"/")
+		i := strings.Index(nameWithOwner, "/")
+		if i < 0 || i == len(nameWithOwner)-1 {
+			return nil, true, errors.Errorf("malformed Bitbucket Cloud repo name: %q", args.Repo)
+		}
+		r, err := conn.client.Repo(ctx, nameWithOwner[:i], nameWithOwner[i+1:])
+		if err != nil {
+			return nil, true, err
+		}
+		return bitbucketCloudRepoInfo(conn, r), true, nil
+	}
+
+	return nil, true, fmt.Errorf("unable to look up Bitbucket Cloud repository (%+v)", args)
+}
+
+func bitbucketCloudRepoInfo(conn *bitbucketCloudConnection, repo *bitbucketcloud.Repo) *protocol.RepoInfo {
+	htmlURL := repo.Links.HTML.Href
+	return &protocol.RepoInfo{
+		Name:         reposource.BitbucketCloudRepoName(conn.config.RepositoryPathPattern, conn.baseURL.Hostname(), repo.FullName),
+		ExternalRepo: bitbucketcloud.ExternalRepoSpec(repo, *conn.baseURL),
+		Description:  repo.Description,
+		Fork:         repo.Parent != nil,
+		VCS: protocol.VCSInfo{
+			URL: conn.authenticatedRemoteURL(repo),
+		},
+		Links: &protocol.RepoLinks{
+			Root:   htmlURL,
+			Tree:   htmlURL + "/src/{rev}/{path}",
+			Blob:   htmlURL + "/src/{rev}/{path}",
+			Commit: htmlURL + "/commits/{commit}",
+		},
+	}
+}
+
+var bitbucketCloudRepositorySyncWorker = &worker{
+	work: func(ctx context.Context, shutdown chan struct{}) {
+		for _, c := range bitbucketCloudConnections.Get().([]*bitbucketCloudConnection) {
+			go func(c *bitbucketCloudConnection) {
+				for {
+					updateBitbucketCloudRepos(ctx, c)
+					bitbucketCloudUpdateTime.WithLabelValues(c.baseURL.String()).Set(float64(time.Now().Unix()))
+					select {
+					case <-shutdown:
+						return
+					case <-time.After(GetUpdateInterval()):
+					}
+				}
+			}(c)
+		}
+	},
+}
+
+// RunBitbucketCloudRepositorySyncWorker runs the worker that syncs repositories from configured
+// Bitbucket Cloud connections to Sourcegraph.
+func RunBitbucketCloudRepositorySyncWorker(ctx context.Context) {
+	bitbucketCloudRepositorySyncWorker.start(ctx)
+}
+
+// updateBitbucketCloudRepos ensures that all provided repositories exist in the repository table.
+func updateBitbucketCloudRepos(ctx context.Context, conn *bitbucketCloudConnection) {
+	repoChan := make(chan repoCreateOrUpdateRequest)
+	defer close(repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("bitbucketcloud:%s", conn.config.Username), repoChan)
+	for r := range conn.listAllRepos(ctx) {
+		if r.SCM != "git" {
+			continue // Mercurial repositories are not supported
+		}
+
+		ri := bitbucketCloudRepoInfo(conn, r)
+		repoChan <- repoCreateOrUpdateRequest{
+			RepoCreateOrUpdateRequest: api.RepoCreateOrUpdateRequest{
+				RepoName:     ri.Name,
+				ExternalRepo: ri.ExternalRepo,
+				Description:  ri.Description,
+				Fork:         ri.Fork,
+				Enabled:      conn.config.InitialRepositoryEnablement,
+			},
+			URL: ri.VCS.URL,
+		}
+	}
+}
+
+func newBitbucketCloudConnection(config *schema.BitbucketCloudConnection) (*bitbucketCloudConnection, error) {
+	rawURL := config.Url
+	if rawURL == "" {
+		rawURL = "https://bitbucket.org"
+	}
+	baseURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = NormalizeBaseURL(baseURL)
+
+	transport, err := cachedTransportWithCertTrusted("")
+	if err != nil {
+		return nil, err
+	}
+
+	return &bitbucketCloudConnection{
+		config:  config,
+		baseURL: baseURL,
+		client: bitbucketcloud.NewClient(nil, config.Username, config.AppPassword, &http.Client{
+			Transport: bitbucketcloud.WithRequestCounter(transport),
+		}),
+	}, nil
+}
+
+type bitbucketCloudConnection struct {
+	config  *schema.BitbucketCloudConnection
+	baseURL *url.URL // normalized base URL (with a trailing slash)
+	client  *bitbucketcloud.Client
+}
+
+// authenticatedRemoteURL returns the Bitbucket Cloud repository's Git remote URL. The configured
+// username and app password are inserted in the URL userinfo of private repositories.
+func (c *bitbucketCloudConnection) authenticatedRemoteURL(repo *bitbucketcloud.Repo) string {
+	wantName := "https"
+	if c.config.GitURLType == "ssh" {
+		wantName = "ssh" // SSH authentication must be provided out-of-band
+	}
+
+	var cloneURL string
+	for _, l := range repo.Links.Clone {
+		if l.Name == wantName {
+			cloneURL = l.Href
+			break
+		}
+	}
+	if cloneURL == "" || wantName == "ssh" {
+		return cloneURL
+	}
+
+	u, err := url.Parse(cloneURL)
+	if err != nil {
+		log15.Warn("Error adding authentication to Bitbucket Cloud repository Git remote URL.", "url", cloneURL, "error", err)
+		return cloneURL
+	}
+	// The HTTPS clone URLs returned by the API include the username of the authenticated user,
+	// which is not needed for public repositories.
+	u.User = nil
+	if !repo.IsPrivate {
+		return u.String()
+	}
+	return setUserinfoBestEffort(u.String(), c.config.Username, c.config.AppPassword)
+}
+
+func (c *bitbucketCloudConnection) listAllRepos(ctx context.Context) <-chan *bitbucketcloud.Repo {
+	ch := make(chan *bitbucketcloud.Repo, 100)
+	go func() {
+		defer close(ch)
+
+		workspaces := c.config.Teams
+		if len(workspaces) == 0 {
+			// Sync the repositories of the user and of all the workspaces that the user is a
+			// member of.
+			workspaces = append(workspaces, c.config.Username)
+			for token, retries := (*bitbucketcloud.PageToken)(nil), 0; token.HasMore() && c.waitForRateLimit(ctx); {
+				page, next, err := c.client.Workspaces(ctx, token, "member")
+				if err != nil {
+					if c.retry(err, &retries) {
+						continue
+					}
+					log15.Error("Error listing Bitbucket Cloud workspaces", "username", c.config.Username, "error", err)
+					break
+				}
+				token, retries = next, 0
+				for _, w := range page {
+					if w.Slug != c.config.Username {
+						workspaces = append(workspaces, w.Slug)
+					}
+				}
+			}
+		}
+
+		seen := map[string]bool{}
+		for _, workspace := range workspaces {
+			for token, retries := (*bitbucketcloud.PageToken)(nil), 0; token.HasMore() && c.waitForRateLimit(ctx); {
+				repos, next, err := c.client.Repos(ctx, token, workspace)
+				if err != nil {
+					if c.retry(err, &retries) {
+						continue
+					}
+					log15.Error("Error listing Bitbucket Cloud repositories", "workspace", workspace, "error", err)
+					break
+				}
+				token, retries = next, 0
+				for _, r := range repos {
+					if !seen[r.UUID] {
+						seen[r.UUID] = true
+						ch <- r
+					}
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// bitbucketCloudMaxRetries is the number of times that a request rejected by the rate limit of
+// the Bitbucket Cloud API is retried.
+const bitbucketCloudMaxRetries = 3
+
+// retry reports whether to retry a request that failed with err, because the rate limit of the
+// Bitbucket Cloud API was exceeded and the request was retried fewer than bitbucketCloudMaxRetries
+// times, and if so increments *retries.
+func (c *bitbucketCloudConnection) retry(err error, retries *int) bool {
+	if !bitbucketcloud.IsRateLimitExceeded(err) || *retries >= bitbucketCloudMaxRetries {
+		return false
+	}
+	*retries++
+	log15.Warn("Bitbucket Cloud API rate limit exceeded, retrying.", "retry", *retries)
+	return true
+}
+
+// waitForRateLimit waits before the next request to the Bitbucket Cloud API for as long as the
+// rate limit monitor of the client recommends (usually not at all, unless the rate limit is nearly
+// exhausted or a request was rejected). It returns false if ctx is done before the wait is over.
+func (c *bitbucketCloudConnection) waitForRateLimit(ctx context.Context) bool {
+	wait := c.client.RateLimit.RecommendedWaitForBackgroundOp(1)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -154,7 +154,8 @@ func bitbucketServerRepoInfo(config *schema.BitbucketServerConnection, repo *bit
 		Description: repo.Name,
 		Fork:        repo.Origin != nil,
 		VCS: protocol.VCSInfo{
-			URL: cloneURL,
+			URL:          cloneURL,
+			CloneOptions: cloneOptions(config.CloneStrategies, project+"/"+repo.Slug),
 		},
 		Links: links,
 	}
@@ -281,7 +282,8 @@ func updateBitbucketServerRepos(ctx context.Context, conn *bitbucketServerConnec
 				Fork:         ri.Fork,
 				Enabled:      conn.config.InitialRepositoryEnablement,
 			},
-			URL: ri.VCS.URL,
+			URL:          ri.VCS.URL,
+			CloneOptions: ri.VCS.CloneOptions,
 		}
 	}
 }
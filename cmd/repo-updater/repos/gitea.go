@@ -0,0 +1,335 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/gitea"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+var giteaConnections = func() *atomicvalue.Value {
+	c := atomicvalue.New()
+	c.Set(func() interface{} {
+		return []*giteaConnection{}
+	})
+	return c
+}()
+
+// SyncGiteaConnections periodically syncs connections from
+// the Frontend API.
+func SyncGiteaConnections(ctx context.Context) {
+	t := time.NewTicker(configWatchInterval)
+	var lastConfig []*schema.GiteaConnection
+	for range t.C {
+		config, err := conf.GiteaConfigs(ctx)
+		if err != nil {
+			log15.Error("unable to fetch Gitea configs", "err", err)
+			continue
+		}
+
+		if reflect.DeepEqual(config, lastConfig) {
+			continue
+		}
+		lastConfig = config
+
+		var conns []*giteaConnection
+		for _, c := range config {
+			conn, err := newGiteaConnection(c)
+			if err != nil {
+				log15.Error("Error processing configured Gitea connection. Skipping it.", "url", c.Url, "error", err)
+				continue
+			}
+			conns = append(conns, conn)
+		}
+
+		giteaConnections.Set(func() interface{} {
+			return conns
+		})
+
+		giteaRepositorySyncWorker.restart()
+	}
+}
+
+// getGiteaConnection returns the Gitea connection (config + API client) that is responsible for
+// the repository specified by the args.
+func getGiteaConnection(args protocol.RepoLookupArgs) (*giteaConnection, error) {
+	conns := giteaConnections.Get().([]*giteaConnection)
+
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == gitea.ServiceType {
+		// Look up by external repository spec.
+		for _, conn := range conns {
+			if args.ExternalRepo.ServiceID == conn.baseURL.String() {
+				return conn, nil
+			}
+		}
+		return nil, errors.Errorf("no configured Gitea connection with URL: %q", args.ExternalRepo.ServiceID)
+	}
+
+	if args.Repo != "" {
+		// Look up by repository name.
+		repo := strings.ToLower(string(args.Repo))
+		for _, conn := range conns {
+			if strings.HasPrefix(repo, conn.baseURL.Hostname()+"/") {
+				return conn, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// GetGiteaRepository queries a configured Gitea connection endpoint for information about the
+// specified repository.
+//
+// If args.Repo refers to a repository that is not known to be on a configured Gitea connection's
+// host, it returns authoritative == false.
+func GetGiteaRepository(ctx context.Context, args protocol.RepoLookupArgs) (repo *protocol.RepoInfo, authoritative bool, err error) {
+	conn, err := getGiteaConnection(args)
+	if err != nil {
+		return nil, true, err // refers to a Gitea repo but the host is not configured
+	}
+	if conn == nil {
+		return nil, false, nil // refers to a non-Gitea repo
+	}
+
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == gitea.ServiceType {
+		// Look up by external repository spec.
+		id, err := strconv.ParseInt(args.ExternalRepo.ID, 10, 64)
+		if err != nil {
+			return nil, true, err
+		}
+		r, err := conn.client.RepoByID(ctx, id)
+		if err == nil {
+			return giteaRepoInfo(conn, r), true, nil
+		}
+		// Gogs does not support looking up repositories by ID, so fall back to looking up by
+		// name if possible.
+		if !errcode.IsNotFound(err) || args.Repo == "" {
+			return nil, true, err
+		}
+	}
+
+	if args.Repo != "" {
+		// Look up by repository name. Expect {host}/{owner}/{name}.
+		nameWithOwner := strings.TrimPrefix(strings.ToLower(string(args.Repo)), conn.baseURL.Hostname()+"/")
+		i := strings.Index(nameWithOwner, "/")
+		if i < 0 || i == len(nameWithOwner)-1 {
+			return nil, true, errors.Errorf("malformed Gitea repo name: %q", args.Repo)
+		}
+		r, err := conn.client.Repo(ctx, nameWithOwner[:i], nameWithOwner[i+1:])
+		if err != nil {
+			return nil, true, err
+		}
+		return giteaRepoInfo(conn, r), true, nil
+	}
+
+	return nil, true, fmt.Errorf("unable to look up Gitea repository (%+v)", args)
+}
+
+func giteaRepoInfo(conn *giteaConnection, repo *gitea.Repo) *protocol.RepoInfo {
+	return &protocol.RepoInfo{
+		Name:         reposource.GiteaRepoName(conn.config.RepositoryPathPattern, conn.baseURL.Hostname(), repo.FullName),
+		ExternalRepo: gitea.ExternalRepoSpec(repo, *conn.baseURL),
+		Description:  repo.Description,
+		Fork:         repo.Fork,
+		Archived:     repo.Archived,
+		VCS: protocol.VCSInfo{
+			URL: conn.authenticatedRemoteURL(repo),
+		},
+		Links: &protocol.RepoLinks{
+			Root:   repo.HTMLURL,
+			Tree:   repo.HTMLURL + "/src/{rev}/{path}",
+			Blob:   repo.HTMLURL + "/src/{rev}/{path}",
+			Commit: repo.HTMLURL + "/commit/{commit}",
+		},
+	}
+}
+
+var giteaRepositorySyncWorker = &worker{
+	work: func(ctx context.Context, shutdown chan struct{}) {
+		for _, c := range giteaConnections.Get().([]*giteaConnection) {
+			go func(c *giteaConnection) {
+				for {
+					updateGiteaRepos(ctx, c)
+					giteaUpdateTime.WithLabelValues(c.baseURL.String()).Set(float64(time.Now().Unix()))
+					select {
+					case <-shutdown:
+						return
+					case <-time.After(GetUpdateInterval()):
+					}
+				}
+			}(c)
+		}
+	},
+}
+
+// RunGiteaRepositorySyncWorker runs the worker that syncs repositories from configured Gitea
+// instances to Sourcegraph.
+func RunGiteaRepositorySyncWorker(ctx context.Context) {
+	giteaRepositorySyncWorker.start(ctx)
+}
+
+// updateGiteaRepos ensures that all provided repositories exist in the repository table.
+func updateGiteaRepos(ctx context.Context, conn *giteaConnection) {
+	repoChan := make(chan repoCreateOrUpdateRequest)
+	defer close(repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("gitea:%s", conn.config.Token), repoChan)
+	for r := range conn.listAllRepos(ctx) {
+		if r.Empty {
+			continue // nothing to clone
+		}
+
+		ri := giteaRepoInfo(conn, r)
+		repoChan <- repoCreateOrUpdateRequest{
+			RepoCreateOrUpdateRequest: api.RepoCreateOrUpdateRequest{
+				RepoName:     ri.Name,
+				ExternalRepo: ri.ExternalRepo,
+				Description:  ri.Description,
+				Fork:         ri.Fork,
+				Archived:     ri.Archived,
+				Enabled:      conn.config.InitialRepositoryEnablement,
+			},
+			URL: ri.VCS.URL,
+		}
+	}
+}
+
+func newGiteaConnection(config *schema.GiteaConnection) (*giteaConnection, error) {
+	baseURL, err := url.Parse(config.Url)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = NormalizeBaseURL(baseURL)
+
+	transport, err := cachedTransportWithCertTrusted(config.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &giteaConnection{
+		config:  config,
+		baseURL: baseURL,
+		client: &gitea.Client{
+			URL:   baseURL,
+			Token: config.Token,
+			HTTPClient: &http.Client{
+				Transport: gitea.WithRequestCounter(transport),
+			},
+		},
+	}, nil
+}
+
+type giteaConnection struct {
+	config  *schema.GiteaConnection
+	baseURL *url.URL // normalized base URL (with a trailing slash)
+	client  *gitea.Client
+}
+
+// authenticatedRemoteURL returns the Gitea repository's Git remote URL with the configured Gitea
+// access token inserted in the URL userinfo.
+func (c *giteaConnection) authenticatedRemoteURL(repo *gitea.Repo) string {
+	if c.config.GitURLType == "ssh" {
+		return repo.SSHURL // SSH authentication must be provided out-of-band
+	}
+	if c.config.Token == "" {
+		return repo.CloneURL
+	}
+	u, err := url.Parse(repo.CloneURL)
+	if err != nil {
+		log15.Warn("Error adding authentication to Gitea repository Git remote URL.", "url", repo.CloneURL, "error", err)
+		return repo.CloneURL
+	}
+	// Gitea and Gogs accept an access token as the username, with this password.
+	u.User = url.UserPassword(c.config.Token, "x-oauth-basic")
+	return u.String()
+}
+
+func (c *giteaConnection) listAllRepos(ctx context.Context) <-chan *gitea.Repo {
+	repositoryQuery := c.config.RepositoryQuery
+	if len(repositoryQuery) == 0 {
+		repositoryQuery = []string{"affiliated"}
+	}
+
+	const perPage = 50 // default maximum page size of the Gitea API
+	ch := make(chan *gitea.Repo, perPage)
+	go func() {
+		defer close(ch)
+
+		seen := map[int64]bool{}
+		send := func(repos []*gitea.Repo) (sent int) {
+			for _, r := range repos {
+				if !seen[r.ID] {
+					seen[r.ID] = true
+					ch <- r
+					sent++
+				}
+			}
+			return sent
+		}
+
+		for _, query := range repositoryQuery {
+			var listPage func(page int) ([]*gitea.Repo, error)
+			switch {
+			case query == "none":
+				continue
+			case query == "affiliated":
+				listPage = func(page int) ([]*gitea.Repo, error) {
+					return c.client.UserRepos(ctx, page, perPage)
+				}
+			default:
+				q, err := url.ParseQuery(strings.TrimPrefix(query, "?"))
+				if err != nil {
+					log15.Error("Skipping invalid Gitea repositoryQuery", "repositoryQuery", query, "error", err)
+					continue
+				}
+				listPage = func(page int) ([]*gitea.Repo, error) {
+					return c.client.SearchRepos(ctx, q, page, perPage)
+				}
+			}
+
+			for page := 1; ; page++ {
+				repos, err := listPage(page)
+				if err != nil {
+					log15.Error("Error listing Gitea repositories", "url", c.baseURL, "repositoryQuery", query, "error", err)
+					break
+				}
+				// Gogs ignores the page parameter of some endpoints and returns all repositories
+				// on each page, so stop when a page has no new repositories.
+				if send(repos) == 0 || len(repos) < perPage {
+					break
+				}
+			}
+		}
+
+		for _, nameWithOwner := range c.config.Repos {
+			i := strings.Index(nameWithOwner, "/")
+			if i < 0 {
+				log15.Error("Skipping invalid Gitea repository", "repo", nameWithOwner)
+				continue
+			}
+			r, err := c.client.Repo(ctx, nameWithOwner[:i], nameWithOwner[i+1:])
+			if err != nil {
+				log15.Error("Error getting Gitea repository", "url", c.baseURL, "repo", nameWithOwner, "error", err)
+				continue
+			}
+			send([]*gitea.Repo{r})
+		}
+	}()
+
+	return ch
+}
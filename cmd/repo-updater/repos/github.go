@@ -161,7 +161,8 @@ func GetGitHubRepository(ctx context.Context, args protocol.RepoLookupArgs) (rep
 				Commit: ghrepo.URL + "/commit/{commit}",
 			},
 			VCS: protocol.VCSInfo{
-				URL: conn.authenticatedRemoteURL(ghrepo),
+				URL:          conn.authenticatedRemoteURL(ghrepo),
+				CloneOptions: cloneOptions(conn.config.CloneStrategies, ghrepo.NameWithOwner),
 			},
 		}
 	}
@@ -317,7 +318,8 @@ func updateGitHubRepositories(ctx context.Context, conn *githubConnection) {
 				Archived:     repo.IsArchived,
 				Enabled:      conn.config.InitialRepositoryEnablement,
 			},
-			URL: conn.authenticatedRemoteURL(repo),
+			URL:          conn.authenticatedRemoteURL(repo),
+			CloneOptions: cloneOptions(conn.config.CloneStrategies, repo.NameWithOwner),
 		}
 	}
 }
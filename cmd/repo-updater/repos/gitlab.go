@@ -129,7 +129,8 @@ func GetGitLabRepository(ctx context.Context, args protocol.RepoLookupArgs) (rep
 			Fork:         proj.ForkedFromProject != nil,
 			Archived:     proj.Archived,
 			VCS: protocol.VCSInfo{
-				URL: conn.authenticatedRemoteURL(proj),
+				URL:          conn.authenticatedRemoteURL(proj),
+				CloneOptions: cloneOptions(conn.config.CloneStrategies, proj.PathWithNamespace),
 			},
 			Links: &protocol.RepoLinks{
 				Root:   proj.WebURL,
@@ -228,7 +229,8 @@ func updateGitLabProjects(ctx context.Context, conn *gitlabConnection) {
 				Archived:     proj.Archived,
 				Enabled:      conn.config.InitialRepositoryEnablement,
 			},
-			URL: conn.authenticatedRemoteURL(proj),
+			URL:          conn.authenticatedRemoteURL(proj),
+			CloneOptions: cloneOptions(conn.config.CloneStrategies, proj.PathWithNamespace),
 		}
 	}
 }
@@ -36,6 +36,18 @@ var (
 		Name:      "time_last_bitbucketserver_sync",
 		Help:      "The last time a comprehensive Bitbucket Server sync finished",
 	}, []string{"id"})
+	bitbucketCloudUpdateTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "time_last_bitbucketcloud_sync",
+		Help:      "The last time a comprehensive Bitbucket Cloud sync finished",
+	}, []string{"id"})
+	giteaUpdateTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "time_last_gitea_sync",
+		Help:      "The last time a comprehensive Gitea sync finished",
+	}, []string{"id"})
 
 	// gitoliteUpdateTime is an ugly duckling as repo-updater just
 	// hits a HTTP endpoint on the frontend that returns after
@@ -89,6 +101,19 @@ var (
 		Help:      "Incremented each time we skip a repository clone to remove.",
 	})
 
+	permissionsSyncSuccess = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "permissions_sync_success",
+		Help:      "Incremented each time we sync the repository permissions of a user.",
+	})
+	permissionsSyncFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "permissions_sync_failed",
+		Help:      "Incremented each time we try and fail to sync the repository permissions of a user.",
+	})
+
 	schedError = promauto.NewCounter(prometheus.CounterOpts{
 		Namespace: "src",
 		Subsystem: "repoupdater",
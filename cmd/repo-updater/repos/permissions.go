@@ -0,0 +1,72 @@
+package repos
+
+import (
+	"context"
+	"time"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+)
+
+const permissionsSyncBatchSize = 100
+
+// permissionsSyncBatchPause is the minimum pause between two batches of the permissions sync
+// worker, so that it never loops without pause against the database and the code hosts.
+const permissionsSyncBatchPause = time.Second
+
+// RunPermissionsSyncWorker is a worker which syncs the repository permissions of users from the
+// authz providers to the database (via the frontend), when the permissionsBackgroundSync
+// experiment is enabled. The permissions of each user are synced about once per
+// conf.PermissionsSyncInterval, least recently synced users first.
+func RunPermissionsSyncWorker(ctx context.Context) {
+	log := log15.Root().New("worker", "permissions-sync")
+
+	for {
+		var synced int
+		if conf.PermissionsBackgroundSyncEnabled() {
+			var err error
+			synced, err = syncPermissions(ctx, log)
+			if err != nil {
+				log.Error("failed to sync repository permissions", "error", err)
+			}
+		}
+		// Continue soon with the next batch if there may be more users to sync.
+		if synced < permissionsSyncBatchSize {
+			randSleep(time.Minute, 10*time.Second)
+		} else {
+			time.Sleep(permissionsSyncBatchPause)
+		}
+	}
+}
+
+// syncPermissions syncs the repository permissions of the next batch of users, and returns the
+// number of users whose permissions were synced.
+func syncPermissions(ctx context.Context, log log15.Logger) (int, error) {
+	userIDs, err := api.InternalClient.PermissionsUsersToSync(ctx, time.Now().Add(-conf.PermissionsSyncInterval()), permissionsSyncBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, userID := range userIDs {
+		if err := api.InternalClient.PermissionsSyncUser(ctx, userID); err != nil {
+			// Do not fail at this point, just log so we can sync other users.
+			log.Error("failed to sync repository permissions of user", "user", userID, "error", err)
+			permissionsSyncFailed.Inc()
+			failed++
+			continue
+		}
+		permissionsSyncSuccess.Inc()
+	}
+
+	// If we did something we log with a higher level.
+	statusLogger := log.Debug
+	if len(userIDs) > 0 {
+		statusLogger = log.Info
+	}
+	statusLogger("repository permissions sync finished", "users", len(userIDs), "failed", failed)
+
+	return len(userIDs) - failed, nil
+}
@@ -22,6 +22,9 @@ const (
 
 	// maxDelay is the maximum amount of time between scheduled updates for a single repository.
 	maxDelay = 8 * time.Hour
+
+	// persistInterval is the amount of time between persisting the changes to the schedule.
+	persistInterval = time.Minute
 )
 
 // updateScheduler schedules repo update (or clone) requests to gitserver.
@@ -37,10 +40,20 @@ const (
 // When it is time for a repo to update, the scheduler inserts the repo into a queue.
 //
 // A worker continuously dequeues repos and sends updates to gitserver, but its concurrency
-// is limited by the gitMaxConcurrentClones site configuration.
+// is limited by the gitMaxConcurrentClones site configuration. Updates are dequeued round-robin
+// across sources (code host connections), and the concurrency of each source is limited by the
+// gitMaxConcurrentClonesPerHost site configuration, so that a slow or rate-limited code host
+// doesn't starve the others.
+//
+// The schedule (update intervals, due times and last update errors) is persisted through the
+// frontend internal API and restored when the scheduler starts, so that restarting repo-updater
+// doesn't update all repos at once and forget the intervals that were learned.
 type updateScheduler struct {
 	mu sync.Mutex
 
+	// restored is whether the persisted schedule was restored.
+	restored bool
+
 	// sourceRepos stores the last known list of repos from each source
 	// so we can compute which repos have been added/removed/enabled/disabled.
 	sourceRepos map[string]sourceRepoMap
@@ -53,9 +66,11 @@ type updateScheduler struct {
 // a configuration source, such as information retrieved from GitHub for a
 // given GitHubConnection.
 type configuredRepo2 struct {
-	URL     string
-	Name    api.RepoName
-	Enabled bool
+	URL          string
+	CloneOptions *gitserverprotocol.CloneOptions // nil for a full clone
+	Name         api.RepoName
+	Enabled      bool
+	Source       string // the configuration source that the repo is from
 }
 
 // sourceRepoMap is the set of repositories associated with a specific configuration source.
@@ -72,10 +87,13 @@ func newUpdateScheduler() *updateScheduler {
 		sourceRepos: make(map[string]sourceRepoMap),
 		updateQueue: &updateQueue{
 			index:         make(map[api.RepoName]*repoUpdate),
+			sources:       make(map[string]*sourceQueue),
 			notifyEnqueue: make(chan struct{}, notifyChanBuffer),
 		},
 		schedule: &schedule{
 			index:  make(map[api.RepoName]*scheduledRepoUpdate),
+			saved:  make(map[api.RepoName]*api.RepoUpdateScheduleState),
+			dirty:  make(map[api.RepoName]struct{}),
 			wakeup: make(chan struct{}, notifyChanBuffer),
 		},
 	}
@@ -83,10 +101,99 @@ func newUpdateScheduler() *updateScheduler {
 
 // run starts scheduled repo updates.
 func (s *updateScheduler) run(ctx context.Context) {
+	go func() {
+		if s.restoreSchedule(ctx) {
+			s.runPersistLoop(ctx)
+		}
+	}()
 	go s.runScheduleLoop(ctx)
 	go s.runUpdateLoop(ctx)
 }
 
+// restoreSchedule restores the persisted schedule, retrying until it succeeds. It returns false if
+// the context is canceled first. The schedule is only restored the first time that the scheduler
+// runs.
+func (s *updateScheduler) restoreSchedule(ctx context.Context) bool {
+	s.mu.Lock()
+	restored := s.restored
+	s.mu.Unlock()
+	if restored {
+		return true
+	}
+
+	for {
+		states, err := listScheduleStates(ctx)
+		if err == nil {
+			s.restore(states)
+			log15.Debug("restored repo update schedule", "count", len(states))
+			return true
+		}
+		log15.Warn("error restoring repo update schedule", "err", err)
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// restore applies the persisted schedule states. Repos that are already in the schedule are
+// rescheduled, and removed from the queue if they were only enqueued because they were added to the
+// schedule. The states of other repos are used when they are added to the schedule.
+func (s *updateScheduler) restore(states []*api.RepoUpdateScheduleState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rescheduled []api.RepoName
+	s.schedule.mu.Lock()
+	for _, state := range states {
+		if update := s.schedule.index[state.Repo]; update != nil {
+			update.restore(state)
+			heap.Fix(s.schedule, update.Index)
+			rescheduled = append(rescheduled, state.Repo)
+		} else {
+			s.schedule.saved[state.Repo] = state
+		}
+	}
+	s.schedule.rescheduleTimer()
+	s.schedule.mu.Unlock()
+
+	for _, name := range rescheduled {
+		s.updateQueue.removeLowPriority(name)
+	}
+	s.restored = true
+}
+
+// runPersistLoop periodically persists the states of the repos whose schedule changed.
+func (s *updateScheduler) runPersistLoop(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(persistInterval):
+		case <-ctx.Done():
+			return
+		}
+
+		states := s.schedule.takeDirty()
+		if len(states) == 0 {
+			continue
+		}
+		if err := saveScheduleStates(ctx, states); err != nil {
+			log15.Warn("error persisting repo update schedule", "count", len(states), "err", err)
+			s.schedule.markDirty(states)
+		}
+	}
+}
+
+// listScheduleStates lists the persisted schedule states.
+var listScheduleStates = func(ctx context.Context) ([]*api.RepoUpdateScheduleState, error) {
+	return api.InternalClient.ReposUpdateScheduleList(ctx)
+}
+
+// saveScheduleStates persists the schedule states.
+var saveScheduleStates = func(ctx context.Context, states []*api.RepoUpdateScheduleState) error {
+	return api.InternalClient.ReposUpdateScheduleSave(ctx, states)
+}
+
 // runScheduleLoop starts the loop that schedules updates by enqueuing them into the updateQueue.
 func (s *updateScheduler) runScheduleLoop(ctx context.Context) {
 	for {
@@ -112,6 +219,7 @@ func (s *updateScheduler) runScheduleLoop(ctx context.Context) {
 			s.updateQueue.enqueue(repoUpdate.Repo, priorityLow)
 			repoUpdate.Due = timeNow().Add(repoUpdate.Interval)
 			heap.Fix(s.schedule, 0)
+			s.schedule.dirty[repoUpdate.Repo.Name] = struct{}{}
 		}
 
 		s.schedule.rescheduleTimer()
@@ -124,6 +232,10 @@ func (s *updateScheduler) runScheduleLoop(ctx context.Context) {
 // runUpdateLoop sends repo update requests to gitserver.
 func (s *updateScheduler) runUpdateLoop(ctx context.Context) {
 	limiter := configuredLimiter()
+	configureSourceLimit(s.updateQueue)
+
+	// backoffTimer wakes up the loop when the sources that are backing off can be updated again.
+	var backoffTimer *time.Timer
 
 	for {
 		select {
@@ -139,9 +251,17 @@ func (s *updateScheduler) runUpdateLoop(ctx context.Context) {
 				return
 			}
 
-			repo := s.updateQueue.acquireNext()
+			repo, wait := s.updateQueue.acquireNext()
 			if repo == nil {
 				cancel()
+				if wait > 0 {
+					if backoffTimer != nil {
+						backoffTimer.Stop()
+					}
+					backoffTimer = timeAfterFunc(wait, func() {
+						notify(s.updateQueue.notifyEnqueue)
+					})
+				}
 				break
 			}
 
@@ -153,6 +273,9 @@ func (s *updateScheduler) runUpdateLoop(ctx context.Context) {
 				if err != nil {
 					schedError.Inc()
 					log15.Warn("error requesting repo update", "uri", repo.Name, "err", err)
+					s.schedule.updateLastError(repo, err.Error())
+				} else if resp != nil {
+					s.schedule.updateLastError(repo, resp.Error)
 				}
 				if resp != nil && resp.LastFetched != nil && resp.LastChanged != nil {
 					// This is the heuristic that is described in the updateScheduler documentation.
@@ -167,7 +290,30 @@ func (s *updateScheduler) runUpdateLoop(ctx context.Context) {
 
 // requestRepoUpdate sends a request to gitserver to request an update.
 var requestRepoUpdate = func(ctx context.Context, repo *configuredRepo2, since time.Duration) (*gitserverprotocol.RepoUpdateResponse, error) {
-	return gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repo.Name, URL: repo.URL}, since)
+	return gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repo.Name, URL: repo.URL, CloneOptions: repo.CloneOptions}, since)
+}
+
+// updateBackoff returns how long updates of the repo (and of the other repos of its source)
+// should wait because its code host is rate limiting us.
+//
+// Updates of repos on GitHub are spaced out like other background operations with a cost of 1
+// (see (*ratelimit.Monitor).RecommendedWaitForBackgroundOp), because the fetches authenticate
+// with the same token as the API requests whose rate limit the monitor tracks.
+var updateBackoff = func(repo *configuredRepo2) time.Duration {
+	conn, err := getGitHubConnection(protocol.RepoLookupArgs{Repo: repo.Name})
+	if err != nil || conn == nil {
+		return 0
+	}
+	return conn.client.RateLimit.RecommendedWaitForBackgroundOp(1)
+}
+
+// configureSourceLimit configures the queue with the maximum
+// number of concurrent update requests for the repos of a single
+// source that repo-updater should send to gitserver.
+var configureSourceLimit = func(q *updateQueue) {
+	conf.Watch(func() {
+		q.setSourceLimit(conf.Get().GitMaxConcurrentClonesPerHost)
+	})
 }
 
 // configuredLimiter returns a mutable limiter that is
@@ -213,8 +359,9 @@ func (s *updateScheduler) updateSource(source string, newList sourceRepoMap) {
 
 		oldRepo := oldList[key]
 		if oldRepo == nil || !oldRepo.Enabled {
-			s.schedule.add(updatedRepo)
-			s.updateQueue.enqueue(updatedRepo, priorityLow)
+			if restored := s.schedule.add(updatedRepo); !restored {
+				s.updateQueue.enqueue(updatedRepo, priorityLow)
+			}
 		} else {
 			s.schedule.update(updatedRepo)
 			s.updateQueue.update(updatedRepo)
@@ -233,6 +380,12 @@ func (s *updateScheduler) UpdateOnce(name api.RepoName, url string) {
 		Name: name,
 		URL:  url,
 	}
+	s.schedule.mu.Lock()
+	if update := s.schedule.index[name]; update != nil {
+		repo.Source = update.Repo.Source // share the source's concurrency limit
+		repo.CloneOptions = update.Repo.CloneOptions
+	}
+	s.schedule.mu.Unlock()
 	schedManualFetch.Inc()
 	s.updateQueue.enqueue(repo, priorityHigh)
 }
@@ -311,6 +464,8 @@ func (s *updateScheduler) ScheduleInfo(name api.RepoName) *protocol.RepoUpdateSc
 			Total:           len(s.schedule.index),
 			IntervalSeconds: int(update.Interval / time.Second),
 			Due:             update.Due,
+			LastError:       update.LastError,
+			LastErrorAt:     update.LastErrorAt,
 		}
 	}
 	s.schedule.mu.Unlock()
@@ -325,6 +480,12 @@ func (s *updateScheduler) ScheduleInfo(name api.RepoName) *protocol.RepoUpdateSc
 	}
 	s.updateQueue.mu.Unlock()
 
+	// The queue has no error of its own; it reports the error of the last update of the repo.
+	if result.Queue != nil && result.Schedule != nil {
+		result.Queue.LastError = result.Schedule.LastError
+		result.Queue.LastErrorAt = result.Schedule.LastErrorAt
+	}
+
 	return &result
 }
 
@@ -336,6 +497,12 @@ type updateQueue struct {
 	heap  []*repoUpdate
 	index map[api.RepoName]*repoUpdate
 
+	// sources holds the queued updates of each source, so that updates can be acquired
+	// round-robin across sources.
+	sources     map[string]*sourceQueue
+	lastSource  string // the source of the last acquired update
+	sourceLimit int    // the maximum number of concurrent updates of a source (0 means unlimited)
+
 	seq uint64
 
 	// The queue performs a non-blocking send on this channel
@@ -358,6 +525,8 @@ type repoUpdate struct {
 	Seq      uint64 // the sequence number of the update
 	Updating bool   // whether the repo has been acquired for update
 	Index    int    `json:"-"` // the index in the heap
+
+	SourceIndex int `json:"-"` // the index in the heap of its source
 }
 
 // enqueue add the repo to the queue with the given priority.
@@ -383,7 +552,7 @@ func (q *updateQueue) enqueue(repo *configuredRepo2, p priority) {
 	// Repo is in the queue at a lower priority.
 	update.Priority = p      // bump the priority
 	update.Seq = q.nextSeq() // put it after all existing updates with this priority
-	heap.Fix(q, update.Index)
+	q.fix(update)
 	notify(q.notifyEnqueue)
 }
 
@@ -399,7 +568,14 @@ func (q *updateQueue) nextSeq() uint64 {
 func (q *updateQueue) update(repo *configuredRepo2) {
 	q.mu.Lock()
 	if update := q.index[repo.Name]; update != nil && !update.Updating {
-		update.Repo = repo
+		if update.Repo.Source == repo.Source {
+			update.Repo = repo
+		} else {
+			// Move the update to the queue of its new source.
+			q.removeFromSource(update)
+			update.Repo = repo
+			q.pushToSource(update)
+		}
 	}
 	q.mu.Unlock()
 }
@@ -413,42 +589,176 @@ func (q *updateQueue) remove(repo *configuredRepo2, updating bool) {
 	q.mu.Unlock()
 }
 
+// removeLowPriority removes the repo from the queue if it is queued with a low priority and is not
+// updating.
+func (q *updateQueue) removeLowPriority(name api.RepoName) {
+	q.mu.Lock()
+	if update := q.index[name]; update != nil && update.Priority == priorityLow && !update.Updating {
+		heap.Remove(q, update.Index)
+	}
+	q.mu.Unlock()
+}
+
+// setSourceLimit sets the maximum number of concurrent updates of the repos of a single source.
+// A limit <= 0 means that the concurrency of sources is not limited.
+func (q *updateQueue) setSourceLimit(limit int) {
+	q.mu.Lock()
+	q.sourceLimit = limit
+	q.mu.Unlock()
+}
+
 // acquireNext acquires the next repo for update.
 // The acquired repo must be removed from the queue
 // when the update finishes (independent of success or failure).
-func (q *updateQueue) acquireNext() *configuredRepo2 {
+//
+// The next repo is the next update of the first source (after the source of
+// the last acquired update) that has an update with the highest priority,
+// that is below the concurrency limit of sources and that is not backing off
+// (see updateBackoff).
+//
+// If no repo can be acquired, acquireNext returns nil and how long it takes
+// until the first source that is backing off can be updated again (0 if no
+// source is backing off).
+func (q *updateQueue) acquireNext() (repo *configuredRepo2, wait time.Duration) {
+	backoff := q.sourceBackoffs()
+
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	if len(q.heap) == 0 {
-		return nil
+
+	var (
+		candidates []string
+		best       priority
+	)
+	for source, sq := range q.sources {
+		if len(sq.heap) == 0 || sq.heap[0].Updating {
+			// Everything in the queue of the source is already updating.
+			continue
+		}
+		if q.sourceLimit > 0 && sq.updating >= q.sourceLimit {
+			continue
+		}
+		if d := backoff[source]; d > 0 {
+			if wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		switch p := sq.heap[0].Priority; {
+		case len(candidates) == 0 || p > best:
+			candidates, best = []string{source}, p
+		case p == best:
+			candidates = append(candidates, source)
+		}
 	}
-	update := q.heap[0]
-	if update.Updating {
-		// Everything in the queue is already updating.
-		return nil
+	if len(candidates) == 0 {
+		return nil, wait
+	}
+
+	sort.Strings(candidates)
+	source := candidates[0]
+	for _, c := range candidates {
+		if c > q.lastSource {
+			source = c
+			break
+		}
 	}
+	q.lastSource = source
+
+	sq := q.sources[source]
+	update := sq.heap[0]
 	update.Updating = true
+	sq.updating++
+	q.fix(update)
+	return update.Repo, 0
+}
+
+// sourceBackoffs returns how long the updates of each source that has a repo to update should
+// wait (see updateBackoff).
+//
+// The backoffs are computed without holding the lock on q.mu, because computing them may need to
+// look up the code host connection of a repo.
+func (q *updateQueue) sourceBackoffs() map[string]time.Duration {
+	q.mu.Lock()
+	next := make(map[string]*configuredRepo2, len(q.sources))
+	for source, sq := range q.sources {
+		if len(sq.heap) > 0 && !sq.heap[0].Updating {
+			next[source] = sq.heap[0].Repo
+		}
+	}
+	q.mu.Unlock()
+
+	backoff := make(map[string]time.Duration, len(next))
+	for source, repo := range next {
+		if d := updateBackoff(repo); d > 0 {
+			backoff[source] = d
+		}
+	}
+	return backoff
+}
+
+// fix re-establishes the ordering of the update in the queue and in the queue of its source
+// after its priority, sequence number or updating state changed.
+// The caller must hold the lock on q.mu.
+func (q *updateQueue) fix(update *repoUpdate) {
 	heap.Fix(q, update.Index)
-	return update.Repo
+	if sq := q.sources[update.Repo.Source]; sq != nil {
+		heap.Fix(sq, update.SourceIndex)
+	}
 }
 
-// The following methods implement heap.Interface based on the priority queue example:
-// https://golang.org/pkg/container/heap/#example__priorityQueue
+// pushToSource adds the update to the queue of its source.
+// The caller must hold the lock on q.mu.
+func (q *updateQueue) pushToSource(update *repoUpdate) {
+	if q.sources == nil {
+		return // a copy of the queue (see DebugDump)
+	}
+	sq := q.sources[update.Repo.Source]
+	if sq == nil {
+		sq = &sourceQueue{}
+		q.sources[update.Repo.Source] = sq
+	}
+	heap.Push(sq, update)
+	if update.Updating {
+		sq.updating++
+	}
+}
 
-func (q *updateQueue) Len() int { return len(q.heap) }
-func (q *updateQueue) Less(i, j int) bool {
-	qi := q.heap[i]
-	qj := q.heap[j]
-	if qi.Updating != qj.Updating {
+// removeFromSource removes the update from the queue of its source.
+// The caller must hold the lock on q.mu.
+func (q *updateQueue) removeFromSource(update *repoUpdate) {
+	sq := q.sources[update.Repo.Source]
+	if sq == nil {
+		return
+	}
+	heap.Remove(sq, update.SourceIndex)
+	if update.Updating {
+		sq.updating--
+	}
+	if len(sq.heap) == 0 {
+		delete(q.sources, update.Repo.Source)
+	}
+}
+
+// lessRepoUpdate reports whether update a should be acquired before update b.
+func lessRepoUpdate(a, b *repoUpdate) bool {
+	if a.Updating != b.Updating {
 		// Repos that are already updating are sorted last.
-		return qj.Updating
+		return b.Updating
 	}
-	if qi.Priority != qj.Priority {
+	if a.Priority != b.Priority {
 		// We want Pop to give us the highest, not lowest, priority so we use greater than here.
-		return qi.Priority > qj.Priority
+		return a.Priority > b.Priority
 	}
 	// Queue semantics for items with the same priority.
-	return qi.Seq < qj.Seq
+	return a.Seq < b.Seq
+}
+
+// The following methods implement heap.Interface based on the priority queue example:
+// https://golang.org/pkg/container/heap/#example__priorityQueue
+
+func (q *updateQueue) Len() int { return len(q.heap) }
+func (q *updateQueue) Less(i, j int) bool {
+	return lessRepoUpdate(q.heap[i], q.heap[j])
 }
 func (q *updateQueue) Swap(i, j int) {
 	q.heap[i], q.heap[j] = q.heap[j], q.heap[i]
@@ -462,6 +772,7 @@ func (q *updateQueue) Push(x interface{}) {
 	item.Seq = q.nextSeq()
 	q.heap = append(q.heap, item)
 	q.index[item.Repo.Name] = item
+	q.pushToSource(item)
 }
 func (q *updateQueue) Pop() interface{} {
 	n := len(q.heap)
@@ -469,6 +780,37 @@ func (q *updateQueue) Pop() interface{} {
 	item.Index = -1 // for safety
 	q.heap = q.heap[0 : n-1]
 	delete(q.index, item.Repo.Name)
+	q.removeFromSource(item)
+	return item
+}
+
+// sourceQueue is the priority queue of the updates of the repos of a single source.
+type sourceQueue struct {
+	heap     []*repoUpdate
+	updating int // the number of updates that are updating
+}
+
+// The following methods implement heap.Interface based on the priority queue example:
+// https://golang.org/pkg/container/heap/#example__priorityQueue
+
+func (q *sourceQueue) Len() int { return len(q.heap) }
+func (q *sourceQueue) Less(i, j int) bool {
+	return lessRepoUpdate(q.heap[i], q.heap[j])
+}
+func (q *sourceQueue) Swap(i, j int) {
+	q.heap[i], q.heap[j] = q.heap[j], q.heap[i]
+	q.heap[i].SourceIndex = i
+	q.heap[j].SourceIndex = j
+}
+func (q *sourceQueue) Push(x interface{}) {
+	item := x.(*repoUpdate)
+	item.SourceIndex = len(q.heap)
+	q.heap = append(q.heap, item)
+}
+func (q *sourceQueue) Pop() interface{} {
+	n := len(q.heap)
+	item := q.heap[n-1]
+	q.heap = q.heap[0 : n-1]
 	return item
 }
 
@@ -479,6 +821,13 @@ type schedule struct {
 	heap  []*scheduledRepoUpdate // min heap of scheduledRepoUpdates based on their due time.
 	index map[api.RepoName]*scheduledRepoUpdate
 
+	// saved holds the persisted states of repos that are not in the schedule yet. They are used
+	// instead of the defaults when the repos are added.
+	saved map[api.RepoName]*api.RepoUpdateScheduleState
+
+	// dirty is the set of repos whose schedule changed since it was last persisted.
+	dirty map[api.RepoName]struct{}
+
 	// timer sends a value on the wakeup channel when it is time
 	timer  *time.Timer
 	wakeup chan struct{}
@@ -490,21 +839,51 @@ type scheduledRepoUpdate struct {
 	Interval time.Duration    // how regularly the repo is updated
 	Due      time.Time        // the next time that the repo will be enqueued for a update
 	Index    int              `json:"-"` // the index in the heap
+
+	LastError   string     // the error of the last update, or empty if it succeeded
+	LastErrorAt *time.Time // the time of the last update error
+}
+
+// restore sets the schedule of the repo to the persisted state.
+func (u *scheduledRepoUpdate) restore(state *api.RepoUpdateScheduleState) {
+	u.Interval = clampInterval(time.Duration(state.IntervalSeconds) * time.Second)
+	u.Due = state.Due
+	u.LastError = state.LastError
+	u.LastErrorAt = state.LastErrorAt
 }
 
-// add adds a repo to the schedule.
+// state returns the persisted state of the repo's schedule.
+func (u *scheduledRepoUpdate) state() *api.RepoUpdateScheduleState {
+	return &api.RepoUpdateScheduleState{
+		Repo:            u.Repo.Name,
+		IntervalSeconds: int(u.Interval / time.Second),
+		Due:             u.Due,
+		LastError:       u.LastError,
+		LastErrorAt:     u.LastErrorAt,
+	}
+}
+
+// add adds a repo to the schedule. If the repo has a persisted state, it is scheduled according
+// to that state and add returns true.
 // It does nothing if the repo already exists in the schedule.
-func (s *schedule) add(repo *configuredRepo2) {
+func (s *schedule) add(repo *configuredRepo2) (restored bool) {
 	s.mu.Lock()
 	if s.index[repo.Name] == nil {
-		heap.Push(s, &scheduledRepoUpdate{
+		update := &scheduledRepoUpdate{
 			Repo:     repo,
 			Interval: minDelay,
 			Due:      timeNow().Add(minDelay),
-		})
+		}
+		if state := s.saved[repo.Name]; state != nil {
+			update.restore(state)
+			delete(s.saved, repo.Name)
+			restored = true
+		}
+		heap.Push(s, update)
 		s.rescheduleTimer()
 	}
 	s.mu.Unlock()
+	return restored
 }
 
 // update updates the repo data in the schedule.
@@ -522,18 +901,66 @@ func (s *schedule) update(repo *configuredRepo2) {
 func (s *schedule) updateInterval(repo *configuredRepo2, interval time.Duration) {
 	s.mu.Lock()
 	if update := s.index[repo.Name]; update != nil {
-		switch {
-		case interval > maxDelay:
-			update.Interval = maxDelay
-		case interval < minDelay:
-			update.Interval = minDelay
-		default:
-			update.Interval = interval
-		}
+		update.Interval = clampInterval(interval)
 		update.Due = timeNow().Add(update.Interval)
 		log15.Debug("updated repo", "repo", repo.Name, "due", update.Due.Sub(timeNow()))
 		heap.Fix(s, update.Index)
 		s.rescheduleTimer()
+		s.dirty[repo.Name] = struct{}{}
+	}
+	s.mu.Unlock()
+}
+
+// clampInterval returns the interval clamped to [minDelay, maxDelay].
+func clampInterval(interval time.Duration) time.Duration {
+	switch {
+	case interval > maxDelay:
+		return maxDelay
+	case interval < minDelay:
+		return minDelay
+	default:
+		return interval
+	}
+}
+
+// updateLastError records the result of the last update of a repo in the schedule. An empty
+// errMsg means that the update succeeded.
+// It does nothing if the repo is not in the schedule.
+func (s *schedule) updateLastError(repo *configuredRepo2, errMsg string) {
+	s.mu.Lock()
+	if update := s.index[repo.Name]; update != nil && update.LastError != errMsg {
+		update.LastError = errMsg
+		if errMsg == "" {
+			update.LastErrorAt = nil
+		} else {
+			now := timeNow()
+			update.LastErrorAt = &now
+		}
+		s.dirty[repo.Name] = struct{}{}
+	}
+	s.mu.Unlock()
+}
+
+// takeDirty returns the states of the repos whose schedule changed since it was last persisted,
+// and resets the set of changed repos.
+func (s *schedule) takeDirty() []*api.RepoUpdateScheduleState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var states []*api.RepoUpdateScheduleState
+	for name := range s.dirty {
+		if update := s.index[name]; update != nil {
+			states = append(states, update.state())
+		}
+	}
+	s.dirty = make(map[api.RepoName]struct{})
+	return states
+}
+
+// markDirty marks the repos of the states as changed, so that they are persisted again.
+func (s *schedule) markDirty(states []*api.RepoUpdateScheduleState) {
+	s.mu.Lock()
+	for _, state := range states {
+		s.dirty[state.Repo] = struct{}{}
 	}
 	s.mu.Unlock()
 }
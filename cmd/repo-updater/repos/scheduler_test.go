@@ -3,7 +3,9 @@ package repos
 import (
 	"container/heap"
 	"context"
+	"errors"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -19,6 +21,8 @@ func init() {
 	timeNow = nil
 	notify = nil
 	timeAfterFunc = nil
+	updateBackoff = nil
+	configureSourceLimit = nil
 }
 
 func mockTime(t time.Time) {
@@ -419,11 +423,19 @@ func TestUpdateQueue_remove(t *testing.T) {
 func TestUpdateQueue_acquireNext(t *testing.T) {
 	a := &configuredRepo2{Name: "a", URL: "a.com"}
 	b := &configuredRepo2{Name: "b", URL: "b.com"}
+	x1 := &configuredRepo2{Name: "x1", URL: "x1.com", Source: "x"}
+	x2 := &configuredRepo2{Name: "x2", URL: "x2.com", Source: "x"}
+	x3 := &configuredRepo2{Name: "x3", URL: "x3.com", Source: "x"}
+	y1 := &configuredRepo2{Name: "y1", URL: "y1.com", Source: "y"}
+	y2 := &configuredRepo2{Name: "y2", URL: "y2.com", Source: "y"}
 
 	tests := []struct {
 		name           string
+		sourceLimit    int
+		backoff        map[api.RepoName]time.Duration
 		initialQueue   []*repoUpdate
 		acquireResults []*configuredRepo2
+		acquireWait    time.Duration // the wait returned by the last acquireNext
 		finalQueue     []*repoUpdate
 	}{
 		{
@@ -462,6 +474,75 @@ func TestUpdateQueue_acquireNext(t *testing.T) {
 				{Repo: a, Updating: true, Seq: 1},
 			},
 		},
+		{
+			name: "acquire round-robin across sources",
+			initialQueue: []*repoUpdate{
+				{Repo: x1, Seq: 1},
+				{Repo: x2, Seq: 2},
+				{Repo: x3, Seq: 3},
+				{Repo: y1, Seq: 4},
+				{Repo: y2, Seq: 5},
+			},
+			acquireResults: []*configuredRepo2{x1, y1, x2, y2, x3, nil},
+			finalQueue: []*repoUpdate{
+				{Repo: x1, Updating: true, Seq: 1},
+				{Repo: x2, Updating: true, Seq: 2},
+				{Repo: x3, Updating: true, Seq: 3},
+				{Repo: y1, Updating: true, Seq: 4},
+				{Repo: y2, Updating: true, Seq: 5},
+			},
+		},
+		{
+			name: "acquire high priority before round-robin",
+			initialQueue: []*repoUpdate{
+				{Repo: x1, Seq: 1},
+				{Repo: x2, Seq: 2},
+				{Repo: y1, Priority: priorityHigh, Seq: 3},
+				{Repo: y2, Priority: priorityHigh, Seq: 4},
+			},
+			acquireResults: []*configuredRepo2{y1, y2, x1},
+			finalQueue: []*repoUpdate{
+				{Repo: x2, Seq: 2},
+				{Repo: y1, Priority: priorityHigh, Updating: true, Seq: 3},
+				{Repo: y2, Priority: priorityHigh, Updating: true, Seq: 4},
+				{Repo: x1, Updating: true, Seq: 1},
+			},
+		},
+		{
+			name:        "acquire respects source limit",
+			sourceLimit: 1,
+			initialQueue: []*repoUpdate{
+				{Repo: x1, Updating: true, Seq: 1},
+				{Repo: x2, Seq: 2},
+				{Repo: y1, Seq: 3},
+				{Repo: y2, Seq: 4},
+			},
+			acquireResults: []*configuredRepo2{y1, nil},
+			finalQueue: []*repoUpdate{
+				{Repo: x2, Seq: 2},
+				{Repo: y2, Seq: 4},
+				{Repo: x1, Updating: true, Seq: 1},
+				{Repo: y1, Updating: true, Seq: 3},
+			},
+		},
+		{
+			name:    "acquire skips sources that are backing off",
+			backoff: map[api.RepoName]time.Duration{"x1": time.Minute, "y2": 2 * time.Minute},
+			initialQueue: []*repoUpdate{
+				{Repo: x1, Seq: 1},
+				{Repo: x2, Seq: 2},
+				{Repo: y1, Seq: 3},
+				{Repo: y2, Seq: 4},
+			},
+			acquireResults: []*configuredRepo2{y1, nil},
+			acquireWait:    time.Minute,
+			finalQueue: []*repoUpdate{
+				{Repo: x1, Seq: 1},
+				{Repo: x2, Seq: 2},
+				{Repo: y2, Seq: 4},
+				{Repo: y1, Updating: true, Seq: 3},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -470,14 +551,29 @@ func TestUpdateQueue_acquireNext(t *testing.T) {
 			defer stop()
 
 			s := newUpdateScheduler()
+
+			updateBackoff = func(repo *configuredRepo2) time.Duration {
+				// The backoff must be computed without holding the lock on the queue.
+				s.updateQueue.mu.Lock()
+				s.updateQueue.mu.Unlock()
+				return test.backoff[repo.Name]
+			}
+			defer func() { updateBackoff = nil }()
+
+			s.updateQueue.setSourceLimit(test.sourceLimit)
 			setupInitialQueue(s, test.initialQueue)
 
 			// Test aquireNext.
+			var wait time.Duration
 			for i, expected := range test.acquireResults {
-				if actual := s.updateQueue.acquireNext(); !reflect.DeepEqual(expected, actual) {
+				var actual *configuredRepo2
+				if actual, wait = s.updateQueue.acquireNext(); !reflect.DeepEqual(expected, actual) {
 					t.Fatalf("\nacquireNext expected %d\n%s\ngot\n%s", i, spew.Sdump(expected), spew.Sdump(actual))
 				}
 			}
+			if wait != test.acquireWait {
+				t.Fatalf("acquireNext wait: expected %s, got %s", test.acquireWait, wait)
+			}
 
 			verifyQueue(t, s, test.finalQueue)
 
@@ -503,6 +599,7 @@ func verifyQueue(t *testing.T, s *updateScheduler, expected []*repoUpdate) {
 	for len(s.updateQueue.heap) > 0 {
 		update := heap.Pop(s.updateQueue).(*repoUpdate)
 		update.Index = 0 // this will always be -1, but easier to set it to 0 to avoid boilerplate in test cases
+		update.SourceIndex = 0
 		actualQueue = append(actualQueue, update)
 	}
 
@@ -1099,6 +1196,8 @@ func TestUpdateScheduler_runUpdateLoop(t *testing.T) {
 	a := &configuredRepo2{Name: "a", URL: "a.com"}
 	b := &configuredRepo2{Name: "b", URL: "b.com"}
 	c := &configuredRepo2{Name: "c", URL: "c.com"}
+	x1 := &configuredRepo2{Name: "x1", URL: "x1.com", Source: "x"}
+	y1 := &configuredRepo2{Name: "y1", URL: "y1.com", Source: "y"}
 
 	type mockRequestRepoUpdate struct {
 		repo *configuredRepo2
@@ -1109,6 +1208,7 @@ func TestUpdateScheduler_runUpdateLoop(t *testing.T) {
 	tests := []struct {
 		name                   string
 		gitMaxConcurrentClones int
+		backoff                map[api.RepoName]time.Duration
 		initialSchedule        []*scheduledRepoUpdate
 		initialQueue           []*repoUpdate
 		mockRequestRepoUpdates []*mockRequestRepoUpdate
@@ -1120,6 +1220,25 @@ func TestUpdateScheduler_runUpdateLoop(t *testing.T) {
 		{
 			name: "empty queue",
 		},
+		{
+			name:                   "source backs off without taking a slot",
+			gitMaxConcurrentClones: 2,
+			backoff:                map[api.RepoName]time.Duration{"x1": time.Minute},
+			initialQueue: []*repoUpdate{
+				{Repo: x1, Seq: 1},
+				{Repo: y1, Seq: 2},
+			},
+			mockRequestRepoUpdates: []*mockRequestRepoUpdate{
+				{repo: y1},
+			},
+			finalQueue: []*repoUpdate{
+				{Repo: x1, Seq: 1},
+			},
+			timeAfterFuncDelays: []time.Duration{time.Minute},
+			expectedNotifications: func(s *updateScheduler) []chan struct{} {
+				return []chan struct{}{s.updateQueue.notifyEnqueue}
+			},
+		},
 		{
 			name: "non-empty queue at clone limit",
 			initialQueue: []*repoUpdate{
@@ -1177,6 +1296,30 @@ func TestUpdateScheduler_runUpdateLoop(t *testing.T) {
 				return []chan struct{}{s.schedule.wakeup}
 			},
 		},
+		{
+			name:                   "last error recorded",
+			gitMaxConcurrentClones: 1,
+			initialSchedule: []*scheduledRepoUpdate{
+				{Repo: a, Interval: time.Hour, Due: defaultTime.Add(time.Hour)},
+				{Repo: b, Interval: time.Hour, Due: defaultTime.Add(2 * time.Hour), LastError: "old error", LastErrorAt: timePtr(defaultTime.Add(-time.Hour))},
+				{Repo: c, Interval: time.Hour, Due: defaultTime.Add(3 * time.Hour)},
+			},
+			initialQueue: []*repoUpdate{
+				{Repo: a, Seq: 1},
+				{Repo: b, Seq: 2},
+				{Repo: c, Seq: 3},
+			},
+			mockRequestRepoUpdates: []*mockRequestRepoUpdate{
+				{repo: a, err: errors.New("update failed")},
+				{repo: b, resp: &gitserverprotocol.RepoUpdateResponse{}},
+				{repo: c, resp: &gitserverprotocol.RepoUpdateResponse{Error: "clone failed"}},
+			},
+			finalSchedule: []*scheduledRepoUpdate{
+				{Repo: a, Interval: time.Hour, Due: defaultTime.Add(time.Hour), LastError: "update failed", LastErrorAt: timePtr(defaultTime)},
+				{Repo: b, Interval: time.Hour, Due: defaultTime.Add(2 * time.Hour)},
+				{Repo: c, Interval: time.Hour, Due: defaultTime.Add(3 * time.Hour), LastError: "clone failed", LastErrorAt: timePtr(defaultTime)},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -1190,6 +1333,25 @@ func TestUpdateScheduler_runUpdateLoop(t *testing.T) {
 			defer func() {
 				configuredLimiter = nil
 			}()
+			configureSourceLimit = func(q *updateQueue) {}
+			defer func() { configureSourceLimit = nil }()
+
+			updateBackoff = func(repo *configuredRepo2) time.Duration {
+				return test.backoff[repo.Name]
+			}
+			defer func() { updateBackoff = nil }()
+
+			// Intercept the timer that wakes up the loop after a backoff, so we can wait for it.
+			backedOff := make(chan struct{}, 1)
+			recordTimeAfterFunc := timeAfterFunc
+			timeAfterFunc = func(delay time.Duration, f func()) *time.Timer {
+				timer := recordTimeAfterFunc(delay, f)
+				select {
+				case backedOff <- struct{}{}:
+				default:
+				}
+				return timer
+			}
 
 			expectedRequestCount := len(test.mockRequestRepoUpdates)
 			mockRequestRepoUpdates := make(chan *mockRequestRepoUpdate, expectedRequestCount)
@@ -1240,6 +1402,9 @@ func TestUpdateScheduler_runUpdateLoop(t *testing.T) {
 				ctx := <-contexts
 				<-ctx.Done()
 			}
+			if len(test.backoff) > 0 {
+				<-backedOff
+			}
 
 			verifySchedule(t, s, test.finalSchedule)
 			verifyQueue(t, s, test.finalQueue)
@@ -1588,3 +1753,84 @@ func TestUpdateScheduler_updateSource(t *testing.T) {
 }
 
 // TODO: update enabled state and url once in the queue?
+
+func TestUpdateScheduler_restore(t *testing.T) {
+	_, stop := startRecording()
+	defer stop()
+
+	a := &configuredRepo2{Name: "a", URL: "a.com", Enabled: true}
+	b := &configuredRepo2{Name: "b", URL: "b.com", Enabled: true}
+	c := &configuredRepo2{Name: "c", URL: "c.com", Enabled: true}
+	d := &configuredRepo2{Name: "d", URL: "d.com", Enabled: true}
+
+	s := newUpdateScheduler()
+	s.updateSource("src", sourceRepoMap{"a": a, "b": b})
+	s.UpdateOnce("b", "b.com")
+
+	s.restore([]*api.RepoUpdateScheduleState{
+		{Repo: "a", IntervalSeconds: 3600, Due: defaultTime.Add(time.Hour), LastError: "failed", LastErrorAt: timePtr(defaultTime)},
+		{Repo: "b", IntervalSeconds: 7200, Due: defaultTime.Add(2 * time.Hour)},
+		{Repo: "c", IntervalSeconds: 1, Due: defaultTime.Add(3 * time.Hour)},
+	})
+
+	// Repos that are added after the schedule is restored use their persisted state (with the
+	// interval clamped) and are not enqueued.
+	s.updateSource("src", sourceRepoMap{"a": a, "b": b, "c": c, "d": d})
+
+	verifySchedule(t, s, []*scheduledRepoUpdate{
+		{Repo: d, Interval: minDelay, Due: defaultTime.Add(minDelay)},
+		{Repo: a, Interval: time.Hour, Due: defaultTime.Add(time.Hour), LastError: "failed", LastErrorAt: timePtr(defaultTime)},
+		{Repo: b, Interval: 2 * time.Hour, Due: defaultTime.Add(2 * time.Hour)},
+		{Repo: c, Interval: minDelay, Due: defaultTime.Add(3 * time.Hour)},
+	})
+	// a was only enqueued because it was added, but the manual update of b is kept.
+	verifyQueue(t, s, []*repoUpdate{
+		{Repo: b, Priority: priorityHigh, Seq: 3},
+		{Repo: d, Priority: priorityLow, Seq: 4},
+	})
+	if len(s.schedule.saved) != 0 {
+		t.Errorf("expected no saved states left, got %v", s.schedule.saved)
+	}
+}
+
+func TestSchedule_persist(t *testing.T) {
+	_, stop := startRecording()
+	defer stop()
+
+	a := &configuredRepo2{Name: "a", URL: "a.com"}
+	b := &configuredRepo2{Name: "b", URL: "b.com"}
+
+	s := newUpdateScheduler()
+	setupInitialSchedule(s, []*scheduledRepoUpdate{
+		{Repo: a, Interval: minDelay, Due: defaultTime},
+		{Repo: b, Interval: minDelay, Due: defaultTime},
+	})
+
+	if states := s.schedule.takeDirty(); len(states) != 0 {
+		t.Fatalf("expected no changed states, got %s", spew.Sdump(states))
+	}
+
+	s.schedule.updateInterval(a, time.Hour)
+	s.schedule.updateLastError(b, "failed")
+	s.schedule.updateLastError(a, "") // unchanged
+
+	states := s.schedule.takeDirty()
+	sort.Slice(states, func(i, j int) bool { return states[i].Repo < states[j].Repo })
+	want := []*api.RepoUpdateScheduleState{
+		{Repo: "a", IntervalSeconds: 3600, Due: defaultTime.Add(time.Hour)},
+		{Repo: "b", IntervalSeconds: 45, Due: defaultTime, LastError: "failed", LastErrorAt: timePtr(defaultTime)},
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Fatalf("\nexpected changed states\n%s\ngot\n%s", spew.Sdump(want), spew.Sdump(states))
+	}
+
+	if states := s.schedule.takeDirty(); len(states) != 0 {
+		t.Fatalf("expected no changed states after take, got %s", spew.Sdump(states))
+	}
+
+	// States that failed to be persisted are persisted again.
+	s.schedule.markDirty(want[:1])
+	if states := s.schedule.takeDirty(); len(states) != 1 || states[0].Repo != "a" {
+		t.Fatalf("expected changed state of a, got %s", spew.Sdump(states))
+	}
+}
@@ -15,7 +15,9 @@ import (
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	gitserverprotocol "github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/pkg/httputil"
+	"github.com/sourcegraph/sourcegraph/schema"
 	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
@@ -61,7 +63,8 @@ func cachedTransportWithCertTrusted(cert string) (http.RoundTripper, error) {
 // plus a specific URL we'd like to use for it.
 type repoCreateOrUpdateRequest struct {
 	api.RepoCreateOrUpdateRequest
-	URL string // the repository's Git remote URL
+	URL          string                          // the repository's Git remote URL
+	CloneOptions *gitserverprotocol.CloneOptions // how gitserver should clone the repository (nil for a full clone)
 }
 
 // createEnableUpdateRepos receives requests on the provided channel. The
@@ -92,9 +95,11 @@ func createEnableUpdateRepos(ctx context.Context, source string, repoChan <-chan
 
 		if newScheduler {
 			newMap[createdRepo.Name] = &configuredRepo2{
-				Name:    createdRepo.Name,
-				URL:     op.URL,
-				Enabled: createdRepo.Enabled,
+				Name:         createdRepo.Name,
+				URL:          op.URL,
+				CloneOptions: op.CloneOptions,
+				Enabled:      createdRepo.Enabled,
+				Source:       source,
 			}
 			return
 		}
@@ -146,6 +151,23 @@ func setUserinfoBestEffort(rawurl, username, password string) string {
 	return u.String()
 }
 
+// cloneOptions returns the options that gitserver should use to clone the repository with the
+// given path on its code host (such as "owner/name"), according to the first of the configured
+// clone strategies that lists it. It returns nil if the repository should be fully cloned.
+func cloneOptions(strategies []*schema.CloneStrategy, path string) *gitserverprotocol.CloneOptions {
+	for _, s := range strategies {
+		for _, r := range s.Repos {
+			if strings.EqualFold(r, path) {
+				if s.Depth == 0 && s.Filter == "" {
+					return nil
+				}
+				return &gitserverprotocol.CloneOptions{Depth: s.Depth, Filter: s.Filter}
+			}
+		}
+	}
+	return nil
+}
+
 // worker represents a worker that does work under some context and can be restarted.
 type worker struct {
 	// work is invoked to perform work under the given context. It should
@@ -1,6 +1,12 @@
 package repos
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	gitserverprotocol "github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
 
 func TestSetUserinfoBestEffort(t *testing.T) {
 	cases := []struct {
@@ -41,3 +47,30 @@ func TestSetUserinfoBestEffort(t *testing.T) {
 		}
 	}
 }
+
+func TestCloneOptions(t *testing.T) {
+	strategies := []*schema.CloneStrategy{
+		{Repos: []string{"org/monorepo", "org/assets"}, Filter: "blob:none"},
+		{Repos: []string{"org/monorepo", "org/history"}, Depth: 50},
+		{Repos: []string{"org/full"}},
+	}
+	cases := []struct {
+		path string
+		want *gitserverprotocol.CloneOptions
+	}{
+		{"org/monorepo", &gitserverprotocol.CloneOptions{Filter: "blob:none"}}, // first match wins
+		{"ORG/Assets", &gitserverprotocol.CloneOptions{Filter: "blob:none"}},
+		{"org/history", &gitserverprotocol.CloneOptions{Depth: 50}},
+		{"org/full", nil},
+		{"org/other", nil},
+	}
+	for _, c := range cases {
+		got := cloneOptions(strategies, c.path)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("cloneOptions(%q): got %+v want %+v", c.path, got, c.want)
+		}
+	}
+	if got := cloneOptions(nil, "org/monorepo"); got != nil {
+		t.Errorf("cloneOptions(nil): got %+v want nil", got)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/errcode"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/awscodecommit"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketcloud"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
@@ -148,6 +149,8 @@ func (s *Server) repoLookup(ctx context.Context, args protocol.RepoLookupArgs) (
 		repos.GetGitHubRepository,
 		repos.GetGitLabRepository,
 		repos.GetBitbucketServerRepository,
+		repos.GetBitbucketCloudRepository,
+		repos.GetGiteaRepository,
 		repos.GetAWSCodeCommitRepository,
 		repos.GetGitoliteRepository,
 	} {
@@ -208,5 +211,5 @@ func isUnauthorized(err error) bool {
 }
 
 func isTemporarilyUnavailable(err error) bool {
-	return err == repos.ErrGitHubAPITemporarilyUnavailable || github.IsRateLimitExceeded(err)
+	return err == repos.ErrGitHubAPITemporarilyUnavailable || github.IsRateLimitExceeded(err) || bitbucketcloud.IsRateLimitExceeded(err)
 }
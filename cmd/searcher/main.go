@@ -53,6 +53,9 @@ func main() {
 			FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
 				return git.Archive(ctx, repo, git.ArchiveOptions{Treeish: string(commit), Format: "tar"})
 			},
+			FetchLFSObject: func(ctx context.Context, repo gitserver.Repo, oid string, size int64) (io.ReadCloser, error) {
+				return gitserver.DefaultClient.LFSObject(ctx, repo.Name, oid, size)
+			},
 			Path:              filepath.Join(cacheDir, "searcher-archives"),
 			MaxCacheSizeBytes: cacheSizeBytes,
 		},
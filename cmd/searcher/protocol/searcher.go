@@ -39,6 +39,11 @@ type Request struct {
 	// The deadline for the search request.
 	// It is parsed with time.Time.UnmarshalText.
 	Deadline string
+
+	// Stream if true will respond with newline-delimited JSON StreamEvents
+	// instead of a single Response. An event is written for each FileMatch
+	// as soon as it is found, followed by a final event with Done set.
+	Stream bool
 }
 
 // GitserverRepo returns the repository information necessary to perform gitserver requests.
@@ -54,6 +59,34 @@ type PatternInfo struct {
 	// IsRegExp if true will treat the Pattern as a regular expression.
 	IsRegExp bool
 
+	// IsStructural if true will treat the Pattern as a structural pattern.
+	// A structural pattern is literal text with holes, eg "foo(:[args])". A
+	// hole matches text with balanced parentheses, brackets, braces and
+	// string literals. IsStructural and IsRegExp are mutually exclusive.
+	IsStructural bool
+
+	// PatternExpr, if set, is a boolean expression of regular expressions
+	// (eg "(a OR b) AND NOT c") which a file's content must match. Matches
+	// of its non-negated regular expressions are returned. Pattern is
+	// ignored and IsRegExp must be true. It is sent as JSON in the
+	// PatternExpr form field.
+	PatternExpr *PatternExpr `schema:"-"`
+
+	// IsMultiline if true will match the pattern against the whole file
+	// instead of line by line, so that matches may span lines (eg
+	// "func\s+Foo\(\n\s+ctx"). The returned LineMatches then have Ranges.
+	IsMultiline bool
+
+	// Replace if true will compute the diff of replacing the matches of the
+	// pattern with Replacement in each matching file (see FileMatch.Diff).
+	// It is not supported for IsStructural or PatternExpr.
+	Replace bool
+
+	// Replacement is the text that matches are replaced with if Replace is
+	// true. It may refer to capture groups of the pattern, eg "$1" or
+	// "${name}" (see regexp.Regexp.Expand).
+	Replacement string
+
 	// IsWordMatch if true will only match the pattern at word boundaries.
 	IsWordMatch bool
 
@@ -99,6 +132,16 @@ type PatternInfo struct {
 	PatternMatchesPath bool
 }
 
+// PatternExpr is a boolean combination of regular expressions. Exactly one of
+// its fields is set, except that a PatternExpr with an empty Pattern matches
+// everything.
+type PatternExpr struct {
+	And     []*PatternExpr `json:",omitempty"`
+	Or      []*PatternExpr `json:",omitempty"`
+	Not     *PatternExpr   `json:",omitempty"`
+	Pattern string         `json:",omitempty"`
+}
+
 // AllIncludePatterns returns all include patterns (including the deprecated
 // single p.IncludePattern).
 func (p PatternInfo) AllIncludePatterns() []string {
@@ -125,6 +168,28 @@ type Response struct {
 	DeadlineHit bool
 }
 
+// StreamEvent is a single frame of a streaming search response (see
+// Request.Stream). Frames are newline-delimited JSON.
+type StreamEvent struct {
+	// Match is a file match. It is nil on the final event.
+	Match *FileMatch `json:",omitempty"`
+
+	// Done is true for the final event of the stream. The remaining fields
+	// are only set on the final event.
+	Done bool `json:",omitempty"`
+
+	// LimitHit is true if the streamed matches may not include all FileMatches because a match limit was hit.
+	LimitHit bool `json:",omitempty"`
+
+	// DeadlineHit is true if the streamed matches may not include all FileMatches because a deadline was hit.
+	DeadlineHit bool `json:",omitempty"`
+
+	// Error is set if the search failed after the stream was started. Errors
+	// which happen before any event is sent are reported via the HTTP status
+	// code, like non-streaming requests.
+	Error string `json:",omitempty"`
+}
+
 // FileMatch is the struct used by vscode to receive search results
 type FileMatch struct {
 	Path        string
@@ -132,6 +197,12 @@ type FileMatch struct {
 
 	// LimitHit is true if LineMatches may not include all LineMatches.
 	LimitHit bool
+
+	// Diff is set if PatternInfo.Replace is true. It is the unified diff of
+	// replacing all matches in the file, with a/ and b/ path prefixes (like
+	// git diff), so that it can be applied with git apply. It is empty if
+	// the replacement does not change the file.
+	Diff string `json:",omitempty"`
 }
 
 // LineMatch is the struct used by vscode to receive search results for a line.
@@ -150,4 +221,26 @@ type LineMatch struct {
 
 	// LimitHit is true if OffsetAndLengths may not include all OffsetAndLengths.
 	LimitHit bool
+
+	// Ranges is set for multiline searches (IsMultiline). It is the range of
+	// each match, which may span lines. Preview then contains all lines from
+	// LineNumber up to the last line of a match, and OffsetAndLengths only
+	// describes the part of each match on line LineNumber.
+	Ranges []Range `json:",omitempty"`
+}
+
+// Range is a range in a file. It is like the LSP Range type.
+type Range struct {
+	Start Position // inclusive
+	End   Position // exclusive
+}
+
+// Position is a position in a file. It is like the LSP Position type.
+type Position struct {
+	// Line is the 0-based line number.
+	Line int
+
+	// Character is the 0-based offset in the line, measured in characters
+	// (not bytes).
+	Character int
 }
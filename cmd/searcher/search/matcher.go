@@ -43,6 +43,10 @@ const (
 	// maxOffsets is the limit on number of matches to return on a line.
 	maxOffsets = 10
 
+	// maxMultilineMatchLines is the maximum number of lines a match may span
+	// in a multiline search. Longer matches are not returned.
+	maxMultilineMatchLines = 20
+
 	// numWorkers is how many concurrent readerGreps run per
 	// concurrentFind
 	numWorkers = 8
@@ -68,6 +72,23 @@ type readerGrep struct {
 	// re is the regexp to match, or nil if empty ("match all files' content").
 	re *regexp.Regexp
 
+	// structural is the structural pattern to match. If it is set, re is
+	// nil.
+	structural *structuralPattern
+
+	// expr is the boolean expression a file's content must match, or nil.
+	// If it is set, re matches its non-negated patterns.
+	expr *patternExpr
+
+	// multiline if true means re is matched against the whole file, so
+	// matches may span lines.
+	multiline bool
+
+	// replace if true means we compute the diff of replacing the matches of
+	// re with replacement in each matching file.
+	replace     bool
+	replacement []byte
+
 	// ignoreCase if true means we need to do case insensitive matching.
 	ignoreCase bool
 
@@ -92,35 +113,58 @@ type readerGrep struct {
 func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 	var (
 		re               *regexp.Regexp
+		structural       *structuralPattern
+		expr             *patternExpr
 		literalSubstring []byte
 	)
-	if p.Pattern != "" {
-		expr := p.Pattern
-		if !p.IsRegExp {
-			expr = regexp.QuoteMeta(expr)
+	if p.IsStructural {
+		if p.IsRegExp {
+			return nil, errors.New("a pattern can not be both structural and a regular expression")
 		}
-		if p.IsWordMatch {
-			expr = `\b` + expr + `\b`
+		if p.PatternExpr != nil {
+			return nil, errors.New("a pattern can not be both structural and a pattern expression")
 		}
-		if p.IsRegExp {
-			// We don't do the search line by line, therefore we want the
-			// regex engine to consider newlines for anchors (^$).
-			expr = "(?m:" + expr + ")"
+		if p.Replace {
+			return nil, errors.New("replacing the matches of a structural pattern is not supported")
+		}
+		var err error
+		structural, err = parseStructural(p.Pattern)
+		if err != nil {
+			return nil, err
 		}
 		if !p.IsCaseSensitive {
-			// We don't just use (?i) because regexp library doesn't seem
-			// to contain good optimizations for case insensitive
-			// search. Instead we lowercase the input and pattern.
-			re, err := syntax.Parse(expr, syntax.Perl)
+			structural.lowerASCII()
+		}
+		literalSubstring = structural.longestLiteral()
+	} else if p.Pattern != "" || p.PatternExpr != nil {
+		pattern := p.Pattern
+		if p.PatternExpr != nil {
+			if !p.IsRegExp {
+				return nil, errors.New("a pattern expression must consist of regular expressions")
+			}
+			if p.Replace {
+				return nil, errors.New("replacing the matches of a pattern expression is not supported")
+			}
+			var err error
+			expr, err = compilePatternExpr(p.PatternExpr, p)
 			if err != nil {
 				return nil, err
 			}
-			lowerRegexpASCII(re)
-			expr = re.String()
+			// We find candidate files with (and highlight) the matches of
+			// the non-negated patterns, so every file matching the
+			// expression must contain one.
+			if !requiresPositiveMatch(p.PatternExpr, false) {
+				return nil, errors.New("a pattern expression must require a match of a pattern that is not negated (such as a AND NOT b, but not a OR NOT b)")
+			}
+			positive := positivePatterns(p.PatternExpr, false)
+			pattern = "(?:" + strings.Join(positive, ")|(?:") + ")"
+			if len(positive) > 1 {
+				pattern = "(?:" + pattern + ")"
+			}
 		}
 
 		var err error
-		re, err = regexp.Compile(expr)
+		re, err = compileRegexp(pattern, p)
 		if err != nil {
 			return nil, err
 		}
@@ -128,7 +172,7 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 		// Only use literalSubstring optimization if the regex engine doesn't
 		// have a prefix to use.
 		if pre, _ := re.LiteralPrefix(); pre == "" {
-			ast, err := syntax.Parse(expr, syntax.Perl)
+			ast, err := syntax.Parse(re.String(), syntax.Perl)
 			if err != nil {
 				return nil, err
 			}
@@ -137,6 +181,10 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 		}
 	}
 
+	if p.Replace && re == nil {
+		return nil, errors.New("replacing requires a pattern")
+	}
+
 	pathOptions := pathmatch.CompileOptions{
 		RegExp:        p.PathPatternsAreRegExps,
 		CaseSensitive: p.PathPatternsAreCaseSensitive,
@@ -148,12 +196,48 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 
 	return &readerGrep{
 		re:               re,
+		structural:       structural,
+		expr:             expr,
+		multiline:        p.IsMultiline && !p.IsStructural,
+		replace:          p.Replace,
+		replacement:      []byte(p.Replacement),
 		ignoreCase:       !p.IsCaseSensitive,
 		matchPath:        matchPath,
 		literalSubstring: literalSubstring,
 	}, nil
 }
 
+// compileRegexp compiles pattern (a regular expression if p.IsRegExp,
+// otherwise a fixed string) according to p's options. If the search is case
+// insensitive, the regexp is lowercased and must be matched against
+// lowercased input.
+func compileRegexp(pattern string, p *protocol.PatternInfo) (*regexp.Regexp, error) {
+	expr := pattern
+	if !p.IsRegExp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if p.IsWordMatch {
+		expr = `\b` + expr + `\b`
+	}
+	if p.IsRegExp {
+		// We don't do the search line by line, therefore we want the
+		// regex engine to consider newlines for anchors (^$).
+		expr = "(?m:" + expr + ")"
+	}
+	if !p.IsCaseSensitive {
+		// We don't just use (?i) because regexp library doesn't seem
+		// to contain good optimizations for case insensitive
+		// search. Instead we lowercase the input and pattern.
+		re, err := syntax.Parse(expr, syntax.Perl)
+		if err != nil {
+			return nil, err
+		}
+		lowerRegexpASCII(re)
+		expr = re.String()
+	}
+	return regexp.Compile(expr)
+}
+
 // Copy returns a copied version of rg that is safe to use from another
 // goroutine.
 func (rg *readerGrep) Copy() *readerGrep {
@@ -163,6 +247,11 @@ func (rg *readerGrep) Copy() *readerGrep {
 	}
 	return &readerGrep{
 		re:               reCopy,
+		structural:       rg.structural,
+		expr:             rg.expr,
+		multiline:        rg.multiline,
+		replace:          rg.replace,
+		replacement:      rg.replacement,
 		ignoreCase:       rg.ignoreCase,
 		matchPath:        rg.matchPath.Copy(),
 		literalSubstring: rg.literalSubstring,
@@ -172,6 +261,11 @@ func (rg *readerGrep) Copy() *readerGrep {
 // matchString returns whether rg's regexp pattern matches s. It is intended to be
 // used to match file paths.
 func (rg *readerGrep) matchString(s string) bool {
+	if rg.structural != nil || rg.expr != nil {
+		// Structural patterns and pattern expressions only match file
+		// content.
+		return false
+	}
 	if rg.re == nil {
 		return true
 	}
@@ -214,10 +308,24 @@ func (rg *readerGrep) Find(zf *zipFile, f *srcFile) (matches []protocol.LineMatc
 	if !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
 		return nil, false, nil
 	}
+	if rg.structural != nil {
+		matches, limitHit, err = findStructural(rg.structural, fileBuf, fileMatchBuf)
+		if err != nil {
+			// The pattern is too expensive to match, which the user can fix.
+			return nil, false, badRequestError{f.Name + ": " + err.Error()}
+		}
+		return matches, limitHit, nil
+	}
 	first := rg.re.FindIndex(fileMatchBuf)
 	if first == nil {
 		return nil, false, nil
 	}
+	if rg.expr != nil && !rg.expr.match(fileMatchBuf) {
+		return nil, false, nil
+	}
+	if rg.multiline {
+		return findMultiline(rg.re, fileBuf, fileMatchBuf)
+	}
 
 	idx := 0
 	for i := 0; len(matches) < maxLineMatches; i++ {
@@ -278,23 +386,214 @@ func (rg *readerGrep) Find(zf *zipFile, f *srcFile) (matches []protocol.LineMatc
 	return matches, limitHit, nil
 }
 
-// FindZip is a convenience function to run Find on f.
+// findStructural returns a LineMatch for each line on which a match of sp
+// starts. fileMatchBuf is what we match on, fileBuf is the original data (for
+// Preview). They must have the same length. A match spanning several lines
+// is highlighted up to the end of the line it starts on.
+func findStructural(sp *structuralPattern, fileBuf, fileMatchBuf []byte) (matches []protocol.LineMatch, limitHit bool, err error) {
+	var (
+		lineNumber = 0
+		lineStart  = 0 // offset of the start of line lineNumber in fileBuf
+	)
+	locs, err := sp.FindAllIndex(fileMatchBuf, -1)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+
+		// Advance to the line containing start.
+		for {
+			nl := bytes.IndexByte(fileBuf[lineStart:start], '\n')
+			if nl < 0 {
+				break
+			}
+			lineStart += nl + 1
+			lineNumber++
+		}
+		lineEnd := len(fileBuf)
+		if nl := bytes.IndexByte(fileBuf[lineStart:], '\n'); nl >= 0 {
+			lineEnd = lineStart + nl
+		}
+		lineBuf := bytes.TrimSuffix(fileBuf[lineStart:lineEnd], []byte{'\r'})
+
+		// Skip lines that are too long.
+		if len(lineBuf) > maxLineSize {
+			continue
+		}
+		if end > lineStart+len(lineBuf) {
+			end = lineStart + len(lineBuf)
+		}
+		offsetAndLength := [2]int{
+			utf8.RuneCount(lineBuf[:start-lineStart]),
+			utf8.RuneCount(fileBuf[start:end]),
+		}
+
+		if n := len(matches); n > 0 && matches[n-1].LineNumber == lineNumber {
+			lm := &matches[n-1]
+			if len(lm.OffsetAndLengths) < maxOffsets {
+				lm.OffsetAndLengths = append(lm.OffsetAndLengths, offsetAndLength)
+			} else {
+				lm.LimitHit = true
+			}
+			continue
+		}
+		if len(matches) == maxLineMatches {
+			limitHit = true
+			break
+		}
+		matches = append(matches, protocol.LineMatch{
+			// Like in Find, making a copy of lineBuf is intentional.
+			Preview:          string(lineBuf),
+			LineNumber:       lineNumber,
+			OffsetAndLengths: [][2]int{offsetAndLength},
+		})
+	}
+	return matches, limitHit, nil
+}
+
+// findMultiline returns LineMatches for the matches of re, which may span
+// lines. fileMatchBuf is what we match on, fileBuf is the original data (for
+// Preview). They must have the same length. Each LineMatch has the Ranges of
+// the matches on its lines. A match starting on a line of the previous
+// LineMatch is added to it.
+func findMultiline(re *regexp.Regexp, fileBuf, fileMatchBuf []byte) (matches []protocol.LineMatch, limitHit bool, err error) {
+	var (
+		line      = 0 // line number of lineStart
+		lineStart = 0 // offset of the start of line in fileBuf
+
+		groupStart    int // offset of the start of the Preview of the last LineMatch
+		groupLastLine int // line number of the last line of the last LineMatch
+	)
+	position := func(pos int) protocol.Position {
+		for {
+			nl := bytes.IndexByte(fileBuf[lineStart:pos], '\n')
+			if nl < 0 {
+				break
+			}
+			lineStart += nl + 1
+			line++
+		}
+		return protocol.Position{Line: line, Character: utf8.RuneCount(fileBuf[lineStart:pos])}
+	}
+
+	locs := re.FindAllIndex(fileMatchBuf, maxLineMatches*maxOffsets)
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+		startPos := position(start)
+		startLineStart := lineStart
+		endPos := position(end)
+
+		// A match ending with a newline ends on the line of the newline,
+		// so don't include the following line in the Preview.
+		last, lastLine := end, endPos.Line
+		if end > start && fileBuf[end-1] == '\n' {
+			last, lastLine = end-1, endPos.Line-1
+		}
+		lineEnd := len(fileBuf)
+		if nl := bytes.IndexByte(fileBuf[last:], '\n'); nl >= 0 {
+			lineEnd = last + nl
+		}
+
+		// Skip matches that are too long.
+		if lastLine-startPos.Line >= maxMultilineMatchLines || lineEnd-startLineStart > maxMultilineMatchLines*maxLineSize {
+			continue
+		}
+
+		// The part of the match on its first line.
+		firstLineEnd := end
+		if nl := bytes.IndexByte(fileBuf[start:end], '\n'); nl >= 0 {
+			firstLineEnd = start + nl
+		}
+		offsetAndLength := [2]int{startPos.Character, utf8.RuneCount(fileBuf[start:firstLineEnd])}
+		r := protocol.Range{Start: startPos, End: endPos}
+
+		if n := len(matches); n > 0 && startPos.Line <= groupLastLine {
+			lm := &matches[n-1]
+			if len(lm.Ranges) == maxOffsets {
+				lm.LimitHit = true
+				continue
+			}
+			lm.Ranges = append(lm.Ranges, r)
+			if startPos.Line == lm.LineNumber {
+				lm.OffsetAndLengths = append(lm.OffsetAndLengths, offsetAndLength)
+			}
+			if lastLine > groupLastLine {
+				lm.Preview = string(fileBuf[groupStart:lineEnd])
+				groupLastLine = lastLine
+			}
+			continue
+		}
+		if len(matches) == maxLineMatches {
+			limitHit = true
+			break
+		}
+		matches = append(matches, protocol.LineMatch{
+			// Like in Find, making a copy of the lines is intentional.
+			Preview:          string(fileBuf[startLineStart:lineEnd]),
+			LineNumber:       startPos.Line,
+			OffsetAndLengths: [][2]int{offsetAndLength},
+			Ranges:           []protocol.Range{r},
+		})
+		groupStart, groupLastLine = startLineStart, lastLine
+	}
+	if len(locs) == maxLineMatches*maxOffsets {
+		limitHit = true
+	}
+	return matches, limitHit, nil
+}
+
+// FindZip is a convenience function to run Find on f. If rg.replace is true,
+// it also computes the Diff of a matching file.
 func (rg *readerGrep) FindZip(zf *zipFile, f *srcFile) (protocol.FileMatch, error) {
 	lm, limitHit, err := rg.Find(zf, f)
-	return protocol.FileMatch{
+	fm := protocol.FileMatch{
 		Path:        f.Name,
 		LineMatches: lm,
 		LimitHit:    limitHit,
-	}, err
+	}
+	if err == nil && rg.replace && len(lm) > 0 {
+		fm.Diff = rg.replaceDiff(zf, f)
+	}
+	return fm, err
+}
+
+// replaceDiff returns the unified diff of replacing all matches of rg.re in f
+// with rg.replacement.
+func (rg *readerGrep) replaceDiff(zf *zipFile, f *srcFile) string {
+	fileBuf := zf.DataFor(f)
+	fileMatchBuf := fileBuf
+	if rg.ignoreCase {
+		// Like in Find, we match on the lowercased input. Capture groups
+		// are expanded from the original data.
+		fileMatchBuf = rg.transformBuf[:len(fileBuf)]
+		bytesToLowerASCII(fileMatchBuf, fileBuf)
+	}
+	reps := findReplacements(rg.re, rg.replacement, fileBuf, fileMatchBuf, rg.multiline)
+	return replaceDiff(f.Name, fileBuf, reps)
 }
 
 // concurrentFind searches files in zr looking for matches using rg.
-func concurrentFind(ctx context.Context, rg *readerGrep, zf *zipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths bool) (fm []protocol.FileMatch, limitHit bool, err error) {
+//
+// If onMatch is non-nil it is called with each FileMatch as soon as it is
+// found, before concurrentFind returns. The workers send their matches to a
+// single goroutine which collects them and calls onMatch, so calls to
+// onMatch are serialized without holding a lock.
+func concurrentFind(ctx context.Context, rg *readerGrep, zf *zipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths bool, onMatch func(protocol.FileMatch)) (fm []protocol.FileMatch, limitHit bool, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "ConcurrentFind")
 	ext.Component.Set(span, "matcher")
 	if rg.re != nil {
 		span.SetTag("re", rg.re.String())
 	}
+	if rg.structural != nil {
+		span.SetTag("structural", rg.structural.String())
+	}
+	if rg.expr != nil {
+		span.SetTag("expr", rg.expr.String())
+	}
+	if rg.replace {
+		span.SetTag("replacement", string(rg.replacement))
+	}
 	span.SetTag("path", rg.matchPath.String())
 	defer func() {
 		if err != nil {
@@ -325,19 +624,22 @@ func concurrentFind(ctx context.Context, rg *readerGrep, zf *zipFile, fileMatchL
 	defer cancel()
 
 	var (
-		filesmu   sync.Mutex // protects files
-		files     = zf.Files
-		matchesmu sync.Mutex // protects matches, limitHit
-		matches   = []protocol.FileMatch{}
+		filesmu sync.Mutex // protects files
+		files   = zf.Files
+		matches = []protocol.FileMatch{}
 	)
 
-	if patternMatchesPaths && (!patternMatchesContent || rg.re == nil) {
+	if patternMatchesPaths && (!patternMatchesContent || (rg.re == nil && rg.structural == nil)) {
 		// Fast path for only matching file paths (or with a nil pattern, which matches all files,
 		// so is effectively matching only on file paths).
 		for _, f := range files {
 			if rg.matchPath.MatchPath(f.Name) && rg.matchString(f.Name) {
 				if len(matches) < fileMatchLimit {
-					matches = append(matches, protocol.FileMatch{Path: f.Name})
+					fm := protocol.FileMatch{Path: f.Name}
+					matches = append(matches, fm)
+					if onMatch != nil {
+						onMatch(fm)
+					}
 				} else {
 					limitHit = true
 					break
@@ -354,9 +656,28 @@ func concurrentFind(ctx context.Context, rg *readerGrep, zf *zipFile, fileMatchL
 		wgErr         error
 		filesSkipped  uint32 // accessed atomically
 		filesSearched uint32 // accessed atomically
+		matchc        = make(chan protocol.FileMatch)
+		collected     = make(chan struct{})
 	)
 
-	// Start workers. They read from files and write to matches.
+	// Start the collector. It is the only writer of matches and limitHit, and
+	// it stops the search once fileMatchLimit is reached.
+	go func() {
+		defer close(collected)
+		for fm := range matchc {
+			if len(matches) < fileMatchLimit {
+				matches = append(matches, fm)
+				if onMatch != nil {
+					onMatch(fm)
+				}
+			} else {
+				limitHit = true
+				cancel()
+			}
+		}
+	}()
+
+	// Start workers. They read from files and send to matchc.
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func(rg *readerGrep) {
@@ -406,20 +727,19 @@ func concurrentFind(ctx context.Context, rg *readerGrep, zf *zipFile, fileMatchL
 					}
 				}
 				if match {
-					matchesmu.Lock()
-					if len(matches) < fileMatchLimit {
-						matches = append(matches, fm)
-					} else {
-						limitHit = true
-						cancel()
+					select {
+					case matchc <- fm:
+					case <-done:
+						return
 					}
-					matchesmu.Unlock()
 				}
 			}
 		}(rg.Copy())
 	}
 
 	wg.Wait()
+	close(matchc)
+	<-collected
 
 	err = wgErr
 	if err == nil && ctx.Err() == context.DeadlineExceeded {
@@ -263,7 +263,7 @@ func benchConcurrentFind(b *testing.B, p *protocol.Request) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		_, _, err := concurrentFind(ctx, rg, zf, 0, p.PatternMatchesContent, p.PatternMatchesPath)
+		_, _, err := concurrentFind(ctx, rg, zf, 0, p.PatternMatchesContent, p.PatternMatchesPath, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -465,6 +465,82 @@ func TestLineLimit(t *testing.T) {
 	}
 }
 
+func TestFindMultiline(t *testing.T) {
+	pos := func(line, character int) protocol.Position {
+		return protocol.Position{Line: line, Character: character}
+	}
+	input := "package main\n\nfunc Foo(\n\tctx context.Context,\n) {}\n\nfunc Bar(ctx context.Context) {}\n"
+	tests := []struct {
+		pattern string
+		want    []protocol.LineMatch
+	}{
+		{
+			pattern: `func\s+Foo\(\n\s+ctx`,
+			want: []protocol.LineMatch{{
+				Preview:          "func Foo(\n\tctx context.Context,",
+				LineNumber:       2,
+				OffsetAndLengths: [][2]int{{0, 9}},
+				Ranges:           []protocol.Range{{Start: pos(2, 0), End: pos(3, 4)}},
+			}},
+		},
+		{
+			// Matches on the lines of a previous match are merged.
+			pattern: `\(\n?\s*ctx|context\.`,
+			want: []protocol.LineMatch{
+				{
+					Preview:          "func Foo(\n\tctx context.Context,",
+					LineNumber:       2,
+					OffsetAndLengths: [][2]int{{8, 1}},
+					Ranges: []protocol.Range{
+						{Start: pos(2, 8), End: pos(3, 4)},
+						{Start: pos(3, 5), End: pos(3, 13)},
+					},
+				},
+				{
+					Preview:          "func Bar(ctx context.Context) {}",
+					LineNumber:       6,
+					OffsetAndLengths: [][2]int{{8, 4}, {13, 8}},
+					Ranges: []protocol.Range{
+						{Start: pos(6, 8), End: pos(6, 12)},
+						{Start: pos(6, 13), End: pos(6, 21)},
+					},
+				},
+			},
+		},
+		{
+			// A trailing newline does not add a line to the Preview.
+			pattern: `\) \{\}\n`,
+			want: []protocol.LineMatch{
+				{
+					Preview:          ") {}",
+					LineNumber:       4,
+					OffsetAndLengths: [][2]int{{0, 4}},
+					Ranges:           []protocol.Range{{Start: pos(4, 0), End: pos(5, 0)}},
+				},
+				{
+					Preview:          "func Bar(ctx context.Context) {}",
+					LineNumber:       6,
+					OffsetAndLengths: [][2]int{{28, 4}},
+					Ranges:           []protocol.Range{{Start: pos(6, 28), End: pos(7, 0)}},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		re := regexp.MustCompile(test.pattern)
+		got, limitHit, err := findMultiline(re, []byte(input), []byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if limitHit {
+			t.Errorf("%s: expected limit to not hit", test.pattern)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", test.pattern, got, test.want)
+		}
+	}
+}
+
 func TestMaxMatches(t *testing.T) {
 	pattern := "foo"
 
@@ -500,7 +576,7 @@ func TestMaxMatches(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fileMatches, limitHit, err := concurrentFind(context.Background(), rg, zf, 0, true, false)
+	fileMatches, limitHit, err := concurrentFind(context.Background(), rg, zf, 0, true, false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -560,7 +636,7 @@ func TestPathMatches(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fileMatches, _, err := concurrentFind(context.Background(), rg, zf, 10, true, true)
+	fileMatches, _, err := concurrentFind(context.Background(), rg, zf, 10, true, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
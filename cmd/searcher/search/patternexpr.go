@@ -0,0 +1,150 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// patternExpr is a compiled protocol.PatternExpr. It is safe for concurrent
+// use.
+type patternExpr struct {
+	and, or []*patternExpr
+	not     *patternExpr
+	re      *regexp.Regexp // if nil (and no other field is set), matches everything
+}
+
+// compilePatternExpr compiles the regular expressions in e with p's options.
+func compilePatternExpr(e *protocol.PatternExpr, p *protocol.PatternInfo) (*patternExpr, error) {
+	switch {
+	case e.Not != nil:
+		not, err := compilePatternExpr(e.Not, p)
+		if err != nil {
+			return nil, err
+		}
+		return &patternExpr{not: not}, nil
+
+	case len(e.And) > 0 || len(e.Or) > 0:
+		var pe patternExpr
+		for _, operand := range e.And {
+			c, err := compilePatternExpr(operand, p)
+			if err != nil {
+				return nil, err
+			}
+			pe.and = append(pe.and, c)
+		}
+		for _, operand := range e.Or {
+			c, err := compilePatternExpr(operand, p)
+			if err != nil {
+				return nil, err
+			}
+			pe.or = append(pe.or, c)
+		}
+		return &pe, nil
+
+	case e.Pattern == "":
+		return &patternExpr{}, nil
+	}
+
+	re, err := compileRegexp(e.Pattern, p)
+	if err != nil {
+		return nil, err
+	}
+	return &patternExpr{re: re}, nil
+}
+
+// match reports whether buf matches e. If e was compiled for a case
+// insensitive search, buf must be lowercased.
+func (e *patternExpr) match(buf []byte) bool {
+	switch {
+	case e.not != nil:
+		return !e.not.match(buf)
+	case len(e.and) > 0:
+		for _, c := range e.and {
+			if !c.match(buf) {
+				return false
+			}
+		}
+		return true
+	case len(e.or) > 0:
+		for _, c := range e.or {
+			if c.match(buf) {
+				return true
+			}
+		}
+		return false
+	case e.re != nil:
+		return e.re.Match(buf)
+	}
+	return true
+}
+
+// String returns a string describing e, for tracing.
+func (e *patternExpr) String() string {
+	join := func(cs []*patternExpr, op string) string {
+		s := make([]string, len(cs))
+		for i, c := range cs {
+			s[i] = c.String()
+		}
+		return "(" + strings.Join(s, " "+op+" ") + ")"
+	}
+	switch {
+	case e.not != nil:
+		return "NOT " + e.not.String()
+	case len(e.and) > 0:
+		return join(e.and, "AND")
+	case len(e.or) > 0:
+		return join(e.or, "OR")
+	case e.re != nil:
+		return "/" + e.re.String() + "/"
+	}
+	return "//"
+}
+
+// positivePatterns returns the non-empty patterns in e which are not negated.
+// not is whether e is inside a negated expression.
+func positivePatterns(e *protocol.PatternExpr, not bool) []string {
+	switch {
+	case e.Not != nil:
+		return positivePatterns(e.Not, !not)
+	case len(e.And) > 0 || len(e.Or) > 0:
+		var patterns []string
+		operands := e.And
+		if len(operands) == 0 {
+			operands = e.Or
+		}
+		for _, operand := range operands {
+			patterns = append(patterns, positivePatterns(operand, not)...)
+		}
+		return patterns
+	case not || e.Pattern == "":
+		return nil
+	}
+	return []string{e.Pattern}
+}
+
+// requiresPositiveMatch reports whether every file matching e contains a
+// match of one of the patterns returned by positivePatterns. not is whether e
+// is inside a negated expression.
+func requiresPositiveMatch(e *protocol.PatternExpr, not bool) bool {
+	switch {
+	case e.Not != nil:
+		return requiresPositiveMatch(e.Not, !not)
+	case len(e.And) > 0 || len(e.Or) > 0:
+		// A conjunction requires a match if any operand does, a disjunction
+		// only if all operands do. Negation swaps them (De Morgan).
+		conjunction := (len(e.And) > 0) != not
+		operands := e.And
+		if len(operands) == 0 {
+			operands = e.Or
+		}
+		for _, operand := range operands {
+			if requiresPositiveMatch(operand, not) == conjunction {
+				return conjunction
+			}
+		}
+		return !conjunction
+	}
+	return !not && e.Pattern != ""
+}
@@ -0,0 +1,204 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+// in a replacement diff, like the default of diff -u.
+const diffContextLines = 3
+
+// replacement replaces buf[start:end] of a file with text.
+type replacement struct {
+	start, end int
+	text       []byte
+}
+
+// findReplacements returns the replacements of the matches of re in fileBuf
+// with template, which may refer to capture groups (see
+// regexp.Regexp.Expand). fileMatchBuf is what we match on, fileBuf is the
+// original data (which the capture groups refer to). They must have the same
+// length. Unless multiline is true, re is matched line by line.
+func findReplacements(re *regexp.Regexp, template []byte, fileBuf, fileMatchBuf []byte, multiline bool) []replacement {
+	var reps []replacement
+	find := func(offset int, buf, matchBuf []byte) {
+		for _, loc := range re.FindAllSubmatchIndex(matchBuf, -1) {
+			reps = append(reps, replacement{
+				start: offset + loc[0],
+				end:   offset + loc[1],
+				text:  re.Expand(nil, template, buf, loc),
+			})
+		}
+	}
+	if multiline {
+		find(0, fileBuf, fileMatchBuf)
+		return reps
+	}
+	for lineStart := 0; lineStart < len(fileBuf); {
+		lineEnd := len(fileBuf)
+		if nl := bytes.IndexByte(fileBuf[lineStart:], '\n'); nl >= 0 {
+			lineEnd = lineStart + nl
+		}
+		find(lineStart, fileBuf[lineStart:lineEnd], fileMatchBuf[lineStart:lineEnd])
+		lineStart = lineEnd + 1
+	}
+	return reps
+}
+
+// replaceDiff returns the unified diff of the file at path, whose contents
+// are buf, with reps applied. reps must be sorted and not overlap. The diff
+// uses the a/ and b/ path prefixes, so that it can be applied with git apply.
+// It is empty if reps do not change the file.
+func replaceDiff(path string, buf []byte, reps []replacement) string {
+	lines := splitLines(buf)
+	lineStarts := make([]int, len(lines)+1)
+	for i, line := range lines {
+		lineStarts[i+1] = lineStarts[i] + len(line)
+	}
+	// lineOf returns the index of the line containing buf[pos]. The end of
+	// the file belongs to the last line.
+	lineOf := func(pos int) int {
+		i := sort.Search(len(lines), func(i int) bool { return lineStarts[i+1] > pos })
+		if i == len(lines) && i > 0 {
+			i--
+		}
+		return i
+	}
+
+	// Compute the changed lines. Each replacement changes the lines from
+	// the one it starts on to the one its end is on (which differs if the
+	// match ends with a newline).
+	type change struct {
+		start    int // index of the first old line
+		old, new [][]byte
+	}
+	var changes []change
+	for i := 0; i < len(reps); {
+		first, last := lineOf(reps[i].start), lineOf(reps[i].end)
+		var (
+			j      = i
+			newBuf []byte
+			pos    = lineStarts[first]
+		)
+		for ; j < len(reps) && lineOf(reps[j].start) <= last; j++ {
+			newBuf = append(newBuf, buf[pos:reps[j].start]...)
+			newBuf = append(newBuf, reps[j].text...)
+			pos = reps[j].end
+			if end := lineOf(reps[j].end); end > last {
+				last = end
+			}
+		}
+		end := len(buf)
+		if last < len(lines) {
+			end = lineStarts[last+1]
+		}
+		newBuf = append(newBuf, buf[pos:end]...)
+		i = j
+
+		c := change{start: first, old: lines[first:min(last+1, len(lines))], new: splitLines(newBuf)}
+		for len(c.old) > 0 && len(c.new) > 0 && bytes.Equal(c.old[0], c.new[0]) {
+			c.start++
+			c.old, c.new = c.old[1:], c.new[1:]
+		}
+		for len(c.old) > 0 && len(c.new) > 0 && bytes.Equal(c.old[len(c.old)-1], c.new[len(c.new)-1]) {
+			c.old, c.new = c.old[:len(c.old)-1], c.new[:len(c.new)-1]
+		}
+		if len(c.old) > 0 || len(c.new) > 0 {
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", path, path)
+	writeLine := func(body *bytes.Buffer, prefix byte, line []byte) {
+		body.WriteByte(prefix)
+		body.Write(line)
+		if !bytes.HasSuffix(line, []byte{'\n'}) {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	// delta is the difference between the new and old line numbers after
+	// the changes written so far.
+	delta := 0
+	for i := 0; i < len(changes); {
+		// A hunk contains the changes whose context overlaps.
+		j := i + 1
+		for j < len(changes) && changes[j].start-(changes[j-1].start+len(changes[j-1].old)) <= 2*diffContextLines {
+			j++
+		}
+
+		var (
+			body     bytes.Buffer
+			oldStart = max(changes[i].start-diffContextLines, 0)
+			newStart = oldStart + delta
+			pos      = oldStart // index of the next old line to write
+		)
+		for _, c := range changes[i:j] {
+			for ; pos < c.start; pos++ {
+				writeLine(&body, ' ', lines[pos])
+			}
+			for _, line := range c.old {
+				writeLine(&body, '-', line)
+			}
+			for _, line := range c.new {
+				writeLine(&body, '+', line)
+			}
+			pos += len(c.old)
+			delta += len(c.new) - len(c.old)
+		}
+		oldEnd := min(pos+diffContextLines, len(lines))
+		for ; pos < oldEnd; pos++ {
+			writeLine(&body, ' ', lines[pos])
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldEnd-oldStart), hunkRange(newStart, oldEnd+delta-newStart))
+		out.Write(body.Bytes())
+		i = j
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of count lines starting at the 0-based line
+// index start for a unified diff hunk header.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before it.
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits buf after each newline. The last line does not end with
+// a newline if buf doesn't.
+func splitLines(buf []byte) [][]byte {
+	var lines [][]byte
+	for len(buf) > 0 {
+		n := len(buf)
+		if nl := bytes.IndexByte(buf, '\n'); nl >= 0 {
+			n = nl + 1
+		}
+		lines = append(lines, buf[:n])
+		buf = buf[n:]
+	}
+	return lines
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,133 @@
+package search
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestReplaceDiff(t *testing.T) {
+	cases := []struct {
+		name        string
+		pattern     string
+		replacement string
+		multiline   bool
+		input       string
+		want        string
+	}{
+		{
+			name:        "capture groups",
+			pattern:     `foo\((\w+)\)`,
+			replacement: "bar($1, nil)",
+			input:       "a\nx := foo(y)\nb\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -1,3 +1,3 @@
+ a
+-x := foo(y)
++x := bar(y, nil)
+ b
+`,
+		},
+		{
+			name:        "context and separate hunks",
+			pattern:     "x",
+			replacement: "y",
+			input:       "x\n1\n2\n3\n4\n5\n6\n7\n8\nx\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -1,4 +1,4 @@
+-x
++y
+ 1
+ 2
+ 3
+@@ -7,4 +7,4 @@
+ 6
+ 7
+ 8
+-x
++y
+`,
+		},
+		{
+			name:        "merged hunk",
+			pattern:     "x",
+			replacement: "y",
+			input:       "x\n1\n2\n3\n4\n5\n6\nx x\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -1,8 +1,8 @@
+-x
++y
+ 1
+ 2
+ 3
+ 4
+ 5
+ 6
+-x x
++y y
+`,
+		},
+		{
+			name:        "no newline at end of file",
+			pattern:     "b",
+			replacement: "c",
+			input:       "a\nb",
+			want: `--- a/f.go
++++ b/f.go
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+		{
+			name:        "multiline deletion",
+			pattern:     `// TODO\n`,
+			replacement: "",
+			multiline:   true,
+			input:       "a\n// TODO\nb\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -1,3 +1,2 @@
+ a
+-// TODO
+ b
+`,
+		},
+		{
+			name:        "multiline join",
+			pattern:     `,\n\s*`,
+			replacement: ", ",
+			multiline:   true,
+			input:       "f(a,\n\tb)\n",
+			want: `--- a/f.go
++++ b/f.go
+@@ -1,2 +1,1 @@
+-f(a,
+-	b)
++f(a, b)
+`,
+		},
+		{
+			name:        "unchanged",
+			pattern:     "a",
+			replacement: "a",
+			input:       "a\n",
+			want:        "",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			re := regexp.MustCompile("(?m:" + c.pattern + ")")
+			buf := []byte(c.input)
+			reps := findReplacements(re, []byte(c.replacement), buf, buf, c.multiline)
+			if got := replaceDiff("f.go", buf, reps); got != c.want {
+				t.Errorf("got diff:\n%s\nwant:\n%s", got, c.want)
+			}
+		})
+	}
+}
@@ -64,6 +64,12 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to decode form: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if expr := r.Form.Get("PatternExpr"); expr != "" {
+		if err := json.Unmarshal([]byte(expr), &p.PatternExpr); err != nil {
+			http.Error(w, "failed to decode PatternExpr: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	if p.Deadline != "" {
 		var deadline time.Time
 		if err := deadline.UnmarshalText([]byte(p.Deadline)); err != nil {
@@ -84,8 +90,25 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches, limitHit, deadlineHit, err := s.search(ctx, &p)
+	var (
+		sw      *streamWriter
+		onMatch func(protocol.FileMatch)
+	)
+	if p.Stream {
+		sw = newStreamWriter(w)
+		onMatch = func(fm protocol.FileMatch) {
+			sw.Send(&protocol.StreamEvent{Match: &fm})
+		}
+	}
+
+	matches, limitHit, deadlineHit, err := s.search(ctx, &p, onMatch)
 	if err != nil {
+		if sw != nil && sw.Started() {
+			// We have already responded with a 200, so the only way to
+			// communicate the error is in the final event.
+			sw.Send(&protocol.StreamEvent{Done: true, Error: err.Error()})
+			return
+		}
 		code := http.StatusInternalServerError
 		if isBadRequest(err) || ctx.Err() == context.Canceled {
 			code = http.StatusBadRequest
@@ -97,6 +120,16 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), code)
 		return
 	}
+
+	if sw != nil {
+		sw.Send(&protocol.StreamEvent{
+			Done:        true,
+			LimitHit:    limitHit,
+			DeadlineHit: deadlineHit,
+		})
+		return
+	}
+
 	if matches == nil {
 		// Return an empty list
 		matches = make([]protocol.FileMatch, 0)
@@ -115,7 +148,7 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(&resp)
 }
 
-func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []protocol.FileMatch, limitHit, deadlineHit bool, err error) {
+func (s *Service) search(ctx context.Context, p *protocol.Request, onMatch func(protocol.FileMatch)) (matches []protocol.FileMatch, limitHit, deadlineHit bool, err error) {
 	tr := trace.New("search", fmt.Sprintf("%s@%s", p.Repo, p.Commit))
 	tr.LazyPrintf("%s", p.Pattern)
 
@@ -126,6 +159,9 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	span.SetTag("commit", p.Commit)
 	span.SetTag("pattern", p.Pattern)
 	span.SetTag("isRegExp", strconv.FormatBool(p.IsRegExp))
+	span.SetTag("isStructural", strconv.FormatBool(p.IsStructural))
+	span.SetTag("isMultiline", strconv.FormatBool(p.IsMultiline))
+	span.SetTag("replace", strconv.FormatBool(p.Replace))
 	span.SetTag("isWordMatch", strconv.FormatBool(p.IsWordMatch))
 	span.SetTag("isCaseSensitive", strconv.FormatBool(p.IsCaseSensitive))
 	span.SetTag("pathPatternsAreRegExps", strconv.FormatBool(p.PathPatternsAreRegExps))
@@ -134,6 +170,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	span.SetTag("patternMatchesContent", p.PatternMatchesContent)
 	span.SetTag("patternMatchesPath", p.PatternMatchesPath)
 	span.SetTag("deadline", p.Deadline)
+	span.SetTag("stream", p.Stream)
 	defer func(start time.Time) {
 		code := "200"
 		// We often have canceled and timed out requests. We do not want to
@@ -167,7 +204,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 		span.SetTag("deadlineHit", deadlineHit)
 		span.Finish()
 		if s.Log != nil {
-			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "matches", len(matches), "code", code, "duration", time.Since(start), "err", err)
+			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isStructural", p.IsStructural, "isMultiline", p.IsMultiline, "replace", p.Replace, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "matches", len(matches), "code", code, "duration", time.Since(start), "err", err)
 		}
 	}(time.Now())
 
@@ -204,7 +241,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	archiveFiles.Observe(float64(nFiles))
 	archiveSize.Observe(float64(bytes))
 
-	matches, limitHit, err = concurrentFind(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath)
+	matches, limitHit, err = concurrentFind(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath, onMatch)
 	return matches, limitHit, false, err
 }
 
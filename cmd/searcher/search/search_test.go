@@ -128,6 +128,47 @@ main.go:6:	fmt.Println("Hello world")
 main.go:6:	fmt.Println("Hello world")
 `},
 
+		{protocol.PatternInfo{Pattern: "fmt.Println(:[args])", IsStructural: true}, `
+main.go:6:	fmt.Println("Hello world")
+`},
+		{protocol.PatternInfo{Pattern: "func :[name]() { :[body] }", IsStructural: true}, `
+main.go:5:func main() {
+`},
+		{protocol.PatternInfo{Pattern: "FMT.println(:[args])", IsStructural: true, IsCaseSensitive: true}, ""},
+
+		{protocol.PatternInfo{IsRegExp: true, PatternExpr: &protocol.PatternExpr{Or: []*protocol.PatternExpr{{Pattern: "world"}, {Pattern: "fmt"}}}}, `
+README.md:1:# Hello World
+README.md:3:Hello world example in go
+main.go:3:import "fmt"
+main.go:6:	fmt.Println("Hello world")
+`},
+		{protocol.PatternInfo{IsRegExp: true, PatternExpr: &protocol.PatternExpr{And: []*protocol.PatternExpr{{Pattern: "world"}, {Not: &protocol.PatternExpr{Pattern: "fmt"}}}}}, `
+README.md:1:# Hello World
+README.md:3:Hello world example in go
+`},
+		{protocol.PatternInfo{IsRegExp: true, PatternExpr: &protocol.PatternExpr{And: []*protocol.PatternExpr{
+			{Pattern: "world"},
+			{Not: &protocol.PatternExpr{Or: []*protocol.PatternExpr{{Pattern: "^package"}, {Pattern: "example"}}}},
+		}}}, ""},
+
+		{protocol.PatternInfo{Pattern: `main\(\) \{\n\s+fmt`, IsRegExp: true, IsMultiline: true}, `
+main.go:5:func main() {
+	fmt.Println("Hello world")
+`},
+
+		{protocol.PatternInfo{Pattern: `println\("(\w+) world"\)`, IsRegExp: true, Replace: true, Replacement: `Printf("$1, %s", name)`}, `
+main.go:6:	fmt.Println("Hello world")
+--- a/main.go
++++ b/main.go
+@@ -3,5 +3,5 @@
+ import "fmt"
+ 
+ func main() {
+-	fmt.Println("Hello world")
++	fmt.Printf("Hello, %s", name)
+ }
+`},
+
 		{protocol.PatternInfo{Pattern: "doesnotmatch"}, ""},
 		{protocol.PatternInfo{Pattern: "", IsRegExp: false, IncludePatterns: []string{"\\.png"}, PathPatternsAreRegExps: true, PatternMatchesPath: true}, `
 milton.png
@@ -182,7 +223,7 @@ milton.png
 		if !test.arg.PathPatternsAreRegExps && (len(test.arg.IncludePatterns) > 0 || test.arg.IncludePattern != "" || test.arg.ExcludePattern != "") {
 			continue
 		}
-		if test.arg.IsWordMatch {
+		if test.arg.IsWordMatch || test.arg.IsStructural || test.arg.PatternExpr != nil || test.arg.IsMultiline || test.arg.Replace {
 			continue
 		}
 
@@ -265,6 +306,52 @@ func TestSearch_badrequest(t *testing.T) {
 			},
 		},
 
+		// Structural pattern starting with a hole
+		{
+			Repo:   "foo",
+			URL:    "u",
+			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			PatternInfo: protocol.PatternInfo{
+				Pattern:      ":[x] foo",
+				IsStructural: true,
+			},
+		},
+
+		// Pattern expression without a non-negated pattern
+		{
+			Repo:   "foo",
+			URL:    "u",
+			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			PatternInfo: protocol.PatternInfo{
+				IsRegExp:    true,
+				PatternExpr: &protocol.PatternExpr{Not: &protocol.PatternExpr{Pattern: "foo"}},
+			},
+		},
+
+		// Pattern expression that can match without a non-negated pattern
+		{
+			Repo:   "foo",
+			URL:    "u",
+			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			PatternInfo: protocol.PatternInfo{
+				IsRegExp:    true,
+				PatternExpr: &protocol.PatternExpr{Or: []*protocol.PatternExpr{{Pattern: "foo"}, {Not: &protocol.PatternExpr{Pattern: "bar"}}}},
+			},
+		},
+
+		// Replacing the matches of a structural pattern
+		{
+			Repo:   "foo",
+			URL:    "u",
+			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			PatternInfo: protocol.PatternInfo{
+				Pattern:      "foo(:[x])",
+				IsStructural: true,
+				Replace:      true,
+				Replacement:  "bar",
+			},
+		},
+
 		// Bad include glob
 		{
 			Repo:   "foo",
@@ -332,6 +419,72 @@ func TestSearch_badrequest(t *testing.T) {
 	}
 }
 
+func TestSearch_stream(t *testing.T) {
+	files := map[string]string{
+		"a.go": "package a\n\nfunc hello() {}\n",
+		"b.go": "package b\n\nfunc hello() {}\n",
+		"c.go": "package c\n",
+	}
+	store, cleanup, err := newStore(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	ts := httptest.NewServer(&search.Service{Store: store})
+	defer ts.Close()
+
+	form := url.Values{
+		"Repo":                  []string{"foo"},
+		"URL":                   []string{"u"},
+		"Commit":                []string{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"},
+		"Pattern":               []string{"hello"},
+		"PatternMatchesContent": []string{"true"},
+		"Stream":                []string{"true"},
+	}
+	resp, err := http.PostForm(ts.URL, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("non-200 response: code=%d", resp.StatusCode)
+	}
+	if got, want := resp.Header.Get("Content-Type"), "application/x-ndjson"; got != want {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	}
+
+	var (
+		matches []protocol.FileMatch
+		last    protocol.StreamEvent
+	)
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var ev protocol.StreamEvent
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if last.Done {
+			t.Fatal("received event after final event")
+		}
+		if ev.Match != nil {
+			matches = append(matches, *ev.Match)
+		}
+		last = ev
+	}
+	if !last.Done {
+		t.Fatal("stream did not end with a final event")
+	}
+	if last.Error != "" || last.LimitHit || last.DeadlineHit {
+		t.Errorf("unexpected final event: %+v", last)
+	}
+
+	sort.Sort(sortByPath(matches))
+	want := "a.go:3:func hello() {}\nb.go:3:func hello() {}\n"
+	if got := toString(matches); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	form := url.Values{
 		"Repo":            []string{string(p.Repo)},
@@ -345,6 +498,23 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	if p.IsRegExp {
 		form.Set("IsRegExp", "true")
 	}
+	if p.IsStructural {
+		form.Set("IsStructural", "true")
+	}
+	if p.IsMultiline {
+		form.Set("IsMultiline", "true")
+	}
+	if p.PatternExpr != nil {
+		expr, err := json.Marshal(p.PatternExpr)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("PatternExpr", string(expr))
+	}
+	if p.Replace {
+		form.Set("Replace", "true")
+		form.Set("Replacement", p.Replacement)
+	}
 	if p.IsWordMatch {
 		form.Set("IsWordMatch", "true")
 	}
@@ -438,7 +608,7 @@ func toString(m []protocol.FileMatch) string {
 			buf.WriteString(l.Preview)
 			buf.WriteByte('\n')
 		}
-
+		buf.WriteString(f.Diff)
 	}
 	return buf.String()
 }
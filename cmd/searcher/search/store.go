@@ -8,11 +8,14 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"io"
+	"io/ioutil"
 	"log"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
@@ -45,6 +48,12 @@ type Store struct {
 	// determine if the error is a bad request (eg invalid repo).
 	FetchTar func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error)
 
+	// FetchLFSObject returns an io.ReadCloser to the contents of the Git LFS object of a
+	// repository with the specified OID and size. If it fails, the pointer file is stored
+	// instead. If nil (or if Git LFS is disabled in the site configuration), Git LFS pointer
+	// files are stored as is.
+	FetchLFSObject func(ctx context.Context, repo gitserver.Repo, oid string, size int64) (io.ReadCloser, error)
+
 	// Path is the directory to store the cache
 	Path string
 
@@ -120,8 +129,17 @@ func (s *Store) prepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 		return "", errors.Errorf("commit must be resolved (repo=%q, commit=%q)", repo.Name, commit)
 	}
 
+	// Archives with Git LFS objects are stored separately from those with
+	// the pointer files, so that changing the site configuration takes
+	// effect without waiting for the cache to be evicted.
+	lfs := s.FetchLFSObject != nil && conf.GitLFSEnabled()
+
 	// key is a sha256 hash since we want to use it for the disk name
-	h := sha256.Sum256([]byte(string(repo.Name) + " " + string(commit)))
+	keyData := string(repo.Name) + " " + string(commit)
+	if lfs {
+		keyData += " lfs"
+	}
+	h := sha256.Sum256([]byte(keyData))
 	key := hex.EncodeToString(h[:])
 	span.LogKV("key", key)
 
@@ -137,7 +155,7 @@ func (s *Store) prepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 		// since we're just going to close it again immediately.
 		bgctx := opentracing.ContextWithSpan(context.Background(), opentracing.SpanFromContext(ctx))
 		f, err := s.cache.Open(bgctx, key, func(ctx context.Context) (io.ReadCloser, error) {
-			return s.fetch(ctx, repo, commit)
+			return s.fetch(ctx, repo, commit, lfs)
 		})
 		var path string
 		if f != nil {
@@ -163,8 +181,9 @@ func (s *Store) prepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 
 // fetch fetches an archive from the network and stores it on disk. It does
 // not populate the in-memory cache. You should probably be calling
-// prepareZip.
-func (s *Store) fetch(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (rc io.ReadCloser, err error) {
+// prepareZip. If fetchLFS is true, the contents of Git LFS objects are stored
+// instead of their pointer files.
+func (s *Store) fetch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, fetchLFS bool) (rc io.ReadCloser, err error) {
 	fetchQueueSize.Inc()
 	ctx, releaseFetchLimiter, err := s.fetchLimiter.Acquire(ctx) // Acquire concurrent fetches semaphore
 	if err != nil {
@@ -213,6 +232,18 @@ func (s *Store) fetch(ctx context.Context, repo gitserver.Repo, commit api.Commi
 		return nil, err
 	}
 
+	var lfs func(oid string, size int64) ([]byte, error)
+	if fetchLFS {
+		lfs = func(oid string, size int64) ([]byte, error) {
+			rc, err := s.FetchLFSObject(ctx, repo, oid, size)
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return ioutil.ReadAll(rc)
+		}
+	}
+
 	pr, pw := io.Pipe()
 
 	// After this point we are not allowed to return an error. Instead we can
@@ -225,7 +256,7 @@ func (s *Store) fetch(ctx context.Context, repo gitserver.Repo, commit api.Commi
 		defer r.Close()
 		tr := tar.NewReader(r)
 		zw := zip.NewWriter(pw)
-		err := copySearchable(tr, zw)
+		err := copySearchable(tr, zw, lfs)
 		if err1 := zw.Close(); err == nil {
 			err = err1
 		}
@@ -239,7 +270,10 @@ func (s *Store) fetch(ctx context.Context, repo gitserver.Repo, commit api.Commi
 // copySearchable copies searchable files from tr to zw. A searchable file is
 // any file that is a candidate for being searched (under size limit and
 // non-binary).
-func copySearchable(tr *tar.Reader, zw *zip.Writer) error {
+//
+// If lfs is non-nil, it is used to fetch the contents of Git LFS objects,
+// which are copied instead of their pointer files.
+func copySearchable(tr *tar.Reader, zw *zip.Writer, lfs func(oid string, size int64) ([]byte, error)) error {
 	// 32*1024 is the same size used by io.Copy
 	buf := make([]byte, 32*1024)
 	for {
@@ -265,7 +299,16 @@ func copySearchable(tr *tar.Reader, zw *zip.Writer) error {
 			return err
 		}
 
-		n, err := tr.Read(buf)
+		var r io.Reader = tr
+		size := hdr.Size
+		if lfs != nil && hdr.Size < gitserver.MaxLFSPointerSize {
+			r, size, err = lfsContent(tr, hdr.Size, lfs)
+			if err != nil {
+				return err
+			}
+		}
+
+		n, err := r.Read(buf)
 		switch err {
 		case io.EOF:
 			if n == 0 {
@@ -277,7 +320,7 @@ func copySearchable(tr *tar.Reader, zw *zip.Writer) error {
 		}
 
 		// We do not search the content of large files
-		if hdr.Size > maxFileSize {
+		if size > maxFileSize {
 			continue
 		}
 
@@ -296,7 +339,7 @@ func copySearchable(tr *tar.Reader, zw *zip.Writer) error {
 			return io.ErrShortWrite
 		}
 
-		_, err = io.CopyBuffer(w, tr, buf)
+		_, err = io.CopyBuffer(w, r, buf)
 		if err != nil {
 			return err
 		}
@@ -304,6 +347,34 @@ func copySearchable(tr *tar.Reader, zw *zip.Writer) error {
 	}
 }
 
+// lfsContent reads the file of the given size from r. If it is a Git LFS
+// pointer file, it returns the contents of the LFS object it points to
+// (fetched with lfs) and its size. Otherwise, or if the object is not
+// available or too large to be searched, it returns the file itself. If the
+// object is too large, the returned size is still the size of the object.
+// Errors fetching the object are logged, and the file itself is returned.
+func lfsContent(r io.Reader, size int64, lfs func(oid string, size int64) ([]byte, error)) (io.Reader, int64, error) {
+	pointer := make([]byte, size)
+	if _, err := io.ReadFull(r, pointer); err != nil {
+		return nil, 0, err
+	}
+	oid, lfsSize, ok := gitserver.ParseLFSPointer(pointer)
+	if !ok {
+		return bytes.NewReader(pointer), size, nil
+	}
+	if lfsSize > maxFileSize {
+		return bytes.NewReader(pointer), lfsSize, nil
+	}
+	content, err := lfs(oid, lfsSize)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("failed to fetch Git LFS object %s, using its pointer file: %s", oid, err)
+		}
+		return bytes.NewReader(pointer), size, nil
+	}
+	return bytes.NewReader(content), lfsSize, nil
+}
+
 func (s *Store) String() string {
 	return "Store(" + s.Path + ")"
 }
@@ -2,11 +2,15 @@ package search
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -95,6 +99,125 @@ func TestPrepareZip_fetchTarFail(t *testing.T) {
 	}
 }
 
+func TestCopySearchable_lfs(t *testing.T) {
+	pointer := func(oid string, size int) string {
+		return fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", oid, size)
+	}
+	var (
+		foundOID   = strings.Repeat("a", 64)
+		missingOID = strings.Repeat("b", 64)
+		largeOID   = strings.Repeat("c", 64)
+	)
+	files := map[string]string{
+		"found.txt":   pointer(foundOID, 11),
+		"missing.txt": pointer(missingOID, 11),
+		"large.bin":   pointer(largeOID, maxFileSize+1),
+		"plain.txt":   "plain file\n",
+	}
+	lfs := func(oid string, size int64) ([]byte, error) {
+		switch oid {
+		case foundOID:
+			return []byte("lfs object\n"), nil
+		case largeOID:
+			t.Errorf("fetched object %s larger than maxFileSize", oid)
+		}
+		return nil, &os.PathError{Op: "LFSObject", Path: oid, Err: os.ErrNotExist}
+	}
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(body))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zbuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zbuf)
+	if err := copySearchable(tar.NewReader(buf), zw, lfs); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(zbuf.Bytes()), int64(zbuf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.Name] = string(b)
+	}
+	want := map[string]string{
+		"found.txt":   "lfs object\n",
+		"missing.txt": files["missing.txt"],
+		"large.bin":   "",
+		"plain.txt":   "plain file\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCopySearchable_lfsError(t *testing.T) {
+	body := "version https://git-lfs.github.com/spec/v1\noid sha256:" + strings.Repeat("a", 64) + "\nsize 1\n"
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0600, Size: int64(len(body))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pointer file is stored if the LFS object can't be fetched.
+	zbuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zbuf)
+	err := copySearchable(tar.NewReader(buf), zw, func(string, int64) ([]byte, error) {
+		return nil, errors.New("test")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zbuf.Bytes()), int64(zbuf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 1 {
+		t.Fatalf("got %d files, want 1", len(zr.File))
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if got, err := ioutil.ReadAll(rc); err != nil || string(got) != body {
+		t.Errorf("got %q (error %v), want the pointer file %q", got, err, body)
+	}
+}
+
 func tmpStore(t *testing.T) (*Store, func()) {
 	d, err := ioutil.TempDir("", "search_test")
 	if err != nil {
@@ -0,0 +1,49 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// streamWriter writes newline-delimited JSON protocol.StreamEvents to an
+// http.ResponseWriter, flushing after every event so the client can consume
+// results as soon as they are found. It is not concurrency safe.
+type streamWriter struct {
+	w       http.ResponseWriter
+	enc     *json.Encoder
+	flusher http.Flusher // nil if w does not support flushing
+	started bool
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	flusher, _ := w.(http.Flusher)
+	return &streamWriter{
+		w:       w,
+		enc:     json.NewEncoder(w),
+		flusher: flusher,
+	}
+}
+
+// Started returns true if an event has been sent. Once an event has been
+// sent it is no longer possible to respond with a non-200 status code.
+func (sw *streamWriter) Started() bool {
+	return sw.started
+}
+
+// Send writes ev to the stream.
+func (sw *streamWriter) Send(ev *protocol.StreamEvent) {
+	if !sw.started {
+		sw.w.Header().Set("Content-Type", "application/x-ndjson")
+		sw.w.WriteHeader(http.StatusOK)
+		sw.started = true
+	}
+	// Like for non-streaming responses, the only reasonable error is the
+	// client going away. Once that happens our request context is canceled,
+	// so we just ignore the error here.
+	_ = sw.enc.Encode(ev)
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+}
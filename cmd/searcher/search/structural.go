@@ -0,0 +1,419 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// This file implements structural search. A structural pattern is literal
+// text with holes, eg "foo(:[args])". A hole matches any text in which
+// parentheses, brackets and braces are balanced and string literals are
+// complete. This allows matching a whole argument list without writing a
+// (fragile) regular expression for it. A hole inside a group of the pattern
+// (eg the parentheses of "foo(:[args])") may span lines, other holes match
+// at most to the end of the line.
+//
+// Whitespace in a pattern matches any amount of whitespace (including none)
+// in the searched text, so patterns are not sensitive to formatting.
+//
+// A hole is written as :[name], where name consists of letters, digits and
+// underscores. If a named hole appears more than once in a pattern, every
+// occurrence must match the same text. The hole :[_] never binds, so it can
+// be used more than once to match different text.
+
+type structuralElemKind int
+
+const (
+	structuralLiteral structuralElemKind = iota
+	structuralHole
+	structuralSpace
+)
+
+type structuralElem struct {
+	kind structuralElemKind
+	text []byte // for structuralLiteral, the text to match. For structuralHole, the name of the hole.
+
+	// inGroup is true for a hole inside a parenthesized, bracketed or
+	// braced group of the pattern. Such holes may span lines.
+	inGroup bool
+}
+
+// structuralPattern is a compiled structural search pattern. It is safe for
+// concurrent use.
+type structuralPattern struct {
+	elems []structuralElem
+
+	// bindsHoles is true if a named hole appears more than once, in which
+	// case matching needs to track what each hole matched.
+	bindsHoles bool
+}
+
+// parseStructural compiles pattern into a structuralPattern.
+func parseStructural(pattern string) (*structuralPattern, error) {
+	var (
+		elems   []structuralElem
+		literal []byte
+		seen    = map[string]bool{}
+		binds   bool
+		depth   int // nesting depth of delimiters in the pattern
+	)
+	flushLiteral := func() {
+		if len(literal) > 0 {
+			elems = append(elems, structuralElem{kind: structuralLiteral, text: literal})
+			literal = nil
+		}
+	}
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if isStructuralSpace(c) {
+			flushLiteral()
+			for i < len(pattern) && isStructuralSpace(pattern[i]) {
+				i++
+			}
+			if len(elems) > 0 {
+				elems = append(elems, structuralElem{kind: structuralSpace})
+			}
+			continue
+		}
+		if name, n := parseHole(pattern[i:]); n > 0 {
+			flushLiteral()
+			if len(elems) > 0 && elems[len(elems)-1].kind == structuralHole {
+				return nil, fmt.Errorf("structural pattern %q has adjacent holes; holes must be separated by text", pattern)
+			}
+			if name != "_" {
+				binds = binds || seen[name]
+				seen[name] = true
+			}
+			elems = append(elems, structuralElem{kind: structuralHole, text: []byte(name), inGroup: depth > 0})
+			i += n
+			continue
+		}
+		if openingDelimiter(c) != 0 {
+			depth++
+		} else if closingDelimiter(c) && depth > 0 {
+			depth--
+		}
+		literal = append(literal, c)
+		i++
+	}
+	flushLiteral()
+
+	// Trailing whitespace would always match, so drop it.
+	if len(elems) > 0 && elems[len(elems)-1].kind == structuralSpace {
+		elems = elems[:len(elems)-1]
+	}
+	if len(elems) == 0 {
+		return nil, errors.New("structural pattern is empty")
+	}
+	if elems[0].kind != structuralLiteral {
+		return nil, fmt.Errorf("structural pattern %q must start with text, not a hole", pattern)
+	}
+	return &structuralPattern{elems: elems, bindsHoles: binds}, nil
+}
+
+// parseHole parses a hole of the form :[name] at the start of s. It returns
+// the name of the hole and the number of bytes consumed, or n == 0 if s does
+// not start with a hole.
+func parseHole(s string) (name string, n int) {
+	if len(s) < 3 || s[0] != ':' || s[1] != '[' {
+		return "", 0
+	}
+	for i := 2; i < len(s); i++ {
+		c := s[i]
+		if c == ']' {
+			if i == 2 {
+				return "", 0
+			}
+			return s[2:i], i + 1
+		}
+		if !(c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return "", 0
+		}
+	}
+	return "", 0
+}
+
+// lowerASCII lowercases the literal text of sp in place. It is used for case
+// insensitive matching against lowercased input.
+func (sp *structuralPattern) lowerASCII() {
+	for _, e := range sp.elems {
+		if e.kind != structuralLiteral {
+			continue
+		}
+		for i, c := range e.text {
+			if 'A' <= c && c <= 'Z' {
+				e.text[i] = c + 'a' - 'A'
+			}
+		}
+	}
+}
+
+// longestLiteral returns the longest literal text which must appear in any
+// match of sp.
+func (sp *structuralPattern) longestLiteral() []byte {
+	var longest []byte
+	for _, e := range sp.elems {
+		if e.kind == structuralLiteral && len(e.text) > len(longest) {
+			longest = e.text
+		}
+	}
+	return longest
+}
+
+// String returns a string describing sp, for tracing.
+func (sp *structuralPattern) String() string {
+	var buf bytes.Buffer
+	for _, e := range sp.elems {
+		switch e.kind {
+		case structuralLiteral:
+			buf.Write(e.text)
+		case structuralHole:
+			buf.WriteString(":[")
+			buf.Write(e.text)
+			buf.WriteString("]")
+		case structuralSpace:
+			buf.WriteByte(' ')
+		}
+	}
+	return buf.String()
+}
+
+// maxStructuralSteps bounds the work done to match a structural pattern in a
+// single buffer: the number of bytes scanned by holes plus the number of hole
+// ends tried. Backtracking over several holes could otherwise take time
+// polynomial in the size of the buffer (eg "f(:[a], :[b], :[c])" on a large
+// minified file).
+const maxStructuralSteps = 10 * 1000 * 1000
+
+var errStructuralTooExpensive = errors.New("structural pattern is too expensive to match; surround its holes with more text")
+
+// FindAllIndex returns the byte ranges of successive non-overlapping matches
+// of sp in buf. If n >= 0, at most n matches are returned. It returns
+// errStructuralTooExpensive if matching takes more than maxStructuralSteps.
+func (sp *structuralPattern) FindAllIndex(buf []byte, n int) ([][]int, error) {
+	var (
+		locs  [][]int
+		first = sp.elems[0].text
+		pos   = 0
+		m     = &structuralMatcher{sp: sp, buf: buf, steps: maxStructuralSteps}
+	)
+	for n < 0 || len(locs) < n {
+		i := bytes.Index(buf[pos:], first)
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		if sp.bindsHoles {
+			m.bindings = map[string][]byte{}
+		}
+		end, ok := m.match(start, 0)
+		if m.steps < 0 {
+			return nil, errStructuralTooExpensive
+		}
+		if ok {
+			locs = append(locs, []int{start, end})
+			pos = end
+		} else {
+			pos = start + 1
+		}
+	}
+	return locs, nil
+}
+
+// structuralMatcher is the state of matching a structuralPattern against buf.
+type structuralMatcher struct {
+	sp       *structuralPattern
+	buf      []byte
+	bindings map[string][]byte // the text matched by each named hole (nil if sp.bindsHoles is false)
+	steps    int               // the remaining steps before giving up (see maxStructuralSteps)
+}
+
+// match reports whether m.sp.elems[i:] matches m.buf at pos, and if so the
+// end of the match. Once m.steps is negative, it no longer matches anything.
+func (m *structuralMatcher) match(pos, i int) (int, bool) {
+	if m.steps < 0 {
+		return 0, false
+	}
+	if i == len(m.sp.elems) {
+		return pos, true
+	}
+	e := m.sp.elems[i]
+	switch e.kind {
+	case structuralLiteral:
+		if !bytes.HasPrefix(m.buf[pos:], e.text) {
+			return 0, false
+		}
+		return m.match(pos+len(e.text), i+1)
+
+	case structuralSpace:
+		for pos < len(m.buf) && isStructuralSpace(m.buf[pos]) {
+			pos++
+		}
+		return m.match(pos, i+1)
+
+	case structuralHole:
+		name := string(e.text)
+		prev, bound := m.bindings[name]
+		ends := holeEnds{m: m, pos: pos, multiline: e.inGroup}
+		if i == len(m.sp.elems)-1 {
+			// A hole at the end of the pattern matches as much as possible
+			// (it would otherwise always be empty).
+			if bound {
+				for end, ok := ends.next(); ok && end <= pos+len(prev); end, ok = ends.next() {
+					if end == pos+len(prev) && bytes.Equal(prev, m.buf[pos:end]) {
+						return end, true
+					}
+				}
+				return 0, false
+			}
+			last := pos
+			for end, ok := ends.next(); ok; end, ok = ends.next() {
+				last = end
+			}
+			return last, m.steps >= 0
+		}
+
+		// Other holes match as little as possible.
+		for end, ok := ends.next(); ok; end, ok = ends.next() {
+			switch {
+			case m.bindings == nil || name == "_":
+			case bound:
+				if !bytes.Equal(prev, m.buf[pos:end]) {
+					continue
+				}
+			default:
+				m.bindings[name] = m.buf[pos:end]
+			}
+			if end, ok := m.match(end, i+1); ok {
+				return end, true
+			}
+		}
+		if m.bindings != nil && !bound {
+			delete(m.bindings, name)
+		}
+	}
+	return 0, false
+}
+
+// holeEnds iterates over the positions in m.buf at which a hole starting at
+// pos may end, in increasing order. A hole consists of whole balanced groups,
+// string literals and other characters. It never extends past an unbalanced
+// closing delimiter. Unless multiline is true, it only extends past the end
+// of the line inside a balanced group.
+type holeEnds struct {
+	m         *structuralMatcher
+	pos       int // the next end
+	multiline bool
+	done      bool
+}
+
+// next returns the next position at which the hole may end. ok is false if
+// there is none left or m.steps is exhausted.
+func (h *holeEnds) next() (end int, ok bool) {
+	h.m.steps--
+	if h.done || h.m.steps < 0 {
+		return 0, false
+	}
+	end = h.pos
+	if h.pos == len(h.m.buf) {
+		h.done = true
+		return end, true
+	}
+	c := h.m.buf[h.pos]
+	switch {
+	case c == '\n' && !h.multiline, closingDelimiter(c):
+		h.done = true
+	case openingDelimiter(c) != 0:
+		h.pos, ok = h.m.skipBalanced(h.pos)
+		h.done = !ok
+	case isQuote(c):
+		h.pos = h.m.skipString(h.pos)
+	default:
+		_, size := utf8.DecodeRune(h.m.buf[h.pos:])
+		h.pos += size
+	}
+	return end, true
+}
+
+// skipBalanced returns the position after the group which starts with the
+// opening delimiter at m.buf[pos]. ok is false if the group is not closed by
+// a matching delimiter.
+func (m *structuralMatcher) skipBalanced(pos int) (end int, ok bool) {
+	var stack []byte // expected closing delimiters
+	for pos < len(m.buf) {
+		m.steps--
+		c := m.buf[pos]
+		switch {
+		case openingDelimiter(c) != 0:
+			stack = append(stack, openingDelimiter(c))
+			pos++
+		case closingDelimiter(c):
+			if c != stack[len(stack)-1] {
+				return 0, false
+			}
+			stack = stack[:len(stack)-1]
+			pos++
+			if len(stack) == 0 {
+				return pos, true
+			}
+		case isQuote(c):
+			pos = m.skipString(pos)
+		default:
+			pos++
+		}
+	}
+	return 0, false
+}
+
+// skipString returns the position after the string literal which starts
+// with the quote at m.buf[pos]. Backslash escapes are respected. Only
+// backquoted strings may span lines. If the string is not terminated, the
+// quote is treated as an ordinary character (so that eg apostrophes in
+// comments do not prevent matches).
+func (m *structuralMatcher) skipString(pos int) int {
+	q := m.buf[pos]
+	for i := pos + 1; i < len(m.buf); i++ {
+		m.steps--
+		switch m.buf[i] {
+		case '\\':
+			if q != '`' {
+				i++
+			}
+		case '\n':
+			if q != '`' {
+				return pos + 1
+			}
+		case q:
+			return i + 1
+		}
+	}
+	return pos + 1
+}
+
+// openingDelimiter returns the closing delimiter for c, or 0 if c is not an
+// opening delimiter.
+func openingDelimiter(c byte) byte {
+	switch c {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	}
+	return 0
+}
+
+func closingDelimiter(c byte) bool {
+	return c == ')' || c == ']' || c == '}'
+}
+
+func isQuote(c byte) bool {
+	return c == '"' || c == '\'' || c == '`'
+}
+
+func isStructuralSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStructuralPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		input   string
+		want    []string
+	}{
+		{
+			pattern: "foo(:[args])",
+			input:   `x := foo(a, b(c), ")") + foo()`,
+			want:    []string{`foo(a, b(c), ")")`, "foo()"},
+		},
+		{
+			pattern: "foo(:[a], :[b])",
+			input:   "foo(x,y) foo(1, f(2, 3)) foo(1)",
+			want:    []string{"foo(x,y)", "foo(1, f(2, 3))"},
+		},
+		{
+			// Holes match balanced groups spanning lines.
+			pattern: "bar(:[args])",
+			input:   "bar(\n\t1,\n\t2,\n)",
+			want:    []string{"bar(\n\t1,\n\t2,\n)"},
+		},
+		{
+			// Unbalanced text does not match.
+			pattern: "foo(:[args])",
+			input:   "foo(a]",
+			want:    nil,
+		},
+		{
+			// Repeated named holes must match the same text.
+			pattern: "if :[x] == :[x] {",
+			input:   "if a == b {\nif a == a {",
+			want:    []string{"if a == a {"},
+		},
+		{
+			// :[_] does not bind.
+			pattern: "if :[_] == :[_] {",
+			input:   "if a == b {",
+			want:    []string{"if a == b {"},
+		},
+		{
+			// A trailing hole matches to the end of the line.
+			pattern: "return :[x]",
+			input:   "return f(1,\n2) + 3\nreturn",
+			want:    []string{"return f(1,\n2) + 3", "return"},
+		},
+		{
+			// Apostrophes do not start unterminated strings.
+			pattern: "foo(:[x])",
+			input:   "foo(it's)",
+			want:    []string{"foo(it's)"},
+		},
+	}
+	for _, c := range cases {
+		sp, err := parseStructural(c.pattern)
+		if err != nil {
+			t.Fatalf("parseStructural(%q): %s", c.pattern, err)
+		}
+		locs, err := sp.FindAllIndex([]byte(c.input), -1)
+		if err != nil {
+			t.Fatalf("%q in %q: %s", c.pattern, c.input, err)
+		}
+		var got []string
+		for _, loc := range locs {
+			got = append(got, c.input[loc[0]:loc[1]])
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q in %q: got %q, want %q", c.pattern, c.input, got, c.want)
+		}
+	}
+}
+
+func TestStructuralPattern_large(t *testing.T) {
+	args := strings.Repeat("a,b,(c),d,", 100000)
+
+	// The call is never closed, so every combination of ends of the holes
+	// is tried before giving up.
+	sp, err := parseStructural("f(:[a], :[b], :[c])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sp.FindAllIndex([]byte("x := f("+args), -1); err != errStructuralTooExpensive {
+		t.Errorf("got error %v, want %v", err, errStructuralTooExpensive)
+	}
+
+	// Matching a single hole over a large input is cheap enough.
+	sp, err = parseStructural("f(:[args])")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := []byte("x := f(" + args + ")")
+	locs, err := sp.FindAllIndex(input, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int{{5, len(input)}}; !reflect.DeepEqual(locs, want) {
+		t.Errorf("got %v, want %v", locs, want)
+	}
+}
+
+func TestParseStructural_error(t *testing.T) {
+	for _, pattern := range []string{"", "  ", ":[x] foo", "foo:[x]:[y]"} {
+		if _, err := parseStructural(pattern); err == nil {
+			t.Errorf("parseStructural(%q) expected to fail", pattern)
+		}
+	}
+}
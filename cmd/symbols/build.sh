@@ -14,10 +14,11 @@ trap cleanup EXIT
 export GO111MODULE=on
 export GOARCH=amd64
 export GOOS=linux
-export CGO_ENABLED=0
+# The SQLite driver uses cgo. Link statically so that the binary runs on alpine.
+export CGO_ENABLED=1
 
 for pkg in github.com/sourcegraph/sourcegraph/cmd/symbols; do
-    go build -ldflags "-X github.com/sourcegraph/sourcegraph/pkg/version.version=$VERSION" -buildmode exe -tags dist -o $OUTPUT/$(basename $pkg) $pkg
+    go build -ldflags "-X github.com/sourcegraph/sourcegraph/pkg/version.version=$VERSION -extldflags '-static'" -buildmode exe -tags "dist netgo" -o $OUTPUT/$(basename $pkg) $pkg
 done
 
 docker build -f cmd/symbols/Dockerfile -t $IMAGE $OUTPUT
@@ -1,22 +1,74 @@
 package symbols
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/gob"
+	"database/sql"
 	"io"
 	"io/ioutil"
+	"os"
+	"strings"
 
+	// Register the "sqlite3" database/sql driver.
+	_ "github.com/mattn/go-sqlite3"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
 	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
 	"golang.org/x/net/trace"
+	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
-func (s *Service) indexedSymbols(ctx context.Context, repo api.RepoName, commitID api.CommitID) (symbols []protocol.Symbol, err error) {
+// The symbols of a repository at a commit are stored in a SQLite database,
+// so that a search only reads the symbols it returns (using the indexes on
+// the name, path and kind columns when possible) instead of decoding all of
+// them.
+//
+// The lowercase columns are used for case insensitive searches. The blob
+// column is the Git object ID of the file that contains the symbol.
+const symbolsSchema = `
+CREATE TABLE symbols (
+	name TEXT NOT NULL,
+	namelowercase TEXT NOT NULL,
+	path TEXT NOT NULL,
+	pathlowercase TEXT NOT NULL,
+	blob TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	kind TEXT NOT NULL,
+	language TEXT NOT NULL,
+	parent TEXT NOT NULL,
+	parentkind TEXT NOT NULL,
+	signature TEXT NOT NULL,
+	pattern TEXT NOT NULL,
+	filelimited BOOLEAN NOT NULL
+);
+CREATE INDEX name_index ON symbols(name);
+CREATE INDEX namelowercase_index ON symbols(namelowercase);
+CREATE INDEX path_index ON symbols(path);
+CREATE INDEX pathlowercase_index ON symbols(pathlowercase);
+CREATE INDEX kind_index ON symbols(kind);
+`
+
+// The maximum number of ancestors of a commit which are checked for an
+// existing index, and the maximum number of changed files for which the index
+// of a commit is derived from its ancestor's instead of parsing all files.
+const (
+	maxIndexedAncestors   = 100
+	maxIncrementalChanges = 1000
+)
+
+// symbolsDBKey returns the cache key of the symbols database of repo at
+// commitID.
+func symbolsDBKey(repo api.RepoName, commitID api.CommitID) string {
+	return string(repo) + ":" + string(commitID) + ":v3" // suffix is index format version (vN)
+}
+
+// indexedSymbols returns the cache entry of the SQLite database with the
+// symbols of repo at commitID, parsing them first if they are not in the
+// cache. The caller must close it when it is done with the database.
+func (s *Service) indexedSymbols(ctx context.Context, repo api.RepoName, commitID api.CommitID) (dbFile *diskcache.File, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "indexedSymbols")
 	defer func() {
 		if err != nil {
@@ -26,14 +78,12 @@ func (s *Service) indexedSymbols(ctx context.Context, repo api.RepoName, commitI
 		span.Finish()
 	}()
 
-	key := string(repo) + ":" + string(commitID) + ":v1" // suffix is index format version (vN)
-
 	tr := trace.New("indexedSymbols", string(repo))
 	tr.LazyPrintf("commitID: %s", commitID)
 
 	var fetched bool
 	defer func() {
-		tr.LazyPrintf("fetched=%v symbols=%d", fetched, len(symbols))
+		tr.LazyPrintf("fetched=%v", fetched)
 		if err != nil {
 			tr.LazyPrintf("error: %s", err)
 			tr.SetError()
@@ -41,55 +91,101 @@ func (s *Service) indexedSymbols(ctx context.Context, repo api.RepoName, commitI
 		tr.Finish()
 	}()
 
-	f, err := s.cache.Open(ctx, key, func(ctx context.Context) (io.ReadCloser, error) {
+	f, err := s.cache.Open(ctx, symbolsDBKey(repo, commitID), func(ctx context.Context) (io.ReadCloser, error) {
 		fetched = true
 
-		var err error
-		symbols, err = s.parseUncached(ctx, repo, commitID)
+		db, err := s.indexIncrementally(ctx, repo, commitID)
+		if err != nil {
+			// We can still parse all the files.
+			log15.Warn("Failed to derive symbols from an ancestor commit.", "repo", repo, "commitID", commitID, "error", err)
+		} else if db != nil {
+			tr.LazyPrintf("derived from ancestor")
+			return db, nil
+		}
+
+		symbols, err := s.parseUncached(ctx, repo, commitID, nil)
 		if err != nil {
 			return nil, err
 		}
-		return encodeSymbols(symbols)
+		tr.LazyPrintf("write symbols=%d", len(symbols))
+		return s.writeSymbolsDB(ctx, nil, nil, symbols)
 	})
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	return f, nil
+}
+
+// indexIncrementally derives the symbols database of repo at commitID from
+// the database of its nearest ancestor in the cache, by only parsing the
+// files which changed between them. It returns a nil reader if there is no
+// such ancestor, or if too many files changed.
+func (s *Service) indexIncrementally(ctx context.Context, repo api.RepoName, commitID api.CommitID) (_ io.ReadCloser, err error) {
+	if s.FetchTarPaths == nil || s.ListAncestors == nil || s.GitDiff == nil {
+		return nil, nil
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "indexIncrementally")
+	defer func() {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(otlog.Error(err))
+		}
+		span.Finish()
+	}()
 
-	// Skip deserializing symbols if we just serialized them in the s.cache.Open call above and still have the
-	// slice.
-	if symbols == nil {
-		var size int64
-		if fi, err := f.Stat(); err == nil {
-			size = fi.Size()
+	ancestors, err := s.ListAncestors(ctx, repo, commitID, maxIndexedAncestors)
+	if err != nil {
+		return nil, errors.Wrap(err, "list ancestors")
+	}
+	var (
+		base       *diskcache.File
+		baseCommit api.CommitID
+	)
+	for _, ancestor := range ancestors {
+		f, err := s.cache.OpenCached(symbolsDBKey(repo, ancestor))
+		if os.IsNotExist(err) {
+			continue
 		}
-		tr.LazyPrintf("decode bytes=%d", size)
-		symbols, err = decodeSymbols(ctx, f)
-		tr.LazyPrintf("decode (done) symbols=%d", len(symbols))
 		if err != nil {
 			return nil, err
 		}
+		base, baseCommit = f, ancestor
+		break
 	}
+	if base == nil {
+		return nil, nil
+	}
+	// We read base from its open file, so it can't be evicted from under us.
+	defer base.Close()
+	span.SetTag("base", string(baseCommit))
 
-	span.LogFields(otlog.String("event", "result"), otlog.Int("count", len(symbols)))
-	return symbols, nil
-}
-
-func encodeSymbols(symbols []protocol.Symbol) (io.ReadCloser, error) {
-	var buf bytes.Buffer
-	zw := gzip.NewWriter(&buf)
-	enc := gob.NewEncoder(zw)
-	if err := enc.Encode(symbols); err != nil {
-		return nil, err
+	changes, err := s.GitDiff(ctx, repo, baseCommit, commitID)
+	if err != nil {
+		return nil, errors.Wrap(err, "git diff")
 	}
-	if err := zw.Close(); err != nil {
-		return nil, err
+	parsePaths := append(append([]string{}, changes.Added...), changes.Modified...)
+	if len(parsePaths) > maxIncrementalChanges {
+		return nil, nil
+	}
+	span.SetTag("changed", len(parsePaths)+len(changes.Deleted))
+
+	var symbols []protocol.Symbol
+	if len(parsePaths) > 0 {
+		symbols, err = s.parseUncached(ctx, repo, commitID, parsePaths)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return ioutil.NopCloser(&buf), nil
+	return s.writeSymbolsDB(ctx, base, append(parsePaths, changes.Deleted...), symbols)
 }
 
-func decodeSymbols(ctx context.Context, r io.Reader) (symbols []protocol.Symbol, err error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "decodeSymbols")
+// writeSymbolsDB writes symbols to a new SQLite database. If base is non-nil,
+// the new database is a copy of the database read from base, in which the
+// symbols in the files at replacePaths are replaced with symbols. It returns
+// a reader of the database file, which is removed when the reader is closed.
+func (s *Service) writeSymbolsDB(ctx context.Context, base io.Reader, replacePaths []string, symbols []protocol.Symbol) (_ io.ReadCloser, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "writeSymbolsDB")
 	defer func() {
 		if err != nil {
 			ext.Error.Set(span, true)
@@ -98,12 +194,108 @@ func decodeSymbols(ctx context.Context, r io.Reader) (symbols []protocol.Symbol,
 		span.Finish()
 	}()
 
-	zr, err := gzip.NewReader(r)
+	tmp, err := ioutil.TempFile(s.Path, "symbols-db-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if base != nil {
+		if _, err := io.Copy(tmp, base); err != nil {
+			tmp.Close()
+			return nil, errors.Wrap(err, "copy base symbols database")
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// The database is only used after it is completely written, so we don't
+	// need the journal.
+	if _, err := db.ExecContext(ctx, "PRAGMA journal_mode = OFF; PRAGMA synchronous = OFF;"); err != nil {
+		return nil, err
+	}
+	if base == nil {
+		if _, err := db.ExecContext(ctx, symbolsSchema); err != nil {
+			return nil, errors.Wrap(err, "create symbols table")
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if len(replacePaths) > 0 {
+		del, err := tx.PrepareContext(ctx, `DELETE FROM symbols WHERE path = ?`)
+		if err != nil {
+			return nil, err
+		}
+		defer del.Close()
+		for _, path := range replacePaths {
+			if _, err := del.ExecContext(ctx, path); err != nil {
+				return nil, errors.Wrap(err, "delete symbols")
+			}
+		}
+	}
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO symbols (name, namelowercase, path, pathlowercase, blob, line, kind, language, parent, parentkind, signature, pattern, filelimited) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, err
+	}
+	defer insert.Close()
+	for _, symbol := range symbols {
+		if _, err := insert.ExecContext(ctx,
+			symbol.Name,
+			strings.ToLower(symbol.Name),
+			symbol.Path,
+			strings.ToLower(symbol.Path),
+			symbol.Blob,
+			symbol.Line,
+			symbol.Kind,
+			symbol.Language,
+			symbol.Parent,
+			symbol.ParentKind,
+			symbol.Signature,
+			symbol.Pattern,
+			symbol.FileLimited,
+		); err != nil {
+			return nil, errors.Wrap(err, "insert symbol")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(tmp.Name())
 	if err != nil {
 		return nil, err
 	}
-	defer zr.Close()
-	dec := gob.NewDecoder(zr)
-	err = dec.Decode(&symbols)
-	return symbols, err
+	return &removeOnClose{File: f}, nil
+}
+
+// removeOnClose is an *os.File which is removed when it is closed.
+type removeOnClose struct {
+	*os.File
+}
+
+func (f *removeOnClose) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
 }
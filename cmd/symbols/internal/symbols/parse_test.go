@@ -0,0 +1,16 @@
+package symbols
+
+import "testing"
+
+func TestGitBlobID(t *testing.T) {
+	// The expected IDs are the output of `git hash-object --stdin`.
+	tests := map[string]string{
+		"":          "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		"var x = 1": "88be1a7aed2d1bd793bc7b0201069adfa369443e",
+	}
+	for data, want := range tests {
+		if got := gitBlobID([]byte(data)); got != want {
+			t.Errorf("gitBlobID(%q) = %s, want %s", data, got, want)
+		}
+	}
+}
@@ -2,12 +2,17 @@ package symbols
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/keegancsmith/sqlf"
 	"github.com/sourcegraph/sourcegraph/pkg/pathmatch"
 
 	opentracing "github.com/opentracing/opentracing-go"
@@ -72,35 +77,33 @@ func (s *Service) search(ctx context.Context, args protocol.SearchArgs) (result
 		tr.Finish()
 	}()
 
-	symbols, err := s.indexedSymbols(ctx, args.Repo, args.CommitID)
+	dbFile, err := s.indexedSymbols(ctx, args.Repo, args.CommitID)
 	if err != nil {
 		return nil, err
 	}
+	// Keep the cache entry open until the query is done. SQLite opens the
+	// database by path, so it is opened read-only: if the entry was evicted
+	// in the meantime, that is an error instead of a new empty database.
+	defer dbFile.Close()
+	db, err := sql.Open("sqlite3", "file:"+dbFile.Path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
 
 	const maxFirst = 500
 	if args.First < 0 || args.First > maxFirst {
 		args.First = maxFirst
 	}
 
-	result = &protocol.SearchResult{}
-	if args.Query == "" && len(args.IncludePatterns) == 0 && args.ExcludePattern == "" {
-		// No filters were provided, save iterating the symbols and return a slice
-		if args.First != 0 && len(symbols) > args.First {
-			symbols = symbols[:args.First]
-		}
-		result.Symbols = symbols
-	} else {
-		res, err := filterSymbols(ctx, symbols, args)
-		if err != nil {
-			return nil, err
-		}
-		result.Symbols = res
-	}
-	return result, nil
+	return filterSymbols(ctx, db, args)
 }
 
-func filterSymbols(ctx context.Context, symbols []protocol.Symbol, args protocol.SearchArgs) (res []protocol.Symbol, err error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "filterSymbols")
+// filterSymbols returns the symbols in db that match args. The conditions
+// which can use the indexes of db are evaluated by SQLite, the regexps and
+// path patterns are evaluated as the rows are read.
+func filterSymbols(ctx context.Context, db *sql.DB, args protocol.SearchArgs) (result *protocol.SearchResult, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "filterSymbols")
 	defer func() {
 		if err != nil {
 			ext.Error.Set(span, true)
@@ -108,7 +111,6 @@ func filterSymbols(ctx context.Context, symbols []protocol.Symbol, args protocol
 		}
 		span.Finish()
 	}()
-	span.SetTag("before", len(symbols))
 
 	query := args.Query
 	if !args.IsRegExp {
@@ -130,16 +132,129 @@ func filterSymbols(ctx context.Context, symbols []protocol.Symbol, args protocol
 		return nil, err
 	}
 
-	for _, symbol := range symbols {
+	var conds []*sqlf.Query
+	if args.IsRegExp {
+		if cond := anchoredLiteralCond("name", args.Query, args.IsCaseSensitive); cond != nil {
+			conds = append(conds, cond)
+		}
+		for _, p := range args.IncludePatterns {
+			if cond := anchoredLiteralCond("path", p, args.IsCaseSensitive); cond != nil {
+				conds = append(conds, cond)
+			}
+		}
+	}
+	if len(args.Kinds) > 0 {
+		kinds := make([]*sqlf.Query, len(args.Kinds))
+		for i, kind := range args.Kinds {
+			kinds[i] = sqlf.Sprintf("%s", kind)
+		}
+		conds = append(conds, sqlf.Sprintf("kind IN (%s)", sqlf.Join(kinds, ",")))
+	}
+	if args.After != "" {
+		after, err := strconv.ParseInt(args.After, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %q", args.After)
+		}
+		conds = append(conds, sqlf.Sprintf("rowid > %s", after))
+	}
+	where := sqlf.Sprintf("")
+	if len(conds) > 0 {
+		where = sqlf.Sprintf("WHERE %s", sqlf.Join(conds, "AND"))
+	}
+	q := sqlf.Sprintf("SELECT rowid, name, path, blob, line, kind, language, parent, parentkind, signature, pattern, filelimited FROM symbols %s ORDER BY rowid", where)
+	span.SetTag("sql", q.Query(sqlf.SimpleBindVar))
+
+	rows, err := db.QueryContext(ctx, q.Query(sqlf.SimpleBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = &protocol.SearchResult{}
+	var scanned, lastRowID int64
+	for rows.Next() {
+		var (
+			rowID  int64
+			symbol protocol.Symbol
+		)
+		if err := rows.Scan(&rowID, &symbol.Name, &symbol.Path, &symbol.Blob, &symbol.Line, &symbol.Kind, &symbol.Language, &symbol.Parent, &symbol.ParentKind, &symbol.Signature, &symbol.Pattern, &symbol.FileLimited); err != nil {
+			return nil, err
+		}
+		scanned++
 		if !fileFilter.MatchPath(symbol.Path) || !queryRegex.MatchString(symbol.Name) {
 			continue
 		}
-		res = append(res, symbol)
-		if args.First > 0 && len(res) == args.First {
+		if args.First > 0 && len(result.Symbols) == args.First {
+			// There are more matches than we return.
+			result.Cursor = strconv.FormatInt(lastRowID, 10)
 			break
 		}
+		result.Symbols = append(result.Symbols, symbol)
+		lastRowID = rowID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	span.SetTag("scanned", scanned)
+	span.SetTag("after", len(result.Symbols))
+	return result, nil
+}
+
+// anchoredLiteralCond returns a condition on column (or its lowercase
+// version) that uses its index to find the values matching the regexp
+// pattern, if pattern is anchored at the start and begins with literal text
+// (eg "^foo"). Otherwise it returns nil.
+func anchoredLiteralCond(column, pattern string, isCaseSensitive bool) *sqlf.Query {
+	prefix, exact := anchoredLiteral(pattern)
+	if prefix == "" {
+		return nil
 	}
+	if !isCaseSensitive {
+		column += "lowercase"
+		prefix = strings.ToLower(prefix)
+	}
+	if exact {
+		return sqlf.Sprintf(column+" = %s", prefix)
+	}
+	return sqlf.Sprintf(column+" GLOB %s", globPrefix(prefix))
+}
 
-	span.SetTag("after", len(res))
-	return res, nil
+// anchoredLiteral returns the literal text that all matches of the regexp
+// pattern start with, if pattern is anchored at the start of the text (eg
+// "foo" for "^foo(bar|baz)"). exact is true if the pattern only matches the
+// literal text (eg "^foo$").
+func anchoredLiteral(pattern string) (prefix string, exact bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) < 2 || re.Sub[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+	lit := re.Sub[1]
+	if lit.Op != syntax.OpLiteral || lit.Flags&syntax.FoldCase != 0 {
+		return "", false
+	}
+	exact = len(re.Sub) == 3 && re.Sub[2].Op == syntax.OpEndText
+	return string(lit.Rune), exact
+}
+
+// globPrefix returns a GLOB pattern that matches the strings that start with
+// prefix.
+func globPrefix(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		switch r {
+		case '*', '?', '[':
+			b.WriteByte('[')
+			b.WriteRune(r)
+			b.WriteByte(']')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('*')
+	return b.String()
 }
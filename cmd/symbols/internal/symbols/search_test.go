@@ -11,6 +11,29 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/testutil"
 )
 
+func TestAnchoredLiteral(t *testing.T) {
+	tests := []struct {
+		pattern string
+		prefix  string
+		exact   bool
+	}{
+		{pattern: "^foo", prefix: "foo"},
+		{pattern: "^foo$", prefix: "foo", exact: true},
+		{pattern: "^foo(bar|baz)", prefix: "foo"},
+		{pattern: `^foo\.bar`, prefix: "foo.bar"},
+		{pattern: "foo"},
+		{pattern: "^(?i)foo"},
+		{pattern: "^.*foo"},
+		{pattern: "(^"},
+	}
+	for _, test := range tests {
+		prefix, exact := anchoredLiteral(test.pattern)
+		if prefix != test.prefix || exact != test.exact {
+			t.Errorf("anchoredLiteral(%q) = %q, %v, want %q, %v", test.pattern, prefix, exact, test.prefix, test.exact)
+		}
+	}
+}
+
 func BenchmarkSearch(b *testing.B) {
 	service := Service{
 		FetchTar: testutil.FetchTarFromGithub,
@@ -13,6 +13,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 // Service is the symbols service.
@@ -22,6 +23,23 @@ type Service struct {
 	// determine if the error is a bad request (eg invalid repo).
 	FetchTar func(context.Context, gitserver.Repo, api.CommitID) (io.ReadCloser, error)
 
+	// FetchTarPaths is like FetchTar, but the archive only contains the
+	// specified paths. It is used to index the files which changed since an
+	// ancestor commit.
+	FetchTarPaths func(context.Context, gitserver.Repo, api.CommitID, []string) (io.ReadCloser, error)
+
+	// ListAncestors returns the IDs of at most n ancestors of a commit, most
+	// recent first. It does not include the commit itself.
+	ListAncestors func(ctx context.Context, repo api.RepoName, commitID api.CommitID, n int) ([]api.CommitID, error)
+
+	// GitDiff returns the paths of the files which changed between two
+	// commits.
+	//
+	// When FetchTarPaths, ListAncestors and GitDiff are set, the symbols of
+	// a commit are derived from those of the nearest indexed ancestor by
+	// only parsing the files which changed.
+	GitDiff func(ctx context.Context, repo api.RepoName, base, head api.CommitID) (*git.ChangedFiles, error)
+
 	// MaxConcurrentFetchTar is the maximum number of concurrent calls allowed
 	// to FetchTar. It defaults to 15.
 	MaxConcurrentFetchTar int
@@ -4,11 +4,13 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
@@ -16,6 +18,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	symbolsclient "github.com/sourcegraph/sourcegraph/pkg/symbols"
 	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 func TestService(t *testing.T) {
@@ -43,22 +46,37 @@ func TestService(t *testing.T) {
 	defer server.Close()
 	client := symbolsclient.Client{URL: server.URL}
 
+	blob := gitBlobID([]byte(files["a.js"]))
+	x := protocol.Symbol{Name: "x", Blob: blob}
+	y := protocol.Symbol{Name: "y", Blob: blob}
 	tests := map[string]struct {
 		args protocol.SearchArgs
 		want protocol.SearchResult
 	}{
 		"simple": {
 			args: protocol.SearchArgs{},
-			want: protocol.SearchResult{Symbols: []protocol.Symbol{{Name: "x"}, {Name: "y"}}},
+			want: protocol.SearchResult{Symbols: []protocol.Symbol{x, y}},
 		},
 		"onematch": {
 			args: protocol.SearchArgs{Query: "x"},
-			want: protocol.SearchResult{Symbols: []protocol.Symbol{{Name: "x"}}},
+			want: protocol.SearchResult{Symbols: []protocol.Symbol{x}},
 		},
 		"nomatches": {
 			args: protocol.SearchArgs{Query: "foo"},
 			want: protocol.SearchResult{},
 		},
+		"anchored regexp": {
+			args: protocol.SearchArgs{Query: "^Y$", IsRegExp: true},
+			want: protocol.SearchResult{Symbols: []protocol.Symbol{y}},
+		},
+		"first": {
+			args: protocol.SearchArgs{First: 1},
+			want: protocol.SearchResult{Symbols: []protocol.Symbol{x}, Cursor: "1"},
+		},
+		"after": {
+			args: protocol.SearchArgs{First: 1, After: "1"},
+			want: protocol.SearchResult{Symbols: []protocol.Symbol{y}},
+		},
 	}
 	for label, test := range tests {
 		t.Run(label, func(t *testing.T) {
@@ -73,6 +91,82 @@ func TestService(t *testing.T) {
 	}
 }
 
+func TestService_incremental(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { os.RemoveAll(tmpDir) }()
+
+	commits := map[api.CommitID]map[string]string{
+		"a": {"a.js": "x", "b.js": "y", "c.js": "z"},
+		"b": {"a.js": "x", "b.js": "w", "d.js": "v"},
+	}
+	var fetchedPaths []string
+	service := Service{
+		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
+			if commit != "a" {
+				t.Errorf("fetched the whole archive of commit %s, want only the changed files", commit)
+			}
+			return createTar(commits[commit])
+		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			fetchedPaths = append(fetchedPaths, paths...)
+			files := map[string]string{}
+			for _, path := range paths {
+				files[path] = commits[commit][path]
+			}
+			return createTar(files)
+		},
+		ListAncestors: func(ctx context.Context, repo api.RepoName, commitID api.CommitID, n int) ([]api.CommitID, error) {
+			if commitID == "b" {
+				return []api.CommitID{"a"}, nil
+			}
+			return nil, nil
+		},
+		GitDiff: func(ctx context.Context, repo api.RepoName, base, head api.CommitID) (*git.ChangedFiles, error) {
+			if base != "a" || head != "b" {
+				return nil, fmt.Errorf("got diff of %s and %s, want a and b", base, head)
+			}
+			return &git.ChangedFiles{Added: []string{"d.js"}, Modified: []string{"b.js"}, Deleted: []string{"c.js"}}, nil
+		},
+		NewParser: func() (ctags.Parser, error) {
+			return contentParser{}, nil
+		},
+		Path: tmpDir,
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(service.Handler())
+	defer server.Close()
+	client := symbolsclient.Client{URL: server.URL}
+
+	search := func(commitID api.CommitID) []string {
+		result, err := client.Search(context.Background(), protocol.SearchArgs{CommitID: commitID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, symbol := range result.Symbols {
+			names = append(names, symbol.Path+":"+symbol.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if got, want := search("a"), []string{"a.js:x", "b.js:y", "c.js:z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols %v at commit a, want %v", got, want)
+	}
+	if got, want := search("b"), []string{"a.js:x", "b.js:w", "d.js:v"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols %v at commit b, want %v", got, want)
+	}
+	sort.Strings(fetchedPaths)
+	if want := []string{"b.js", "d.js"}; !reflect.DeepEqual(fetchedPaths, want) {
+		t.Errorf("fetched paths %v, want %v", fetchedPaths, want)
+	}
+}
+
 func createTar(files map[string]string) (io.ReadCloser, error) {
 	buf := new(bytes.Buffer)
 	w := tar.NewWriter(buf)
@@ -108,3 +202,12 @@ func (m mockParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
 }
 
 func (mockParser) Close() {}
+
+// contentParser returns one symbol per file, named after the file's contents.
+type contentParser struct{}
+
+func (contentParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
+	return []ctags.Entry{{Name: string(content), Path: name}}, nil
+}
+
+func (contentParser) Close() {}
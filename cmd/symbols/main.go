@@ -51,6 +51,32 @@ func main() {
 		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
 			return git.Archive(ctx, repo, git.ArchiveOptions{Treeish: string(commit), Format: "tar"})
 		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			pathspecs := make([]string, len(paths))
+			for i, p := range paths {
+				// Match the paths exactly (git would otherwise expand glob
+				// characters such as "*" in file names).
+				pathspecs[i] = ":(literal)" + p
+			}
+			return git.Archive(ctx, repo, git.ArchiveOptions{Treeish: string(commit), Format: "tar", Paths: pathspecs})
+		},
+		ListAncestors: func(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error) {
+			// The first commit of the log is commit itself.
+			commits, err := git.Commits(ctx, gitserver.Repo{Name: repo}, git.CommitsOptions{Range: string(commit), N: uint(n + 1)})
+			if err != nil {
+				return nil, err
+			}
+			var ancestors []api.CommitID
+			for _, c := range commits {
+				if c.ID != commit {
+					ancestors = append(ancestors, c.ID)
+				}
+			}
+			return ancestors, nil
+		},
+		GitDiff: func(ctx context.Context, repo api.RepoName, base, head api.CommitID) (*git.ChangedFiles, error) {
+			return git.DiffNameStatus(ctx, gitserver.Repo{Name: repo}, base, head)
+		},
 		NewParser: func() (ctags.Parser, error) {
 			parser, err := ctags.NewParser(ctagsCommand)
 			if err != nil {
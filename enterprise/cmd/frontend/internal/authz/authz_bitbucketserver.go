@@ -0,0 +1,110 @@
+package authz
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	permbbs "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"golang.org/x/time/rate"
+)
+
+func bitbucketServerProviders(ctx context.Context) (
+	authzProviders []authz.Provider,
+	seriousProblems []string,
+	warnings []string,
+) {
+	bbss, err := db.ExternalServices.ListBitbucketServerConnections(ctx)
+	if err != nil {
+		seriousProblems = append(seriousProblems, fmt.Sprintf("Could not load Bitbucket Server external service configs: %s", err))
+		return
+	}
+
+	for _, c := range bbss {
+		p, err := bitbucketServerProvider(c)
+		if err != nil {
+			seriousProblems = append(seriousProblems, err.Error())
+			continue
+		}
+		if p != nil {
+			authzProviders = append(authzProviders, p)
+		}
+	}
+	return authzProviders, seriousProblems, warnings
+}
+
+func bitbucketServerProvider(c *schema.BitbucketServerConnection) (authz.Provider, error) {
+	if c.Authorization == nil {
+		return nil, nil
+	}
+
+	baseURL, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse URL for Bitbucket Server instance %q: %s", c.Url, err)
+	}
+	baseURL = extsvc.NormalizeBaseURL(baseURL)
+
+	signingKey, err := bitbucketserver.ParseSigningKey(c.Authorization.Oauth.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid OAuth signing key for Bitbucket Server instance %q: %s", c.Url, err)
+	}
+
+	var identity permbbs.IdentityProvider
+	switch idp := c.Authorization.IdentityProvider; idp.Type {
+	case "username":
+		identity.Username = true
+	case "external":
+		if idp.AuthProviderType == "" {
+			return nil, fmt.Errorf("Invalid identity provider for Bitbucket Server instance %q: authProviderType is required for type \"external\"", c.Url)
+		}
+		identity.AuthProviderType, identity.AuthProviderID = idp.AuthProviderType, idp.AuthProviderID
+	default:
+		return nil, fmt.Errorf("Invalid identity provider for Bitbucket Server instance %q: unknown type %q", c.Url, idp.Type)
+	}
+
+	ttl, err := parseTTL(c.Authorization.Ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport
+	if c.Certificate != "" {
+		certPool := x509.NewCertPool()
+		if ok := certPool.AppendCertsFromPEM([]byte(c.Certificate)); !ok {
+			return nil, fmt.Errorf("Invalid certificate for Bitbucket Server instance %q", c.Url)
+		}
+		transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certPool}}
+	}
+
+	return NewBitbucketServerProvider(&bitbucketserver.Client{
+		URL:      baseURL,
+		Token:    c.Token,
+		Username: c.Username,
+		Password: c.Password,
+		HTTPClient: &http.Client{
+			Transport: bitbucketserver.WithRequestCounter(&nethttp.Transport{RoundTripper: transport}),
+		},
+		// Permissions are fetched while serving requests, so we allow more
+		// requests than repo-updater does when syncing repositories.
+		RateLimit: rate.NewLimiter(8, 500),
+		OAuth: &bitbucketserver.OAuth{
+			ConsumerKey: c.Authorization.Oauth.ConsumerKey,
+			SigningKey:  signingKey,
+		},
+	}, identity, ttl), nil
+}
+
+// NewBitbucketServerProvider is a mockable constructor for new Bitbucket Server authz providers.
+var NewBitbucketServerProvider = func(client *bitbucketserver.Client, identity permbbs.IdentityProvider, ttl time.Duration) authz.Provider {
+	return permbbs.NewProvider(client, identity, ttl, nil)
+}
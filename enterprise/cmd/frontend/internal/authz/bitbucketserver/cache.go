@@ -0,0 +1,19 @@
+package bitbucketserver
+
+import "time"
+
+type cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, b []byte)
+	Delete(key string)
+}
+
+type cacheVal struct {
+	// RepoIDs is the set of external IDs ("PROJECT/slug") of the repositories
+	// which a Bitbucket Server user can read.
+	RepoIDs map[string]struct{} `json:"repos"`
+
+	// TTL is the ttl of the cache entry. This must be checked for equality in case the TTL has
+	// changed (and the cache entry should therefore be invalidated).
+	TTL time.Duration `json:"ttl"`
+}
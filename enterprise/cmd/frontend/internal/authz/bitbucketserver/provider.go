@@ -0,0 +1,238 @@
+// Package bitbucketserver contains an authorization provider for Bitbucket Server, which
+// impersonates users with OAuth to list the repositories they can read.
+package bitbucketserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/pkg/rcache"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Provider implements authz.Provider for Bitbucket Server repository permissions.
+type Provider struct {
+	// client is authenticated as a Bitbucket Server admin, and has OAuth
+	// configured to impersonate users.
+	client *bitbucketserver.Client
+	// anonymous is not authenticated, so it only sees public repositories.
+	anonymous *bitbucketserver.Client
+	codeHost  *bitbucketserver.CodeHost
+	identity  IdentityProvider
+	cache     cache
+	cacheTTL  time.Duration
+}
+
+var _ authz.Provider = ((*Provider)(nil))
+
+// IdentityProvider determines the Bitbucket Server user that a Sourcegraph user is matched with.
+type IdentityProvider struct {
+	// Username is true if a user is matched with the Bitbucket Server user with the same
+	// username. This is only safe if users can't choose their usernames.
+	Username bool
+
+	// AuthProviderType and AuthProviderID identify the auth provider whose external accounts are
+	// matched (unless Username is true): a user is matched with the Bitbucket Server user whose
+	// username is the account ID of the user's external account from that auth provider.
+	AuthProviderType, AuthProviderID string
+}
+
+// NewProvider returns a Provider for the Bitbucket Server instance of client, which must have
+// OAuth configured (see bitbucketserver.Client.Sudo).
+func NewProvider(client *bitbucketserver.Client, identity IdentityProvider, cacheTTL time.Duration, mockCache cache) *Provider {
+	p := &Provider{
+		client: client,
+		anonymous: &bitbucketserver.Client{
+			URL:        client.URL,
+			HTTPClient: client.HTTPClient,
+			RateLimit:  client.RateLimit,
+		},
+		codeHost: bitbucketserver.NewCodeHost(client.URL),
+		identity: identity,
+		cache:    mockCache,
+		cacheTTL: cacheTTL,
+	}
+	if p.cache == nil {
+		p.cache = rcache.NewWithTTL(fmt.Sprintf("bitbucketServerAuthz:%s", client.URL.String()), int(math.Ceil(cacheTTL.Seconds())))
+	}
+	return p
+}
+
+func (p *Provider) Validate() (problems []string) {
+	return nil
+}
+
+func (p *Provider) ServiceID() string {
+	return p.codeHost.ServiceID()
+}
+
+func (p *Provider) ServiceType() string {
+	return p.codeHost.ServiceType()
+}
+
+// Repos implements the authz.Provider interface.
+func (p *Provider) Repos(ctx context.Context, repos map[authz.Repo]struct{}) (mine map[authz.Repo]struct{}, others map[authz.Repo]struct{}) {
+	return authz.GetCodeHostRepos(p.codeHost, repos)
+}
+
+// RepoPerms implements the authz.Provider interface. A user can read the repositories that the
+// Bitbucket Server user of their account can read, and users without an account can read public
+// repositories. The list of readable repositories of each user is cached for the cache TTL.
+func (p *Provider) RepoPerms(ctx context.Context, account *extsvc.ExternalAccount, repos map[authz.Repo]struct{}) (map[api.RepoName]map[authz.Perm]bool, error) {
+	var username, accountID string // empty means public / unauthenticated to the code host
+	if account != nil && account.ServiceID == p.codeHost.ServiceID() && account.ServiceType == p.codeHost.ServiceType() {
+		user, err := getAccountUser(account)
+		if err != nil {
+			return nil, err
+		}
+		username, accountID = user.Name, account.AccountID
+	}
+
+	myRepos, _ := p.Repos(ctx, repos)
+	if len(myRepos) == 0 {
+		return nil, nil
+	}
+
+	readable, exists := p.getCachedRepoIDs(accountID)
+	if !exists {
+		var err error
+		readable, err = p.fetchRepoIDs(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(cacheVal{RepoIDs: readable, TTL: p.cacheTTL})
+		if err != nil {
+			return nil, err
+		}
+		p.cache.Set(accountID, b)
+	}
+
+	perms := make(map[api.RepoName]map[authz.Perm]bool, len(myRepos))
+	for repo := range myRepos {
+		perms[repo.RepoName] = map[authz.Perm]bool{}
+		if _, ok := readable[repo.ExternalRepoSpec.ID]; ok {
+			perms[repo.RepoName][authz.Read] = true
+		}
+	}
+	return perms, nil
+}
+
+// FetchAccount implements the authz.Provider interface. It returns an account for the Bitbucket
+// Server user that user is matched with (see IdentityProvider), or nil if there is none.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, current []*extsvc.ExternalAccount) (mine *extsvc.ExternalAccount, err error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	username := p.username(user, current)
+	if username == "" {
+		return nil, nil
+	}
+	users, _, err := p.client.Users(ctx, &bitbucketserver.PageToken{Limit: 1000}, username)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		// Bitbucket Server usernames are case insensitive.
+		if !strings.EqualFold(u.Name, username) {
+			continue
+		}
+		account := &extsvc.ExternalAccount{
+			UserID: user.ID,
+			ExternalAccountSpec: extsvc.ExternalAccountSpec{
+				ServiceType: p.codeHost.ServiceType(),
+				ServiceID:   p.codeHost.ServiceID(),
+				AccountID:   strconv.Itoa(u.ID),
+			},
+		}
+		account.SetAccountData(u)
+		return account, nil
+	}
+	return nil, nil
+}
+
+// username returns the username of the Bitbucket Server user that user is matched with, or "" if
+// user has no external account from the auth provider of p.identity.
+func (p *Provider) username(user *types.User, current []*extsvc.ExternalAccount) string {
+	if p.identity.Username {
+		return user.Username
+	}
+	for _, account := range current {
+		if account.ServiceType == p.identity.AuthProviderType && account.ServiceID == p.identity.AuthProviderID {
+			return account.AccountID
+		}
+	}
+	return ""
+}
+
+// getAccountUser returns the Bitbucket Server user stored in the data of account.
+func getAccountUser(account *extsvc.ExternalAccount) (*bitbucketserver.User, error) {
+	var user bitbucketserver.User
+	if account.AccountData == nil {
+		return nil, fmt.Errorf("no Bitbucket Server user data in external account %d", account.ID)
+	}
+	if err := account.GetAccountData(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// getCachedRepoIDs returns the external IDs of the repositories readable by a user from the
+// cache and whether the cache entry exists.
+func (p *Provider) getCachedRepoIDs(accountID string) (map[string]struct{}, bool) {
+	b, exists := p.cache.Get(accountID)
+	if !exists {
+		return nil, false
+	}
+	var v cacheVal
+	if err := json.Unmarshal(b, &v); err != nil || v.TTL == 0 || v.TTL > p.cacheTTL {
+		if err != nil {
+			log15.Warn("Failed to unmarshal repo perm cache entry", "err", err.Error())
+		}
+		p.cache.Delete(accountID)
+		return nil, false
+	}
+	return v.RepoIDs, true
+}
+
+// fetchRepoIDs fetches the external IDs of the repositories readable by the Bitbucket Server user
+// with the given username (or the public repositories if it is empty) from the Bitbucket Server
+// API.
+func (p *Provider) fetchRepoIDs(ctx context.Context, username string) (map[string]struct{}, error) {
+	client := p.anonymous
+	if username != "" {
+		var err error
+		if client, err = p.client.Sudo(username); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make(map[string]struct{})
+	pageToken := &bitbucketserver.PageToken{Limit: 1000}
+	for pageToken.HasMore() {
+		repos, next, err := client.ReposWithPermission(ctx, pageToken, "REPO_READ")
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			if r.Project != nil {
+				ids[r.Project.Key+"/"+r.Slug] = struct{}{}
+			}
+		}
+		if next == nil {
+			break
+		}
+		pageToken = next
+	}
+	return ids, nil
+}
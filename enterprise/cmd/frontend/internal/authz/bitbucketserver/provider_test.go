@@ -0,0 +1,186 @@
+package bitbucketserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
+)
+
+func TestProvider(t *testing.T) {
+	users := []*bitbucketserver.User{
+		{ID: 1, Name: "alice", Slug: "alice"},
+		{ID: 2, Name: "bob", Slug: "bob"},
+	}
+	// readable maps usernames to the repositories they can read. The empty username is used for
+	// anonymous requests.
+	readable := map[string][]string{
+		"":      {"PUB/public"},
+		"alice": {"PUB/public", "SEC/alice"},
+		"bob":   {"PUB/public", "SEC/bob"},
+	}
+
+	var repoRequests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var values interface{}
+		switch r.URL.Path {
+		case "/rest/api/1.0/users":
+			var matches []*bitbucketserver.User
+			for _, u := range users {
+				if strings.Contains(u.Name, strings.ToLower(r.URL.Query().Get("filter"))) {
+					matches = append(matches, u)
+				}
+			}
+			values = matches
+
+		case "/rest/api/1.0/repos":
+			repoRequests++
+			if r.URL.Query().Get("permission") != "REPO_READ" {
+				http.Error(w, "unexpected permission", http.StatusBadRequest)
+				return
+			}
+			userID := r.URL.Query().Get("user_id")
+			if auth := r.Header.Get("Authorization"); (userID != "") != strings.HasPrefix(auth, "OAuth ") {
+				http.Error(w, "unexpected Authorization header "+auth, http.StatusUnauthorized)
+				return
+			}
+			var repos []*bitbucketserver.Repo
+			for _, id := range readable[userID] {
+				parts := strings.SplitN(id, "/", 2)
+				repos = append(repos, &bitbucketserver.Repo{Slug: parts[1], Project: &bitbucketserver.Project{Key: parts[0]}})
+			}
+			values = repos
+
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"values": values, "isLastPage": true})
+	}))
+	defer srv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &bitbucketserver.Client{
+		URL:        baseURL,
+		HTTPClient: http.DefaultClient,
+		OAuth:      &bitbucketserver.OAuth{ConsumerKey: "sourcegraph", SigningKey: key},
+	}
+	p := NewProvider(client, IdentityProvider{AuthProviderType: "http-header"}, time.Hour, make(authz.MockCache))
+
+	ctx := context.Background()
+	proxyAccount := func(username string) []*extsvc.ExternalAccount {
+		return []*extsvc.ExternalAccount{{
+			ExternalAccountSpec: extsvc.ExternalAccountSpec{ServiceType: "http-header", AccountID: username},
+		}}
+	}
+	accounts := map[string]*extsvc.ExternalAccount{}
+	for _, username := range []string{"alice", "bob"} {
+		account, err := p.FetchAccount(ctx, &types.User{Username: "sg-" + username}, proxyAccount(strings.ToUpper(username)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account == nil {
+			t.Fatalf("no account for %s", username)
+		}
+		if account.ServiceID != p.ServiceID() || account.ServiceType != bitbucketserver.ServiceType {
+			t.Errorf("got account for %s/%s, want %s/%s", account.ServiceType, account.ServiceID, bitbucketserver.ServiceType, p.ServiceID())
+		}
+		accounts[username] = account
+	}
+	if account, err := p.FetchAccount(ctx, &types.User{Username: "carol"}, proxyAccount("carol")); err != nil || account != nil {
+		t.Errorf("got account %+v and error %v for unknown user, want none", account, err)
+	}
+	// A user who signed up with the username of a Bitbucket Server user is not matched with it.
+	if account, err := p.FetchAccount(ctx, &types.User{Username: "alice"}, nil); err != nil || account != nil {
+		t.Errorf("got account %+v and error %v for user without external account, want none", account, err)
+	}
+
+	// Matching by username must be enabled explicitly.
+	byUsername := NewProvider(client, IdentityProvider{Username: true}, time.Hour, make(authz.MockCache))
+	if account, err := byUsername.FetchAccount(ctx, &types.User{Username: "ALICE"}, nil); err != nil || account == nil || account.AccountID != accounts["alice"].AccountID {
+		t.Errorf("got account %+v and error %v for username, want %+v", account, err, accounts["alice"])
+	}
+
+	repo := func(name, id string) authz.Repo {
+		return authz.Repo{
+			RepoName: api.RepoName(name),
+			ExternalRepoSpec: api.ExternalRepoSpec{
+				ID:          id,
+				ServiceType: bitbucketserver.ServiceType,
+				ServiceID:   p.ServiceID(),
+			},
+		}
+	}
+	repos := map[authz.Repo]struct{}{
+		repo("public", "PUB/public"): {},
+		repo("alice", "SEC/alice"):   {},
+		repo("bob", "SEC/bob"):       {},
+	}
+	for _, tc := range []struct {
+		name      string
+		account   *extsvc.ExternalAccount
+		wantPerms map[api.RepoName]map[authz.Perm]bool
+	}{
+		{
+			name:    "anonymous",
+			account: nil,
+			wantPerms: map[api.RepoName]map[authz.Perm]bool{
+				"public": {authz.Read: true},
+				"alice":  {},
+				"bob":    {},
+			},
+		},
+		{
+			name:    "alice",
+			account: accounts["alice"],
+			wantPerms: map[api.RepoName]map[authz.Perm]bool{
+				"public": {authz.Read: true},
+				"alice":  {authz.Read: true},
+				"bob":    {},
+			},
+		},
+		{
+			name:    "bob",
+			account: accounts["bob"],
+			wantPerms: map[api.RepoName]map[authz.Perm]bool{
+				"public": {authz.Read: true},
+				"alice":  {},
+				"bob":    {authz.Read: true},
+			},
+		},
+	} {
+		repoRequests = 0
+		for i := 0; i < 2; i++ { // run twice for cache coherency
+			perms, err := p.RepoPerms(ctx, tc.account, repos)
+			if err != nil {
+				t.Fatalf("%s: %s", tc.name, err)
+			}
+			if !reflect.DeepEqual(perms, tc.wantPerms) {
+				t.Errorf("%s: got perms %v, want %v", tc.name, perms, tc.wantPerms)
+			}
+		}
+		if repoRequests != 1 {
+			t.Errorf("%s: got %d repos requests, want 1 (cached)", tc.name, repoRequests)
+		}
+	}
+}
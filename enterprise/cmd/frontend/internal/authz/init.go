@@ -59,6 +59,20 @@ func init() {
 			}
 		}
 
+		bbss, err := db.ExternalServices.ListBitbucketServerConnections(ctx)
+		if err != nil {
+			return []*graphqlbackend.Alert{{
+				TypeValue:    graphqlbackend.AlertTypeError,
+				MessageValue: fmt.Sprintf("Unable to fetch Bitbucket Server external services: %s", err),
+			}}
+		}
+		for _, c := range bbss {
+			if c.Authorization != nil {
+				authzTypes = append(authzTypes, "Bitbucket Server")
+				break
+			}
+		}
+
 		if len(authzTypes) > 0 {
 			return []*graphqlbackend.Alert{{
 				TypeValue:    graphqlbackend.AlertTypeError,
@@ -114,5 +128,10 @@ func providersFromConfig(ctx context.Context, cfg *conf.Unified) (
 	seriousProblems = append(seriousProblems, ghproblems...)
 	warnings = append(warnings, ghwarnings...)
 
+	bbsp, bbsproblems, bbswarnings := bitbucketServerProviders(ctx)
+	authzProviders = append(authzProviders, bbsp...)
+	seriousProblems = append(seriousProblems, bbsproblems...)
+	warnings = append(warnings, bbswarnings...)
+
 	return allowAccessByDefault, authzProviders, seriousProblems, warnings
 }
@@ -0,0 +1,14 @@
+-- user_permissions holds the permissions of users on the repositories of each authz provider, as
+-- materialized by the background permissions syncer.
+CREATE TABLE user_permissions (
+  user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  permission text NOT NULL,
+  service_type text NOT NULL,
+  service_id text NOT NULL,
+  repo_ids integer[] NOT NULL DEFAULT '{}',
+  synced_at timestamp with time zone,
+  sync_error text,
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, permission, service_type, service_id)
+);
+CREATE INDEX user_permissions_updated_at ON user_permissions(updated_at);
@@ -0,0 +1,10 @@
+-- repo_update_schedule holds the state of the repo-updater update scheduler for each repository,
+-- so that it is restored when repo-updater restarts.
+CREATE TABLE repo_update_schedule (
+  repo_id integer PRIMARY KEY REFERENCES repo(id) ON DELETE CASCADE,
+  interval_seconds integer NOT NULL,
+  due_at timestamp with time zone NOT NULL,
+  last_error text,
+  last_error_at timestamp with time zone,
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
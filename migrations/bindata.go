@@ -192,6 +192,10 @@
 // 1528395563_.up.sql (181B)
 // 1528395564_.down.sql (0)
 // 1528395564_.up.sql (0)
+// 1528395565_.down.sql (39B)
+// 1528395565_.up.sql (639B)
+// 1528395566_.down.sql (43B)
+// 1528395566_.up.sql (463B)
 
 package migrations
 
@@ -4100,6 +4104,86 @@ func _1528395564_UpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1528395565_DownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x27\x00\xd8\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x75\x73\x65\x72\x5f\x70\x65\x72\x6d\x69\x73\x73\x69\x6f\x6e\x73\x3b\x0a\x01\x00\x00\xff\xff\x8c\x60\x69\x93\x27\x00\x00\x00")
+
+func _1528395565_DownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395565_DownSql,
+		"1528395565_.down.sql",
+	)
+}
+
+func _1528395565_DownSql() (*asset, error) {
+	bytes, err := _1528395565_DownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395565_.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x78, 0x12, 0x58, 0x12, 0xcc, 0xaf, 0x2f, 0x38, 0x37, 0x11, 0x24, 0xed, 0xdd, 0xc5, 0xa, 0x3d, 0xf0, 0xd2, 0x3a, 0x6b, 0xbf, 0x38, 0xc9, 0x99, 0xf0, 0xb2, 0x54, 0xfc, 0xed, 0x23, 0xc1, 0xa4}}
+	return a, nil
+}
+
+var __1528395565_UpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x91\xc1\x6e\x82\x40\x10\x86\xef\x3c\xc5\xdc\x84\x04\xfa\x02\x9e\x28\xac\x89\x29\xc5\x06\x31\xa9\x69\x1a\xb2\xb2\x53\x99\x54\x58\xb2\xbb\x68\xb5\xe9\xbb\x37\x6c\xab\x62\x48\x7b\xdc\xf9\xfe\xd9\x7f\xe6\x9f\x20\x80\x4e\xa3\x2a\x5a\x54\x35\x69\x4d\xb2\xd1\x50\xc9\x9d\xd0\x60\x2a\x84\x61\x55\xbe\x59\xa5\x06\xd9\x58\xa6\xb0\x95\x9a\x8c\x54\x84\x16\x22\x2f\x2b\xe0\x9d\xa9\x4e\xd0\x2a\xb9\x27\x81\xca\x07\xae\x9d\x20\x80\x9a\x1b\x54\xc4\x77\x74\x42\x01\x9b\xa3\x6d\xdf\xf0\xf2\x7d\xab\x64\xd7\x88\x1b\x17\x7d\x6c\x4a\x54\x77\x4e\x94\xb1\x30\x67\x90\x87\xf7\x09\x1b\x4f\xe8\x3a\xf0\x53\x24\x01\xd4\x18\xdc\xa2\x82\x74\x91\x43\xba\x4a\x12\xc8\xd8\x8c\x65\x2c\x8d\xd8\xd2\x6a\xb4\x4b\xc2\x83\x45\x0a\x31\x4b\x58\xce\x20\x0a\x97\x51\x18\x33\xdf\x81\x81\x31\x18\xfc\x30\x97\x2f\x7a\xa6\x51\xed\xa9\xc4\xc2\x1c\x5b\xfc\x9b\x92\x18\xb3\x3e\x98\x82\x84\x3e\x4f\xf6\xf2\x7a\xc1\x10\xb3\x59\xb8\x4a\x72\x98\x7c\x7e\x4d\x7a\xad\x5d\x57\x14\xdc\x80\xa1\x1a\xb5\xe1\x75\x0b\x07\x32\x95\x7d\xc2\x49\x36\x78\x56\x15\xa8\x94\x54\xd6\xad\x2f\x75\xad\xe0\xe6\xff\xce\xb1\x6b\x23\x0f\xae\xd7\x77\x3f\x65\xf3\xc7\x30\x5b\xc3\x03\x5b\x83\xfb\x1b\xa4\x3f\x88\xc3\xbf\x59\xff\xfa\x22\xe1\x39\xde\xf4\x7c\x9c\x79\x1a\xb3\xe7\xd1\x71\x8a\xc1\x68\x8b\x74\x84\xdd\x2b\xf6\xa6\xce\x77\x00\x00\x00\xff\xff\x7c\xb9\x26\x61\x7f\x02\x00\x00")
+
+func _1528395565_UpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395565_UpSql,
+		"1528395565_.up.sql",
+	)
+}
+
+func _1528395565_UpSql() (*asset, error) {
+	bytes, err := _1528395565_UpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395565_.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x17, 0x46, 0x5e, 0xd2, 0x47, 0xd4, 0x74, 0xd8, 0xaf, 0xb2, 0xfb, 0xfb, 0x5c, 0xf0, 0xee, 0x35, 0x7f, 0xef, 0xfc, 0xbe, 0x72, 0x52, 0xe7, 0xae, 0x2c, 0x42, 0xff, 0xad, 0x3d, 0xe2, 0xff, 0xcc}}
+	return a, nil
+}
+
+var __1528395566_DownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x2b\x00\xd4\xff\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x72\x65\x70\x6f\x5f\x75\x70\x64\x61\x74\x65\x5f\x73\x63\x68\x65\x64\x75\x6c\x65\x3b\x0a\x01\x00\x00\xff\xff\xda\x78\x3d\xed\x2b\x00\x00\x00")
+
+func _1528395566_DownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395566_DownSql,
+		"1528395566_.down.sql",
+	)
+}
+
+func _1528395566_DownSql() (*asset, error) {
+	bytes, err := _1528395566_DownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395566_.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x32, 0xf3, 0x2, 0x9, 0x80, 0x9a, 0x65, 0xf, 0x13, 0x75, 0x60, 0x68, 0x29, 0xf6, 0xe3, 0x55, 0xae, 0xaa, 0xe2, 0x2b, 0x72, 0xa8, 0x8e, 0xb9, 0x7f, 0x71, 0x6d, 0x8d, 0xe, 0x7e, 0x31, 0xab}}
+	return a, nil
+}
+
+var __1528395566_UpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x8f\x41\x6a\xc3\x30\x10\x45\xf7\x3e\xc5\x5f\x26\x10\xf7\x02\x5d\xa9\xce\x04\x4a\x5d\xa7\x38\xce\x22\x2b\x23\xa2\x49\x25\x70\xac\x30\x9a\x34\x6d\x4f\x5f\x6c\xd3\x82\xa1\x94\xae\xc4\x68\xde\x7f\xfa\xca\x73\x08\x5f\x62\x7b\xbd\x38\xab\xdc\xa6\xa3\x67\x77\xed\x18\x3e\x76\x2e\x41\x3d\x23\xa9\x55\x46\x3c\x8d\xc3\xc0\xe6\x13\x2b\x98\x4e\x7c\x67\x04\xa7\x28\x60\x7b\xf4\xa3\x32\x05\x8d\xf2\xb1\xca\xf2\x1c\x29\x42\xbd\x55\x04\x45\x48\x10\x4e\x1a\x85\x1d\x6e\x9e\xfb\xb9\x71\x58\x59\xd1\x74\x97\x15\x35\x99\x86\xd0\x98\x87\x92\x7e\x6f\xb8\xc8\x30\x2d\x82\x43\xe8\x95\x5f\x59\xf0\x52\x3f\x3e\x9b\xfa\x80\x27\x3a\xa0\xa6\x0d\xd5\x54\x15\xb4\x1b\xb1\x45\x70\x4b\x6c\x2b\xac\xa9\xa4\x86\x50\x98\x5d\x61\xd6\xb4\xca\x30\x86\xe5\xcd\x76\x6d\xe2\x63\xec\x5d\xfa\xb1\x55\xdb\x06\xd5\xbe\x2c\x07\xc8\x5d\xb9\xb5\x0a\x0d\xe7\xa1\xe2\xf9\x82\x5b\x50\x3f\x8e\xf8\x8c\x3d\xcf\xd8\xce\x26\x6d\x59\x24\x0a\x94\xdf\x75\x7e\xf5\x97\x65\x20\xa7\x6f\xba\x7f\x3d\x86\x35\x6d\xcc\xbe\x6c\xd0\xc7\xdb\x62\x99\x2d\xef\xb3\xaf\x00\x00\x00\xff\xff\x1b\x63\x82\xa3\xcf\x01\x00\x00")
+
+func _1528395566_UpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395566_UpSql,
+		"1528395566_.up.sql",
+	)
+}
+
+func _1528395566_UpSql() (*asset, error) {
+	bytes, err := _1528395566_UpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395566_.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x3a, 0xb7, 0xa9, 0x6f, 0x62, 0x5c, 0x7a, 0x3c, 0x38, 0xed, 0x86, 0x20, 0x48, 0x93, 0x5a, 0xf6, 0x18, 0x25, 0x81, 0x46, 0xae, 0xb9, 0x6d, 0x29, 0xcf, 0xea, 0x9e, 0xb3, 0x9, 0x33, 0xa2, 0xf6}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -4574,6 +4658,14 @@ var _bindata = map[string]func() (*asset, error){
 	"1528395564_.down.sql": _1528395564_DownSql,
 
 	"1528395564_.up.sql": _1528395564_UpSql,
+
+	"1528395565_.down.sql": _1528395565_DownSql,
+
+	"1528395565_.up.sql": _1528395565_UpSql,
+
+	"1528395566_.down.sql": _1528395566_DownSql,
+
+	"1528395566_.up.sql": _1528395566_UpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -4809,6 +4901,10 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1528395563_.up.sql":                                          {_1528395563_UpSql, map[string]*bintree{}},
 	"1528395564_.down.sql":                                        {_1528395564_DownSql, map[string]*bintree{}},
 	"1528395564_.up.sql":                                          {_1528395564_UpSql, map[string]*bintree{}},
+	"1528395565_.down.sql":                                        {_1528395565_DownSql, map[string]*bintree{}},
+	"1528395565_.up.sql":                                          {_1528395565_UpSql, map[string]*bintree{}},
+	"1528395566_.down.sql":                                        {_1528395566_DownSql, map[string]*bintree{}},
+	"1528395566_.up.sql":                                          {_1528395566_UpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory.
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 // RepoCreateOrUpdateRequest is a request to create or update a repository.
 //
 // The request handler determines if the request refers to an existing repository (and should therefore update
@@ -59,6 +61,24 @@ type PhabricatorRepoCreateRequest struct {
 	URL      string `json:"url"`
 }
 
+type PermissionsUsersToSyncRequest struct {
+	// SyncedBefore excludes the users whose permissions were synced at or after this time.
+	SyncedBefore time.Time `json:"syncedBefore"`
+	Limit        int       `json:"limit"`
+}
+
+// RepoUpdateScheduleState is the state of the repo-updater update scheduler for a repository. It
+// is persisted so that it is restored when repo-updater restarts.
+type RepoUpdateScheduleState struct {
+	Repo            RepoName  `json:"repo"`
+	IntervalSeconds int       `json:"intervalSeconds"` // the interval between scheduled updates
+	Due             time.Time `json:"due"`             // the time of the next scheduled update
+
+	// LastError is the error of the last update, or empty if it succeeded.
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
 type ExternalServiceConfigsRequest struct {
 	Kind string `json:"kind"`
 }
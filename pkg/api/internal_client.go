@@ -260,6 +260,35 @@ func (c *internalClient) ReposCreateIfNotExists(ctx context.Context, op RepoCrea
 	return &repo, nil
 }
 
+// PermissionsUsersToSync returns the IDs of at most limit users whose repository permissions were
+// never synced or were last synced before syncedBefore, least recently synced first.
+func (c *internalClient) PermissionsUsersToSync(ctx context.Context, syncedBefore time.Time, limit int) ([]int32, error) {
+	var userIDs []int32
+	err := c.postInternal(ctx, "permissions/users-to-sync", PermissionsUsersToSyncRequest{
+		SyncedBefore: syncedBefore,
+		Limit:        limit,
+	}, &userIDs)
+	return userIDs, err
+}
+
+// PermissionsSyncUser syncs the repository permissions of the user from the authz providers.
+func (c *internalClient) PermissionsSyncUser(ctx context.Context, userID int32) error {
+	return c.postInternal(ctx, "permissions/sync-user", userID, nil)
+}
+
+// ReposUpdateScheduleList returns the persisted state of the repo-updater update scheduler.
+func (c *internalClient) ReposUpdateScheduleList(ctx context.Context) ([]*RepoUpdateScheduleState, error) {
+	var states []*RepoUpdateScheduleState
+	err := c.postInternal(ctx, "repos/update-schedule/list", nil, &states)
+	return states, err
+}
+
+// ReposUpdateScheduleSave persists the state of the repo-updater update scheduler for the
+// repositories.
+func (c *internalClient) ReposUpdateScheduleSave(ctx context.Context, states []*RepoUpdateScheduleState) error {
+	return c.postInternal(ctx, "repos/update-schedule/save", states, nil)
+}
+
 // ReposListEnabled returns a list of all enabled repository names.
 func (c *internalClient) ReposListEnabled(ctx context.Context) ([]RepoName, error) {
 	var names []RepoName
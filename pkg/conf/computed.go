@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/conf/confdefaults"
@@ -98,6 +99,36 @@ func UpdateScheduler2Enabled() bool {
 	return p != "disabled"
 }
 
+// PermissionsBackgroundSyncEnabled returns true if the permissionsBackgroundSync experiment is
+// enabled.
+func PermissionsBackgroundSyncEnabled() bool {
+	experimentalFeatures := Get().ExperimentalFeatures
+	return experimentalFeatures != nil && experimentalFeatures.PermissionsBackgroundSync == "enabled"
+}
+
+// PermissionsSyncInterval returns how often the background permissions syncer syncs the repository
+// permissions of each user.
+func PermissionsSyncInterval() time.Duration {
+	if v := Get().PermissionsSyncInterval; v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+	return time.Hour
+}
+
+// GitLFSEnabled returns true if the Git LFS objects of files stored with Git LFS should be fetched.
+func GitLFSEnabled() bool {
+	lfs := Get().GitLfs
+	return lfs != nil && lfs.Enabled
+}
+
+// GitLFSMaxFileSize returns the size (in bytes) of the largest Git LFS object that is fetched.
+func GitLFSMaxFileSize() int64 {
+	if lfs := Get().GitLfs; lfs != nil && lfs.MaxFileSize > 0 {
+		return int64(lfs.MaxFileSize)
+	}
+	return 10 << 20 // 10 MB
+}
+
 func AWSCodeCommitConfigs(ctx context.Context) ([]*schema.AWSCodeCommitConnection, error) {
 	var config []*schema.AWSCodeCommitConnection
 	if err := api.InternalClient.ExternalServiceConfigs(ctx, "AWSCODECOMMIT", &config); err != nil {
@@ -106,6 +137,14 @@ func AWSCodeCommitConfigs(ctx context.Context) ([]*schema.AWSCodeCommitConnectio
 	return config, nil
 }
 
+func BitbucketCloudConfigs(ctx context.Context) ([]*schema.BitbucketCloudConnection, error) {
+	var config []*schema.BitbucketCloudConnection
+	if err := api.InternalClient.ExternalServiceConfigs(ctx, "BITBUCKETCLOUD", &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
 func BitbucketServerConfigs(ctx context.Context) ([]*schema.BitbucketServerConnection, error) {
 	var config []*schema.BitbucketServerConnection
 	if err := api.InternalClient.ExternalServiceConfigs(ctx, "BITBUCKETSERVER", &config); err != nil {
@@ -130,6 +169,14 @@ func GitLabConfigs(ctx context.Context) ([]*schema.GitLabConnection, error) {
 	return config, nil
 }
 
+func GiteaConfigs(ctx context.Context) ([]*schema.GiteaConnection, error) {
+	var config []*schema.GiteaConnection
+	if err := api.InternalClient.ExternalServiceConfigs(ctx, "GITEA", &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
 func GitoliteConfigs(ctx context.Context) ([]*schema.GitoliteConnection, error) {
 	var config []*schema.GitoliteConnection
 	if err := api.InternalClient.ExternalServiceConfigs(ctx, "GITOLITE", &config); err != nil {
@@ -0,0 +1,48 @@
+package reposource
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestBitbucketCloud_cloneURLToRepoName(t *testing.T) {
+	var tests = []struct {
+		conn schema.BitbucketCloudConnection
+		urls []urlToRepoName
+	}{{
+		conn: schema.BitbucketCloudConnection{},
+		urls: []urlToRepoName{
+			{"git@bitbucket.org:myteam/myrepo.git", "bitbucket.org/myteam/myrepo"},
+			{"ssh://git@bitbucket.org/myteam/myrepo.git", "bitbucket.org/myteam/myrepo"},
+			{"https://bitbucket.org/myteam/myrepo.git", "bitbucket.org/myteam/myrepo"},
+			{"https://alice@bitbucket.org/myteam/myrepo.git", "bitbucket.org/myteam/myrepo"},
+
+			{"git@asdf.com:myteam/myrepo.git", ""},
+			{"https://asdf.com/myteam/myrepo.git", ""},
+		},
+	}, {
+		conn: schema.BitbucketCloudConnection{
+			Url:                   "https://bitbucket.org",
+			RepositoryPathPattern: "bb/{nameWithOwner}",
+		},
+		urls: []urlToRepoName{
+			{"git@bitbucket.org:myteam/myrepo.git", "bb/myteam/myrepo"},
+			{"https://bitbucket.org/myteam/myrepo", "bb/myteam/myrepo"},
+
+			{"https://bitbucket.example.com/myteam/myrepo.git", ""},
+		},
+	}}
+
+	for _, test := range tests {
+		for _, u := range test.urls {
+			repoName, err := BitbucketCloud{&test.conn}.CloneURLToRepoName(u.cloneURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.repoName != string(repoName) {
+				t.Errorf("expected %q but got %q for clone URL %q (connection: %+v)", u.repoName, repoName, u.cloneURL, test.conn)
+			}
+		}
+	}
+}
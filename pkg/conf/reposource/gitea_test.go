@@ -0,0 +1,50 @@
+package reposource
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestGitea_cloneURLToRepoName(t *testing.T) {
+	var tests = []struct {
+		conn schema.GiteaConnection
+		urls []urlToRepoName
+	}{{
+		conn: schema.GiteaConnection{
+			Url: "https://gitea.example.com",
+		},
+		urls: []urlToRepoName{
+			{"git@gitea.example.com:myorg/myrepo.git", "gitea.example.com/myorg/myrepo"},
+			{"ssh://git@gitea.example.com/myorg/myrepo.git", "gitea.example.com/myorg/myrepo"},
+			{"https://gitea.example.com/myorg/myrepo.git", "gitea.example.com/myorg/myrepo"},
+			{"https://token@gitea.example.com/myorg/myrepo.git", "gitea.example.com/myorg/myrepo"},
+
+			{"git@asdf.com:myorg/myrepo.git", ""},
+			{"https://asdf.com/myorg/myrepo.git", ""},
+		},
+	}, {
+		conn: schema.GiteaConnection{
+			Url:                   "https://git.mycompany.com/gitea/",
+			RepositoryPathPattern: "gitea/{nameWithOwner}",
+		},
+		urls: []urlToRepoName{
+			{"git@git.mycompany.com:myorg/myrepo.git", "gitea/myorg/myrepo"},
+			{"https://git.mycompany.com/gitea/myorg/myrepo.git", "gitea/myorg/myrepo"},
+
+			{"https://asdf.com/gitea/myorg/myrepo.git", ""},
+		},
+	}}
+
+	for _, test := range tests {
+		for _, u := range test.urls {
+			repoName, err := Gitea{&test.conn}.CloneURLToRepoName(u.cloneURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.repoName != string(repoName) {
+				t.Errorf("expected %q but got %q for clone URL %q (connection: %+v)", u.repoName, repoName, u.cloneURL, test.conn)
+			}
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/metrics"
+	"github.com/sourcegraph/sourcegraph/pkg/ratelimit"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+var requestCounter = metrics.NewRequestCounter("bitbucket_cloud", "Total number of requests sent to the Bitbucket Cloud API.")
+
+// WithRequestCounter wraps the given transport with a request counter metric.
+func WithRequestCounter(transport http.RoundTripper) http.RoundTripper {
+	return requestCounter.Transport(transport, func(u *url.URL) string {
+		// API to URL mapping looks like this:
+		//
+		// 	Repo -> 2.0/repositories/%s/%s
+		// 	Repos -> 2.0/repositories/%s
+		// 	Workspaces -> 2.0/workspaces
+		//
+		// We guess the category based on the path components after "2.0".
+		parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+		switch {
+		case len(parts) == 4 && parts[1] == "repositories":
+			return "Repo"
+		case len(parts) == 3 && parts[1] == "repositories":
+			return "Repos"
+		case len(parts) == 2 && parts[1] == "workspaces":
+			return "Workspaces"
+		default:
+			// don't return the path directly as that could introduce too much dimensionality
+			return "unknown"
+		}
+	})
+}
+
+// DefaultAPIURL is the base URL of the Bitbucket Cloud API.
+var DefaultAPIURL = &url.URL{Scheme: "https", Host: "api.bitbucket.org", Path: "/"}
+
+// Client accesses Bitbucket Cloud via its REST API (version 2.0).
+type Client struct {
+	// URL is the base URL of the Bitbucket Cloud API (usually DefaultAPIURL).
+	URL *url.URL
+
+	// Username and AppPassword are the credentials used to authenticate requests: the username of
+	// a Bitbucket Cloud account and one of its app passwords (see
+	// https://confluence.atlassian.com/bitbucket/app-passwords-828781300.html). If empty,
+	// requests are unauthenticated (and can only access public repositories).
+	Username, AppPassword string
+
+	// HTTPClient is the client used to access Bitbucket Cloud. To enable metrics, always wrap the
+	// Transport using WithRequestCounter.
+	HTTPClient *http.Client
+
+	// RateLimit is the API rate limit monitor, updated from the rate limit headers of the responses,
+	// or from the Retry-After header of requests rejected by the rate limit if there are none.
+	RateLimit *ratelimit.Monitor
+}
+
+// defaultRetryAfter is how long to wait before retrying a request rejected by the rate limit if its
+// response doesn't say.
+const defaultRetryAfter = time.Minute
+
+// NewClient returns a client for the Bitbucket Cloud API at apiURL (or DefaultAPIURL if nil).
+func NewClient(apiURL *url.URL, username, appPassword string, httpClient *http.Client) *Client {
+	if apiURL == nil {
+		apiURL = DefaultAPIURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		URL:         apiURL,
+		Username:    username,
+		AppPassword: appPassword,
+		HTTPClient:  httpClient,
+		RateLimit:   &ratelimit.Monitor{HeaderPrefix: "X-"},
+	}
+}
+
+// Repos lists a page of the repositories of the workspace (a user or team account).
+func (c *Client) Repos(ctx context.Context, pageToken *PageToken, workspace string) ([]*Repo, *PageToken, error) {
+	var repos []*Repo
+	next, err := c.page(ctx, "2.0/repositories/"+url.PathEscape(workspace), pageToken, &repos)
+	return repos, next, err
+}
+
+// Workspaces lists a page of the workspaces in which the authenticated user has the given role
+// ("member" or "owner").
+func (c *Client) Workspaces(ctx context.Context, pageToken *PageToken, role string) ([]*Workspace, *PageToken, error) {
+	var workspaces []*Workspace
+	next, err := c.page(ctx, "2.0/workspaces?role="+url.QueryEscape(role), pageToken, &workspaces)
+	return workspaces, next, err
+}
+
+// Repo returns the repository of the workspace with the given slug.
+func (c *Client) Repo(ctx context.Context, workspace, slug string) (*Repo, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("2.0/repositories/%s/%s", url.PathEscape(workspace), url.PathEscape(slug)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var repo Repo
+	if err := c.do(ctx, req, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// page fetches the page of pageToken (or the first page at path if pageToken is nil) and decodes
+// its values into values.
+func (c *Client) page(ctx context.Context, path string, pageToken *PageToken, values interface{}) (*PageToken, error) {
+	u := path
+	if pageToken != nil && pageToken.Next != "" {
+		// 🚨 SECURITY: Only follow next page URLs of the API, to avoid sending the credentials to
+		// another host.
+		next, err := url.Parse(pageToken.Next)
+		if err != nil {
+			return nil, err
+		}
+		if next.IsAbs() && next.Host != c.URL.Host {
+			return nil, errors.Errorf("bitbucketcloud: next page URL %q is not on the API host %q", pageToken.Next, c.URL.Host)
+		}
+		u = pageToken.Next
+	} else {
+		if strings.Contains(u, "?") {
+			u += "&"
+		} else {
+			u += "?"
+		}
+		u += "pagelen=100"
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		*PageToken
+		Values json.RawMessage `json:"values"`
+	}
+	if err := c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.PageToken == nil {
+		resp.PageToken = &PageToken{}
+	}
+	if len(resp.Values) > 0 {
+		if err := json.Unmarshal(resp.Values, values); err != nil {
+			return nil, err
+		}
+	}
+	return resp.PageToken, nil
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.URL = c.URL.ResolveReference(req.URL)
+	req.Header.Set("Accept", "application/json")
+	if c.Username != "" || c.AppPassword != "" {
+		req.SetBasicAuth(c.Username, c.AppPassword)
+	}
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(), req,
+		nethttp.OperationName("Bitbucket Cloud"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if c.RateLimit != nil {
+		c.RateLimit.Update(resp.Header)
+		if _, _, known := c.RateLimit.Get(); !known && resp.StatusCode == http.StatusTooManyRequests {
+			// Without rate limit headers, the Retry-After header is the only hint of when to retry.
+			retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if !ok {
+				retryAfter = defaultRetryAfter
+			}
+			c.RateLimit.UpdateRetryAfter(retryAfter)
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.WithStack(&httpError{URL: req.URL, StatusCode: resp.StatusCode})
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// PageToken is the pagination information of a paginated response.
+type PageToken struct {
+	Size    int    `json:"size"`
+	Page    int    `json:"page"`
+	Pagelen int    `json:"pagelen"`
+	Next    string `json:"next"`
+}
+
+// HasMore reports whether there are more pages after the page of t (or whether the first page
+// should be fetched, if t is nil).
+func (t *PageToken) HasMore() bool {
+	if t == nil {
+		return true
+	}
+	return t.Next != ""
+}
+
+// Repo is a Bitbucket Cloud repository.
+type Repo struct {
+	UUID        string   `json:"uuid"`
+	Slug        string   `json:"slug"`
+	Name        string   `json:"name"`
+	FullName    string   `json:"full_name"`
+	Description string   `json:"description"`
+	SCM         string   `json:"scm"`
+	IsPrivate   bool     `json:"is_private"`
+	Parent      *Repo    `json:"parent"`
+	Owner       *Account `json:"owner"`
+	Links       struct {
+		Clone []struct {
+			Href string `json:"href"`
+			Name string `json:"name"`
+		} `json:"clone"`
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+// Workspace is a Bitbucket Cloud workspace, which holds the repositories of a user or team.
+type Workspace struct {
+	UUID string `json:"uuid"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// Account is a Bitbucket Cloud user or team account.
+type Account struct {
+	UUID        string `json:"uuid"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
+
+// IsRateLimitExceeded reports whether err is caused by the Bitbucket Cloud API rate limit being
+// exceeded.
+func IsRateLimitExceeded(err error) bool {
+	e, ok := errors.Cause(err).(*httpError)
+	return ok && e.StatusCode == http.StatusTooManyRequests
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or
+// an HTTP date. ok is false if the value is empty or invalid.
+func parseRetryAfter(value string, now time.Time) (d time.Duration, ok bool) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if t.Before(now) {
+			return 0, true
+		}
+		return t.Sub(now), true
+	}
+	return 0, false
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("unexpected %d response from Bitbucket Cloud API at %s", e.StatusCode, e.URL)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
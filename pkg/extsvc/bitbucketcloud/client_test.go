@@ -0,0 +1,114 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClient_Repos(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "alice" || password != "app-password" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/2.0/repositories/myteam" {
+			http.NotFound(w, r)
+			return
+		}
+		resp := map[string]interface{}{"pagelen": 1}
+		switch r.URL.Query().Get("page") {
+		case "":
+			if r.URL.Query().Get("pagelen") != "100" {
+				http.Error(w, "unexpected pagelen", http.StatusBadRequest)
+				return
+			}
+			resp["values"] = []*Repo{{UUID: "{1}", FullName: "myteam/a"}}
+			resp["next"] = srvURL + "/2.0/repositories/myteam?page=2"
+		case "2":
+			resp["values"] = []*Repo{{UUID: "{2}", FullName: "myteam/b", IsPrivate: true}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	apiURL, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(apiURL, "alice", "app-password", nil)
+	ctx := context.Background()
+
+	var (
+		got   []*Repo
+		pages int
+	)
+	for token := (*PageToken)(nil); token.HasMore(); pages++ {
+		var repos []*Repo
+		repos, token, err = c.Repos(ctx, token, "myteam")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, repos...)
+	}
+	want := []*Repo{
+		{UUID: "{1}", FullName: "myteam/a"},
+		{UUID: "{2}", FullName: "myteam/b", IsPrivate: true},
+	}
+	if !reflect.DeepEqual(got, want) || pages != 2 {
+		t.Errorf("got %+v in %d pages, want %+v in 2 pages", got, pages, want)
+	}
+
+	// Next page URLs on other hosts are not followed.
+	if _, _, err := c.Repos(ctx, &PageToken{Next: "https://example.com/2.0/repositories/myteam?page=2"}, "myteam"); err == nil {
+		t.Error("got no error for next page URL on another host")
+	}
+}
+
+func TestClient_rateLimitExceeded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	apiURL, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(apiURL, "", "", nil)
+	_, err = c.Repo(context.Background(), "myteam", "a")
+	if !IsRateLimitExceeded(err) {
+		t.Errorf("got error %v, want rate limit exceeded", err)
+	}
+	if wait := c.RateLimit.RecommendedWaitForBackgroundOp(1); wait < 119*time.Second || wait > 2*time.Minute {
+		t.Errorf("got recommended wait %s, want about 2m", wait)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	for value, want := range map[string]struct {
+		d  time.Duration
+		ok bool
+	}{
+		"":                              {0, false},
+		"30":                            {30 * time.Second, true},
+		"0":                             {0, true},
+		"-1":                            {0, false},
+		"Wed, 02 Jan 2019 03:05:05 GMT": {time.Minute, true},
+		"Wed, 02 Jan 2019 03:03:05 GMT": {0, true}, // in the past
+		"soon":                          {0, false},
+	} {
+		if d, ok := parseRetryAfter(value, now); d != want.d || ok != want.ok {
+			t.Errorf("%q: got %s, %v, want %s, %v", value, d, ok, want.d, want.ok)
+		}
+	}
+}
@@ -70,6 +70,68 @@ type Client struct {
 	// RateLimit is the self-imposed rate limiter (since Bitbucket does not have a concept
 	// of rate limiting in HTTP response headers).
 	RateLimit *rate.Limiter
+
+	// OAuth, if non-nil, is used to sign the requests of the clients returned
+	// by Sudo.
+	OAuth *OAuth
+
+	// sudo is the username of the user impersonated with OAuth (see Sudo).
+	sudo string
+}
+
+// Sudo returns a copy of c which makes requests on behalf of the user with
+// the given username. It requires OAuth, with an application link that
+// allows user impersonation.
+func (c *Client) Sudo(username string) (*Client, error) {
+	if c.OAuth == nil {
+		return nil, errors.New("bitbucketserver: impersonating users requires OAuth")
+	}
+	sudo := *c
+	sudo.sudo = username
+	return &sudo, nil
+}
+
+// Users lists the users whose username, name or email address match filter
+// (or all users if filter is empty).
+func (c *Client) Users(ctx context.Context, pageToken *PageToken, filter string) ([]*User, *PageToken, error) {
+	qry := pageToken.Values()
+	if filter != "" {
+		qry.Set("filter", filter)
+	}
+	req, err := http.NewRequest("GET", "rest/api/1.0/users?"+qry.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp struct {
+		*PageToken
+		Values []*User
+	}
+	err = c.do(ctx, req, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Values, resp.PageToken, nil
+}
+
+// ReposWithPermission lists the repositories on which the user of the client
+// (or the impersonated user, see Sudo) has the given permission, such as
+// "REPO_READ".
+func (c *Client) ReposWithPermission(ctx context.Context, pageToken *PageToken, permission string) ([]*Repo, *PageToken, error) {
+	qry := pageToken.Values()
+	qry.Set("permission", permission)
+	req, err := http.NewRequest("GET", "rest/api/1.0/repos?"+qry.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp struct {
+		*PageToken
+		Values []*Repo
+	}
+	err = c.do(ctx, req, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Values, resp.PageToken, nil
 }
 
 func (c *Client) Repo(ctx context.Context, projectKey, repoSlug string) (*Repo, error) {
@@ -121,8 +183,15 @@ func (c *Client) do(ctx context.Context, req *http.Request, result interface{})
 	req.URL = c.URL.ResolveReference(req.URL)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	// Authenticate request, preferring token.
-	if c.Token != "" {
+	// Authenticate request, preferring impersonation and then token.
+	if c.sudo != "" {
+		qry := req.URL.Query()
+		qry.Set("user_id", c.sudo)
+		req.URL.RawQuery = qry.Encode()
+		if err := c.OAuth.sign(req); err != nil {
+			return errors.Wrap(err, "sign request")
+		}
+	} else if c.Token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.Token)
 	} else if c.Username != "" || c.Password != "" {
 		req.SetBasicAuth(c.Username, c.Password)
@@ -165,20 +234,26 @@ func (t *PageToken) HasMore() bool {
 }
 
 func (t *PageToken) Query() string {
-	if t == nil {
+	v := t.Values()
+	if len(v) == 0 {
 		return ""
 	}
+	return "?" + v.Encode()
+}
+
+// Values returns the query parameters which request the page of t.
+func (t *PageToken) Values() url.Values {
 	v := url.Values{}
+	if t == nil {
+		return v
+	}
 	if t.NextPageStart != 0 {
 		v.Set("start", strconv.Itoa(t.NextPageStart))
 	}
 	if t.Limit != 0 {
 		v.Set("limit", strconv.Itoa(t.Limit))
 	}
-	if len(v) == 0 {
-		return ""
-	}
-	return "?" + v.Encode()
+	return v
 }
 
 type Repo struct {
@@ -208,6 +283,16 @@ func (r *Repo) IsPersonalRepository() bool {
 	return r.Project.Type == "PERSONAL"
 }
 
+type User struct {
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+	ID           int    `json:"id"`
+	DisplayName  string `json:"displayName"`
+	Active       bool   `json:"active"`
+	Slug         string `json:"slug"`
+	Type         string `json:"type"`
+}
+
 type Project struct {
 	Key    string `json:"key"`
 	ID     int    `json:"id"`
@@ -0,0 +1,149 @@
+package bitbucketserver
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OAuth signs requests to Bitbucket Server with 2-legged OAuth 1.0a
+// (RSA-SHA1), as the consumer of an application link. If the incoming link
+// allows user impersonation, requests can be made on behalf of any user (see
+// Client.Sudo).
+type OAuth struct {
+	// ConsumerKey is the consumer key of the application link.
+	ConsumerKey string
+
+	// SigningKey is the private key whose public key is configured in the
+	// application link.
+	SigningKey *rsa.PrivateKey
+}
+
+// ParseSigningKey parses a PEM encoded RSA private key, in PKCS #1 or PKCS #8
+// form.
+func ParseSigningKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse signing key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an RSA private key")
+	}
+	return rsaKey, nil
+}
+
+// sign sets the OAuth Authorization header of req, as described in
+// https://tools.ietf.org/html/rfc5849#section-3. The query of req must
+// already contain all its parameters.
+func (o *OAuth) sign(req *http.Request) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	oauthParams := map[string]string{
+		"oauth_consumer_key":     o.ConsumerKey,
+		"oauth_nonce":            hex.EncodeToString(nonce),
+		"oauth_signature_method": "RSA-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+
+	hashed := sha1.Sum([]byte(signatureBase(req, oauthParams)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, o.SigningKey, crypto.SHA1, hashed[:])
+	if err != nil {
+		return err
+	}
+	oauthParams["oauth_signature"] = base64.StdEncoding.EncodeToString(signature)
+
+	names := make([]string, 0, len(oauthParams))
+	for name := range oauthParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString("OAuth ")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(percentEncode(name) + `="` + percentEncode(oauthParams[name]) + `"`)
+	}
+	req.Header.Set("Authorization", b.String())
+	return nil
+}
+
+// signatureBase returns the signature base string of req with the given
+// OAuth protocol parameters.
+func signatureBase(req *http.Request, oauthParams map[string]string) string {
+	// Parameters are sorted by their encoded name, then value.
+	var params [][2]string
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			params = append(params, [2]string{percentEncode(name), percentEncode(v)})
+		}
+	}
+	for name, v := range oauthParams {
+		params = append(params, [2]string{percentEncode(name), percentEncode(v)})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p[0] + "=" + p[1]
+	}
+
+	baseURL := url.URL{
+		Scheme:  strings.ToLower(req.URL.Scheme),
+		Host:    strings.ToLower(req.URL.Host),
+		Path:    req.URL.Path,
+		RawPath: req.URL.RawPath,
+	}
+	if port := baseURL.Port(); (baseURL.Scheme == "http" && port == "80") || (baseURL.Scheme == "https" && port == "443") {
+		baseURL.Host = baseURL.Hostname()
+	}
+	return strings.Join([]string{
+		strings.ToUpper(req.Method),
+		percentEncode(baseURL.String()),
+		percentEncode(strings.Join(pairs, "&")),
+	}, "&")
+}
+
+// percentEncode encodes s as described in
+// https://tools.ietf.org/html/rfc5849#section-3.6.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package bitbucketserver
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestOAuth_sign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	signingKey, err := ParseSigningKey(pemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &OAuth{ConsumerKey: "sourcegraph", SigningKey: signingKey}
+
+	req, err := http.NewRequest("GET", "https://bitbucket.example.com:443/rest/api/1.0/repos?permission=REPO_READ&user_id=j%20doe", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "OAuth ") {
+		t.Fatalf("got Authorization header %q, want OAuth", header)
+	}
+	params := map[string]string{}
+	for _, param := range strings.Split(strings.TrimPrefix(header, "OAuth "), ", ") {
+		kv := strings.SplitN(param, "=", 2)
+		v, err := url.PathUnescape(strings.Trim(kv[1], `"`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		params[kv[0]] = v
+	}
+	if got, want := params["oauth_consumer_key"], "sourcegraph"; got != want {
+		t.Errorf("got consumer key %q, want %q", got, want)
+	}
+	if got, want := params["oauth_signature_method"], "RSA-SHA1"; got != want {
+		t.Errorf("got signature method %q, want %q", got, want)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(params["oauth_signature"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(params, "oauth_signature")
+	base := signatureBase(req, params)
+	if want := "GET&https%3A%2F%2Fbitbucket.example.com%2Frest%2Fapi%2F1.0%2Frepos&oauth_consumer_key%3Dsourcegraph%26oauth_nonce%3D"; !strings.HasPrefix(base, want) {
+		t.Errorf("got signature base %q, want prefix %q", base, want)
+	}
+	if want := "%26permission%3DREPO_READ%26user_id%3Dj%2520doe"; !strings.HasSuffix(base, want) {
+		t.Errorf("got signature base %q, want suffix %q", base, want)
+	}
+	hashed := sha1.Sum([]byte(base))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hashed[:], signature); err != nil {
+		t.Errorf("invalid signature: %s", err)
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	for in, want := range map[string]string{
+		"abc-._~XYZ019": "abc-._~XYZ019",
+		"a b+c":         "a%20b%2Bc",
+		"é/=&":          "%C3%A9%2F%3D%26",
+	} {
+		if got := percentEncode(in); got != want {
+			t.Errorf("percentEncode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/metrics"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+var requestCounter = metrics.NewRequestCounter("gitea", "Total number of requests sent to the Gitea API.")
+
+// WithRequestCounter wraps the given transport with a request counter metric.
+func WithRequestCounter(transport http.RoundTripper) http.RoundTripper {
+	return requestCounter.Transport(transport, func(u *url.URL) string {
+		// API to URL mapping looks like this:
+		//
+		// 	Repo -> api/v1/repos/%s/%s
+		// 	SearchRepos -> api/v1/repos/search
+		// 	RepoByID -> api/v1/repositories/%d
+		// 	UserRepos -> api/v1/user/repos
+		//
+		// We guess the category based on the path components after "api/v1/".
+		var category string
+		if i := strings.Index(u.Path, "/api/v1/"); i >= 0 {
+			category = u.Path[i+len("/api/v1/"):]
+		}
+		switch {
+		case category == "repos/search":
+			return "SearchRepos"
+		case strings.HasPrefix(category, "repos/"):
+			return "Repo"
+		case strings.HasPrefix(category, "repositories/"):
+			return "RepoByID"
+		case category == "user/repos":
+			return "UserRepos"
+		default:
+			// don't return category directly as that could introduce too much dimensionality
+			return "unknown"
+		}
+	})
+}
+
+// Client accesses a Gitea (or Gogs) instance via its REST API.
+type Client struct {
+	// URL is the base URL of the Gitea instance (such as https://gitea.example.com), without the
+	// "api/v1" path.
+	URL *url.URL
+
+	// Token is the access token used to authenticate requests. If empty, requests are
+	// unauthenticated (and can only access public repositories).
+	Token string
+
+	// HTTPClient is the client used to access Gitea. To enable metrics, always wrap the Transport
+	// using WithRequestCounter.
+	HTTPClient *http.Client
+}
+
+// UserRepos lists a page of the repositories which the authenticated user owns or is a
+// collaborator or organization member of.
+//
+// Gogs ignores the page and limit parameters, and returns all of the repositories at once.
+func (c *Client) UserRepos(ctx context.Context, page, limit int) ([]*Repo, error) {
+	var repos []*Repo
+	err := c.do(ctx, "user/repos?"+pageValues(url.Values{}, page, limit).Encode(), &repos)
+	return repos, err
+}
+
+// SearchRepos lists a page of the repositories matching the given query parameters of the
+// repository search API (such as "q" or "uid").
+func (c *Client) SearchRepos(ctx context.Context, query url.Values, page, limit int) ([]*Repo, error) {
+	var resp struct {
+		OK   bool    `json:"ok"`
+		Data []*Repo `json:"data"`
+	}
+	if err := c.do(ctx, "repos/search?"+pageValues(query, page, limit).Encode(), &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// Repo returns the repository with the given owner and name.
+func (c *Client) Repo(ctx context.Context, owner, name string) (*Repo, error) {
+	var repo Repo
+	if err := c.do(ctx, fmt.Sprintf("repos/%s/%s", url.PathEscape(owner), url.PathEscape(name)), &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// RepoByID returns the repository with the given ID. It is not supported by Gogs, which returns a
+// not found error.
+func (c *Client) RepoByID(ctx context.Context, id int64) (*Repo, error) {
+	var repo Repo
+	if err := c.do(ctx, "repositories/"+strconv.FormatInt(id, 10), &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// pageValues returns a copy of query with the page and limit parameters set.
+func pageValues(query url.Values, page, limit int) url.Values {
+	v := url.Values{}
+	for k, vs := range query {
+		v[k] = vs
+	}
+	if page > 0 {
+		v.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	return v
+}
+
+func (c *Client) do(ctx context.Context, path string, result interface{}) error {
+	u, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	apiURL := *c.URL
+	apiURL.Path = strings.TrimSuffix(apiURL.Path, "/") + "/api/v1/"
+	req, err := http.NewRequest("GET", apiURL.ResolveReference(u).String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(), req,
+		nethttp.OperationName("Gitea"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.WithStack(&httpError{URL: req.URL, StatusCode: resp.StatusCode})
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Repo is a Gitea (or Gogs) repository.
+type Repo struct {
+	ID          int64  `json:"id"`
+	Owner       *User  `json:"owner"`
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	Empty       bool   `json:"empty"`
+	Private     bool   `json:"private"`
+	Fork        bool   `json:"fork"`
+	Parent      *Repo  `json:"parent"`
+	Mirror      bool   `json:"mirror"`
+	Archived    bool   `json:"archived"` // always false on Gogs
+	HTMLURL     string `json:"html_url"`
+	SSHURL      string `json:"ssh_url"`
+	CloneURL    string `json:"clone_url"`
+}
+
+// User is a Gitea (or Gogs) user or organization.
+type User struct {
+	ID       int64  `json:"id"`
+	Login    string `json:"login"`
+	Username string `json:"username"`
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("unexpected %d response from Gitea API at %s", e.StatusCode, e.URL)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
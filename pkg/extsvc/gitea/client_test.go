@@ -0,0 +1,100 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+)
+
+func TestClient(t *testing.T) {
+	repos := []*Repo{
+		{ID: 1, Name: "a", FullName: "org/a", Owner: &User{ID: 10, Login: "org"}},
+		{ID: 2, Name: "b", FullName: "org/b", Owner: &User{ID: 10, Login: "org"}, Fork: true},
+	}
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		if got, want := r.Header.Get("Authorization"), "token secret"; got != want {
+			http.Error(w, "unexpected Authorization header "+got, http.StatusUnauthorized)
+			return
+		}
+
+		var v interface{}
+		switch r.URL.Path {
+		case "/gitea/api/v1/user/repos":
+			v = repos
+		case "/gitea/api/v1/repos/search":
+			v = map[string]interface{}{"ok": true, "data": repos[1:]}
+		case "/gitea/api/v1/repos/org/a":
+			v = repos[0]
+		case "/gitea/api/v1/repositories/2":
+			v = repos[1]
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+	defer srv.Close()
+
+	baseURL, err := url.Parse(srv.URL + "/gitea/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{URL: baseURL, Token: "secret", HTTPClient: http.DefaultClient}
+	ctx := context.Background()
+
+	userRepos, err := c.UserRepos(ctx, 2, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(userRepos, repos) {
+		t.Errorf("UserRepos: got %+v, want %+v", userRepos, repos)
+	}
+
+	searchRepos, err := c.SearchRepos(ctx, url.Values{"q": []string{"b"}}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(searchRepos, repos[1:]) {
+		t.Errorf("SearchRepos: got %+v, want %+v", searchRepos, repos[1:])
+	}
+
+	repo, err := c.Repo(ctx, "org", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repo, repos[0]) {
+		t.Errorf("Repo: got %+v, want %+v", repo, repos[0])
+	}
+
+	repo, err = c.RepoByID(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repo, repos[1]) {
+		t.Errorf("RepoByID: got %+v, want %+v", repo, repos[1])
+	}
+
+	if _, err := c.Repo(ctx, "org", "missing"); !errcode.IsNotFound(err) {
+		t.Errorf("Repo: got error %v, want not found", err)
+	}
+
+	wantRequests := []string{
+		"/gitea/api/v1/user/repos?limit=50&page=2",
+		"/gitea/api/v1/repos/search?limit=10&page=1&q=b",
+		"/gitea/api/v1/repos/org/a",
+		"/gitea/api/v1/repositories/2",
+		"/gitea/api/v1/repos/org/missing",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("got requests %q, want %q", requests, wantRequests)
+	}
+}
@@ -0,0 +1,3 @@
+// Package gitea implements a Gitea API client, which also works with Gogs (whose API Gitea's
+// extends).
+package gitea
@@ -3,13 +3,12 @@ package gitserver
 import (
 	"bytes"
 	"context"
-	"crypto/md5"
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -48,6 +47,9 @@ var DefaultClient = &Client{
 			RoundTripper: &http.Transport{
 				// Default is 2, but we can send many concurrent requests
 				MaxIdleConnsPerHost: 500,
+				// Fail fast when a gitserver is down, so that we can fail
+				// over to another replica.
+				DialContext: (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
 			},
 		},
 	},
@@ -56,8 +58,16 @@ var DefaultClient = &Client{
 	// which service is making the request (excluding requests proxied via the
 	// frontend internal API)
 	UserAgent: filepath.Base(os.Args[0]),
+
+	ShardMigration:    shardMigration,
+	ReplicationFactor: replicationFactor,
 }
 
+var (
+	shardMigration, _    = strconv.ParseBool(env.Get("SRC_GITSERVER_SHARD_MIGRATION", "false", "Serve repositories from the gitserver they were assigned to before consistent hashing until their new gitserver has cloned them."))
+	replicationFactor, _ = strconv.Atoi(env.Get("SRC_GITSERVER_REPLICATION_FACTOR", "1", "Number of gitservers each repository is cloned to. Requests fail over to another replica when a gitserver is unreachable."))
+)
+
 func init() {
 	gitserverAddrList.Store([]string{})
 }
@@ -123,12 +133,61 @@ type Client struct {
 	// UserAgent is a string identifing who the client is. It will be logged in
 	// the telemetry in gitserver.
 	UserAgent string
+
+	// ShardMigration is true while repositories are moved from the gitserver
+	// they were assigned to by hashing modulo the number of gitservers to the
+	// one assigned by AddrForRepo. Requests for a repository are sent to its
+	// old gitserver until its new gitserver has cloned it, and the janitor of
+	// the old gitserver removes it afterwards.
+	ShardMigration bool
+
+	// ReplicationFactor is the number of gitservers each repository is cloned
+	// to (see AddrsForRepo). Commands are sent to the other replicas of a
+	// repository when its primary gitserver can't be reached. Values less
+	// than 2 disable replication.
+	ReplicationFactor int
 }
 
 // addrForRepo returns the gitserver address to use for the given repo name.
+// During a shard migration, it is the address of the old gitserver of the
+// repo if its new gitserver hasn't cloned it yet.
 func (c *Client) addrForRepo(ctx context.Context, repo api.RepoName) string {
 	repo = protocol.NormalizeRepo(repo) // in case the caller didn't already normalize it
-	return c.addrForKey(ctx, string(repo))
+	addrs := c.Addrs(ctx)
+	if len(addrs) == 0 {
+		panic("unexpected state: no gitserver addresses")
+	}
+	addr := AddrForRepo(repo, addrs)
+	if !c.ShardMigration {
+		return addr
+	}
+	if legacy := legacyAddrForKey(string(repo), addrs); legacy != addr && !c.isClonedOnShard(ctx, addr, repo) {
+		return legacy
+	}
+	return addr
+}
+
+// addrsForRepo returns the gitserver addresses to use for the given repo
+// name, in the order they should be tried: the address returned by
+// addrForRepo, followed by the other replicas of the repo.
+func (c *Client) addrsForRepo(ctx context.Context, repo api.RepoName) []string {
+	addr := c.addrForRepo(ctx, repo)
+	if c.ReplicationFactor < 2 {
+		return []string{addr}
+	}
+	addrs := []string{addr}
+	for _, replica := range AddrsForRepo(protocol.NormalizeRepo(repo), c.Addrs(ctx), c.ReplicationFactor) {
+		if replica != addr {
+			addrs = append(addrs, replica)
+		}
+	}
+	return addrs
+}
+
+// ownerForRepo returns the address of the gitserver which the given repo
+// name is assigned to, even if it hasn't cloned it yet.
+func (c *Client) ownerForRepo(ctx context.Context, repo api.RepoName) string {
+	return c.addrForKey(ctx, string(protocol.NormalizeRepo(repo)))
 }
 
 // addrForKey returns the gitserver address to use for the given string key,
@@ -138,9 +197,56 @@ func (c *Client) addrForKey(ctx context.Context, key string) string {
 	if len(addrs) == 0 {
 		panic("unexpected state: no gitserver addresses")
 	}
-	sum := md5.Sum([]byte(key))
-	serverIndex := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
-	return addrs[serverIndex]
+	return addrForKey(key, addrs)
+}
+
+// shardCloneCheckInterval is how long we wait before asking a gitserver
+// again whether it has cloned a repository that it hadn't cloned, during a
+// shard migration.
+const shardCloneCheckInterval = time.Minute
+
+// shardClones caches whether the gitserver at an address has cloned a
+// repository (keyed by address and repository name), during a shard
+// migration. A repository which is cloned stays cloned, so only negative
+// answers expire.
+var shardClones = struct {
+	sync.Mutex
+	checked map[string]time.Time // the time of the last negative answer
+	cloned  map[string]bool
+}{
+	checked: map[string]time.Time{},
+	cloned:  map[string]bool{},
+}
+
+// isClonedOnShard reports whether the gitserver at addr has cloned repo. It
+// is false if the gitserver can't be reached.
+func (c *Client) isClonedOnShard(ctx context.Context, addr string, repo api.RepoName) bool {
+	key := addr + "\x00" + string(repo)
+	shardClones.Lock()
+	if shardClones.cloned[key] {
+		shardClones.Unlock()
+		return true
+	}
+	if checked, ok := shardClones.checked[key]; ok && time.Since(checked) < shardCloneCheckInterval {
+		shardClones.Unlock()
+		return false
+	}
+	shardClones.Unlock()
+
+	cloned, err := c.IsRepoClonedAt(ctx, addr, repo)
+	if err != nil {
+		return false
+	}
+
+	shardClones.Lock()
+	defer shardClones.Unlock()
+	if cloned {
+		shardClones.cloned[key] = true
+		delete(shardClones.checked, key)
+	} else {
+		shardClones.checked[key] = time.Now()
+	}
+	return cloned
 }
 
 func (c *Cmd) sendExec(ctx context.Context) (_ io.ReadCloser, _ http.Header, errRes error) {
@@ -168,12 +274,27 @@ func (c *Cmd) sendExec(ctx context.Context) (_ io.ReadCloser, _ http.Header, err
 	req := &protocol.ExecRequest{
 		Repo:           repoName,
 		URL:            c.Repo.URL,
+		CloneOptions:   c.Repo.CloneOptions,
 		EnsureRevision: c.EnsureRevision,
 		Args:           c.Args[1:],
 	}
-	resp, err := c.client.httpPost(ctx, repoName, "exec", req)
-	if err != nil {
-		return nil, nil, err
+	var (
+		resp *http.Response
+		err  error
+	)
+	addrs := c.client.addrsForRepo(ctx, repoName)
+	for i, addr := range addrs {
+		resp, err = c.client.httpPostAddr(ctx, addr, "exec", req)
+		if err == nil {
+			break
+		}
+		if i == len(addrs)-1 || !isUnavailable(ctx, err) {
+			return nil, nil, err
+		}
+		// Try the next replica.
+		execFailovers.Inc()
+		log15.Warn("gitserver unavailable, trying next replica", "repo", repoName, "addr", addr, "error", err)
+		span.LogKV("event", "failover", "addr", addr)
 	}
 
 	switch resp.StatusCode {
@@ -202,8 +323,27 @@ var deadlineExceededCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Help:      "Times that Client.sendExec() returned context.DeadlineExceeded",
 })
 
+var execFailovers = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "client_exec_failovers",
+	Help:      "Times that Client.sendExec() sent a command to another replica because a gitserver was unavailable",
+})
+
+// isUnavailable reports whether err, returned by a request to a gitserver,
+// means that the gitserver couldn't be reached or timed out (as opposed to
+// ctx being done).
+func isUnavailable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	_, ok := errors.Cause(err).(net.Error)
+	return ok
+}
+
 func init() {
 	prometheus.MustRegister(deadlineExceededCounter)
+	prometheus.MustRegister(execFailovers)
 }
 
 // Cmd represents a command to be executed remotely.
@@ -225,6 +365,9 @@ type Repo struct {
 	// this field is optional (it will use the last-used Git remote URL). If the repository is not
 	// cloned on the gitserver, the request will fail.
 	URL string
+
+	// CloneOptions configures how the repository is cloned if the gitserver has not cloned it yet.
+	CloneOptions *protocol.CloneOptions
 }
 
 // Command creates a new Cmd. Command name must be 'git',
@@ -435,12 +578,22 @@ func doListOne(ctx context.Context, urlSuffix string, addr string) ([]string, er
 // recently (within the Since duration specified in the request), the
 // update won't happen.
 func (c *Client) RequestRepoUpdate(ctx context.Context, repo Repo, since time.Duration) (*protocol.RepoUpdateResponse, error) {
+	// Updates always go to the gitserver that the repo is assigned to, so
+	// that it clones the repo during a shard migration. It forwards them to
+	// the other replicas of the repo.
+	return c.RequestRepoUpdateAt(ctx, c.ownerForRepo(ctx, repo.Name), repo, since)
+}
+
+// RequestRepoUpdateAt is like RequestRepoUpdate, but sends the request to the
+// gitserver at addr.
+func (c *Client) RequestRepoUpdateAt(ctx context.Context, addr string, repo Repo, since time.Duration) (*protocol.RepoUpdateResponse, error) {
 	req := &protocol.RepoUpdateRequest{
-		Repo:  repo.Name,
-		URL:   repo.URL,
-		Since: since,
+		Repo:         repo.Name,
+		URL:          repo.URL,
+		CloneOptions: repo.CloneOptions,
+		Since:        since,
 	}
-	resp, err := c.httpPost(ctx, repo.Name, "repo-update", req)
+	resp, err := c.httpPostAddr(ctx, addr, "repo-update", req)
 	if err != nil {
 		return nil, err
 	}
@@ -523,10 +676,15 @@ func (e *RepoNotCloneableErr) Error() string {
 }
 
 func (c *Client) IsRepoCloned(ctx context.Context, repo api.RepoName) (bool, error) {
+	return c.IsRepoClonedAt(ctx, c.addrForRepo(ctx, repo), repo)
+}
+
+// IsRepoClonedAt reports whether the gitserver at addr has cloned repo.
+func (c *Client) IsRepoClonedAt(ctx context.Context, addr string, repo api.RepoName) (bool, error) {
 	req := &protocol.IsRepoClonedRequest{
-		Repo: repo,
+		Repo: protocol.NormalizeRepo(repo),
 	}
-	resp, err := c.httpPost(ctx, repo, "is-repo-cloned", req)
+	resp, err := c.httpPostAddr(ctx, addr, "is-repo-cloned", req)
 	if err != nil {
 		return false, err
 	}
@@ -561,12 +719,41 @@ func (c *Client) RepoInfo(ctx context.Context, repo api.RepoName) (*protocol.Rep
 	return info, err
 }
 
-// Remove removes the repository clone from gitserver.
+// Remove removes the repository clone from gitserver. It is removed from all
+// of its replicas and, during a shard migration, from its old gitserver. All
+// of them are tried even if removing it from one of them fails.
 func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
+	addrs := c.Addrs(ctx)
+	if len(addrs) == 0 {
+		panic("unexpected state: no gitserver addresses")
+	}
+	key := protocol.NormalizeRepo(repo)
+	targets := AddrsForRepo(key, addrs, c.ReplicationFactor)
+	if c.ShardMigration {
+		legacy := legacyAddrForKey(string(key), addrs)
+		isTarget := false
+		for _, addr := range targets {
+			isTarget = isTarget || addr == legacy
+		}
+		if !isTarget {
+			targets = append(targets, legacy)
+		}
+	}
+
+	var firstErr error
+	for _, addr := range targets {
+		if err := c.removeAt(ctx, addr, repo); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (c *Client) removeAt(ctx context.Context, addr string, repo api.RepoName) error {
 	req := &protocol.RepoDeleteRequest{
 		Repo: repo,
 	}
-	resp, err := c.httpPost(ctx, repo, "delete", req)
+	resp, err := c.httpPostAddr(ctx, addr, "delete", req)
 	if err != nil {
 		return err
 	}
@@ -580,6 +767,12 @@ func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
 }
 
 func (c *Client) httpPost(ctx context.Context, repo api.RepoName, method string, payload interface{}) (resp *http.Response, err error) {
+	return c.httpPostAddr(ctx, c.addrForRepo(ctx, repo), method, payload)
+}
+
+// httpPostAddr is like httpPost, but sends the request to the gitserver at
+// addr.
+func (c *Client) httpPostAddr(ctx context.Context, addr string, method string, payload interface{}) (resp *http.Response, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "Client.httpPost")
 	defer func() {
 		if err != nil {
@@ -594,7 +787,6 @@ func (c *Client) httpPost(ctx context.Context, repo api.RepoName, method string,
 		return nil, err
 	}
 
-	addr := c.addrForRepo(ctx, repo)
 	req, err := http.NewRequest("POST", "http://"+addr+"/"+method, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
@@ -0,0 +1,97 @@
+package gitserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func TestClient_sendExecFailover(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	// Nothing listens on down once the listener is closed.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := l.Addr().String()
+	l.Close()
+
+	addrs := []string{down, strings.TrimPrefix(ts.URL, "http://")}
+	var repo api.RepoName
+	for i := 0; repo == ""; i++ {
+		if name := api.RepoName(fmt.Sprintf("github.com/foo/bar%d", i)); AddrForRepo(name, addrs) == down {
+			repo = name
+		}
+	}
+
+	for _, factor := range []int{1, 2} {
+		c := &Client{
+			HTTPClient:        http.DefaultClient,
+			Addrs:             func(context.Context) []string { return addrs },
+			ReplicationFactor: factor,
+		}
+		cmd := c.Command("git", "rev-parse", "HEAD")
+		cmd.Repo = Repo{Name: repo}
+		out, err := cmd.Output(context.Background())
+		if factor == 1 {
+			if err == nil {
+				t.Errorf("replication factor %d: got no error from an unavailable gitserver", factor)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("replication factor %d: %s", factor, err)
+		}
+		if string(out) != "ok" {
+			t.Errorf("replication factor %d: got output %q, want %q", factor, out, "ok")
+		}
+	}
+}
+
+func TestClient_RemoveReplicas(t *testing.T) {
+	var (
+		addrs   []string
+		mu      sync.Mutex
+		removed = map[string]bool{}
+	)
+	for i := 0; i < 3; i++ {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/delete" {
+				t.Errorf("unexpected request %s", r.URL.Path)
+			}
+			mu.Lock()
+			removed[r.Host] = true
+			mu.Unlock()
+		}))
+		defer ts.Close()
+		addrs = append(addrs, strings.TrimPrefix(ts.URL, "http://"))
+	}
+
+	repo := api.RepoName("github.com/foo/bar")
+	replicas := AddrsForRepo(repo, addrs, 2)
+	c := &Client{
+		HTTPClient:        http.DefaultClient,
+		Addrs:             func(context.Context) []string { return addrs },
+		ReplicationFactor: 2,
+	}
+	if err := c.Remove(context.Background(), repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range addrs {
+		want := addr == replicas[0] || addr == replicas[1]
+		if removed[addr] != want {
+			t.Errorf("%s: got removed %v, want %v", addr, removed[addr], want)
+		}
+	}
+}
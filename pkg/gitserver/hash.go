@@ -0,0 +1,70 @@
+package gitserver
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+// AddrForRepo returns the address of the gitserver, among addrs, which repo
+// is assigned to. repo must be normalized (see protocol.NormalizeRepo).
+//
+// Repositories are assigned with rendezvous (highest random weight) hashing:
+// each address is scored with a hash of itself and the repository name, and
+// the address with the highest score wins. Unlike hashing modulo the number
+// of gitservers, adding or removing a gitserver only moves the repositories
+// assigned to it (about 1/len(addrs) of them), and the assignment doesn't
+// depend on the order of addrs.
+func AddrForRepo(repo api.RepoName, addrs []string) string {
+	return addrForKey(string(repo), addrs)
+}
+
+// AddrsForRepo returns the addresses of the n gitservers, among addrs, which
+// repo is replicated to. The first one is the primary gitserver of repo (see
+// AddrForRepo), and the others are the ones which it would be assigned to if
+// the previous ones didn't exist, so that they are stable when gitservers are
+// added or removed too.
+func AddrsForRepo(repo api.RepoName, addrs []string, n int) []string {
+	return addrsForKey(string(repo), addrs, n)
+}
+
+func addrForKey(key string, addrs []string) string {
+	if best := addrsForKey(key, addrs, 1); len(best) > 0 {
+		return best[0]
+	}
+	return ""
+}
+
+func addrsForKey(key string, addrs []string, n int) []string {
+	if n < 1 {
+		n = 1
+	}
+	if n > len(addrs) {
+		n = len(addrs)
+	}
+	scores := make(map[string]uint64, len(addrs))
+	for _, addr := range addrs {
+		sum := md5.Sum([]byte(addr + "\x00" + key))
+		scores[addr] = binary.BigEndian.Uint64(sum[:])
+	}
+	sorted := append([]string{}, addrs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if si, sj := scores[sorted[i]], scores[sorted[j]]; si != sj {
+			return si > sj
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted[:n]
+}
+
+// legacyAddrForKey returns the gitserver address which key was assigned to
+// before rendezvous hashing, by hashing it modulo the number of gitservers.
+// It is only used to find the old location of repositories while they are
+// migrated (see Client.ShardMigration).
+func legacyAddrForKey(key string, addrs []string) string {
+	sum := md5.Sum([]byte(key))
+	serverIndex := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
+	return addrs[serverIndex]
+}
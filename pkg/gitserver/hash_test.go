@@ -0,0 +1,78 @@
+package gitserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func TestAddrForRepo(t *testing.T) {
+	addrs := []string{"gitserver-0:3178", "gitserver-1:3178", "gitserver-2:3178", "gitserver-3:3178"}
+	grown := append(append([]string{}, addrs...), "gitserver-4:3178")
+	reordered := []string{addrs[2], addrs[0], addrs[3], addrs[1]}
+
+	const n = 10000
+	counts := map[string]int{}
+	moved := 0
+	for i := 0; i < n; i++ {
+		repo := api.RepoName(fmt.Sprintf("github.com/foo/bar%d", i))
+		addr := AddrForRepo(repo, addrs)
+		counts[addr]++
+
+		if got := AddrForRepo(repo, reordered); got != addr {
+			t.Fatalf("%s: got %s with reordered addrs, want %s", repo, got, addr)
+		}
+		if got := AddrForRepo(repo, grown); got != addr {
+			if got != "gitserver-4:3178" {
+				t.Fatalf("%s: moved from %s to %s, want only moves to the new gitserver", repo, addr, got)
+			}
+			moved++
+		}
+	}
+
+	for _, addr := range addrs {
+		if c := counts[addr]; c < n/len(addrs)*9/10 || c > n/len(addrs)*11/10 {
+			t.Errorf("%s: got %d repos, want about %d", addr, c, n/len(addrs))
+		}
+	}
+	if want := n / len(grown); moved < want*9/10 || moved > want*11/10 {
+		t.Errorf("got %d repos moved to the new gitserver, want about %d", moved, want)
+	}
+}
+
+func TestAddrsForRepo(t *testing.T) {
+	addrs := []string{"gitserver-0:3178", "gitserver-1:3178", "gitserver-2:3178", "gitserver-3:3178"}
+	for i := 0; i < 100; i++ {
+		repo := api.RepoName(fmt.Sprintf("github.com/foo/bar%d", i))
+		replicas := AddrsForRepo(repo, addrs, 3)
+		if len(replicas) != 3 {
+			t.Fatalf("%s: got %d replicas, want 3", repo, len(replicas))
+		}
+		if replicas[0] != AddrForRepo(repo, addrs) {
+			t.Fatalf("%s: got primary %s, want %s", repo, replicas[0], AddrForRepo(repo, addrs))
+		}
+		seen := map[string]bool{}
+		for _, addr := range replicas {
+			if seen[addr] {
+				t.Fatalf("%s: got duplicate replica %s in %v", repo, addr, replicas)
+			}
+			seen[addr] = true
+		}
+
+		// Removing the primary promotes the first replica.
+		var rest []string
+		for _, addr := range addrs {
+			if addr != replicas[0] {
+				rest = append(rest, addr)
+			}
+		}
+		if got := AddrForRepo(repo, rest); got != replicas[1] {
+			t.Fatalf("%s: got primary %s without %s, want %s", repo, got, replicas[0], replicas[1])
+		}
+	}
+
+	if got := AddrsForRepo("github.com/foo/bar", addrs[:2], 3); len(got) != 2 {
+		t.Errorf("got %d replicas among 2 gitservers, want 2", len(got))
+	}
+}
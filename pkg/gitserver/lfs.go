@@ -0,0 +1,98 @@
+package gitserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+// MaxLFSPointerSize is the size of the largest Git LFS pointer file. See
+// https://github.com/git-lfs/git-lfs/blob/master/docs/spec.md.
+const MaxLFSPointerSize = 1024
+
+var (
+	lfsPointerVersion = []byte("version https://git-lfs.github.com/spec/v1\n")
+	lfsOIDPattern     = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// IsLFSOID reports whether oid is a valid Git LFS object ID (a hex SHA-256 hash).
+func IsLFSOID(oid string) bool {
+	return lfsOIDPattern.MatchString(oid)
+}
+
+// ParseLFSPointer parses the contents of a Git LFS pointer file, and returns the OID and size of
+// the object that it points to. If b is not a Git LFS pointer file, ok is false.
+func ParseLFSPointer(b []byte) (oid string, size int64, ok bool) {
+	if len(b) >= MaxLFSPointerSize || !bytes.HasPrefix(b, lfsPointerVersion) {
+		return "", 0, false
+	}
+	size = -1
+	lines := strings.Split(strings.TrimSuffix(string(b[len(lfsPointerVersion):]), "\n"), "\n")
+	for _, line := range lines {
+		i := strings.IndexByte(line, ' ')
+		if i == -1 {
+			return "", 0, false
+		}
+		switch key, value := line[:i], line[i+1:]; key {
+		case "oid":
+			if !strings.HasPrefix(value, "sha256:") || !IsLFSOID(value[len("sha256:"):]) {
+				return "", 0, false
+			}
+			oid = value[len("sha256:"):]
+		case "size":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return "", 0, false
+			}
+			size = n
+		}
+	}
+	if oid == "" || size == -1 {
+		return "", 0, false
+	}
+	return oid, size, true
+}
+
+// LFSObject returns the contents of the Git LFS object of repo with the given OID and size (from
+// its pointer file, see ParseLFSPointer). gitserver fetches the object from the repository's LFS
+// endpoint if it hasn't yet. If Git LFS is disabled, if the object is larger than the configured
+// size limit, or if it can't be found, the returned error satisfies os.IsNotExist.
+func (c *Client) LFSObject(ctx context.Context, repo api.RepoName, oid string, size int64) (io.ReadCloser, error) {
+	if !conf.GitLFSEnabled() || size > conf.GitLFSMaxFileSize() {
+		return nil, &os.PathError{Op: "LFSObject", Path: oid, Err: os.ErrNotExist}
+	}
+
+	req := &protocol.LFSObjectRequest{
+		Repo: repo,
+		OID:  oid,
+		Size: size,
+	}
+	resp, err := c.httpPost(ctx, repo, "lfs-object", req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, &os.PathError{Op: "LFSObject", Path: oid, Err: os.ErrNotExist}
+	default:
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &url.Error{URL: resp.Request.URL.String(), Op: "LFSObject", Err: fmt.Errorf("LFSObject: http status %d %s", resp.StatusCode, string(b))}
+	}
+}
@@ -0,0 +1,55 @@
+package gitserver
+
+import "testing"
+
+func TestParseLFSPointer(t *testing.T) {
+	const oid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	tests := map[string]struct {
+		pointer  string
+		wantOID  string
+		wantSize int64
+		wantOK   bool
+	}{
+		"pointer": {
+			pointer:  "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 12345\n",
+			wantOID:  oid,
+			wantSize: 12345,
+			wantOK:   true,
+		},
+		"extension keys": {
+			pointer:  "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:" + oid + "\noid sha256:" + oid + "\nsize 0\n",
+			wantOID:  oid,
+			wantSize: 0,
+			wantOK:   true,
+		},
+		"no trailing newline": {
+			pointer:  "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 1",
+			wantOID:  oid,
+			wantSize: 1,
+			wantOK:   true,
+		},
+		"not a pointer": {
+			pointer: "package main\n",
+		},
+		"missing size": {
+			pointer: "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\n",
+		},
+		"invalid oid": {
+			pointer: "version https://git-lfs.github.com/spec/v1\noid sha256:../../etc/passwd\nsize 1\n",
+		},
+		"invalid size": {
+			pointer: "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize -1\n",
+		},
+		"malformed line": {
+			pointer: "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 1\nfoo\n",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			oid, size, ok := ParseLFSPointer([]byte(test.pointer))
+			if oid != test.wantOID || size != test.wantSize || ok != test.wantOK {
+				t.Errorf("got (%q, %d, %v), want (%q, %d, %v)", oid, size, ok, test.wantOID, test.wantSize, test.wantOK)
+			}
+		})
+	}
+}
@@ -15,6 +15,9 @@ type ExecRequest struct {
 	// cloned on the gitserver, the request will fail.
 	URL string `json:"url,omitempty"`
 
+	// CloneOptions configures how the repository is cloned if it is not cloned yet.
+	CloneOptions *CloneOptions `json:"cloneOptions,omitempty"`
+
 	EnsureRevision string      `json:"ensureRevision"`
 	Args           []string    `json:"args"`
 	Opt            *RemoteOpts `json:"opt"`
@@ -39,11 +42,27 @@ type HTTPSConfig struct {
 	Pass string `json:"pass"` // the password provided to the remote
 }
 
+// CloneOptions configures how a repository is cloned, to save time and disk space for huge
+// repositories. Objects that were not fetched are fetched when they are needed.
+//
+// The options are recorded in the repository's Git config when it is cloned, and later updates
+// of the repository honor them. They only take effect for repositories that are (re)cloned.
+type CloneOptions struct {
+	// Depth, if positive, makes a shallow clone with the history truncated to this number of
+	// commits (git clone --depth).
+	Depth int `json:"depth,omitempty"`
+
+	// Filter, if set, makes a partial clone that omits the objects that don't match the filter
+	// spec, such as "blob:none" or "blob:limit=1m" (git clone --filter).
+	Filter string `json:"filter,omitempty"`
+}
+
 // RepoUpdateRequest is a request to update the contents of a given repo, or clone it if it doesn't exist.
 type RepoUpdateRequest struct {
-	Repo  api.RepoName  `json:"repo"`  // identifying URL for repo
-	URL   string        `json:"url"`   // repo's remote URL
-	Since time.Duration `json:"since"` // debounce interval for queries, used only with request-repo-update
+	Repo         api.RepoName  `json:"repo"`                   // identifying URL for repo
+	URL          string        `json:"url"`                    // repo's remote URL
+	CloneOptions *CloneOptions `json:"cloneOptions,omitempty"` // how to clone the repo if it doesn't exist
+	Since        time.Duration `json:"since"`                  // debounce interval for queries, used only with request-repo-update
 }
 
 // RepoUpdateResponse returns meta information of the repo enqueued for
@@ -148,3 +167,14 @@ type CreatePatchFromPatchResponse struct {
 	// Rev is the tag that the staging object can be found at
 	Rev string
 }
+
+// LFSObjectRequest is a request for the content of a Git LFS object of a repository. The
+// response body is the content of the object.
+type LFSObjectRequest struct {
+	// Repo is the repository whose LFS object to get.
+	Repo api.RepoName
+	// OID is the SHA-256 hash of the object (in hex), from its LFS pointer file.
+	OID string
+	// Size is the size of the object in bytes, from its LFS pointer file.
+	Size int64
+}
@@ -19,6 +19,7 @@ type Monitor struct {
 	limit     int       // last RateLimit-Limit HTTP response header value
 	remaining int       // last RateLimit-Remaining HTTP response header value
 	reset     time.Time // last RateLimit-Remaining HTTP response header value
+	retryAt   time.Time // when to retry a request rejected by the rate limit (see UpdateRetryAfter)
 }
 
 // Get reports the client's rate limit status (as of the last API response it received).
@@ -53,6 +54,10 @@ func (c *Monitor) Get() (remaining int, reset time.Duration, known bool) {
 func (c *Monitor) RecommendedWaitForBackgroundOp(cost int) time.Duration {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if wait := time.Until(c.retryAt); wait > 0 {
+		// A request was rejected and we were told when to retry.
+		return wait
+	}
 	if !c.known {
 		return 0
 	}
@@ -117,3 +122,12 @@ func (c *Monitor) Update(h http.Header) {
 	c.remaining = remaining
 	c.reset = time.Unix(resetAtSeconds, 0)
 }
+
+// UpdateRetryAfter records that a request was rejected by the rate limit and should be retried after
+// d (as given by the Retry-After HTTP response header). It is for APIs that don't report their rate
+// limit in the headers read by Update, such as the Bitbucket Cloud API.
+func (c *Monitor) UpdateRetryAfter(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retryAt = time.Now().Add(d)
+}
@@ -53,3 +53,15 @@ func TestMonitor_RecommendedWaitForBackgroundOp(t *testing.T) {
 		}
 	}
 }
+
+func TestMonitor_UpdateRetryAfter(t *testing.T) {
+	var m Monitor
+	m.UpdateRetryAfter(time.Minute)
+	if got := m.RecommendedWaitForBackgroundOp(1); got < 59*time.Second || got > time.Minute {
+		t.Errorf("got %s, want about 1m", got)
+	}
+	m.UpdateRetryAfter(0)
+	if got := m.RecommendedWaitForBackgroundOp(1); got != 0 {
+		t.Errorf("got %s after the retry time, want 0", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	gitserverprotocol "github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 )
 
 type RepoUpdateSchedulerInfoArgs struct {
@@ -23,12 +24,16 @@ type RepoScheduleState struct {
 	Total           int
 	IntervalSeconds int
 	Due             time.Time
+	LastError       string     `json:",omitempty"` // the error of the last update, if it failed
+	LastErrorAt     *time.Time `json:",omitempty"` // the time of the last update error
 }
 
 type RepoQueueState struct {
-	Index    int
-	Total    int
-	Updating bool
+	Index       int
+	Total       int
+	Updating    bool
+	LastError   string     `json:",omitempty"` // the error of the last update, if it failed
+	LastErrorAt *time.Time `json:",omitempty"` // the time of the last update error
 }
 
 // RepoLookupArgs is a request for information about a repository on repoupdater.
@@ -103,6 +108,10 @@ func (r *RepoInfo) String() string {
 // VCSInfo describes how to access an external repository's Git data (to clone or update it).
 type VCSInfo struct {
 	URL string // the Git remote URL
+
+	// CloneOptions configures how the repository is cloned (from the clone strategies of the
+	// external service configuration), or nil for a full clone.
+	CloneOptions *gitserverprotocol.CloneOptions `json:",omitempty"`
 }
 
 // RepoLinks contains URLs and URL patterns for objects in this repository.
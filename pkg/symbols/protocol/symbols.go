@@ -35,19 +35,37 @@ type SearchArgs struct {
 	// need to match to get included in the result
 	ExcludePattern string
 
+	// Kinds, if set, is the list of symbol kinds (as reported by ctags, eg
+	// "function") of the symbols to return.
+	Kinds []string `json:",omitempty"`
+
 	// First indicates that only the first n symbols should be returned.
 	First int
+
+	// After, if set, is the Cursor of a previous SearchResult for the same
+	// arguments. Only the symbols after the ones in that result are
+	// returned.
+	After string `json:",omitempty"`
 }
 
 // SearchResult is the result of a search on the symbols service.
 type SearchResult struct {
 	Symbols []Symbol // code symbols
+
+	// Cursor is set if there are more symbols matching the search than
+	// SearchArgs.First. Pass it as SearchArgs.After to get the next ones.
+	Cursor string `json:",omitempty"`
 }
 
 // Symbol is a code symbol.
 type Symbol struct {
-	Name       string
-	Path       string
+	Name string
+	Path string
+
+	// Blob is the Git object ID of the file that contains the symbol. It is
+	// the same for the symbols of identical files at different commits.
+	Blob string `json:",omitempty"`
+
 	Line       int
 	Kind       string
 	Language   string
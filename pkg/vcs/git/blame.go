@@ -21,8 +21,26 @@ type BlameOptions struct {
 
 	StartLine int `json:",omitempty" url:",omitempty"` // 1-indexed start byte (or 0 for beginning of file)
 	EndLine   int `json:",omitempty" url:",omitempty"` // 1-indexed end byte (or 0 for end of file)
+
+	// IgnoreRevs are commits whose changes are ignored, such as bulk formatting commits. Lines
+	// changed by them are blamed on the previous commit that changed them (git blame
+	// --ignore-rev). Commits that don't exist in the repository are skipped.
+	IgnoreRevs []api.CommitID `json:",omitempty" url:",omitempty"`
+
+	// UseIgnoreRevsFile also ignores the commits listed in the repository's
+	// .git-blame-ignore-revs file (at NewestCommit), if any.
+	UseIgnoreRevsFile bool `json:",omitempty" url:",omitempty"`
+
+	// DetectMoves detects lines moved or copied within the file (git blame -M), and DetectCopies
+	// detects lines moved or copied from other files changed in the same commit (git blame -C).
+	// Such lines are blamed on the commit that originally added them.
+	DetectMoves  bool `json:",omitempty" url:",omitempty"`
+	DetectCopies bool `json:",omitempty" url:",omitempty"`
 }
 
+// ignoreRevsFile is the conventional name of the file that lists the commits to ignore in blames.
+const ignoreRevsFile = ".git-blame-ignore-revs"
+
 // A Hunk is a contiguous portion of a file associated with a commit.
 type Hunk struct {
 	StartLine int // 1-indexed start line number
@@ -32,6 +50,12 @@ type Hunk struct {
 	api.CommitID
 	Author  Signature
 	Message string
+
+	// OrigPath and OrigStartLine are the path and 1-indexed start line number of the hunk in the
+	// commit. They differ from the blamed path and StartLine if the lines were moved or copied
+	// (or if the file was renamed) since.
+	OrigPath      string
+	OrigStartLine int
 }
 
 // BlameFile returns Git blame information about a file.
@@ -58,7 +82,21 @@ func blameFileCmd(ctx context.Context, command cmdFunc, path string, opt *BlameO
 		return nil, err
 	}
 
+	ignoreRevs, err := blameIgnoreRevs(ctx, command, opt)
+	if err != nil {
+		return nil, err
+	}
+
 	args := []string{"blame", "-w", "--porcelain"}
+	if opt.DetectMoves {
+		args = append(args, "-M")
+	}
+	if opt.DetectCopies {
+		args = append(args, "-C")
+	}
+	for _, rev := range ignoreRevs {
+		args = append(args, "--ignore-rev", rev)
+	}
 	if opt.StartLine != 0 || opt.EndLine != 0 {
 		args = append(args, fmt.Sprintf("-L%d,%d", opt.StartLine, opt.EndLine))
 	}
@@ -72,7 +110,11 @@ func blameFileCmd(ctx context.Context, command cmdFunc, path string, opt *BlameO
 		return nil, nil
 	}
 
+	// Each hunk starts with a header line, followed by information about its commit (only the
+	// first time the commit appears), the filename (always the first time), and its lines
+	// (each preceded by another header line, except the first).
 	commits := make(map[string]Commit)
+	filenames := make(map[string]string)
 	hunks := make([]*Hunk, 0)
 	remainingLines := strings.Split(string(out[:len(out)-1]), "\n")
 	byteOffset := 0
@@ -83,71 +125,68 @@ func blameFileCmd(ctx context.Context, command cmdFunc, path string, opt *BlameO
 			return nil, fmt.Errorf("Expected at least 4 parts to hunkHeader, but got: '%s'", hunkHeader)
 		}
 		commitID := hunkHeader[0]
+		lineNoOrig, _ := strconv.Atoi(hunkHeader[1])
 		lineNoCur, _ := strconv.Atoi(hunkHeader[2])
 		nLines, _ := strconv.Atoi(hunkHeader[3])
 		hunk := &Hunk{
-			CommitID:  api.CommitID(commitID),
-			StartLine: int(lineNoCur),
-			EndLine:   int(lineNoCur + nLines),
-			StartByte: byteOffset,
+			CommitID:      api.CommitID(commitID),
+			StartLine:     int(lineNoCur),
+			EndLine:       int(lineNoCur + nLines),
+			StartByte:     byteOffset,
+			OrigStartLine: lineNoOrig,
 		}
-
-		if _, in := commits[commitID]; in {
-			// Already seen commit
-			byteOffset += len(remainingLines[1])
-			remainingLines = remainingLines[2:]
-		} else {
-			// New commit
-			author := strings.Join(strings.Split(remainingLines[1], " ")[1:], " ")
-			email := strings.Join(strings.Split(remainingLines[2], " ")[1:], " ")
-			if len(email) >= 2 && email[0] == '<' && email[len(email)-1] == '>' {
-				email = email[1 : len(email)-1]
-			}
-			authorTime, err := strconv.ParseInt(strings.Join(strings.Split(remainingLines[3], " ")[1:], " "), 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("Failed to parse author-time %q", remainingLines[3])
+		remainingLines = remainingLines[1:]
+
+		// Consume commit information and filename
+		commit, seen := commits[commitID]
+		for len(remainingLines) > 0 && !strings.HasPrefix(remainingLines[0], "\t") {
+			key, value := remainingLines[0], ""
+			if i := strings.Index(key, " "); i != -1 {
+				key, value = key[:i], key[i+1:]
 			}
-			summary := strings.Join(strings.Split(remainingLines[9], " ")[1:], " ")
-			commit := Commit{
-				ID:      api.CommitID(commitID),
-				Message: summary,
-				Author: Signature{
-					Name:  author,
-					Email: email,
-					Date:  time.Unix(authorTime, 0).UTC(),
-				},
+			switch key {
+			case "author":
+				commit.Author.Name = value
+			case "author-mail":
+				if len(value) >= 2 && value[0] == '<' && value[len(value)-1] == '>' {
+					value = value[1 : len(value)-1]
+				}
+				commit.Author.Email = value
+			case "author-time":
+				authorTime, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to parse author-time %q", remainingLines[0])
+				}
+				commit.Author.Date = time.Unix(authorTime, 0).UTC()
+			case "summary":
+				commit.Message = value
+			case "filename":
+				filenames[commitID] = value
 			}
-
-			if len(remainingLines) >= 13 && strings.HasPrefix(remainingLines[10], "previous ") {
-				byteOffset += len(remainingLines[12])
-				remainingLines = remainingLines[13:]
-			} else if len(remainingLines) >= 13 && remainingLines[10] == "boundary" {
-				byteOffset += len(remainingLines[12])
-				remainingLines = remainingLines[13:]
-			} else if len(remainingLines) >= 12 {
-				byteOffset += len(remainingLines[11])
-				remainingLines = remainingLines[12:]
-			} else if len(remainingLines) == 11 {
-				// Empty file
-				remainingLines = remainingLines[11:]
-			} else {
-				return nil, fmt.Errorf("Unexpected number of remaining lines (%d):\n%s", len(remainingLines), "  "+strings.Join(remainingLines, "\n  "))
-			}
-
+			remainingLines = remainingLines[1:]
+		}
+		if !seen {
+			commit.ID = api.CommitID(commitID)
 			commits[commitID] = commit
 		}
-
-		if commit, present := commits[commitID]; present {
-			// Should always be present, but check just to avoid
-			// panicking in case of a (somewhat likely) bug in our
-			// git-blame parser above.
-			hunk.CommitID = commit.ID
-			hunk.Author = commit.Author
-			hunk.Message = commit.Message
+		hunk.Author = commit.Author
+		hunk.Message = commit.Message
+		hunk.OrigPath = filenames[commitID]
+
+		if len(remainingLines) == 0 {
+			// Empty file
+			hunk.EndByte = byteOffset
+			hunks = append(hunks, hunk)
+			break
 		}
 
-		// Consume remaining lines in hunk
+		// Consume lines in hunk
+		byteOffset += len(remainingLines[0])
+		remainingLines = remainingLines[1:]
 		for i := 1; i < nLines; i++ {
+			if len(remainingLines) < 2 {
+				return nil, fmt.Errorf("Unexpected number of remaining lines (%d) in hunk of %s", len(remainingLines), commitID)
+			}
 			byteOffset += len(remainingLines[1])
 			remainingLines = remainingLines[2:]
 		}
@@ -158,3 +197,51 @@ func blameFileCmd(ctx context.Context, command cmdFunc, path string, opt *BlameO
 
 	return hunks, nil
 }
+
+// blameIgnoreRevs returns the full IDs of the commits to ignore in a blame with opt that exist in
+// the repository.
+func blameIgnoreRevs(ctx context.Context, command cmdFunc, opt *BlameOptions) ([]string, error) {
+	var revs []string
+	for _, rev := range opt.IgnoreRevs {
+		if err := checkSpecArgSafety(string(rev)); err != nil {
+			return nil, err
+		}
+		revs = append(revs, string(rev))
+	}
+	if opt.UseIgnoreRevsFile {
+		newestCommit := string(opt.NewestCommit)
+		if newestCommit == "" {
+			newestCommit = "HEAD"
+		}
+		// The file usually doesn't exist, so errors are ignored.
+		if out, err := command([]string{"show", newestCommit + ":" + ignoreRevsFile}).Output(ctx); err == nil {
+			revs = append(revs, parseIgnoreRevs(out)...)
+		}
+	}
+	if len(revs) == 0 {
+		return nil, nil
+	}
+
+	// git blame fails if a commit to ignore doesn't exist, so only keep those that exist.
+	args := append([]string{"rev-list", "--no-walk", "--ignore-missing"}, revs...)
+	out, err := command(args).Output(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", args, out))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// parseIgnoreRevs parses the commit IDs in the contents of a .git-blame-ignore-revs file, which
+// lists one commit per line, with comments starting with '#'.
+func parseIgnoreRevs(data []byte) []string {
+	var revs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		if rev := strings.TrimSpace(line); rev != "" && checkSpecArgSafety(rev) == nil {
+			revs = append(revs, rev)
+		}
+	}
+	return revs
+}
@@ -1,10 +1,12 @@
 package git_test
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
@@ -24,10 +26,12 @@ func TestRepository_BlameFile(t *testing.T) {
 		{
 			StartLine: 1, EndLine: 2, StartByte: 0, EndByte: 6, CommitID: "e6093374dcf5725d8517db0dccbbf69df65dbde0",
 			Message: "foo", Author: git.Signature{Name: "a", Email: "a@a.com", Date: mustParseTime(time.RFC3339, "2006-01-02T15:04:05Z")},
+			OrigPath: "f", OrigStartLine: 1,
 		},
 		{
 			StartLine: 2, EndLine: 3, StartByte: 6, EndByte: 12, CommitID: "fad406f4fe02c358a09df0d03ec7a36c2c8a20f1",
 			Message: "foo", Author: git.Signature{Name: "a", Email: "a@a.com", Date: mustParseTime(time.RFC3339, "2006-01-02T15:04:05Z")},
+			OrigPath: "f", OrigStartLine: 2,
 		},
 	}
 	tests := map[string]struct {
@@ -66,3 +70,66 @@ func TestRepository_BlameFile(t *testing.T) {
 		}
 	}
 }
+
+func TestRepository_BlameFile_ignoreRevsAndMoves(t *testing.T) {
+	t.Parallel()
+
+	const commit = "GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit --author='a <a@a.com>' --date 2006-01-02T15:04:05Z"
+	repo := makeGitRepository(t,
+		"printf 'line one\\nline two\\n' > f",
+		"git add f",
+		commit+" -m add",
+		"printf 'Line one\\nline two\\n' > f",
+		commit+" -am format",
+		"git tag format",
+		"printf 'the quick brown fox jumps over the lazy dog\\nthe lazy dog sleeps under the quick brown fox\\n' > g",
+		"git add g",
+		commit+" -m add-g",
+		"cat g >> f && echo > g",
+		commit+" -am move",
+		"git rev-parse format > .git-blame-ignore-revs",
+		"git add .git-blame-ignore-revs",
+		commit+" -m ignore",
+	)
+	formatCommitID, err := git.ResolveRevision(ctx, repo, nil, "format", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opt       git.BlameOptions
+		wantHunks []string
+	}{
+		"default": {
+			wantHunks: []string{"format f:1 1-2", "add f:2 2-3", "move f:3 3-5"},
+		},
+		"ignore revs file": {
+			opt:       git.BlameOptions{UseIgnoreRevsFile: true},
+			wantHunks: []string{"add f:1 1-2", "add f:2 2-3", "move f:3 3-5"},
+		},
+		"ignore revs": {
+			opt:       git.BlameOptions{IgnoreRevs: []api.CommitID{formatCommitID, nonexistentCommitID}},
+			wantHunks: []string{"add f:1 1-2", "add f:2 2-3", "move f:3 3-5"},
+		},
+		"detect copies": {
+			opt:       git.BlameOptions{DetectMoves: true, DetectCopies: true},
+			wantHunks: []string{"format f:1 1-2", "add f:2 2-3", "add-g g:1 3-5"},
+		},
+	}
+	for label, test := range tests {
+		test.opt.NewestCommit = "master"
+		hunks, err := git.BlameFile(ctx, repo, "f", &test.opt)
+		if err != nil {
+			t.Errorf("%s: BlameFile: %s", label, err)
+			continue
+		}
+
+		var got []string
+		for _, h := range hunks {
+			got = append(got, fmt.Sprintf("%s %s:%d %d-%d", h.Message, h.OrigPath, h.OrigStartLine, h.StartLine, h.EndLine))
+		}
+		if !reflect.DeepEqual(got, test.wantHunks) {
+			t.Errorf("%s: got hunks %q, want %q", label, got, test.wantHunks)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 
 	opentracing "github.com/opentracing/opentracing-go"
@@ -11,6 +12,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs/util"
+	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
 // ReadFile returns the content of the named file at commit.
@@ -28,9 +30,33 @@ func ReadFile(ctx context.Context, repo gitserver.Repo, commit api.CommitID, nam
 	if err != nil {
 		return nil, err
 	}
+	if oid, size, ok := gitserver.ParseLFSPointer(b); ok {
+		return readLFSObject(ctx, repo, oid, size, b)
+	}
 	return b, nil
 }
 
+// readLFSObject returns the content of the Git LFS object that the given pointer
+// file points to, or the pointer file itself if the object is not available or
+// can't be fetched.
+func readLFSObject(ctx context.Context, repo gitserver.Repo, oid string, size int64, pointer []byte) ([]byte, error) {
+	rc, err := gitserver.DefaultClient.LFSObject(ctx, repo.Name, oid, size)
+	if err == nil {
+		defer rc.Close()
+		var b []byte
+		if b, err = ioutil.ReadAll(rc); err == nil {
+			return b, nil
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if !os.IsNotExist(err) {
+		log15.Warn("Failed to fetch Git LFS object, using its pointer file.", "repo", repo.Name, "oid", oid, "error", err)
+	}
+	return pointer, nil
+}
+
 func readFileBytes(ctx context.Context, repo gitserver.Repo, commit api.CommitID, name string) ([]byte, error) {
 	ensureAbsCommit(commit)
 
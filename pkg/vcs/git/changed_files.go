@@ -0,0 +1,73 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+)
+
+// ChangedFiles are the paths of the files that differ between two commits.
+type ChangedFiles struct {
+	Added    []string
+	Modified []string
+	Deleted  []string
+}
+
+// DiffNameStatus returns the paths of the files that differ between the base
+// and head commits, as reported by `git diff --name-status`. Renamed files are
+// reported as deleted (the old path) and added (the new path).
+func DiffNameStatus(ctx context.Context, repo gitserver.Repo, base, head api.CommitID) (*ChangedFiles, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: DiffNameStatus")
+	span.SetTag("Base", base)
+	span.SetTag("Head", head)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(string(base)); err != nil {
+		return nil, err
+	}
+	if err := checkSpecArgSafety(string(head)); err != nil {
+		return nil, err
+	}
+
+	cmd := gitserver.DefaultClient.Command("git", "diff", "-z", "--name-status", "--no-renames", string(base), string(head), "--")
+	cmd.Repo = repo
+	out, stderr, err := cmd.DividedOutput(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", cmd.Args, stderr))
+	}
+	return parseDiffNameStatus(out)
+}
+
+// parseDiffNameStatus parses the output of `git diff -z --name-status
+// --no-renames`, which is a sequence of NUL-terminated status and path pairs.
+func parseDiffNameStatus(out []byte) (*ChangedFiles, error) {
+	var changes ChangedFiles
+	fields := bytes.Split(bytes.TrimSuffix(out, []byte{0}), []byte{0})
+	if len(out) == 0 {
+		return &changes, nil
+	}
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid git diff --name-status output %q", out)
+	}
+	for i := 0; i < len(fields); i += 2 {
+		status, path := string(fields[i]), string(fields[i+1])
+		switch status {
+		case "A":
+			changes.Added = append(changes.Added, path)
+		case "M", "T":
+			// A type change (for example from a file to a symlink) is a
+			// modification of the path.
+			changes.Modified = append(changes.Modified, path)
+		case "D":
+			changes.Deleted = append(changes.Deleted, path)
+		default:
+			return nil, fmt.Errorf("unexpected status %q for %q in git diff --name-status output", status, path)
+		}
+	}
+	return &changes, nil
+}
@@ -0,0 +1,45 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiffNameStatus(t *testing.T) {
+	tests := map[string]struct {
+		out  string
+		want ChangedFiles
+	}{
+		"empty": {
+			out:  "",
+			want: ChangedFiles{},
+		},
+		"all statuses": {
+			out: "A\x00a.go\x00M\x00dir/m.go\x00T\x00link\x00D\x00d.go\x00",
+			want: ChangedFiles{
+				Added:    []string{"a.go"},
+				Modified: []string{"dir/m.go", "link"},
+				Deleted:  []string{"d.go"},
+			},
+		},
+		"path with spaces and newlines": {
+			out:  "A\x00a b\nc.go\x00",
+			want: ChangedFiles{Added: []string{"a b\nc.go"}},
+		},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			got, err := parseDiffNameStatus([]byte(test.out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("got %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+
+	if _, err := parseDiffNameStatus([]byte("R100\x00a\x00b\x00")); err == nil {
+		t.Error("got no error for a rename, want an error")
+	}
+}
@@ -0,0 +1,191 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+)
+
+// CommitGraphOptions configures a commit graph.
+type CommitGraphOptions struct {
+	Revisions []string // the revisions (revspecs or ranges such as "A..B") to draw (HEAD if empty)
+
+	N uint // limit the number of returned commits to this many (0 means no limit)
+
+	// After continues the commit graph after a node (see CommitGraphNode.Position), to paginate
+	// it. The returned commits are laid out as if the commits up to that node were drawn above
+	// them.
+	After *CommitGraphPosition
+}
+
+// A CommitGraphNode is a commit in a commit graph, with its position in the graph layout.
+type CommitGraphNode struct {
+	Commit *Commit
+
+	// Lane is the 0-indexed column of the commit in the graph (as drawn by git log --graph, with
+	// the leftmost lane being the first-parent line of the first revision).
+	Lane int
+
+	// Parents are the edges to the commit's parents, in the order of Commit.Parents.
+	Parents []CommitGraphEdge
+
+	// Position is the position of the commit in the graph, to continue the graph after it.
+	Position CommitGraphPosition
+}
+
+// A CommitGraphPosition is the position of a commit in a commit graph, with the state of the layout
+// after the commit.
+type CommitGraphPosition struct {
+	Index  uint           // the 0-indexed position of the commit in the graph
+	Commit api.CommitID   // the commit (to check that the graph is unchanged when continuing it)
+	Lanes  []api.CommitID // the commit expected next in each lane ("" if the lane is free)
+}
+
+// A CommitGraphEdge is an edge from a commit to one of its parents.
+type CommitGraphEdge struct {
+	Parent api.CommitID
+
+	// Lane is the lane that the edge continues in below the commit. The parent is drawn in this
+	// lane, unless another edge leads to it in a lane further left (then the edges merge).
+	Lane int
+}
+
+// CommitGraph returns the commits reachable from the given revisions in topological order (no
+// parent is shown before all of its children), with the lanes and parent edges needed to draw
+// them as a graph similar to git log --graph.
+//
+// If opt.After is set and the commit at its position is no longer opt.After.Commit (because the
+// revisions now point to other commits), it returns an error.
+func CommitGraph(ctx context.Context, repo gitserver.Repo, opt CommitGraphOptions) ([]*CommitGraphNode, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: CommitGraph")
+	span.SetTag("repo", repo.Name)
+	span.SetTag("opt", opt)
+	defer span.Finish()
+	return commitGraphCmd(ctx, gitserverCmdFunc(repo), opt)
+}
+
+func commitGraphCmd(ctx context.Context, command cmdFunc, opt CommitGraphOptions) ([]*CommitGraphNode, error) {
+	for _, rev := range opt.Revisions {
+		if err := checkSpecArgSafety(rev); err != nil {
+			return nil, err
+		}
+	}
+
+	args := []string{"log", logFormatWithoutRefs, "--topo-order", "--parents"}
+	n := opt.N
+	if opt.After != nil {
+		// Also get the commit of opt.After, to check that it is still at the same position.
+		args = append(args, "--skip="+strconv.FormatUint(uint64(opt.After.Index), 10))
+		if n != 0 {
+			n++
+		}
+	}
+	if n != 0 {
+		args = append(args, "-n", strconv.FormatUint(uint64(n), 10))
+	}
+	args = append(args, opt.Revisions...)
+	args = append(args, "--")
+	out, err := command(args).Output(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", args, out))
+	}
+
+	var commits []*Commit
+	for data := out; len(data) > 0; {
+		var commit *Commit
+		commit, _, data, err = parseCommitFromLog(data)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+	if opt.After != nil {
+		if len(commits) == 0 || commits[0].ID != opt.After.Commit {
+			return nil, errors.Errorf("commit graph changed: commit %d is no longer %s", opt.After.Index, opt.After.Commit)
+		}
+		commits = commits[1:]
+	}
+	return layoutCommitGraph(commits, opt.After), nil
+}
+
+// layoutCommitGraph assigns lanes to commits given in topological order. Each lane holds the
+// commit that is expected next in it (the parent of the commit last drawn in it). A commit takes
+// the leftmost lane that expects it (or the leftmost free lane), its first parent continues in
+// the same lane, and its other parents continue in the lane that already expects them or in a
+// free lane.
+//
+// If after is set, the layout continues from the lanes after the commit at its position.
+func layoutCommitGraph(commits []*Commit, after *CommitGraphPosition) []*CommitGraphNode {
+	var (
+		lanes []api.CommitID // the commit expected in each lane ("" if the lane is free)
+		index uint           // the index of the first commit in the graph
+	)
+	if after != nil {
+		lanes = append(lanes, after.Lanes...)
+		index = after.Index + 1
+	}
+	lane := func(id api.CommitID) int {
+		for i, expected := range lanes {
+			if expected == id {
+				return i
+			}
+		}
+		return -1
+	}
+	freeLane := func() int {
+		if i := lane(""); i != -1 {
+			return i
+		}
+		lanes = append(lanes, "")
+		return len(lanes) - 1
+	}
+
+	nodes := make([]*CommitGraphNode, len(commits))
+	for i, commit := range commits {
+		node := &CommitGraphNode{Commit: commit, Lane: lane(commit.ID)}
+		if node.Lane == -1 {
+			node.Lane = freeLane() // a branch tip
+		}
+		// Other lanes that expect the commit (from merges) end here.
+		for j, expected := range lanes {
+			if expected == commit.ID {
+				lanes[j] = ""
+			}
+		}
+
+		node.Parents = make([]CommitGraphEdge, len(commit.Parents))
+		for j, parent := range commit.Parents {
+			parentLane := lane(parent)
+			switch {
+			case j == 0 && (parentLane == -1 || parentLane > node.Lane):
+				// The first parent continues in the commit's lane. If it was expected in another
+				// lane further right, that lane merges into this one.
+				if parentLane != -1 {
+					lanes[parentLane] = ""
+				}
+				parentLane = node.Lane
+			case parentLane == -1:
+				parentLane = freeLane()
+			}
+			lanes[parentLane] = parent
+			node.Parents[j] = CommitGraphEdge{Parent: parent, Lane: parentLane}
+		}
+
+		// Drop free lanes on the right so that the graph doesn't get wider than needed.
+		for len(lanes) > 0 && lanes[len(lanes)-1] == "" {
+			lanes = lanes[:len(lanes)-1]
+		}
+		node.Position = CommitGraphPosition{
+			Index:  index + uint(i),
+			Commit: commit.ID,
+			Lanes:  append([]api.CommitID(nil), lanes...),
+		}
+		nodes[i] = node
+	}
+	return nodes
+}
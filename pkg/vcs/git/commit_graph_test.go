@@ -0,0 +1,132 @@
+package git_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+func TestRepository_CommitGraph(t *testing.T) {
+	t.Parallel()
+
+	commit := func(date string) string {
+		return fmt.Sprintf("GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=%[1]s git commit --allow-empty --author='a <a@a.com>' --date %[1]s", date)
+	}
+	repo := makeGitRepository(t,
+		commit("2006-01-02T15:04:01Z")+" -m base",
+		"git checkout -b feature",
+		commit("2006-01-02T15:04:02Z")+" -m feature1",
+		"git checkout -b fix master",
+		commit("2006-01-02T15:04:03Z")+" -m fix1",
+		"git checkout master",
+		commit("2006-01-02T15:04:04Z")+" -m master1",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git merge --no-ff -m merge feature",
+		"git checkout feature",
+		commit("2006-01-02T15:04:06Z")+" -m feature2",
+	)
+
+	// Name the parents by their messages (the parents of the last nodes may not be in the graph).
+	messages := map[api.CommitID]string{}
+	for _, rev := range []string{"master", "feature", "fix"} {
+		commits, err := git.Commits(ctx, repo, git.CommitsOptions{Range: rev})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range commits {
+			messages[c.ID] = c.Message
+		}
+	}
+
+	tests := map[string]struct {
+		opt  git.CommitGraphOptions
+		want []string // "message lane [parent:lane...]"
+	}{
+		"HEAD": {
+			want: []string{
+				"feature2 0 [feature1:0]",
+				"feature1 0 [base:0]",
+				"base 0 []",
+			},
+		},
+		"branches": {
+			opt: git.CommitGraphOptions{Revisions: []string{"master", "feature", "fix"}},
+			want: []string{
+				"feature2 0 [feature1:0]",
+				"merge 1 [master1:1 feature1:0]",
+				"feature1 0 [base:0]",
+				"master1 1 [base:0]",
+				"fix1 1 [base:0]",
+				"base 0 []",
+			},
+		},
+		"first": {
+			opt: git.CommitGraphOptions{Revisions: []string{"master"}, N: 2},
+			want: []string{
+				"merge 0 [master1:0 feature1:1]",
+				"feature1 1 [base:1]",
+			},
+		},
+		"range": {
+			opt: git.CommitGraphOptions{Revisions: []string{"feature..master"}},
+			want: []string{
+				"merge 0 [master1:0 feature1:1]",
+				"master1 0 [base:0]",
+			},
+		},
+	}
+	format := func(nodes []*git.CommitGraphNode) []string {
+		var got []string
+		for _, node := range nodes {
+			var parents []string
+			for _, edge := range node.Parents {
+				parents = append(parents, fmt.Sprintf("%s:%d", messages[edge.Parent], edge.Lane))
+			}
+			got = append(got, fmt.Sprintf("%s %d %v", node.Commit.Message, node.Lane, parents))
+		}
+		return got
+	}
+	for label, test := range tests {
+		nodes, err := git.CommitGraph(ctx, repo, test.opt)
+		if err != nil {
+			t.Errorf("%s: CommitGraph: %s", label, err)
+			continue
+		}
+		if got := format(nodes); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", label, got, test.want)
+		}
+	}
+
+	// Continuing the graph after each page lays out the commits the same way as the whole graph.
+	revisions := []string{"master", "feature", "fix"}
+	var (
+		got   []string
+		after *git.CommitGraphPosition
+	)
+	for {
+		nodes, err := git.CommitGraph(ctx, repo, git.CommitGraphOptions{Revisions: revisions, N: 2, After: after})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) == 0 {
+			break
+		}
+		got = append(got, format(nodes)...)
+		after = &nodes[len(nodes)-1].Position
+	}
+	if want := tests["branches"].want; !reflect.DeepEqual(got, want) {
+		t.Errorf("paginated: got %q, want %q", got, want)
+	}
+
+	// The graph can't be continued from a position whose commit is no longer there.
+	after = &git.CommitGraphPosition{Index: 0, Commit: "0000000000000000000000000000000000000000"}
+	if _, err := git.CommitGraph(ctx, repo, git.CommitGraphOptions{Revisions: revisions, N: 2, After: after}); err == nil {
+		t.Error("CommitGraph: want error for changed graph")
+	}
+
+	if _, err := git.CommitGraph(ctx, repo, git.CommitGraphOptions{Revisions: []string{"--output=x"}}); err == nil {
+		t.Error("CommitGraph: want error for revision starting with '-'")
+	}
+}
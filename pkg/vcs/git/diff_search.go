@@ -40,6 +40,21 @@ func CompilePathMatcher(options PathOptions) (pathmatch.PathMatcher, error) {
 	)
 }
 
+// compileTextSearchQuery compiles the query into a regexp, or returns nil if the query is empty.
+func compileTextSearchQuery(query TextSearchOptions) (*regexp.Regexp, error) {
+	if query.Pattern == "" {
+		return nil, nil
+	}
+	pattern := query.Pattern
+	if !query.IsRegExp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !query.IsCaseSensitive {
+		pattern = "(?i:" + pattern + ")"
+	}
+	return regexp.Compile(pattern)
+}
+
 // RawLogDiffSearchOptions specifies options to (Repository).RawLogDiffSearch.
 type RawLogDiffSearchOptions struct {
 	// Query specifies the search query to find.
@@ -296,19 +311,9 @@ func RawLogDiffSearch(ctx context.Context, repo gitserver.Repo, opt RawLogDiffSe
 	// Even though we've already searched using the query, we need to
 	// search the returned diff again to filter to only matching hunks
 	// and to highlight matches.
-	var query *regexp.Regexp
-	if pattern := opt.Query.Pattern; pattern != "" {
-		if !opt.Query.IsRegExp {
-			pattern = regexp.QuoteMeta(pattern)
-		}
-		if !opt.Query.IsCaseSensitive {
-			pattern = "(?i:" + pattern + ")"
-		}
-		var err error
-		query, err = regexp.Compile(pattern)
-		if err != nil {
-			return nil, false, err
-		}
+	query, err := compileTextSearchQuery(opt.Query)
+	if err != nil {
+		return nil, false, err
 	}
 
 	pathMatcher, err := compilePathMatcher(opt.Paths)
@@ -0,0 +1,204 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+)
+
+// A FileDiffReader reads the file diffs of `git diff` output one at a time, so that the whole
+// diff is never buffered in memory.
+type FileDiffReader struct {
+	r        *bufio.Reader
+	nextLine []byte // the first line of the next file diff, if it was already read
+
+	// MaxHunksSize is the maximum size (in bytes) of the hunks of a file diff whose hunks are
+	// returned, not counting the headers of the file diff (the lines before its first hunk). The
+	// hunks of larger file diffs are discarded as they are read. If 0, all hunks are returned.
+	MaxHunksSize int
+}
+
+// NewFileDiffReader returns a FileDiffReader that reads the `git diff` output from r.
+func NewFileDiffReader(r io.Reader) *FileDiffReader {
+	return &FileDiffReader{r: bufio.NewReader(r)}
+}
+
+// A FileDiff is a file diff read by a FileDiffReader.
+type FileDiff struct {
+	Diff *diff.FileDiff
+
+	// HunksSkipped indicates that the hunks of the file diff were discarded because they are
+	// larger than MaxHunksSize.
+	HunksSkipped bool
+
+	// Stat is the diff stat of the file diff, including the lines of skipped hunks.
+	Stat diff.Stat
+}
+
+// ReadFile reads the next file diff. It returns io.EOF if there are no more file diffs.
+func (r *FileDiffReader) ReadFile() (*FileDiff, error) {
+	var (
+		buf       bytes.Buffer
+		headerLen = -1 // the length of the headers (the lines before the first hunk) in buf
+		skipped   bool
+		stat      diff.Stat
+		last      byte // the status of the previous hunk line, for the stat
+	)
+	err := r.readFileLines(func(line []byte) {
+		if headerLen == -1 && bytes.HasPrefix(line, []byte("@@ ")) {
+			headerLen = buf.Len()
+		}
+		if headerLen != -1 {
+			last = addLineStat(&stat, last, line)
+		}
+		if skipped {
+			return
+		}
+		buf.Write(line)
+		if r.MaxHunksSize > 0 && headerLen != -1 && buf.Len()-headerLen > r.MaxHunksSize {
+			buf.Truncate(headerLen)
+			skipped = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fileDiff, err := diff.NewMultiFileDiffReader(&buf).ReadFile()
+	if err == io.EOF && fileDiff != nil {
+		err = nil // a file diff without hunks is returned with io.EOF, because nothing follows it
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FileDiff{Diff: fileDiff, HunksSkipped: skipped, Stat: stat}, nil
+}
+
+// SkipFile discards the next file diff without parsing it. It returns io.EOF if there are no more
+// file diffs.
+func (r *FileDiffReader) SkipFile() error {
+	return r.readFileLines(func([]byte) {})
+}
+
+// readFileLines calls fn with each line of the next file diff (which starts with a "diff " line).
+func (r *FileDiffReader) readFileLines(fn func(line []byte)) error {
+	line := r.nextLine
+	r.nextLine = nil
+	for first := true; ; first = false {
+		if line == nil {
+			var err error
+			line, err = r.r.ReadBytes('\n')
+			if err == io.EOF && len(line) > 0 {
+				err = nil // the last line has no trailing newline
+			}
+			if err == io.EOF && !first {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if !first && bytes.HasPrefix(line, []byte("diff ")) {
+			r.nextLine = line
+			return nil
+		}
+		fn(line)
+		line = nil
+	}
+}
+
+// A ChangedFile is a file changed by a diff, as listed by `git diff --raw -z`.
+type ChangedFile struct {
+	Status   byte   // the status letter of the change (such as 'M', 'A', 'D', 'R' or 'C')
+	OrigName string // the path of the file before the change
+	NewName  string // the path of the file after the change (the same as OrigName unless renamed or copied)
+}
+
+// A ChangedFileReader reads the changed files listed by `git diff --raw -z` one at a time.
+type ChangedFileReader struct {
+	r *bufio.Reader
+}
+
+// NewChangedFileReader returns a ChangedFileReader that reads the `git diff --raw -z` output from
+// r.
+func NewChangedFileReader(r io.Reader) *ChangedFileReader {
+	return &ChangedFileReader{r: bufio.NewReader(r)}
+}
+
+// Read reads the next changed file. It returns io.EOF if there are no more changed files.
+func (r *ChangedFileReader) Read() (*ChangedFile, error) {
+	// Each changed file is listed as ":<old mode> <new mode> <old sha> <new sha> <status>", followed
+	// by its path (or by the source and destination paths of a rename or copy), each terminated by
+	// a NUL.
+	info, err := r.readField()
+	if err != nil {
+		return nil, err
+	}
+	i := bytes.LastIndexByte(info, ' ')
+	if !bytes.HasPrefix(info, []byte(":")) || i == -1 || i == len(info)-1 {
+		return nil, fmt.Errorf("invalid git diff --raw line: %q", info)
+	}
+	f := &ChangedFile{Status: info[i+1]}
+	if f.OrigName, err = r.readPath(); err != nil {
+		return nil, err
+	}
+	f.NewName = f.OrigName
+	if f.Status == 'R' || f.Status == 'C' {
+		if f.NewName, err = r.readPath(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// readField reads the next NUL-terminated field, without the NUL.
+func (r *ChangedFileReader) readField() ([]byte, error) {
+	field, err := r.r.ReadBytes(0)
+	if err == io.EOF && len(field) > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return field[:len(field)-1], nil
+}
+
+// readPath reads the next path of a changed file.
+func (r *ChangedFileReader) readPath() (string, error) {
+	path, err := r.readField()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return string(path), err
+}
+
+// addLineStat adds a line of a hunk to stat, the way (*diff.Hunk).Stat counts it, given the
+// status of the previous line. It returns the status to pass for the next line.
+func addLineStat(stat *diff.Stat, last byte, line []byte) byte {
+	if len(line) == 0 {
+		return 0
+	}
+	switch line[0] {
+	case '-':
+		if last == '+' {
+			stat.Added--
+			stat.Changed++
+			return 0 // the next line can't change this one since this is already a change
+		}
+		stat.Deleted++
+		return '-'
+	case '+':
+		if last == '-' {
+			stat.Deleted--
+			stat.Changed++
+			return 0
+		}
+		stat.Added++
+		return '+'
+	case '\\':
+		return last // "\ No newline at end of file" lines are not in hunk bodies
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package git
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+)
+
+func TestFileDiffReader(t *testing.T) {
+	const rawDiff = `diff --git a a
+index 7898192..6178079 100644
+--- a
++++ a
+@@ -1 +1 @@
+-a
++b
+diff --git b b
+new file mode 100644
+index 0000000..0f4c3c6
+--- /dev/null
++++ b
+@@ -0,0 +1,3 @@
++diff --git
++xxxxxxxxxxxxxxxxxxxxxxxxxxxxxx
++y
+diff --git c c
+deleted file mode 100644
+index f2ad6c7..0000000
+--- c
++++ /dev/null
+@@ -1 +0,0 @@
+-c
+\ No newline at end of file
+`
+	type file struct {
+		name         string
+		hunks        int
+		hunksSkipped bool
+		stat         diff.Stat
+	}
+	tests := map[string]struct {
+		maxHunksSize int
+		skip         int
+		want         []file
+	}{
+		"all": {
+			want: []file{
+				{name: "a", hunks: 1, stat: diff.Stat{Changed: 1}},
+				{name: "b", hunks: 1, stat: diff.Stat{Added: 3}},
+				{name: "/dev/null", hunks: 1, stat: diff.Stat{Deleted: 1}},
+			},
+		},
+		"skip": {
+			skip: 2,
+			want: []file{
+				{name: "/dev/null", hunks: 1, stat: diff.Stat{Deleted: 1}},
+			},
+		},
+		"max hunks size": {
+			maxHunksSize: 50,
+			want: []file{
+				{name: "a", hunks: 1, stat: diff.Stat{Changed: 1}},
+				{name: "b", hunksSkipped: true, stat: diff.Stat{Added: 3}},
+				{name: "/dev/null", hunks: 1, stat: diff.Stat{Deleted: 1}},
+			},
+		},
+		"max hunks size excludes headers": {
+			maxHunksSize: 18, // the size of the hunk of a, whose headers are larger
+			want: []file{
+				{name: "a", hunks: 1, stat: diff.Stat{Changed: 1}},
+				{name: "b", hunksSkipped: true, stat: diff.Stat{Added: 3}},
+				{name: "/dev/null", hunksSkipped: true, stat: diff.Stat{Deleted: 1}},
+			},
+		},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			r := NewFileDiffReader(strings.NewReader(rawDiff))
+			r.MaxHunksSize = test.maxHunksSize
+			for i := 0; i < test.skip; i++ {
+				if err := r.SkipFile(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []file
+			for {
+				fileDiff, err := r.ReadFile()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				if fileDiff.Diff.Stat() != fileDiff.Stat && !fileDiff.HunksSkipped {
+					t.Errorf("got stat %+v, want %+v (computed from the hunks)", fileDiff.Stat, fileDiff.Diff.Stat())
+				}
+				got = append(got, file{
+					name:         fileDiff.Diff.NewName,
+					hunks:        len(fileDiff.Diff.Hunks),
+					hunksSkipped: fileDiff.HunksSkipped,
+					stat:         fileDiff.Stat,
+				})
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestChangedFileReader(t *testing.T) {
+	const rawDiff = ":100644 100644 7898192 6178079 M\x00a\x00" +
+		":100644 000000 f2ad6c7 0000000 D\x00c\x00" +
+		":100644 100644 96cc558 96cc558 R100\x00b\x00d\x00" +
+		":000000 100644 0000000 d905d9d A\x00e\x00"
+
+	r := NewChangedFileReader(strings.NewReader(rawDiff))
+	var got []ChangedFile
+	for {
+		f, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, *f)
+	}
+	want := []ChangedFile{
+		{Status: 'M', OrigName: "a", NewName: "a"},
+		{Status: 'D', OrigName: "c", NewName: "c"},
+		{Status: 'R', OrigName: "b", NewName: "d"},
+		{Status: 'A', OrigName: "e", NewName: "e"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := NewChangedFileReader(strings.NewReader(":100644 100644 96cc558 96cc558 R100\x00b\x00")).Read(); err != io.ErrUnexpectedEOF {
+		t.Errorf("got error %v for a truncated rename, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
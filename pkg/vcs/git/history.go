@@ -0,0 +1,153 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+)
+
+// FileHistoryOptions configures a file history.
+type FileHistoryOptions struct {
+	NewestCommit api.CommitID // the commit to start the history at
+
+	// Follow continues the history of the file past renames (git log --follow). The history of a
+	// line range always follows renames.
+	Follow bool
+
+	StartLine int // 1-indexed start line of the line range (or 0 for the whole file)
+	EndLine   int // 1-indexed end line of the line range (or 0 for the whole file)
+
+	N    uint // limit the number of returned entries to this many (0 means no limit)
+	Skip uint // skip this many entries at the beginning
+}
+
+// A FileHistoryEntry is a commit in the history of a file (or of a line range in a file).
+type FileHistoryEntry struct {
+	Commit *Commit
+
+	// Diff is the diff of the file in the commit. For the history of a line range, it only
+	// contains the hunk of the line range. Its OrigName is "/dev/null" if the file was added in
+	// the commit, and its NewName differs from OrigName if the file was renamed. It is nil if
+	// git doesn't show a diff for the commit (such as for merge commits).
+	Diff *diff.FileDiff
+}
+
+// FileHistory returns the commits that changed the file at path (or the lines of the file in the
+// given range), most recent first, with the diff of the file in each commit. It is built on
+// git log --follow and git log -L.
+func FileHistory(ctx context.Context, repo gitserver.Repo, path string, opt *FileHistoryOptions) ([]*FileHistoryEntry, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: FileHistory")
+	span.SetTag("repo", repo.Name)
+	span.SetTag("path", path)
+	span.SetTag("opt", opt)
+	defer span.Finish()
+	return fileHistoryCmd(ctx, gitserverCmdFunc(repo), path, opt)
+}
+
+func fileHistoryCmd(ctx context.Context, command cmdFunc, path string, opt *FileHistoryOptions) ([]*FileHistoryEntry, error) {
+	if opt == nil {
+		opt = &FileHistoryOptions{}
+	}
+	if err := checkSpecArgSafety(string(opt.NewestCommit)); err != nil {
+		return nil, err
+	}
+	if opt.StartLine < 0 || opt.EndLine < opt.StartLine {
+		return nil, fmt.Errorf("invalid line range %d-%d", opt.StartLine, opt.EndLine)
+	}
+
+	// Each entry starts with a record separator, followed by the NUL-separated commit fields and
+	// the patch of the file.
+	args := []string{"log", "--format=format:%x1e" + strings.TrimPrefix(logFormatWithoutRefs, "--format=format:"), "--no-prefix", "--no-color"}
+	if opt.N != 0 {
+		args = append(args, "-n", strconv.FormatUint(uint64(opt.N), 10))
+	}
+	if opt.Skip != 0 {
+		args = append(args, "--skip="+strconv.FormatUint(uint64(opt.Skip), 10))
+	}
+	if opt.StartLine != 0 {
+		// git log -L doesn't accept pathspecs, and always follows renames.
+		args = append(args, fmt.Sprintf("-L%d,%d:%s", opt.StartLine, opt.EndLine, filepath.ToSlash(path)))
+		if opt.NewestCommit != "" {
+			args = append(args, string(opt.NewestCommit))
+		}
+	} else {
+		args = append(args, "--patch", "--find-renames")
+		if opt.Follow {
+			args = append(args, "--follow")
+		}
+		if opt.NewestCommit != "" {
+			args = append(args, string(opt.NewestCommit))
+		}
+		args = append(args, "--", filepath.ToSlash(path))
+	}
+
+	out, err := command(args).Output(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", args, out))
+	}
+	return parseFileHistory(out, opt.StartLine != 0)
+}
+
+// parseFileHistory parses the output of the git log command run by fileHistoryCmd.
+func parseFileHistory(data []byte, lineRange bool) ([]*FileHistoryEntry, error) {
+	var entries []*FileHistoryEntry
+	for _, record := range bytes.Split(data, []byte{'\x1e'}) {
+		if len(bytes.TrimSpace(record)) == 0 {
+			continue
+		}
+		commit, _, patch, err := parseCommitFromLog(record)
+		if err != nil {
+			return nil, err
+		}
+		entry := &FileHistoryEntry{Commit: commit}
+
+		// The patch is preceded and followed by blank lines, which are never part of a diff.
+		if patch = bytes.Trim(patch, "\n"); len(patch) > 0 {
+			entry.Diff, err = parseFileHistoryDiff(append(patch, '\n'), lineRange)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing diff of commit %s", commit.ID)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseFileHistoryDiff parses the patch of a file in a commit. The patches of line ranges
+// (git log -L) have "a/" and "b/" prefixes, even with --no-prefix.
+func parseFileHistoryDiff(patch []byte, lineRange bool) (*diff.FileDiff, error) {
+	fileDiffs, err := diff.ParseMultiFileDiff(patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(fileDiffs) == 0 {
+		return nil, nil
+	}
+	fileDiff := fileDiffs[0]
+
+	// The names of a file that was renamed without changes are only in the extended headers
+	// (there are no "---" and "+++" lines).
+	for _, h := range fileDiff.Extended {
+		switch {
+		case fileDiff.OrigName == "" && strings.HasPrefix(h, "rename from "):
+			fileDiff.OrigName = strings.TrimPrefix(h, "rename from ")
+		case fileDiff.NewName == "" && strings.HasPrefix(h, "rename to "):
+			fileDiff.NewName = strings.TrimPrefix(h, "rename to ")
+		}
+	}
+
+	if lineRange {
+		fileDiff.OrigName = strings.TrimPrefix(fileDiff.OrigName, "a/")
+		fileDiff.NewName = strings.TrimPrefix(fileDiff.NewName, "b/")
+	}
+	return fileDiff, nil
+}
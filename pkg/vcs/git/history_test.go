@@ -0,0 +1,91 @@
+package git_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+func TestRepository_FileHistory(t *testing.T) {
+	t.Parallel()
+
+	const commit = "GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit --author='a <a@a.com>' --date 2006-01-02T15:04:05Z"
+	repo := makeGitRepository(t,
+		"printf 'a\\nb\\nc\\nd\\n' > f",
+		"git add f",
+		commit+" -m add",
+		"printf 'a\\nB\\nc\\nd\\n' > f",
+		commit+" -am edit",
+		"git mv f g",
+		commit+" -m rename",
+		"printf 'a\\nB\\nC\\nd\\ne\\n' > g",
+		commit+" -am edit2",
+	)
+	newestCommitID, err := git.ResolveRevision(ctx, repo, nil, "master", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opt  git.FileHistoryOptions
+		want []string
+	}{
+		"file": {
+			want: []string{
+				"edit2 g->g @@ -1,4 +1,5 @@ [ a  B -c +C  d +e]",
+				"rename /dev/null->g @@ -0,0 +1,4 @@ [+a +B +c +d]",
+			},
+		},
+		"follow": {
+			opt: git.FileHistoryOptions{Follow: true},
+			want: []string{
+				"edit2 g->g @@ -1,4 +1,5 @@ [ a  B -c +C  d +e]",
+				"rename f->g",
+				"edit f->f @@ -1,4 +1,4 @@ [ a -b +B  c  d]",
+				"add /dev/null->f @@ -0,0 +1,4 @@ [+a +b +c +d]",
+			},
+		},
+		"follow paginated": {
+			opt: git.FileHistoryOptions{Follow: true, N: 2, Skip: 1},
+			want: []string{
+				"rename f->g",
+				"edit f->f @@ -1,4 +1,4 @@ [ a -b +B  c  d]",
+			},
+		},
+		"line range": {
+			opt: git.FileHistoryOptions{StartLine: 2, EndLine: 3},
+			want: []string{
+				"edit2 g->g @@ -2,2 +2,2 @@ [ B -c +C]",
+				"edit f->f @@ -2,2 +2,2 @@ [-b +B  c]",
+				"add /dev/null->f @@ -0,0 +2,2 @@ [+b +c]",
+			},
+		},
+	}
+	for label, test := range tests {
+		test.opt.NewestCommit = newestCommitID
+		entries, err := git.FileHistory(ctx, repo, "g", &test.opt)
+		if err != nil {
+			t.Errorf("%s: FileHistory: %s", label, err)
+			continue
+		}
+
+		var got []string
+		for _, e := range entries {
+			s := e.Commit.Message
+			if e.Diff != nil {
+				s += " " + e.Diff.OrigName + "->" + e.Diff.NewName
+				for _, h := range e.Diff.Hunks {
+					lines := strings.Split(strings.TrimSuffix(string(h.Body), "\n"), "\n")
+					s += fmt.Sprintf(" @@ -%d,%d +%d,%d @@ %v", h.OrigStartLine, h.OrigLines, h.NewStartLine, h.NewLines, lines)
+				}
+			}
+			got = append(got, s)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", label, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/pathmatch"
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+)
+
+// MergeBaseDiffSearchOptions specifies options to MergeBaseDiffSearch.
+type MergeBaseDiffSearchOptions struct {
+	Base api.CommitID // the commit whose merge base with Head is compared against
+	Head api.CommitID // the commit whose changes are searched
+
+	// Query specifies the search query to find in the changed lines. If empty, all changed lines
+	// match.
+	Query TextSearchOptions
+
+	// Paths specifies the paths to include/exclude.
+	Paths PathOptions
+
+	// Limit is the maximum number of results to return (0 means no limit). The diff is only read
+	// until the result after the last one is found.
+	Limit int
+}
+
+// MergeBaseDiffSearchResult is a file with changes that match a MergeBaseDiffSearch.
+type MergeBaseDiffSearchResult struct {
+	FileDiff *diff.FileDiff // the diff of the file, with only the matching hunks
+
+	// Highlights are the query matches in the hunks, with line numbers relative to the output of
+	// diff.PrintHunks(FileDiff.Hunks).
+	Highlights []Highlight
+}
+
+// MergeBaseDiffSearch searches the changes made on Head since it diverged from Base (the diff
+// between their merge base and Head, as shown by `git diff Base...Head`). Unlike RawLogDiffSearch,
+// the diff is computed once for the whole range instead of for each commit.
+//
+// It returns the files with changed lines that match the query, in the order git shows them, and
+// whether there were more results than opt.Limit.
+func MergeBaseDiffSearch(ctx context.Context, repo gitserver.Repo, opt MergeBaseDiffSearchOptions) (results []*MergeBaseDiffSearchResult, limitHit bool, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: MergeBaseDiffSearch")
+	span.SetTag("repo", repo.Name)
+	span.SetTag("opt", opt)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(string(opt.Base)); err != nil {
+		return nil, false, err
+	}
+	if err := checkSpecArgSafety(string(opt.Head)); err != nil {
+		return nil, false, err
+	}
+	query, err := compileTextSearchQuery(opt.Query)
+	if err != nil {
+		return nil, false, err
+	}
+	pathMatcher, err := compilePathMatcher(opt.Paths)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The diff is streamed from gitserver and read one file diff at a time, so that the diff of a
+	// large range is never buffered in memory, and so that we stop reading (and gitserver stops
+	// diffing) once enough results are found.
+	args := []string{"diff", "--find-renames", "--no-prefix", "--no-color", string(opt.Base) + "..." + string(opt.Head), "--"}
+	rc, err := ExecReader(ctx, repo, args)
+	if err != nil {
+		return nil, false, errors.WithMessage(err, fmt.Sprintf("git command %v failed", args))
+	}
+	defer rc.Close()
+	return mergeBaseDiffSearch(NewFileDiffReader(rc), query, pathMatcher, opt.Limit)
+}
+
+func mergeBaseDiffSearch(dr *FileDiffReader, query *regexp.Regexp, pathMatcher pathmatch.PathMatcher, limit int) (results []*MergeBaseDiffSearchResult, limitHit bool, err error) {
+	const matchContextLines = 3 // the same as the context lines of the full diff
+
+	for {
+		f, err := dr.ReadFile()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, false, err
+		}
+		fileDiff := f.Diff
+
+		origNameMatches := fileDiff.OrigName != "/dev/null" && pathMatcher.MatchPath(fileDiff.OrigName)
+		newNameMatches := fileDiff.NewName != "/dev/null" && pathMatcher.MatchPath(fileDiff.NewName)
+		if !origNameMatches && !newNameMatches {
+			continue
+		}
+
+		// The "no newline" offsets are not adjusted when hunks are split (see
+		// filterAndHighlightDiff).
+		for _, hunk := range fileDiff.Hunks {
+			hunk.OrigNoNewlineAt = 0
+		}
+		fileDiff.Hunks = splitHunkMatches(fileDiff.Hunks, query, matchContextLines, 0)
+		if len(fileDiff.Hunks) == 0 {
+			continue
+		}
+		if limit > 0 && len(results) == limit {
+			return results, true, nil
+		}
+		highlights, err := highlightHunks(fileDiff.Hunks, query)
+		if err != nil {
+			return nil, false, err
+		}
+		results = append(results, &MergeBaseDiffSearchResult{FileDiff: fileDiff, Highlights: highlights})
+	}
+	return results, false, nil
+}
+
+// highlightHunks returns the query matches in the printed hunks, ignoring the hunk headers (as
+// filterAndHighlightDiff does).
+func highlightHunks(hunks []*diff.Hunk, query *regexp.Regexp) ([]Highlight, error) {
+	const maxMatchesPerLine = 100
+
+	if query == nil {
+		return nil, nil
+	}
+	data, err := diff.PrintHunks(hunks)
+	if err != nil {
+		return nil, err
+	}
+	var highlights []Highlight
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 || bytes.HasPrefix(line, []byte("@@")) {
+			continue
+		}
+		lineWithoutStatus := line[1:] // don't match '-' or '+' line status
+		for _, match := range query.FindAllIndex(lineWithoutStatus, maxMatchesPerLine) {
+			highlights = append(highlights, Highlight{
+				Line:      i + 1,
+				Character: match[0] + 1,
+				Length:    match[1] - match[0],
+			})
+		}
+	}
+	return highlights, nil
+}
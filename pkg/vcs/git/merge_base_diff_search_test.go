@@ -0,0 +1,119 @@
+package git_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+func TestMergeBaseDiffSearch(t *testing.T) {
+	t.Parallel()
+
+	const commit = "GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit --author='a <a@a.com>' --date 2006-01-02T15:04:05Z"
+	repo := makeGitRepository(t,
+		"printf 'a\\nb\\nc\\nd\\ne\\nf\\ng\\nh\\ni\\nj\\n' > f",
+		"git add f",
+		commit+" -m base",
+		"git checkout -b release",
+		"printf 'a\\nb\\nTODO 1\\nd\\ne\\nf\\ng\\nh\\ni\\nj\\n' > f",
+		commit+" -am todo1",
+		"printf 'a\\nb\\nTODO 1\\nd\\ne\\nf\\ng\\nh\\ni\\nTODO 2\\n' > f",
+		commit+" -am todo2",
+		"printf 'TODO 3\\n' > g.txt",
+		"git add g.txt",
+		commit+" -m todo3",
+		"git checkout master",
+		"printf 'TODO master\\n' > h",
+		"git add h",
+		commit+" -m master",
+	)
+	resolve := func(rev string) api.CommitID {
+		commitID, err := git.ResolveRevision(ctx, repo, nil, rev, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commitID
+	}
+	base, head := resolve("master"), resolve("release")
+
+	tests := map[string]struct {
+		opt            git.MergeBaseDiffSearchOptions
+		want           []string
+		wantHighlights []git.Highlight
+		wantLimitHit   bool
+	}{
+		"all changes": {
+			want: []string{
+				"f @@ -1,10 +1,10 @@ [ a  b -c +TODO 1  d  e  f  g  h  i -j +TODO 2]",
+				"g.txt @@ -0,0 +1,1 @@ [+TODO 3]",
+			},
+		},
+		"query": {
+			opt: git.MergeBaseDiffSearchOptions{Query: git.TextSearchOptions{Pattern: "todo 2"}},
+			want: []string{
+				"f @@ -8,3 +8,3 @@ [ h  i -j +TODO 2]",
+			},
+			wantHighlights: []git.Highlight{{Line: 5, Character: 1, Length: 6}},
+		},
+		"limit": {
+			opt: git.MergeBaseDiffSearchOptions{Limit: 1},
+			want: []string{
+				"f @@ -1,10 +1,10 @@ [ a  b -c +TODO 1  d  e  f  g  h  i -j +TODO 2]",
+			},
+			wantLimitHit: true,
+		},
+		"limit not hit": {
+			opt: git.MergeBaseDiffSearchOptions{Query: git.TextSearchOptions{Pattern: "todo 3"}, Limit: 1},
+			want: []string{
+				"g.txt @@ -0,0 +1,1 @@ [+TODO 3]",
+			},
+			wantHighlights: []git.Highlight{{Line: 2, Character: 1, Length: 6}},
+		},
+		"query case sensitive": {
+			opt: git.MergeBaseDiffSearchOptions{Query: git.TextSearchOptions{Pattern: "todo", IsCaseSensitive: true}},
+		},
+		"paths": {
+			opt: git.MergeBaseDiffSearchOptions{
+				Query: git.TextSearchOptions{Pattern: "TODO"},
+				Paths: git.PathOptions{IncludePatterns: []string{`\.txt$`}, IsRegExp: true},
+			},
+			want: []string{
+				"g.txt @@ -0,0 +1,1 @@ [+TODO 3]",
+			},
+			wantHighlights: []git.Highlight{{Line: 2, Character: 1, Length: 4}},
+		},
+	}
+	for label, test := range tests {
+		test.opt.Base, test.opt.Head = base, head
+		results, limitHit, err := git.MergeBaseDiffSearch(ctx, repo, test.opt)
+		if err != nil {
+			t.Errorf("%s: MergeBaseDiffSearch: %s", label, err)
+			continue
+		}
+
+		var got []string
+		var gotHighlights []git.Highlight
+		for _, r := range results {
+			s := r.FileDiff.NewName
+			for _, h := range r.FileDiff.Hunks {
+				lines := strings.Split(strings.TrimSuffix(string(h.Body), "\n"), "\n")
+				s += fmt.Sprintf(" @@ -%d,%d +%d,%d @@ %v", h.OrigStartLine, h.OrigLines, h.NewStartLine, h.NewLines, lines)
+			}
+			got = append(got, s)
+			gotHighlights = append(gotHighlights, r.Highlights...)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", label, got, test.want)
+		}
+		if !reflect.DeepEqual(gotHighlights, test.wantHighlights) {
+			t.Errorf("%s: got highlights %+v, want %+v", label, gotHighlights, test.wantHighlights)
+		}
+		if limitHit != test.wantLimitHit {
+			t.Errorf("%s: got limitHit %v, want %v", label, limitHit, test.wantLimitHit)
+		}
+	}
+}
@@ -247,6 +247,47 @@ func ListTags(ctx context.Context, repo gitserver.Repo) ([]*Tag, error) {
 	return tags, nil
 }
 
+// A Ref is a Git reference.
+type Ref struct {
+	// Name is the full name of the ref (for example "refs/heads/master").
+	Name string
+	// CommitID is the commit that the ref points to. For an annotated tag, it
+	// is the tagged commit.
+	CommitID api.CommitID
+}
+
+// ListRefs returns all refs (branches, tags and other refs) in the
+// repository, sorted by name.
+func ListRefs(ctx context.Context, repo gitserver.Repo) ([]Ref, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: ListRefs")
+	defer span.Finish()
+
+	cmd := gitserver.DefaultClient.Command("git", "for-each-ref", "--sort", "refname", "--format", "%(if)%(*objectname)%(then)%(*objectname)%(else)%(objectname)%(end)%00%(refname)")
+	cmd.Repo = repo
+	out, err := cmd.CombinedOutput(ctx)
+	if err != nil {
+		if vcs.IsRepoNotExist(err) {
+			return nil, err
+		}
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", cmd.Args, out))
+	}
+
+	out = bytes.TrimSuffix(out, []byte("\n")) // remove trailing newline
+	if len(out) == 0 {
+		return nil, nil // no refs
+	}
+	lines := bytes.Split(out, []byte("\n"))
+	refs := make([]Ref, len(lines))
+	for i, line := range lines {
+		parts := bytes.SplitN(line, []byte("\x00"), 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid git for-each-ref output line: %q", line)
+		}
+		refs[i] = Ref{Name: string(parts[1]), CommitID: api.CommitID(parts[0])}
+	}
+	return refs, nil
+}
+
 type byteSlices [][]byte
 
 func (p byteSlices) Len() int           { return len(p) }
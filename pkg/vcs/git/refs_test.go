@@ -280,3 +280,31 @@ func TestRepository_ListTags(t *testing.T) {
 		}
 	}
 }
+
+func TestRepository_ListRefs(t *testing.T) {
+	t.Parallel()
+
+	dateEnv := "GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z"
+	gitCommands := []string{
+		dateEnv + " git commit --allow-empty -m foo --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+		"git branch b0",
+		"git tag t0",
+		dateEnv + " git tag --annotate -m foo t1",
+	}
+	repo := makeGitRepository(t, gitCommands...)
+
+	refs, err := git.ListRefs(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const commitID = "ea167fe3d76b1e5fd3ed8ca44cbd2fe3897684f8"
+	want := []git.Ref{
+		{Name: "refs/heads/b0", CommitID: commitID},
+		{Name: "refs/heads/master", CommitID: commitID},
+		{Name: "refs/tags/t0", CommitID: commitID},
+		{Name: "refs/tags/t1", CommitID: commitID},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs %v, want %v", refs, want)
+	}
+}
@@ -79,16 +79,49 @@ func (v *AuthProviders) UnmarshalJSON(data []byte) error {
 	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"builtin", "saml", "openidconnect", "http-header", "github", "gitlab"})
 }
 
+// BitbucketCloudConnection description: Configuration for a connection to Bitbucket Cloud (bitbucket.org).
+type BitbucketCloudConnection struct {
+	AppPassword                 string   `json:"appPassword"`
+	GitURLType                  string   `json:"gitURLType,omitempty"`
+	InitialRepositoryEnablement bool     `json:"initialRepositoryEnablement,omitempty"`
+	RepositoryPathPattern       string   `json:"repositoryPathPattern,omitempty"`
+	Teams                       []string `json:"teams,omitempty"`
+	Url                         string   `json:"url,omitempty"`
+	Username                    string   `json:"username"`
+}
+
+// BitbucketServerAuthorization description: If non-null, enforces Bitbucket Server repository permissions. Each Sourcegraph user is matched with a Bitbucket Server user (see identityProvider), and can only access the repositories which that Bitbucket Server user can read. Users without a matching Bitbucket Server user can only access public repositories.
+type BitbucketServerAuthorization struct {
+	IdentityProvider BitbucketServerIdentityProvider `json:"identityProvider"`
+	Oauth            BitbucketServerOAuth            `json:"oauth"`
+	Ttl              string                          `json:"ttl,omitempty"`
+}
 type BitbucketServerConnection struct {
-	Certificate                 string `json:"certificate,omitempty"`
-	ExcludePersonalRepositories bool   `json:"excludePersonalRepositories,omitempty"`
-	GitURLType                  string `json:"gitURLType,omitempty"`
-	InitialRepositoryEnablement bool   `json:"initialRepositoryEnablement,omitempty"`
-	Password                    string `json:"password,omitempty"`
-	RepositoryPathPattern       string `json:"repositoryPathPattern,omitempty"`
-	Token                       string `json:"token,omitempty"`
-	Url                         string `json:"url"`
-	Username                    string `json:"username,omitempty"`
+	Authorization               *BitbucketServerAuthorization `json:"authorization,omitempty"`
+	Certificate                 string                        `json:"certificate,omitempty"`
+	CloneStrategies             []*CloneStrategy              `json:"cloneStrategies,omitempty"`
+	ExcludePersonalRepositories bool                          `json:"excludePersonalRepositories,omitempty"`
+	GitURLType                  string                        `json:"gitURLType,omitempty"`
+	InitialRepositoryEnablement bool                          `json:"initialRepositoryEnablement,omitempty"`
+	Password                    string                        `json:"password,omitempty"`
+	RepositoryPathPattern       string                        `json:"repositoryPathPattern,omitempty"`
+	Token                       string                        `json:"token,omitempty"`
+	Url                         string                        `json:"url"`
+	Username                    string                        `json:"username,omitempty"`
+	WebhookSecret               string                        `json:"webhookSecret,omitempty"`
+}
+
+// BitbucketServerIdentityProvider description: How each Sourcegraph user is matched with a Bitbucket Server user.
+type BitbucketServerIdentityProvider struct {
+	AuthProviderID   string `json:"authProviderID,omitempty"`
+	AuthProviderType string `json:"authProviderType,omitempty"`
+	Type             string `json:"type"`
+}
+
+// BitbucketServerOAuth description: OAuth configuration of an application link in Bitbucket Server (with an incoming link that allows user impersonation), used to list the repositories that each user can read. See https://confluence.atlassian.com/bitbucketserver/linking-bitbucket-server-with-jira-776640408.html.
+type BitbucketServerOAuth struct {
+	ConsumerKey string `json:"consumerKey"`
+	SigningKey  string `json:"signingKey"`
 }
 
 // BuiltinAuthProvider description: Configures the builtin username-password authentication provider.
@@ -97,6 +130,13 @@ type BuiltinAuthProvider struct {
 	Type        string `json:"type"`
 }
 
+// CloneStrategy description: Describes how gitserver clones and fetches a set of repositories. Shallow and partial clones use less disk and clone faster; gitserver fetches missing history or objects on demand when a request needs them.
+type CloneStrategy struct {
+	Depth  int      `json:"depth,omitempty"`
+	Filter string   `json:"filter,omitempty"`
+	Repos  []string `json:"repos"`
+}
+
 // CloneURLToRepositoryName description: Describes a mapping from clone URL to repository name. The `from` field contains a regular expression with named capturing groups. The `to` field contains a template string that references capturing group names. For instance, if `from` is "^../(?P<name>\w+)$" and `to` is "github.com/user/{name}", the clone URL "../myRepository" would be mapped to the repository name "github.com/user/myRepository".
 type CloneURLToRepositoryName struct {
 	From string `json:"from"`
@@ -130,8 +170,9 @@ type Discussions struct {
 
 // ExperimentalFeatures description: Experimental features to enable or disable. Features that are now enabled by default are marked as deprecated.
 type ExperimentalFeatures struct {
-	Discussions      string `json:"discussions,omitempty"`
-	UpdateScheduler2 string `json:"updateScheduler2,omitempty"`
+	Discussions               string `json:"discussions,omitempty"`
+	PermissionsBackgroundSync string `json:"permissionsBackgroundSync,omitempty"`
+	UpdateScheduler2          string `json:"updateScheduler2,omitempty"`
 }
 
 // Extensions description: Configures Sourcegraph extensions.
@@ -158,6 +199,7 @@ type GitHubAuthorization struct {
 type GitHubConnection struct {
 	Authorization               *GitHubAuthorization `json:"authorization,omitempty"`
 	Certificate                 string               `json:"certificate,omitempty"`
+	CloneStrategies             []*CloneStrategy     `json:"cloneStrategies,omitempty"`
 	GitURLType                  string               `json:"gitURLType,omitempty"`
 	InitialRepositoryEnablement bool                 `json:"initialRepositoryEnablement,omitempty"`
 	Repos                       []string             `json:"repos,omitempty"`
@@ -165,6 +207,14 @@ type GitHubConnection struct {
 	RepositoryQuery             []string             `json:"repositoryQuery,omitempty"`
 	Token                       string               `json:"token"`
 	Url                         string               `json:"url"`
+	WebhookSecret               string               `json:"webhookSecret,omitempty"`
+}
+
+// GitLFS description: Configures fetching Git LFS objects.
+type GitLFS struct {
+	Enabled     bool   `json:"enabled,omitempty"`
+	MaxFileSize int    `json:"maxFileSize,omitempty"`
+	Url         string `json:"url,omitempty"`
 }
 
 // GitLabAuthProvider description: Configures the GitLab OAuth authentication provider for SSO. In addition to specifying this configuration object, you must also create a OAuth App on your GitLab instance: https://docs.gitlab.com/ee/integration/oauth_provider.html. The application should have `api` and `read_user` scopes and the callback URL set to the concatenation of your Sourcegraph instance URL and "/.auth/gitlab/callback".
@@ -183,12 +233,26 @@ type GitLabAuthorization struct {
 type GitLabConnection struct {
 	Authorization               *GitLabAuthorization `json:"authorization,omitempty"`
 	Certificate                 string               `json:"certificate,omitempty"`
+	CloneStrategies             []*CloneStrategy     `json:"cloneStrategies,omitempty"`
 	GitURLType                  string               `json:"gitURLType,omitempty"`
 	InitialRepositoryEnablement bool                 `json:"initialRepositoryEnablement,omitempty"`
 	ProjectQuery                []string             `json:"projectQuery,omitempty"`
 	RepositoryPathPattern       string               `json:"repositoryPathPattern,omitempty"`
 	Token                       string               `json:"token"`
 	Url                         string               `json:"url"`
+	WebhookSecret               string               `json:"webhookSecret,omitempty"`
+}
+
+// GiteaConnection description: Configuration for a connection to a Gitea instance. Gogs instances are also supported.
+type GiteaConnection struct {
+	Certificate                 string   `json:"certificate,omitempty"`
+	GitURLType                  string   `json:"gitURLType,omitempty"`
+	InitialRepositoryEnablement bool     `json:"initialRepositoryEnablement,omitempty"`
+	RepositoryPathPattern       string   `json:"repositoryPathPattern,omitempty"`
+	RepositoryQuery             []string `json:"repositoryQuery,omitempty"`
+	Repos                       []string `json:"repos,omitempty"`
+	Token                       string   `json:"token,omitempty"`
+	Url                         string   `json:"url"`
 }
 type GitoliteConnection struct {
 	Blacklist                  string       `json:"blacklist,omitempty"`
@@ -331,11 +395,14 @@ type SiteConfiguration struct {
 	ExperimentalFeatures              *ExperimentalFeatures       `json:"experimentalFeatures,omitempty"`
 	Extensions                        *Extensions                 `json:"extensions,omitempty"`
 	GitCloneURLToRepositoryName       []*CloneURLToRepositoryName `json:"git.cloneURLToRepositoryName,omitempty"`
+	GitLfs                            *GitLFS                     `json:"git.lfs,omitempty"`
 	GitMaxConcurrentClones            int                         `json:"gitMaxConcurrentClones,omitempty"`
+	GitMaxConcurrentClonesPerHost     int                         `json:"gitMaxConcurrentClonesPerHost,omitempty"`
 	GithubClientID                    string                      `json:"githubClientID,omitempty"`
 	GithubClientSecret                string                      `json:"githubClientSecret,omitempty"`
 	MaxReposToSearch                  int                         `json:"maxReposToSearch,omitempty"`
 	ParentSourcegraph                 *ParentSourcegraph          `json:"parentSourcegraph,omitempty"`
+	PermissionsSyncInterval           int                         `json:"permissionsSyncInterval,omitempty"`
 	RepoListUpdateInterval            int                         `json:"repoListUpdateInterval,omitempty"`
 	ReviewBoard                       []*ReviewBoard              `json:"reviewBoard,omitempty"`
 	SearchIndexEnabled                *bool                       `json:"search.index.enabled,omitempty"`
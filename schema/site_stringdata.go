@@ -37,6 +37,13 @@ const SiteSchemaJSON = `{
           "enum": ["enabled", "disabled"],
           "default": "disabled"
         },
+        "permissionsBackgroundSync": {
+          "description":
+            "Enables syncing the repository permissions of users from the authorization providers in the background (in repo-updater) and storing them in the database, so that permissions checks do not need to query the code hosts.",
+          "type": "string",
+          "enum": ["enabled", "disabled"],
+          "default": "disabled"
+        },
         "updateScheduler2": {
           "description": "Enables a new update scheduler algorithm",
           "type": "string",
@@ -85,6 +92,16 @@ const SiteSchemaJSON = `{
       "type": "integer",
       "default": 5
     },
+    "git.lfs": {
+      "description":
+        "Configures fetching Git LFS objects, so that searches and file views use the content of files stored with Git LFS instead of their LFS pointer files.",
+      "$ref": "#/definitions/GitLFS"
+    },
+    "gitMaxConcurrentClonesPerHost": {
+      "description": "Maximum number of git clone processes that will be run concurrently to update the repositories of a single code host connection (external service), so that a slow or rate-limited code host doesn't use all of the gitMaxConcurrentClones processes. By default, it is only limited by gitMaxConcurrentClones.",
+      "type": "integer",
+      "minimum": 1
+    },
     "reviewBoard": {
       "description": "JSON array of configuration for Review Board.",
       "type": "array",
@@ -105,6 +122,13 @@ const SiteSchemaJSON = `{
       "type": "integer",
       "default": 1
     },
+    "permissionsSyncInterval": {
+      "description":
+        "Interval (in minutes) for syncing the repository permissions of each user from the authorization providers when the permissionsBackgroundSync experiment is enabled. Synced permissions that are more than twice as old are not used (the authorization providers are asked instead).",
+      "type": "integer",
+      "minimum": 1,
+      "default": 60
+    },
     "maxReposToSearch": {
       "description":
         "The maximum number of repositories to search across. The user is prompted to narrow their query if exceeded. The value -1 means unlimited.",
@@ -270,6 +294,12 @@ const SiteSchemaJSON = `{
           "type": "string",
           "minLength": 1
         },
+        "webhookSecret": {
+          "description":
+            "The secret used to verify the push event webhook requests that GitHub sends to Sourcegraph, which trigger an immediate update of the pushed repository. It is the \"Secret\" of the webhook (with the \"push\" event and the \"application/json\" content type) that GitHub uses to sign its requests in the X-Hub-Signature header.\n\nThe webhook URL is https://sourcegraph.example.com/.api/webhooks/{id}, where {id} is the ID of this external service (shown by the webhookURL field of the ExternalService GraphQL type). If empty, webhook requests for this external service are rejected.",
+          "type": "string",
+          "minLength": 1
+        },
         "certificate": {
           "description": "TLS certificate of a GitHub Enterprise instance. To get the certificate run ` + "`" + `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM` + "`" + `",
           "type": "string",
@@ -294,6 +324,12 @@ const SiteSchemaJSON = `{
           "type": "string",
           "default": "{host}/{nameWithOwner}"
         },
+        "cloneStrategies": {
+          "description":
+            "Per-repository clone strategies for large repositories. Each repository uses the first strategy whose ` + "`" + `repos` + "`" + ` list contains it; repositories that match no strategy are fully cloned. A strategy only takes effect when the repository is (re)cloned.",
+          "type": "array",
+          "items": { "$ref": "#/definitions/CloneStrategy" }
+        },
         "initialRepositoryEnablement": {
           "description":
             "Defines whether repositories from this GitHub instance should be enabled and cloned when they are first seen by Sourcegraph. If false, the site admin must explicitly enable GitHub repositories (in the site admin area) to clone them and make them searchable on Sourcegraph. If true, they will be enabled and cloned immediately (subject to rate limiting by GitHub); site admins can still disable them explicitly, and they'll remain disabled.",
@@ -337,6 +373,12 @@ const SiteSchemaJSON = `{
           "type": "string",
           "minLength": 1
         },
+        "webhookSecret": {
+          "description":
+            "The secret used to verify the push event webhook requests that GitLab sends to Sourcegraph, which trigger an immediate update of the pushed repository. It is the \"Secret Token\" of the webhook (with the \"Push events\" and \"Tag push events\" triggers) that GitLab sends in the X-Gitlab-Token header.\n\nThe webhook URL is https://sourcegraph.example.com/.api/webhooks/{id}, where {id} is the ID of this external service (shown by the webhookURL field of the ExternalService GraphQL type). If empty, webhook requests for this external service are rejected.",
+          "type": "string",
+          "minLength": 1
+        },
         "gitURLType": {
           "description":
             "The type of Git URLs to use for cloning and fetching Git repositories on this GitLab instance.\n\nIf \"http\", Sourcegraph will access GitLab repositories using Git URLs of the form http(s)://gitlab.example.com/myteam/myproject.git (using https: if the GitLab instance uses HTTPS).\n\nIf \"ssh\", Sourcegraph will access GitLab repositories using Git URLs of the form git@example.gitlab.com:myteam/myproject.git. See the documentation for how to provide SSH private keys and known_hosts: https://docs.sourcegraph.com/admin/repo/auth#repositories-that-need-http-s-or-ssh-authentication.",
@@ -364,6 +406,12 @@ const SiteSchemaJSON = `{
           "type": "string",
           "default": "{host}/{pathWithNamespace}"
         },
+        "cloneStrategies": {
+          "description":
+            "Per-repository clone strategies for large repositories. Each repository uses the first strategy whose ` + "`" + `repos` + "`" + ` list contains it; repositories that match no strategy are fully cloned. A strategy only takes effect when the repository is (re)cloned.",
+          "type": "array",
+          "items": { "$ref": "#/definitions/CloneStrategy" }
+        },
         "initialRepositoryEnablement": {
           "description":
             "Defines whether repositories from this GitLab instance should be enabled and cloned when they are first seen by Sourcegraph. If false, the site admin must explicitly enable GitLab repositories (in the site admin area) to clone them and make them searchable on Sourcegraph. If true, they will be enabled and cloned immediately (subject to rate limiting by GitLab); site admins can still disable them explicitly, and they'll remain disabled.",
@@ -386,6 +434,61 @@ const SiteSchemaJSON = `{
         }
       }
     },
+    "BitbucketCloudConnection": {
+      "description": "Configuration for a connection to Bitbucket Cloud (bitbucket.org).",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["username", "appPassword"],
+      "properties": {
+        "url": {
+          "description": "URL of Bitbucket Cloud. It is used as the external service ID of its repositories, and its host is the {host} of repositoryPathPattern.",
+          "type": "string",
+          "pattern": "^https?://",
+          "format": "uri",
+          "default": "https://bitbucket.org",
+          "examples": ["https://bitbucket.org"]
+        },
+        "username": {
+          "description": "The username of the Bitbucket Cloud account whose app password is used to access the Bitbucket Cloud API and to clone repositories.",
+          "type": "string",
+          "minLength": 1
+        },
+        "appPassword": {
+          "description":
+            "An app password of the Bitbucket Cloud account with the \"Repositories: Read\" and \"Account: Read\" permissions. Create one at https://bitbucket.org/account/settings/app-passwords/.",
+          "type": "string",
+          "minLength": 1
+        },
+        "gitURLType": {
+          "description":
+            "The type of Git URLs to use for cloning and fetching Git repositories on Bitbucket Cloud.\n\nIf \"http\", Sourcegraph will access Bitbucket Cloud repositories using Git URLs of the form https://bitbucket.org/myteam/myrepo.git, with the username and app password inserted in the URL of private repositories.\n\nIf \"ssh\", Sourcegraph will access Bitbucket Cloud repositories using Git URLs of the form git@bitbucket.org:myteam/myrepo.git. See the documentation for how to provide SSH private keys and known_hosts: https://docs.sourcegraph.com/admin/repo/auth#repositories-that-need-http-s-or-ssh-authentication.",
+          "type": "string",
+          "enum": ["http", "ssh"],
+          "default": "http"
+        },
+        "teams": {
+          "description":
+            "An array of the names of the workspaces (user or team accounts) whose repositories are mirrored on Sourcegraph.\n\nIf empty, the repositories of the account of \"username\" and of all the workspaces that it is a member of are mirrored.",
+          "type": "array",
+          "items": {
+            "type": "string",
+            "minLength": 1
+          },
+          "examples": [["myteam", "myuser"]]
+        },
+        "repositoryPathPattern": {
+          "description":
+            "The pattern used to generate the corresponding Sourcegraph repository name for a Bitbucket Cloud repository. In the pattern, the variable \"{host}\" is replaced with the Bitbucket Cloud URL's host (bitbucket.org), and \"{nameWithOwner}\" is replaced with the Bitbucket Cloud repository's \"workspace/slug\" (such as \"myteam/myrepo\").\n\nFor example, if your Sourcegraph is https://src.example.com, then a repositoryPathPattern of \"{host}/{nameWithOwner}\" would mean that a Bitbucket Cloud repository at https://bitbucket.org/myteam/myrepo is available on Sourcegraph at https://src.example.com/bitbucket.org/myteam/myrepo.\n\nIt is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.",
+          "type": "string",
+          "default": "{host}/{nameWithOwner}"
+        },
+        "initialRepositoryEnablement": {
+          "description":
+            "Defines whether repositories from Bitbucket Cloud should be enabled and cloned when they are first seen by Sourcegraph. If false, the site admin must explicitly enable Bitbucket Cloud repositories (in the site admin area) to clone them and make them searchable on Sourcegraph. If true, they will be enabled and cloned immediately (subject to rate limiting by Bitbucket Cloud); site admins can still disable them explicitly, and they'll remain disabled.",
+          "type": "boolean"
+        }
+      }
+    },
     "BitbucketServerConnection": {
       "type": "object",
       "additionalProperties": false,
@@ -417,6 +520,12 @@ const SiteSchemaJSON = `{
           "format": "uri",
           "examples": ["https://bitbucket.example.com"]
         },
+        "webhookSecret": {
+          "description":
+            "The secret used to verify the push event webhook requests that Bitbucket Server sends to Sourcegraph, which trigger an immediate update of the pushed repository. It is the \"Secret\" of the webhook (with the \"Repository: Push\" event) that Bitbucket Server uses to sign its requests in the X-Hub-Signature header.\n\nThe webhook URL is https://sourcegraph.example.com/.api/webhooks/{id}, where {id} is the ID of this external service (shown by the webhookURL field of the ExternalService GraphQL type). If empty, webhook requests for this external service are rejected.",
+          "type": "string",
+          "minLength": 1
+        },
         "token": {
           "description":
             "A Bitbucket Server personal access token with Read scope. Create one at https://[your-bitbucket-hostname]/plugins/servlet/access-tokens/add.\n\nFor Bitbucket Server instances that don't support personal access tokens (Bitbucket Server version 5.4 and older), specify user-password credentials in the \"username\" and \"password\" fields.",
@@ -456,10 +565,142 @@ const SiteSchemaJSON = `{
             "Whether or not personal repositories should be excluded or not. When true, Sourcegraph will ignore personal repositories it may have access to. See https://docs.sourcegraph.com/integration/bitbucket_server#excluding-personal-repositories for more information. Default: false.",
           "type": "boolean"
         },
+        "cloneStrategies": {
+          "description":
+            "Per-repository clone strategies for large repositories. Each repository uses the first strategy whose ` + "`" + `repos` + "`" + ` list contains it; repositories that match no strategy are fully cloned. A strategy only takes effect when the repository is (re)cloned.",
+          "type": "array",
+          "items": { "$ref": "#/definitions/CloneStrategy" }
+        },
         "initialRepositoryEnablement": {
           "description":
             "Defines whether repositories from this Bitbucket Server instance should be enabled and cloned when they are first seen by Sourcegraph. If false, the site admin must explicitly enable Bitbucket Server repositories (in the site admin area) to clone them and make them searchable on Sourcegraph. If true, they will be enabled and cloned immediately (subject to rate limiting by Bitbucket Server); site admins can still disable them explicitly, and they'll remain disabled.",
           "type": "boolean"
+        },
+        "authorization": { "$ref": "#/definitions/BitbucketServerAuthorization" }
+      }
+    },
+    "BitbucketServerAuthorization": {
+      "description":
+        "If non-null, enforces Bitbucket Server repository permissions. Each Sourcegraph user is matched with a Bitbucket Server user (see identityProvider), and can only access the repositories which that Bitbucket Server user can read. Users without a matching Bitbucket Server user can only access public repositories.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["oauth", "identityProvider"],
+      "properties": {
+        "oauth": { "$ref": "#/definitions/BitbucketServerOAuth" },
+        "identityProvider": { "$ref": "#/definitions/BitbucketServerIdentityProvider" },
+        "ttl": {
+          "description":
+            "The TTL of how long to cache permissions data. This is 3 hours by default.\n\nDecreasing the TTL will increase the load on the code host API. If you have X repos on your instance, it will take ~X/1000 API requests to fetch the complete list for 1 user.  If you have Y users, you will incur X*Y/1000 API requests per cache refresh period.\n\nIf set to zero, Sourcegraph will sync a user's entire accessible repository list on every request (NOT recommended).",
+          "type": "string",
+          "default": "3h"
+        }
+      }
+    },
+    "BitbucketServerIdentityProvider": {
+      "description": "How each Sourcegraph user is matched with a Bitbucket Server user.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type"],
+      "properties": {
+        "type": {
+          "description":
+            "If \"external\", a Sourcegraph user is matched with the Bitbucket Server user whose username is the account ID of the user's external account from the auth provider given by authProviderType and authProviderID (such as the username set by an HTTP authentication proxy, or a SAML NameID). Users who signed up otherwise are not matched.\n\nIf \"username\", a Sourcegraph user is matched with the Bitbucket Server user with the same username. WARNING: Only use this if users can't choose their Sourcegraph usernames (eg with builtin sign-up and username changes disabled). Otherwise anyone can sign up with the username of a Bitbucket Server user and read that user's repositories.",
+          "type": "string",
+          "enum": ["external", "username"]
+        },
+        "authProviderType": {
+          "description": "The type of the auth provider (in auth.providers) whose external accounts are matched with Bitbucket Server users, such as \"http-header\", \"saml\" or \"openidconnect\". Required if type is \"external\".",
+          "type": "string"
+        },
+        "authProviderID": {
+          "description": "The ID of the auth provider whose external accounts are matched with Bitbucket Server users: the issuer of a SAML identity provider or OpenID Connect provider, or empty for an HTTP authentication proxy.",
+          "type": "string"
+        }
+      }
+    },
+    "BitbucketServerOAuth": {
+      "description":
+        "OAuth configuration of an application link in Bitbucket Server (with an incoming link that allows user impersonation), used to list the repositories that each user can read. See https://confluence.atlassian.com/bitbucketserver/linking-bitbucket-server-with-jira-776640408.html.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["consumerKey", "signingKey"],
+      "properties": {
+        "consumerKey": {
+          "description": "The OAuth consumer key of the application link.",
+          "type": "string",
+          "minLength": 1
+        },
+        "signingKey": {
+          "description":
+            "The PEM encoded RSA private key used to sign OAuth requests. Its public key must be set as the public key of the application link.",
+          "type": "string",
+          "pattern": "^-----BEGIN (RSA )?PRIVATE KEY-----\n"
+        }
+      }
+    },
+    "GiteaConnection": {
+      "description": "Configuration for a connection to a Gitea instance. Gogs instances are also supported.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["url"],
+      "properties": {
+        "url": {
+          "description": "URL of a Gitea (or Gogs) instance, such as https://gitea.example.com.",
+          "type": "string",
+          "pattern": "^https?://",
+          "not": {
+            "type": "string",
+            "pattern": "example\\.com"
+          },
+          "format": "uri",
+          "examples": ["https://gitea.example.com"]
+        },
+        "token": {
+          "description":
+            "A Gitea access token, created at https://[your-gitea-hostname]/user/settings/applications. It is used to list and clone the repositories that its user can access. If empty, only public repositories are accessible.",
+          "type": "string"
+        },
+        "gitURLType": {
+          "description":
+            "The type of Git URLs to use for cloning and fetching Git repositories on this Gitea instance.\n\nIf \"http\", Sourcegraph will access Gitea repositories using Git URLs of the form http(s)://gitea.example.com/myorg/myrepo.git (using https: if the Gitea instance uses HTTPS), with the token inserted in the URL.\n\nIf \"ssh\", Sourcegraph will access Gitea repositories using Git URLs of the form git@gitea.example.com:myorg/myrepo.git. See the documentation for how to provide SSH private keys and known_hosts: https://docs.sourcegraph.com/admin/repo/auth#repositories-that-need-http-s-or-ssh-authentication.",
+          "type": "string",
+          "enum": ["http", "ssh"],
+          "default": "http"
+        },
+        "certificate": {
+          "description": "TLS certificate of a Gitea instance. To get the certificate run ` + "`" + `openssl s_client -connect HOST:443 -showcerts < /dev/null 2> /dev/null | openssl x509 -outform PEM` + "`" + `",
+          "type": "string",
+          "pattern": "^-----BEGIN CERTIFICATE-----\n"
+        },
+        "repositoryQuery": {
+          "description":
+            "An array of strings specifying which Gitea repositories to mirror on Sourcegraph. The valid values are:\n\n- \"affiliated\" mirrors the repositories that the token's user owns, collaborates on or can access through an organization.\n\n- \"none\" disables this feature (it must be the only element).\n\n- A URL query string for the Gitea repository search API, such as \"?q=foo\" or \"?uid=42\", mirrors the matching repositories. See https://try.gitea.io/api/swagger#/repository/repoSearch for the available parameters.\n\nRepositories matched by multiple query strings are only imported once.",
+          "type": "array",
+          "default": ["affiliated"],
+          "items": {
+            "type": "string"
+          }
+        },
+        "repos": {
+          "description":
+            "An array of repository \"owner/name\" strings specifying which Gitea repositories to mirror on Sourcegraph, in addition to those matched by repositoryQuery.",
+          "type": "array",
+          "items": {
+            "type": "string",
+            "pattern": "^[\\w.-]+/[\\w.-]+$"
+          },
+          "examples": [["myorg/myrepo", "myuser/myproject"]]
+        },
+        "repositoryPathPattern": {
+          "description":
+            "The pattern used to generate the corresponding Sourcegraph repository name for a Gitea repository. In the pattern, the variable \"{host}\" is replaced with the Gitea URL's host (such as gitea.example.com), and \"{nameWithOwner}\" is replaced with the Gitea repository's \"owner/name\" (such as \"myorg/myrepo\").\n\nFor example, if your Gitea is https://gitea.example.com and your Sourcegraph is https://src.example.com, then a repositoryPathPattern of \"{host}/{nameWithOwner}\" would mean that a Gitea repository at https://gitea.example.com/myorg/myrepo is available on Sourcegraph at https://src.example.com/gitea.example.com/myorg/myrepo.\n\nIt is important that the Sourcegraph repository name generated with this pattern be unique to this code host. If different code hosts generate repository names that collide, Sourcegraph's behavior is undefined.",
+          "type": "string",
+          "default": "{host}/{nameWithOwner}"
+        },
+        "initialRepositoryEnablement": {
+          "description":
+            "Defines whether repositories from this Gitea instance should be enabled and cloned when they are first seen by Sourcegraph. If false, the site admin must explicitly enable Gitea repositories (in the site admin area) to clone them and make them searchable on Sourcegraph. If true, they will be enabled and cloned immediately; site admins can still disable them explicitly, and they'll remain disabled.",
+          "type": "boolean"
         }
       }
     },
@@ -604,6 +845,58 @@ const SiteSchemaJSON = `{
         }
       }
     },
+    "CloneStrategy": {
+      "description":
+        "Describes how gitserver clones and fetches a set of repositories. Shallow and partial clones use less disk and clone faster; gitserver fetches missing history or objects on demand when a request needs them.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["repos"],
+      "properties": {
+        "repos": {
+          "description":
+            "The repositories to which this strategy applies, matched case-insensitively against the code host's repository path (\"owner/name\" on GitHub, \"namespace/project\" on GitLab, or \"projectKey/repositorySlug\" on Bitbucket Server).",
+          "type": "array",
+          "items": { "type": "string" },
+          "minItems": 1
+        },
+        "depth": {
+          "description":
+            "If set, the repository is cloned and fetched with this history depth (git clone --depth). The full history is fetched the first time a request needs it (such as a commit log or blame).",
+          "type": "integer",
+          "minimum": 1
+        },
+        "filter": {
+          "description":
+            "If set, the repository is cloned as a partial clone with this object filter (git clone --filter), such as \"blob:none\" or \"blob:limit=1m\". Missing objects are fetched from the code host on demand. Requires a code host that supports partial clone.",
+          "type": "string",
+          "pattern": "^(blob:none|blob:limit=\\d+[kmg]?|tree:\\d+)$"
+        }
+      }
+    },
+    "GitLFS": {
+      "description": "Configures fetching Git LFS objects.",
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "enabled": {
+          "description": "Whether gitserver fetches the Git LFS objects of files stored with Git LFS when their content is needed.",
+          "type": "boolean",
+          "default": false
+        },
+        "url": {
+          "description":
+            "The URL of the Git LFS server to fetch objects from. If not set, the LFS endpoint of each repository is used: the lfs.url of the repository's .lfsconfig file, or else the repository's Git remote URL followed by \"/info/lfs\" (as git-lfs does). Only HTTP(S) endpoints are supported.",
+          "type": "string",
+          "pattern": "^https?://"
+        },
+        "maxFileSize": {
+          "description":
+            "The size (in bytes) of the largest Git LFS object that is fetched. The pointer files of larger objects are used instead, so that huge binaries are excluded. Search also skips the content of files larger than its own size limit. Default: 10485760 (10 MB).",
+          "type": "integer",
+          "minimum": 0
+        }
+      }
+    },
     "CloneURLToRepositoryName": {
       "description":
         "Describes a mapping from clone URL to repository name. The ` + "`" + `from` + "`" + ` field contains a regular expression with named capturing groups. The ` + "`" + `to` + "`" + ` field contains a template string that references capturing group names. For instance, if ` + "`" + `from` + "`" + ` is \"^../(?P<name>\\w+)$\" and ` + "`" + `to` + "`" + ` is \"github.com/user/{name}\", the clone URL \"../myRepository\" would be mapped to the repository name \"github.com/user/myRepository\".",
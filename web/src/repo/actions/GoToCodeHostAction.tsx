@@ -110,10 +110,14 @@ export class GoToCodeHostAction extends React.PureComponent<Props, State> {
 
         // Extract url to add branch, line numbers or commit range.
         let url = externalURL.url
-        if (externalURL.serviceType === 'github' || externalURL.serviceType === 'gitlab') {
+        if (
+            externalURL.serviceType === 'github' ||
+            externalURL.serviceType === 'gitlab' ||
+            externalURL.serviceType === 'gitea'
+        ) {
             // If in a branch, add branch path to the code host URL.
             if (this.props.rev && this.props.rev !== defaultBranch && !this.state.fileExternalLinksOrError) {
-                url += `/tree/${this.props.rev}`
+                url += revPath(externalURL.serviceType, this.props.rev)
             }
             // If showing a comparison, add comparison specifier to the code host URL.
             if (this.props.commitRange) {
@@ -135,6 +139,18 @@ export class GoToCodeHostAction extends React.PureComponent<Props, State> {
     }
 }
 
+/**
+ * Returns the path of the tree at the given revision, relative to the repository URL on the code host.
+ */
+function revPath(serviceType: string, rev: string): string {
+    if (serviceType === 'gitea') {
+        // Gitea has no path that accepts any revision, so assume that revisions other than commit IDs are
+        // branches.
+        return /^[0-9a-f]{40}$/i.test(rev) ? `/src/commit/${rev}` : `/src/branch/${rev}`
+    }
+    return `/tree/${rev}`
+}
+
 function serviceTypeDisplayNameAndIcon(
     serviceType: string | null
 ): { displayName: string; icon?: React.ComponentType<{ className?: string }> } {
@@ -149,6 +165,8 @@ function serviceTypeDisplayNameAndIcon(
             return { displayName: 'Phabricator', icon: PhabricatorIcon }
         case 'awscodecommit':
             return { displayName: 'AWS CodeCommit' }
+        case 'gitea':
+            return { displayName: 'Gitea' }
     }
     return { displayName: serviceType ? upperFirst(serviceType) : 'code host' }
 }
@@ -80,6 +80,14 @@ class UpdateMirrorRepositoryActionContainer extends React.PureComponent<UpdateMi
                             {updateSchedule.index + 1} out of {updateSchedule.total} in the schedule)
                         </div>
                     )}
+                    {updateSchedule &&
+                        updateSchedule.lastError &&
+                        updateSchedule.lastErrorAt && (
+                            <div>
+                                Last update failed <Timestamp date={updateSchedule.lastErrorAt} />:{' '}
+                                <code>{updateSchedule.lastError}</code>
+                            </div>
+                        )}
                     {this.props.repo.mirrorInfo.updateQueue && !this.props.repo.mirrorInfo.updateQueue.updating && (
                         <div>
                             Queued for update (position {this.props.repo.mirrorInfo.updateQueue.index + 1} out of{' '}
@@ -117,6 +117,29 @@ export function search(
                                             }
                                         }
                                     }
+                                    ... on FileDiffSearchResult {
+                                        __typename
+                                        label {
+                                            html
+                                        }
+                                        url
+                                        icon
+                                        detail {
+                                            html
+                                        }
+                                        matches {
+                                            url
+                                            body {
+                                                text
+                                                html
+                                            }
+                                            highlights {
+                                                line
+                                                character
+                                                length
+                                            }
+                                        }
+                                    }
                                 }
                                 alert {
                                     title
@@ -104,8 +104,12 @@ function getKindDefinitionId(kind: GQL.ExternalServiceKind): string {
     switch (kind) {
         case GQL.ExternalServiceKind.AWSCODECOMMIT:
             return 'AWSCodeCommitConnection'
+        case GQL.ExternalServiceKind.BITBUCKETCLOUD:
+            return 'BitbucketCloudConnection'
         case GQL.ExternalServiceKind.BITBUCKETSERVER:
             return 'BitbucketServerConnection'
+        case GQL.ExternalServiceKind.GITEA:
+            return 'GiteaConnection'
         case GQL.ExternalServiceKind.GITHUB:
             return 'GitHubConnection'
         case GQL.ExternalServiceKind.GITLAB: